@@ -0,0 +1,202 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2018-present Datadog, Inc.
+
+package dogstatsd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	cconfig "github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	replay "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/impl"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+type captureFlags struct {
+	path       string
+	output     string
+	prefixes   []string
+	pids       []int32
+	nmetrics   int
+	compressed bool
+}
+
+func (f *captureFlags) filter() replay.MessageFilter {
+	return replay.MessageFilter{Prefixes: f.prefixes, Pids: f.pids}
+}
+
+// captureCommand returns the "capture" sub-command tree, inspecting the captures
+// written by dogstatsd-capture.
+func captureCommand(globalParams *command.GlobalParams) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "capture",
+		Short: "Inspect, filter and convert dogstatsd traffic captures",
+	}
+
+	flags := captureFlags{}
+	oneShot := func(fct interface{}) func(*cobra.Command, []string) error {
+		return func(_ *cobra.Command, _ []string) error {
+			return fxutil.OneShot(fct,
+				fx.Supply(&flags),
+				fx.Supply(core.BundleParams{
+					ConfigParams: cconfig.NewAgentParams(globalParams.ConfFilePath, cconfig.WithExtraConfFiles(globalParams.ExtraConfFilePath), cconfig.WithFleetPoliciesDirPath(globalParams.FleetPoliciesDirPath)),
+					LogParams:    log.ForOneShot(command.LoggerName, "off", true)}),
+				core.Bundle(),
+			)
+		}
+	}
+
+	c.PersistentFlags().StringVarP(&flags.path, "file", "f", "", "capture file written by dogstatsd-capture")
+	c.PersistentFlags().StringSliceVar(&flags.prefixes, "prefix", nil, "only consider the metrics and service checks whose name starts with one of these prefixes")
+	c.PersistentFlags().Int32SliceVar(&flags.pids, "pid", nil, "only consider the messages sent by these PIDs")
+	_ = c.MarkPersistentFlagRequired("file")
+
+	c.AddCommand(&cobra.Command{
+		Use:   "contexts",
+		Short: "List the metric contexts of a capture",
+		RunE:  oneShot(captureContexts),
+	})
+
+	topCmd := &cobra.Command{
+		Use:   "top",
+		Short: "Display the metrics with the highest rate in a capture",
+		RunE:  oneShot(captureTop),
+	}
+	topCmd.Flags().IntVarP(&flags.nmetrics, "num-metrics", "m", 10, "number of metrics to show")
+	c.AddCommand(topCmd)
+
+	filterCmd := &cobra.Command{
+		Use:   "filter",
+		Short: "Write the messages of a capture matching --prefix and --pid to a new capture",
+		RunE:  oneShot(captureFilter),
+	}
+	filterCmd.Flags().StringVarP(&flags.output, "output", "o", "", "path of the new capture")
+	filterCmd.Flags().BoolVarP(&flags.compressed, "compressed", "z", true, "should the new capture be zstd compressed")
+	_ = filterCmd.MarkFlagRequired("output")
+	c.AddCommand(filterCmd)
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the messages of a capture as plain-text statsd lines",
+		RunE:  oneShot(captureExport),
+	}
+	exportCmd.Flags().StringVarP(&flags.output, "output", "o", "", "path of the output file, the standard output is used when empty")
+	c.AddCommand(exportCmd)
+
+	return c
+}
+
+func openCapture(flags *captureFlags) (*replay.TrafficCaptureReader, error) {
+	reader, err := replay.NewTrafficCaptureReader(flags.path, 0, false)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %w", flags.path, err)
+	}
+	return reader, nil
+}
+
+func captureContexts(flags *captureFlags, _ log.Component) error {
+	reader, err := openCapture(flags)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	summary, err := replay.SummarizeCapture(reader, flags.filter())
+	if err != nil {
+		return err
+	}
+
+	printContexts(os.Stdout, summary)
+	return nil
+}
+
+func printContexts(w io.Writer, summary *replay.CaptureSummary) {
+	fmt.Fprintf(w, " % 10s\t%s\t%s\t%s\n", "Messages", "Type", "Metric name", "Tags")
+	for _, c := range summary.Contexts {
+		fmt.Fprintf(w, " % 10d\t%s\t%s\t%s\n", c.Messages, c.Type, c.Name, strings.Join(c.Tags, ","))
+	}
+	fmt.Fprintf(w, "\n%d contexts, %d messages (%d events, %d service checks) in %d packets over %s\n",
+		len(summary.Contexts), summary.Messages, summary.Events, summary.ServiceChecks, summary.Packets, summary.Duration)
+}
+
+func captureTop(flags *captureFlags, _ log.Component) error {
+	reader, err := openCapture(flags)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	summary, err := replay.SummarizeCapture(reader, flags.filter())
+	if err != nil {
+		return err
+	}
+
+	printTopMetrics(os.Stdout, summary.TopMetrics(flags.nmetrics))
+	return nil
+}
+
+func printTopMetrics(w io.Writer, rates []replay.MetricRate) {
+	fmt.Fprintf(w, " % 10s\t% 10s\t% 10s\t%s\n", "Rate (/s)", "Messages", "Contexts", "Metric name")
+	for _, m := range rates {
+		fmt.Fprintf(w, " % 10.2f\t% 10d\t% 10d\t%s\n", m.Rate, m.Messages, m.Contexts, m.Name)
+	}
+}
+
+func captureFilter(flags *captureFlags, _ log.Component) error {
+	reader, err := openCapture(flags)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	f, err := os.OpenFile(flags.output, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	count, err := replay.FilterCapture(reader, f, flags.filter(), flags.compressed)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d messages to %s\n", count, flags.output)
+	return f.Close()
+}
+
+func captureExport(flags *captureFlags, _ log.Component) error {
+	reader, err := openCapture(flags)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if flags.output == "" {
+		_, err = replay.ExportCapture(reader, os.Stdout, flags.filter())
+		return err
+	}
+
+	f, err := os.OpenFile(flags.output, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	count, err := replay.ExportCapture(reader, f, flags.filter())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %d messages to %s\n", count, flags.output)
+	return f.Close()
+}
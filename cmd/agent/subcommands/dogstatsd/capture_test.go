@@ -0,0 +1,78 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dogstatsd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	replay "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/impl"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestCaptureCommands(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd", "capture", "contexts", "-f", "capture.dog", "--prefix", "api.,db."},
+		captureContexts,
+		func(f *captureFlags) {
+			assert.Equal(t, "capture.dog", f.path)
+			assert.Equal(t, replay.MessageFilter{Prefixes: []string{"api.", "db."}}, f.filter())
+		})
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd", "capture", "top", "-f", "capture.dog", "-m", "3", "--pid", "12", "--pid", "34"},
+		captureTop,
+		func(f *captureFlags) {
+			assert.Equal(t, 3, f.nmetrics)
+			assert.Equal(t, []int32{12, 34}, f.pids)
+		})
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd", "capture", "filter", "-f", "capture.dog", "-o", "small.dog", "-z=false"},
+		captureFilter,
+		func(f *captureFlags) {
+			assert.Equal(t, "small.dog", f.output)
+			assert.False(t, f.compressed)
+		})
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd", "capture", "export", "-f", "capture.dog"},
+		captureExport,
+		func(f *captureFlags) {
+			assert.Equal(t, "", f.output)
+		})
+}
+
+func TestPrintCaptureSummary(t *testing.T) {
+	summary := &replay.CaptureSummary{
+		Packets:  2,
+		Messages: 4,
+		Events:   1,
+		Duration: 2 * time.Second,
+		Contexts: []*replay.CaptureContext{
+			{Name: "api.requests", Type: "c", Tags: []string{"code:200", "env:prod"}, Messages: 3},
+		},
+	}
+
+	var buf bytes.Buffer
+	printContexts(&buf, summary)
+	assert.Equal(t, `   Messages	Type	Metric name	Tags
+          3	c	api.requests	code:200,env:prod
+
+1 contexts, 4 messages (1 events, 0 service checks) in 2 packets over 2s
+`, buf.String())
+
+	buf.Reset()
+	printTopMetrics(&buf, summary.TopMetrics(10))
+	assert.Equal(t, `  Rate (/s)	  Messages	  Contexts	Metric name
+       1.50	         3	         1	api.requests
+`, buf.String())
+}
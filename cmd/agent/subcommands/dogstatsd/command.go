@@ -76,6 +76,8 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 		},
 	})
 
+	c.AddCommand(captureCommand(globalParams))
+
 	return []*cobra.Command{c}
 }
 
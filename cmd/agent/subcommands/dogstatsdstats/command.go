@@ -12,6 +12,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"go.uber.org/fx"
 
@@ -19,7 +20,9 @@ import (
 	"github.com/DataDog/datadog-agent/comp/core"
 	"github.com/DataDog/datadog-agent/comp/core/config"
 	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/serverDebug/serverdebugimpl"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/api/util"
 	pkgconfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
@@ -115,6 +118,22 @@ func requestDogstatsdStats(_ log.Component, config config.Component, cliParams *
 			fmt.Printf("Could not format the statistics, the data must be inconsistent. You may want to try the JSON output. Contact the support if you continue having issues.\n")
 			return nil
 		}
+
+		// the agents prior to the context budgets don't expose the cardinality offenders
+		offendersURL := fmt.Sprintf("https://%v:%v/agent/dogstatsd-cardinality", ipcAddress, pkgconfig.Datadog().GetInt("cmd_port"))
+		if r, err := util.DoGet(c, offendersURL, util.LeaveConnectionOpen); err == nil {
+			if offenders, err := formatCardinalityOffenders(r); err == nil {
+				s += offenders
+			}
+		}
+
+		// the agents prior to the mapper hit counters don't expose them either
+		mapperURL := fmt.Sprintf("https://%v:%v/agent/dogstatsd-mapper", ipcAddress, pkgconfig.Datadog().GetInt("cmd_port"))
+		if r, err := util.DoGet(c, mapperURL, util.LeaveConnectionOpen); err == nil {
+			if mappings, err := formatMapperStats(r); err == nil {
+				s += mappings
+			}
+		}
 	}
 
 	if cliParams.dsdStatsFilePath == "" {
@@ -138,3 +157,49 @@ func requestDogstatsdStats(_ log.Component, config config.Component, cliParams *
 
 	return nil
 }
+
+// formatCardinalityOffenders returns a printable version of the metrics over their context budget.
+func formatCardinalityOffenders(data []byte) (string, error) {
+	var offenders []aggregator.CardinalityOffender
+	if err := json.Unmarshal(data, &offenders); err != nil {
+		return "", err
+	}
+	if len(offenders) == 0 {
+		return "", nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("\n\nMetrics over their context budget, tagged with " + aggregator.CardinalityOverflowTag + ":\n\n")
+	header := fmt.Sprintf("%-40s | %-10s | %-10s | %-10s | %-20s\n", "Metric", "Limit", "Contexts", "Overflows", "Last Overflow")
+	buf.WriteString(header)
+	buf.WriteString(strings.Repeat("-", len(header)) + "\n")
+	for _, offender := range offenders {
+		buf.WriteString(fmt.Sprintf("%-40s | %-10s | %-10d | %-10d | %-20v\n", offender.Name, offender.Limit, offender.Contexts, offender.Overflows, offender.LastOverflow))
+	}
+	return buf.String(), nil
+}
+
+// formatMapperStats returns a printable version of the number of metrics matched by each mapping.
+func formatMapperStats(data []byte) (string, error) {
+	var stats []mapper.MappingStats
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return "", err
+	}
+	if len(stats) == 0 {
+		return "", nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString("\n\nMetrics matched by the dogstatsd_mapper_profiles mappings:\n\n")
+	header := fmt.Sprintf("%-20s | %-40s | %-40s | %-10s\n", "Profile", "Match", "Name", "Hits")
+	buf.WriteString(header)
+	buf.WriteString(strings.Repeat("-", len(header)) + "\n")
+	for _, mapping := range stats {
+		name := mapping.Name
+		if mapping.Action == "drop" {
+			name = "(dropped)"
+		}
+		buf.WriteString(fmt.Sprintf("%-20s | %-40s | %-40s | %-10d\n", mapping.Profile, mapping.Match, name, mapping.Hits))
+	}
+	return buf.String(), nil
+}
@@ -8,6 +8,7 @@ package dogstatsdstats
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/command"
@@ -26,3 +27,31 @@ func TestCommand(t *testing.T) {
 			require.Equal(t, false, secretParams.Enabled)
 		})
 }
+
+func TestFormatCardinalityOffenders(t *testing.T) {
+	s, err := formatCardinalityOffenders([]byte(`[]`))
+	require.NoError(t, err)
+	assert.Empty(t, s)
+
+	s, err = formatCardinalityOffenders([]byte(`[{"name":"my.metric","limit":"per_metric","contexts":100,"overflows":42,"last_overflow":"2024-01-01T00:00:00Z"}]`))
+	require.NoError(t, err)
+	assert.Contains(t, s, "cardinality_overflow:true")
+	assert.Regexp(t, `my\.metric +\| per_metric +\| 100 +\| 42 +\|`, s)
+
+	_, err = formatCardinalityOffenders([]byte(`{}`))
+	assert.Error(t, err)
+}
+
+func TestFormatMapperStats(t *testing.T) {
+	s, err := formatMapperStats([]byte(`[]`))
+	require.NoError(t, err)
+	assert.Empty(t, s)
+
+	s, err = formatMapperStats([]byte(`[{"profile":"airflow","match":"airflow.job.*","name":"airflow.job","action":"map","hits":12},{"profile":"airflow","match":"airflow.debug.*","action":"drop","hits":3}]`))
+	require.NoError(t, err)
+	assert.Regexp(t, `airflow +\| airflow\.job\.\* +\| airflow\.job +\| 12 `, s)
+	assert.Regexp(t, `airflow +\| airflow\.debug\.\* +\| \(dropped\) +\| 3 `, s)
+
+	_, err = formatMapperStats([]byte(`{}`))
+	assert.Error(t, err)
+}
@@ -128,6 +128,7 @@ import (
 	traceagentStatusImpl "github.com/DataDog/datadog-agent/comp/trace/status/statusimpl"
 	pkgcollector "github.com/DataDog/datadog-agent/pkg/collector"
 	"github.com/DataDog/datadog-agent/pkg/collector/check"
+	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
 	"github.com/DataDog/datadog-agent/pkg/collector/corechecks/net"
 	profileStatus "github.com/DataDog/datadog-agent/pkg/collector/corechecks/snmp/status"
 	"github.com/DataDog/datadog-agent/pkg/collector/python"
@@ -136,6 +137,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config/remote/data"
 	commonsettings "github.com/DataDog/datadog-agent/pkg/config/settings"
 	"github.com/DataDog/datadog-agent/pkg/jmxfetch"
+	logsprocessor "github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
 	clusteragentStatus "github.com/DataDog/datadog-agent/pkg/status/clusteragent"
 	endpointsStatus "github.com/DataDog/datadog-agent/pkg/status/endpoints"
@@ -460,6 +462,7 @@ func getSharedFxOption() fx.Option {
 					"runtime_block_profile_rate":             commonsettings.NewRuntimeBlockProfileRate(),
 					"dogstatsd_stats":                        internalsettings.NewDsdStatsRuntimeSetting(serverDebug),
 					"dogstatsd_capture_duration":             internalsettings.NewDsdCaptureDurationRuntimeSetting("dogstatsd_capture_duration"),
+					"dogstatsd_tag_rules":                    internalsettings.NewDsdTagRulesRuntimeSetting(),
 					"log_payloads":                           commonsettings.NewLogPayloadsRuntimeSetting(),
 					"internal_profiling_goroutines":          commonsettings.NewProfilingGoroutines(),
 					"multi_region_failover.enabled":          internalsettings.NewMultiRegionFailoverRuntimeSetting("multi_region_failover.enabled", "Enable/disable Multi-Region Failover support."),
@@ -574,6 +577,11 @@ func startAgent(
 		pkgTelemetry.RegisterStatsSender(sender)
 	}
 
+	// Setup the sender of the metrics generated from logs by the generate_metric processing rules
+	if sender, err := demultiplexer.GetSender(checkid.ID(logsprocessor.GeneratedMetricsSenderID)); err == nil {
+		logsprocessor.RegisterMetricSender(sender)
+	}
+
 	// Append version and timestamp to version history log file if this Agent is different than the last run version
 	installinfo.LogVersionHistory()
 
@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/server"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+// DsdTagRulesRuntimeSetting wraps operations to change the tags removed from the dogstatsd metrics at runtime.
+type DsdTagRulesRuntimeSetting struct{}
+
+// NewDsdTagRulesRuntimeSetting creates a new instance of DsdTagRulesRuntimeSetting
+func NewDsdTagRulesRuntimeSetting() *DsdTagRulesRuntimeSetting {
+	return &DsdTagRulesRuntimeSetting{}
+}
+
+// Description returns the runtime setting's description
+func (s *DsdTagRulesRuntimeSetting) Description() string {
+	return `Set the tags removed from the dogstatsd metrics. Possible values: a JSON list of rules, e.g. [{"metric": "http.*", "exclude_tags": ["user_id"]}]`
+}
+
+// Hidden returns whether or not this setting is hidden from the list of runtime settings
+func (s *DsdTagRulesRuntimeSetting) Hidden() bool {
+	return false
+}
+
+// Name returns the name of the runtime setting
+func (s *DsdTagRulesRuntimeSetting) Name() string {
+	return "dogstatsd_tag_rules"
+}
+
+// Get returns the current value of the runtime setting
+func (s *DsdTagRulesRuntimeSetting) Get(config config.Component) (interface{}, error) {
+	return config.Get(s.Name()), nil
+}
+
+// Set changes the value of the runtime setting
+func (s *DsdTagRulesRuntimeSetting) Set(config config.Component, v interface{}, source model.Source) error {
+	var data []byte
+	switch value := v.(type) {
+	case string:
+		data = []byte(value)
+	default:
+		var err error
+		if data, err = json.Marshal(value); err != nil {
+			return fmt.Errorf("DsdTagRulesRuntimeSetting: %v", err)
+		}
+	}
+
+	var rules []server.TagRuleConfig
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("DsdTagRulesRuntimeSetting: invalid tag rules: %v", err)
+	}
+	if err := server.ValidateTagRules(rules); err != nil {
+		return fmt.Errorf("DsdTagRulesRuntimeSetting: %v", err)
+	}
+
+	// the rules are stored as the generic structure read from the configuration file
+	var raw []interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("DsdTagRulesRuntimeSetting: invalid tag rules: %v", err)
+	}
+	config.Set(s.Name(), raw, source)
+	return nil
+}
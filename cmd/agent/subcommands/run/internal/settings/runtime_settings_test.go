@@ -96,3 +96,42 @@ func TestDogstatsdMetricsStats(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal(v, true)
 }
+
+func TestDogstatsdTagRules(t *testing.T) {
+	assert := assert.New(t)
+
+	deps := fxutil.Test[testDeps](t, fx.Options(
+		core.MockBundle(),
+		fx.Supply(core.BundleParams{}),
+		demultiplexerimpl.MockModule(),
+		dogstatsd.Bundle(server.Params{Serverless: false}),
+		defaultforwarder.MockModule(),
+		workloadmetafxmock.MockModule(workloadmeta.NewParams()),
+	))
+
+	s := NewDsdTagRulesRuntimeSetting()
+
+	// JSON string
+
+	err := s.Set(deps.Config, `[{"metric": "http.*", "exclude_tags": ["user_id"]}]`, model.SourceCLI)
+	assert.Nil(err)
+	v, err := s.Get(deps.Config)
+	assert.Nil(err)
+	assert.Equal([]interface{}{map[string]interface{}{"metric": "http.*", "exclude_tags": []interface{}{"user_id"}}}, v)
+
+	// structured value
+
+	err = s.Set(deps.Config, []interface{}{map[string]interface{}{"metric": "db.*", "include_tags": []interface{}{"env"}}}, model.SourceCLI)
+	assert.Nil(err)
+	v, err = s.Get(deps.Config)
+	assert.Nil(err)
+	assert.Equal([]interface{}{map[string]interface{}{"metric": "db.*", "include_tags": []interface{}{"env"}}}, v)
+
+	// invalid rules are rejected and the current rules are kept
+
+	assert.NotNil(s.Set(deps.Config, `[{"metric": "db.*"}]`, model.SourceCLI))
+	assert.NotNil(s.Set(deps.Config, `{"metric": "db.*"}`, model.SourceCLI))
+	v, err = s.Get(deps.Config)
+	assert.Nil(err)
+	assert.Equal([]interface{}{map[string]interface{}{"metric": "db.*", "include_tags": []interface{}{"env"}}}, v)
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package traces implements 'trace-agent traces' cli.
+package traces
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands"
+	coreconfig "github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+	"github.com/DataDog/datadog-agent/comp/core/secrets/secretsimpl"
+	"github.com/DataDog/datadog-agent/comp/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/recorder"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+	"github.com/DataDog/datadog-agent/pkg/util/optional"
+)
+
+type cliParams struct {
+	query   recorder.Query
+	errored bool
+	json    bool
+}
+
+// MakeCommand returns the traces subcommand for the 'trace-agent' command.
+func MakeCommand(globalParamsGetter func() *subcommands.GlobalParams) *cobra.Command {
+	params := &cliParams{}
+	tracesCmd := &cobra.Command{
+		Use:   "traces",
+		Short: "Show the traces recently processed by a running trace-agent.",
+		Long: `Use this to show the traces recently processed by the running trace-agent, along with
+their sampling decision and its reason. The trace-agent keeps them only when
+apm_config.debug.recent_traces is set.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if cmd.Flags().Changed("error") {
+				params.query.Error = &params.errored
+			}
+			globalParams := globalParamsGetter()
+			return fxutil.OneShot(showTraces,
+				config.Module(),
+				fx.Supply(params),
+				fx.Supply(coreconfig.NewAgentParams(globalParams.ConfPath, coreconfig.WithFleetPoliciesDirPath(globalParams.FleetPoliciesDirPath))),
+				fx.Supply(optional.NewNoneOption[secrets.Component]()),
+				fx.Supply(secrets.NewEnabledParams()),
+				coreconfig.Module(),
+				secretsimpl.Module(),
+			)
+		},
+		SilenceUsage: true,
+	}
+	tracesCmd.Flags().StringVar(&params.query.Service, "service", "", "show the traces having a span of this service")
+	tracesCmd.Flags().StringVar(&params.query.Resource, "resource", "", "show the traces whose root span has this resource")
+	tracesCmd.Flags().StringVar(&params.query.TraceID, "trace-id", "", "show the trace with this decimal, or 128 bits hexadecimal, ID")
+	tracesCmd.Flags().BoolVar(&params.errored, "error", false, "show the traces having an error, or not with --error=false")
+	tracesCmd.Flags().IntVar(&params.query.Limit, "limit", 20, "maximum number of traces shown")
+	tracesCmd.Flags().BoolVar(&params.json, "json", false, "print the traces in JSON")
+
+	return tracesCmd
+}
+
+func showTraces(config config.Component, params *cliParams) error {
+	tracecfg := config.Object()
+	if tracecfg == nil {
+		return fmt.Errorf("Unable to successfully parse config")
+	}
+	url := fmt.Sprintf("http://127.0.0.1:%d%s?%s", tracecfg.DebugServerPort, recorder.Path, params.query.Values().Encode())
+	client := http.Client{Timeout: 3 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("could not reach the trace-agent debug server on port %d, is the trace-agent running? %s", tracecfg.DebugServerPort, err)
+	}
+	defer resp.Body.Close()
+	return writeTraces(os.Stdout, resp, params.json)
+}
+
+func writeTraces(w io.Writer, resp *http.Response, asJSON bool) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return fmt.Errorf("the trace-agent doesn't keep the recent traces, set apm_config.debug.recent_traces to enable it")
+	default:
+		return fmt.Errorf("error querying the recent traces: %s: %s", resp.Status, body)
+	}
+	if asJSON {
+		_, err = w.Write(body)
+		return err
+	}
+	var traces []*recorder.Trace
+	if err := json.Unmarshal(body, &traces); err != nil {
+		return fmt.Errorf("error decoding the recent traces: %s", err)
+	}
+	if len(traces) == 0 {
+		_, err = fmt.Fprintln(w, "No trace found.")
+		return err
+	}
+	return recorder.WriteTree(w, traces)
+}
@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package traces
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands"
+	"github.com/DataDog/datadog-agent/pkg/trace/recorder"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestTracesCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		[]*cobra.Command{MakeCommand(func() *subcommands.GlobalParams {
+			return &subcommands.GlobalParams{}
+		})},
+		[]string{"traces", "--service", "web", "--error=false", "--limit", "5"},
+		showTraces,
+		func(params *cliParams) {
+			require.NotNil(t, params.query.Error)
+			assert.False(t, *params.query.Error)
+			assert.Equal(t, recorder.Query{Service: "web", Error: params.query.Error, Limit: 5}, params.query)
+		})
+}
+
+func TestWriteTraces(t *testing.T) {
+	response := func(code int, body string) *http.Response {
+		return &http.Response{StatusCode: code, Status: http.StatusText(code), Body: io.NopCloser(strings.NewReader(body))}
+	}
+	body := `[{"trace_id":1,"received_at":"2024-01-02T15:04:05Z","env":"prod","priority":1,"decision":"kept","reason":"priority",` +
+		`"spans":[{"span_id":1,"service":"web","name":"http.request","resource":"GET /","duration":1000000}]}]`
+
+	var buf bytes.Buffer
+	require.NoError(t, writeTraces(&buf, response(http.StatusOK, body), false))
+	assert.Equal(t, "trace 1 (env: prod, priority: 1) kept by priority at 2024-01-02T15:04:05Z\n└─ web http.request GET / 1ms\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeTraces(&buf, response(http.StatusOK, body), true))
+	assert.Equal(t, body, buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeTraces(&buf, response(http.StatusOK, "[]\n"), false))
+	assert.Equal(t, "No trace found.\n", buf.String())
+
+	assert.EqualError(t, writeTraces(&buf, response(http.StatusNotFound, ""), false),
+		"the trace-agent doesn't keep the recent traces, set apm_config.debug.recent_traces to enable it")
+	assert.EqualError(t, writeTraces(&buf, response(http.StatusBadRequest, "invalid limit"), false),
+		"error querying the recent traces: Bad Request: invalid limit")
+}
@@ -0,0 +1,268 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	httpExpvars         = expvar.NewMap("dogstatsd-http")
+	httpRequestErrors   = expvar.Int{}
+	httpRequests        = expvar.Int{}
+	httpBytes           = expvar.Int{}
+	httpMessagesTooLong = expvar.Int{}
+)
+
+const (
+	// HTTPPath is the path the HTTP listener accepts payloads on.
+	HTTPPath = "/v1/dogstatsd"
+
+	// anonymousClient is the client name used in the telemetry when no token is configured.
+	anonymousClient = "anonymous"
+)
+
+func init() {
+	httpExpvars.Set("RequestErrors", &httpRequestErrors)
+	httpExpvars.Set("Requests", &httpRequests)
+	httpExpvars.Set("Bytes", &httpBytes)
+	httpExpvars.Set("MessagesTooLong", &httpMessagesTooLong)
+}
+
+// HTTPListener implements the StatsdListener interface for HTTP.
+// It accepts newline-delimited DogStatsD messages in the body of POST requests,
+// optionally gzip-compressed, and sends them back as packets ready to be processed.
+// Origin detection is not implemented for HTTP.
+type HTTPListener struct {
+	listener        net.Listener
+	server          *http.Server
+	packetsBuffer   *packets.Buffer
+	packetAssembler *packets.Assembler
+	tokens          map[string]string // client name -> token
+	maxPayloadSize  int64
+	maxMessageSize  int
+	listenWg        sync.WaitGroup
+	telemetryStore  *TelemetryStore
+}
+
+// NewHTTPListener returns an idle HTTP Statsd listener
+func NewHTTPListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg config.Reader, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*HTTPListener, error) {
+	var url string
+
+	port := cfg.GetString("dogstatsd_http_port")
+	if port == RandomPortName {
+		port = "0"
+	}
+
+	nonLocalTraffic := cfg.GetBool("dogstatsd_non_local_traffic")
+	if nonLocalTraffic {
+		// Listen to all network interfaces
+		url = fmt.Sprintf(":%s", port)
+	} else {
+		url = net.JoinHostPort(config.GetBindHostFromConfig(cfg), port)
+	}
+
+	tokens := cfg.GetStringMapString("dogstatsd_http_tokens")
+	for client, token := range tokens {
+		if token == "" {
+			return nil, fmt.Errorf("empty token for the dogstatsd_http_tokens client %q", client)
+		}
+	}
+	if len(tokens) == 0 && nonLocalTraffic && !cfg.GetBool("dogstatsd_http_allow_unauthenticated") {
+		return nil, errors.New("dogstatsd_http_tokens must be set when dogstatsd_non_local_traffic is enabled, unless dogstatsd_http_allow_unauthenticated is enabled")
+	}
+
+	listener, err := net.Listen("tcp", url)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+
+	packetsBufferSize := cfg.GetInt("dogstatsd_packet_buffer_size")
+	flushTimeout := cfg.GetDuration("dogstatsd_packet_buffer_flush_timeout")
+
+	packetsBuffer := packets.NewBuffer(uint(packetsBufferSize), flushTimeout, packetOut, "http", packetsTelemetryStore)
+	packetAssembler := packets.NewAssembler(flushTimeout, packetsBuffer, sharedPacketPoolManager, packets.HTTP)
+
+	l := &HTTPListener{
+		listener:        listener,
+		packetsBuffer:   packetsBuffer,
+		packetAssembler: packetAssembler,
+		tokens:          tokens,
+		maxPayloadSize:  cfg.GetInt64("dogstatsd_http_max_payload_size"),
+		maxMessageSize:  cfg.GetInt("dogstatsd_buffer_size"),
+		telemetryStore:  telemetryStore,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(HTTPPath, l)
+	l.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if len(tokens) == 0 {
+		log.Warnf("dogstatsd-http: no token configured in dogstatsd_http_tokens, requests are not authenticated")
+	}
+	log.Debugf("dogstatsd-http: %s successfully initialized", listener.Addr())
+	return l, nil
+}
+
+// LocalAddr returns the local network address of the listener.
+func (l *HTTPListener) LocalAddr() string {
+	return l.listener.Addr().String()
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *HTTPListener) Listen() {
+	l.listenWg.Add(1)
+
+	go func() {
+		defer l.listenWg.Done()
+		log.Infof("dogstatsd-http: starting to listen on %s", l.listener.Addr())
+		if err := l.server.Serve(l.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("dogstatsd-http: error serving requests: %v", err)
+		}
+	}()
+}
+
+// ServeHTTP handles a request carrying DogStatsD messages.
+func (l *HTTPListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	t1 := time.Now()
+	httpRequests.Add(1)
+
+	client, status, err := l.handleRequest(r)
+	if err != nil {
+		httpRequestErrors.Add(1)
+		l.telemetryStore.tlmHTTPRequests.Inc(client, "error")
+		log.Debugf("dogstatsd-http: rejecting request from %s: %v", r.RemoteAddr, err)
+		http.Error(w, err.Error(), status)
+	} else {
+		l.telemetryStore.tlmHTTPRequests.Inc(client, "ok")
+		w.WriteHeader(status)
+	}
+
+	l.telemetryStore.tlmListener.Observe(float64(time.Since(t1).Nanoseconds()), "http", "http", "http")
+}
+
+// handleRequest authenticates the request and forwards its messages. It returns the
+// name of the client and the status of the response.
+func (l *HTTPListener) handleRequest(r *http.Request) (string, int, error) {
+	if r.Method != http.MethodPost {
+		return anonymousClient, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method)
+	}
+
+	client, ok := l.authenticate(r)
+	if !ok {
+		return client, http.StatusUnauthorized, errors.New("invalid or missing token")
+	}
+
+	var body io.Reader = http.MaxBytesReader(nil, r.Body, l.maxPayloadSize)
+	switch r.Header.Get("Content-Encoding") {
+	case "":
+	case "gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return client, http.StatusBadRequest, fmt.Errorf("invalid gzip payload: %v", err)
+		}
+		defer gz.Close()
+		body = gz
+	default:
+		return client, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content encoding %q", r.Header.Get("Content-Encoding"))
+	}
+
+	// the decompressed payload is bounded as well to protect against compression bombs
+	payload, err := io.ReadAll(io.LimitReader(body, l.maxPayloadSize+1))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return client, http.StatusRequestEntityTooLarge, fmt.Errorf("payload larger than %d bytes", l.maxPayloadSize)
+		}
+		return client, http.StatusBadRequest, fmt.Errorf("could not read the payload: %v", err)
+	}
+	if int64(len(payload)) > l.maxPayloadSize {
+		return client, http.StatusRequestEntityTooLarge, fmt.Errorf("decompressed payload larger than %d bytes", l.maxPayloadSize)
+	}
+
+	httpBytes.Add(int64(len(payload)))
+	l.telemetryStore.tlmHTTPRequestsBytes.Add(float64(len(payload)), client)
+
+	l.addMessages(payload)
+	return client, http.StatusAccepted, nil
+}
+
+// authenticate returns the client whose token is carried by the request. All the
+// requests are accepted when no token is configured.
+func (l *HTTPListener) authenticate(r *http.Request) (string, bool) {
+	if len(l.tokens) == 0 {
+		return anonymousClient, true
+	}
+
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return anonymousClient, false
+	}
+	for client, expected := range l.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+			return client, true
+		}
+	}
+	return anonymousClient, false
+}
+
+// addMessages splits the payload on newlines and hands the messages to the packet
+// assembler, which packs them into packets of the shared pool. The messages larger
+// than a packet are dropped.
+func (l *HTTPListener) addMessages(payload []byte) {
+	for len(payload) > 0 {
+		var message []byte
+		if idx := bytes.IndexByte(payload, '\n'); idx >= 0 {
+			message, payload = payload[:idx], payload[idx+1:]
+		} else {
+			message, payload = payload, nil
+		}
+		message = bytes.TrimSuffix(message, []byte{'\r'})
+
+		if len(message) == 0 {
+			continue
+		}
+		if len(message) > l.maxMessageSize {
+			httpMessagesTooLong.Add(1)
+			log.Debugf("dogstatsd-http: dropping a message of %d bytes, larger than dogstatsd_buffer_size", len(message))
+			continue
+		}
+		l.packetAssembler.AddMessage(message)
+	}
+}
+
+// Stop waits for the pending requests and stops listening
+func (l *HTTPListener) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := l.server.Shutdown(ctx); err != nil {
+		log.Debugf("dogstatsd-http: error stopping the server: %v", err)
+	}
+	// the listener is not closed by Shutdown if Listen was never called
+	_ = l.listener.Close()
+	l.listenWg.Wait()
+	l.packetAssembler.Close()
+	l.packetsBuffer.Close()
+}
@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+
+package listeners
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/telemetry"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+)
+
+func newTestHTTPListener(t *testing.T, cfg map[string]interface{}, packetChannel chan packets.Packets) (*HTTPListener, listenerDeps) {
+	cfg["dogstatsd_http_port"] = RandomPortName
+	deps := fulfillDepsWithConfig(t, cfg)
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	pool := packets.NewPoolManager[packets.Packet](packets.NewPool(deps.Config.GetInt("dogstatsd_buffer_size"), packetsTelemetryStore))
+	s, err := NewHTTPListener(packetChannel, pool, deps.Config, telemetryStore, packetsTelemetryStore)
+	require.NoError(t, err)
+	t.Cleanup(s.Stop)
+	return s, deps
+}
+
+func postPayload(l *HTTPListener, token string, encoding string, body io.Reader) *http.Response {
+	req := httptest.NewRequest(http.MethodPost, HTTPPath, body)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	rec := httptest.NewRecorder()
+	l.ServeHTTP(rec, req)
+	return rec.Result()
+}
+
+func gzipPayload(t *testing.T, payload string) *bytes.Buffer {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(payload))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return &buf
+}
+
+func receivePacket(t *testing.T, packetChannel chan packets.Packets) *packets.Packet {
+	select {
+	case pkts := <-packetChannel:
+		require.Len(t, pkts, 1)
+		return pkts[0]
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Timeout on receive channel")
+	}
+	return nil
+}
+
+func TestHTTPListenerReceive(t *testing.T) {
+	packetChannel := make(chan packets.Packets, 1)
+	l, deps := newTestHTTPListener(t, map[string]interface{}{}, packetChannel)
+	l.Listen()
+
+	resp, err := http.Post("http://"+l.LocalAddr()+HTTPPath, "text/plain", strings.NewReader("foo:1|c\r\n\nbar:2|g\n"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	packet := receivePacket(t, packetChannel)
+	assert.Equal(t, "foo:1|c\nbar:2|g", string(packet.Contents))
+	assert.Equal(t, packets.HTTP, packet.Source)
+	assert.Equal(t, "http", packet.ListenerID)
+
+	telemetryMock, ok := deps.Telemetry.(telemetry.Mock)
+	require.True(t, ok)
+	requestsMetrics, err := telemetryMock.GetCountMetric("dogstatsd", "http_requests")
+	require.NoError(t, err)
+	require.Len(t, requestsMetrics, 1)
+	assert.Equal(t, map[string]string{"client": anonymousClient, "state": "ok"}, requestsMetrics[0].Tags())
+	assert.Equal(t, float64(1), requestsMetrics[0].Value())
+}
+
+func TestHTTPListenerGzip(t *testing.T) {
+	packetChannel := make(chan packets.Packets, 1)
+	l, _ := newTestHTTPListener(t, map[string]interface{}{}, packetChannel)
+
+	resp := postPayload(l, "", "gzip", gzipPayload(t, "foo:1|c\nbar:2|g"))
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "foo:1|c\nbar:2|g", string(receivePacket(t, packetChannel).Contents))
+
+	resp = postPayload(l, "", "gzip", strings.NewReader("foo:1|c"))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = postPayload(l, "", "br", strings.NewReader("foo:1|c"))
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+}
+
+func TestHTTPListenerAuth(t *testing.T) {
+	packetChannel := make(chan packets.Packets, 1)
+	l, deps := newTestHTTPListener(t, map[string]interface{}{
+		"dogstatsd_http_tokens": map[string]string{"app-a": "token-a", "app-b": "token-b"},
+	}, packetChannel)
+
+	assert.Equal(t, http.StatusUnauthorized, postPayload(l, "", "", strings.NewReader("foo:1|c")).StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, postPayload(l, "token-c", "", strings.NewReader("foo:1|c")).StatusCode)
+	assert.Equal(t, http.StatusAccepted, postPayload(l, "token-b", "", strings.NewReader("foo:1|c")).StatusCode)
+	assert.Equal(t, "foo:1|c", string(receivePacket(t, packetChannel).Contents))
+
+	telemetryMock, ok := deps.Telemetry.(telemetry.Mock)
+	require.True(t, ok)
+	requestsMetrics, err := telemetryMock.GetCountMetric("dogstatsd", "http_requests")
+	require.NoError(t, err)
+	byClient := map[string]float64{}
+	for _, m := range requestsMetrics {
+		byClient[m.Tags()["client"]+"/"+m.Tags()["state"]] = m.Value()
+	}
+	assert.Equal(t, map[string]float64{anonymousClient + "/error": 2, "app-b/ok": 1}, byClient)
+}
+
+func TestHTTPListenerNonLocalTrafficRequiresTokens(t *testing.T) {
+	newListener := func(cfg map[string]interface{}) (*HTTPListener, error) {
+		cfg["dogstatsd_http_port"] = RandomPortName
+		cfg["dogstatsd_non_local_traffic"] = true
+		deps := fulfillDepsWithConfig(t, cfg)
+		telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+		packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+		pool := packets.NewPoolManager[packets.Packet](packets.NewPool(deps.Config.GetInt("dogstatsd_buffer_size"), packetsTelemetryStore))
+		return NewHTTPListener(make(chan packets.Packets), pool, deps.Config, telemetryStore, packetsTelemetryStore)
+	}
+
+	_, err := newListener(map[string]interface{}{})
+	assert.Error(t, err)
+
+	l, err := newListener(map[string]interface{}{"dogstatsd_http_tokens": map[string]string{"app-a": "token-a"}})
+	require.NoError(t, err)
+	l.Stop()
+
+	l, err = newListener(map[string]interface{}{"dogstatsd_http_allow_unauthenticated": true})
+	require.NoError(t, err)
+	l.Stop()
+}
+
+func TestHTTPListenerLimits(t *testing.T) {
+	packetChannel := make(chan packets.Packets, 1)
+	l, _ := newTestHTTPListener(t, map[string]interface{}{
+		"dogstatsd_http_max_payload_size": 64,
+		"dogstatsd_buffer_size":           16,
+	}, packetChannel)
+
+	req := httptest.NewRequest(http.MethodGet, HTTPPath, nil)
+	rec := httptest.NewRecorder()
+	l.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	resp := postPayload(l, "", "", strings.NewReader(strings.Repeat("a", 65)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	// the limit applies to the decompressed payload
+	resp = postPayload(l, "", "gzip", gzipPayload(t, strings.Repeat("a", 65)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	// the messages larger than a packet are dropped
+	resp = postPayload(l, "", "", strings.NewReader("foo:1|c\nmetric.with.a.long.name:1|c\nbar:1|c"))
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "foo:1|c\nbar:1|c", string(receivePacket(t, packetChannel).Contents))
+}
@@ -23,6 +23,9 @@ type TelemetryStore struct {
 	tlmUDSOriginDetectionError telemetry.Counter
 	tlmUDSPacketsBytes         telemetry.Counter
 	tlmUDSConnections          telemetry.Gauge
+	// HTTP
+	tlmHTTPRequests      telemetry.Counter
+	tlmHTTPRequestsBytes telemetry.Counter
 
 	tlmListener telemetry.Histogram
 }
@@ -46,6 +49,10 @@ func NewTelemetryStore(buckets []float64, telemetrycomp telemetry.Component) *Te
 			[]string{"listener_id", "transport"}, "Dogstatsd UDS packets bytes"),
 		tlmUDSConnections: telemetrycomp.NewGauge("dogstatsd", "uds_connections",
 			[]string{"listener_id", "transport"}, "Dogstatsd UDS connections count"),
+		tlmHTTPRequests: telemetrycomp.NewCounter("dogstatsd", "http_requests",
+			[]string{"client", "state"}, "Dogstatsd HTTP requests count"),
+		tlmHTTPRequestsBytes: telemetrycomp.NewCounter("dogstatsd", "http_requests_bytes",
+			[]string{"client"}, "Dogstatsd HTTP requests bytes count, after decompression"),
 		tlmListener: telemetrycomp.NewHistogram(
 			"dogstatsd",
 			"listener_read_latency",
@@ -12,6 +12,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync/atomic"
 )
 
 var (
@@ -21,8 +22,21 @@ var (
 const (
 	matchTypeWildcard = "wildcard"
 	matchTypeRegex    = "regex"
+
+	actionMap  = "map"
+	actionDrop = "drop"
 )
 
+// allowedTypes are the metric types a mapping can convert the matching metrics to.
+// Sets are excluded: their values are strings and can't be converted.
+var allowedTypes = map[string]struct{}{
+	"gauge":        {},
+	"count":        {},
+	"distribution": {},
+	"histogram":    {},
+	"timing":       {},
+}
+
 //
 // Those two structs are used to pull data from the configuration into typed struct. We currently load the data from the
 // configuration into MappingProfileConfig and then convert it to MappingProfile.
@@ -44,6 +58,14 @@ type MetricMappingConfig struct {
 	MatchType string            `mapstructure:"match_type" json:"match_type" yaml:"match_type"`
 	Name      string            `mapstructure:"name" json:"name" yaml:"name"`
 	Tags      map[string]string `mapstructure:"tags" json:"tags" yaml:"tags"`
+	// Action is either `map` (default) or `drop` to discard the matching metrics
+	Action string `mapstructure:"action" json:"action" yaml:"action"`
+	// Type converts the matching metrics to another type, e.g. timers to distributions
+	Type string `mapstructure:"type" json:"type" yaml:"type"`
+	// Scale multiplies the values of the matching metrics, e.g. 0.001 to convert milliseconds to seconds
+	Scale float64 `mapstructure:"scale" json:"scale" yaml:"scale"`
+	// Continue keeps matching the metric against the next profiles once this mapping matched
+	Continue bool `mapstructure:"continue" json:"continue" yaml:"continue"`
 }
 
 // MetricMapper contains mappings and cache instance
@@ -52,6 +74,15 @@ type MetricMapper struct {
 	cache    *mapperCache
 }
 
+// MappingStats reports how many metrics a mapping matched.
+type MappingStats struct {
+	Profile string `json:"profile"`
+	Match   string `json:"match"`
+	Name    string `json:"name"`
+	Action  string `json:"action"`
+	Hits    uint64 `json:"hits"`
+}
+
 // MappingProfile represent a group of mappings
 type MappingProfile struct {
 	Name     string
@@ -61,16 +92,32 @@ type MappingProfile struct {
 
 // MetricMapping represent one mapping rule
 type MetricMapping struct {
-	name  string
-	tags  map[string]string
-	regex *regexp.Regexp
+	name        string
+	tags        map[string]string
+	regex       *regexp.Regexp
+	match       string
+	action      string
+	mtype       string
+	scale       float64
+	fallThrough bool
+
+	hits atomic.Uint64
 }
 
 // MapResult represent the outcome of the mapping
 type MapResult struct {
-	Name    string
-	Tags    []string
+	Name string
+	Tags []string
+	// Drop is true when the metric must be discarded
+	Drop bool
+	// Type is the type the metric must be converted to, empty to keep its type
+	Type string
+	// Scale is the factor the values must be multiplied by, 0 to keep them as is
+	Scale   float64
 	matched bool
+
+	// mappings are the mappings which matched, to count their hits on the cached results
+	mappings []*MetricMapping
 }
 
 // NewMetricMapper creates, validates, prepares a new MetricMapper
@@ -96,17 +143,39 @@ func NewMetricMapper(configProfiles []MappingProfileConfig, cacheSize int) (*Met
 			if matchType != matchTypeWildcard && matchType != matchTypeRegex {
 				return nil, fmt.Errorf("profile: %s, mapping num %d: invalid match type, must be `wildcard` or `regex`", profile.Name, i)
 			}
-			if currentMapping.Name == "" {
+			action := currentMapping.Action
+			if action == "" {
+				action = actionMap
+			}
+			if action != actionMap && action != actionDrop {
+				return nil, fmt.Errorf("profile: %s, mapping num %d: invalid action, must be `map` or `drop`", profile.Name, i)
+			}
+			if currentMapping.Name == "" && action == actionMap {
 				return nil, fmt.Errorf("profile: %s, mapping num %d: name is required", profile.Name, i)
 			}
 			if currentMapping.Match == "" {
 				return nil, fmt.Errorf("profile: %s, mapping num %d: match is required", profile.Name, i)
 			}
+			if _, ok := allowedTypes[currentMapping.Type]; currentMapping.Type != "" && !ok {
+				return nil, fmt.Errorf("profile: %s, mapping num %d: invalid type `%s`, must be one of `gauge`, `count`, `distribution`, `histogram` or `timing`", profile.Name, i, currentMapping.Type)
+			}
+			if currentMapping.Scale < 0 {
+				return nil, fmt.Errorf("profile: %s, mapping num %d: scale must be positive", profile.Name, i)
+			}
 			regex, err := buildRegex(currentMapping.Match, matchType)
 			if err != nil {
 				return nil, err
 			}
-			profile.Mappings = append(profile.Mappings, &MetricMapping{name: currentMapping.Name, tags: currentMapping.Tags, regex: regex})
+			profile.Mappings = append(profile.Mappings, &MetricMapping{
+				name:        currentMapping.Name,
+				tags:        currentMapping.Tags,
+				regex:       regex,
+				match:       currentMapping.Match,
+				action:      action,
+				mtype:       currentMapping.Type,
+				scale:       currentMapping.Scale,
+				fallThrough: currentMapping.Continue,
+			})
 		}
 		profiles = append(profiles, profile)
 	}
@@ -135,45 +204,100 @@ func buildRegex(matchRe string, matchType string) (*regexp.Regexp, error) {
 	return regex, nil
 }
 
-// Map returns a MapResult
+// Map returns a MapResult, or nil when the metric doesn't match any mapping.
+//
+// The metric is matched against the mappings of the first profile with a matching
+// prefix. When the matching mapping has `continue` set, the metric is also matched
+// against the next profiles: the tags of every matching mapping are kept while the
+// name, type and scale of the last matching mapping win.
 func (m *MetricMapper) Map(metricName string) *MapResult {
+	result, cached := m.cache.get(metricName)
+	if !cached {
+		result = m.match(metricName)
+		m.cache.add(metricName, result)
+	}
+	if !result.matched {
+		return nil
+	}
+	for _, mapping := range result.mappings {
+		mapping.hits.Add(1)
+	}
+	return result
+}
+
+func (m *MetricMapper) match(metricName string) *MapResult {
+	result := &MapResult{matched: false}
 	for _, profile := range m.Profiles {
 		if !strings.HasPrefix(metricName, profile.Prefix) && profile.Prefix != "*" {
 			continue
 		}
-		result, cached := m.cache.get(metricName)
-		if cached {
+		mapping, matches := profile.match(metricName)
+		if mapping == nil {
 			if result.matched {
-				return result
-			}
-			return nil
-		}
-		for _, mapping := range profile.Mappings {
-			matches := mapping.regex.FindStringSubmatchIndex(metricName)
-			if len(matches) == 0 {
 				continue
 			}
+			return result
+		}
 
-			name := string(mapping.regex.ExpandString(
-				[]byte{},
-				mapping.name,
-				metricName,
-				matches,
-			))
-
-			tags := make([]string, 0, len(mapping.tags))
-			for tagKey, tagValueExpr := range mapping.tags {
-				tagValue := string(mapping.regex.ExpandString([]byte{}, tagValueExpr, metricName, matches))
-				tags = append(tags, tagKey+":"+tagValue)
-			}
+		if !result.matched {
+			result.Tags = make([]string, 0, len(mapping.tags))
+		}
+		result.matched = true
+		result.mappings = append(result.mappings, mapping)
 
-			mapResult := &MapResult{Name: name, matched: true, Tags: tags}
-			m.cache.add(metricName, mapResult)
-			return mapResult
+		if mapping.action == actionDrop {
+			result.Drop = true
+			return result
+		}
+
+		result.Name = string(mapping.regex.ExpandString(
+			[]byte{},
+			mapping.name,
+			metricName,
+			matches,
+		))
+		for tagKey, tagValueExpr := range mapping.tags {
+			tagValue := string(mapping.regex.ExpandString([]byte{}, tagValueExpr, metricName, matches))
+			result.Tags = append(result.Tags, tagKey+":"+tagValue)
+		}
+		if mapping.mtype != "" {
+			result.Type = mapping.mtype
+		}
+		if mapping.scale != 0 {
+			result.Scale = mapping.scale
+		}
+
+		if !mapping.fallThrough {
+			return result
+		}
+	}
+	return result
+}
+
+// match returns the first mapping of the profile matching the metric, and the
+// indexes of its submatches.
+func (p *MappingProfile) match(metricName string) (*MetricMapping, []int) {
+	for _, mapping := range p.Mappings {
+		if matches := mapping.regex.FindStringSubmatchIndex(metricName); len(matches) != 0 {
+			return mapping, matches
+		}
+	}
+	return nil, nil
+}
+
+// Stats returns the number of metrics matched by each mapping.
+func (m *MetricMapper) Stats() []MappingStats {
+	var stats []MappingStats
+	for _, profile := range m.Profiles {
+		for _, mapping := range profile.Mappings {
+			stats = append(stats, MappingStats{
+				Profile: profile.Name,
+				Match:   mapping.match,
+				Name:    mapping.name,
+				Action:  mapping.action,
+				Hits:    mapping.hits.Load(),
+			})
 		}
-		mapResult := &MapResult{matched: false}
-		m.cache.add(metricName, mapResult)
-		return nil
 	}
-	return nil
+	return stats
 }
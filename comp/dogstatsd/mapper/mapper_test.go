@@ -328,6 +328,93 @@ dogstatsd_mapper_profiles:
 				{Name: "foo.bar1.duration", Tags: []string{"bar:bar", "foo:foo_name"}, matched: true},
 			},
 		},
+		{
+			name: "Type conversion and scaling",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.*.response_time_ms"
+        name: "test.response_time"
+        type: distribution
+        scale: 0.001
+        tags:
+          service: "$1"
+`,
+			packets: []string{
+				"test.web.response_time_ms",
+			},
+			expectedResults: []MapResult{
+				{Name: "test.response_time", Tags: []string{"service:web"}, Type: "distribution", Scale: 0.001, matched: true},
+			},
+		},
+		{
+			name: "Drop action",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.debug.*"
+        action: drop
+      - match: "test.*"
+        name: "test.kept"
+`,
+			packets: []string{
+				"test.debug.foo",
+				"test.bar",
+			},
+			expectedResults: []MapResult{
+				{Tags: make([]string, 0), Drop: true, matched: true},
+				{Name: "test.kept", Tags: make([]string, 0), matched: true},
+			},
+		},
+		{
+			name: "Continue to the next profiles",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: env
+    prefix: '*'
+    mappings:
+      - match: "*.*.*.*"
+        name: "$2.$3.$4"
+        continue: true
+        tags:
+          env: "$1"
+  - name: unrelated
+    prefix: 'other.'
+    mappings:
+      - match: "other.*"
+        name: "other"
+  - name: no_match
+    prefix: '*'
+    mappings:
+      - match: "nope.*"
+        name: "nope"
+  - name: jobs
+    prefix: 'prod.'
+    mappings:
+      - match: "prod.jobs.*.duration"
+        name: "jobs.duration"
+        type: distribution
+        tags:
+          job: "$1"
+  - name: never
+    prefix: '*'
+    mappings:
+      - match: "prod.jobs.*.duration"
+        name: "never"
+`,
+			packets: []string{
+				"prod.jobs.backup.duration",
+				"staging.queue.size.max",
+			},
+			expectedResults: []MapResult{
+				{Name: "jobs.duration", Tags: []string{"env:prod", "job:backup"}, Type: "distribution", matched: true},
+				{Name: "queue.size.max", Tags: []string{"env:staging"}, matched: true},
+			},
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -339,7 +426,9 @@ dogstatsd_mapper_profiles:
 			for _, packet := range scenario.packets {
 				mapResult := mapper.Map(packet)
 				if mapResult != nil {
-					actualResults = append(actualResults, *mapResult)
+					result := *mapResult
+					result.mappings = nil
+					actualResults = append(actualResults, result)
 				}
 			}
 			for _, sample := range scenario.expectedResults {
@@ -353,6 +442,32 @@ dogstatsd_mapper_profiles:
 	}
 }
 
+func TestMappingStats(t *testing.T) {
+	mapper, err := getMapper(t, `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.debug.*"
+        action: drop
+      - match: "test.job.*"
+        name: "test.job"
+`)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		// the hits are counted on the cached results too
+		mapper.Map("test.job.foo")
+	}
+	mapper.Map("test.debug.foo")
+	mapper.Map("test.unknown")
+
+	assert.Equal(t, []MappingStats{
+		{Profile: "test", Match: "test.debug.*", Action: "drop", Hits: 1},
+		{Profile: "test", Match: "test.job.*", Name: "test.job", Action: "map", Hits: 3},
+	}, mapper.Stats())
+}
+
 func TestMappingErrors(t *testing.T) {
 	scenarios := []struct {
 		name          string
@@ -503,6 +618,45 @@ dogstatsd_mapper_profiles:
 			},
 			expectedError: "missing prefix for profile",
 		},
+		{
+			name: "Invalid action",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.*"
+        name: "test"
+        action: rename
+`,
+			expectedError: "invalid action",
+		},
+		{
+			name: "Invalid type",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.*"
+        name: "test"
+        type: set
+`,
+			expectedError: "invalid type `set`",
+		},
+		{
+			name: "Negative scale",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.*"
+        name: "test"
+        scale: -1
+`,
+			expectedError: "scale must be positive",
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -21,6 +21,8 @@ const (
 	UDS
 	// NamedPipe Windows named pipe listner
 	NamedPipe
+	// HTTP listener
+	HTTP
 )
 
 // Packet represents a statsd packet ready to process,
@@ -0,0 +1,429 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/zstd"
+	"github.com/golang/protobuf/proto"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+const (
+	// EventMessageType is the type of the event messages of a capture.
+	EventMessageType = "event"
+	// ServiceCheckMessageType is the type of the service check messages of a capture.
+	ServiceCheckMessageType = "service_check"
+)
+
+// MessageFilter selects the messages of a capture.
+type MessageFilter struct {
+	// Prefixes the metric and service check names must start with. Events are
+	// dropped when set. All the messages are selected when empty.
+	Prefixes []string
+	// Pids of the clients the messages must have been sent by. All the clients
+	// are selected when empty.
+	Pids []int32
+}
+
+func (f MessageFilter) matchPid(pid int32) bool {
+	if len(f.Pids) == 0 {
+		return true
+	}
+	for _, p := range f.Pids {
+		if p == pid {
+			return true
+		}
+	}
+	return false
+}
+
+func (f MessageFilter) matchMessage(message []byte) bool {
+	if len(f.Prefixes) == 0 {
+		return true
+	}
+	msgType, name := parseMessageName(message)
+	if msgType == EventMessageType {
+		return false
+	}
+	for _, prefix := range f.Prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CaptureContext is a metric context found in a capture.
+type CaptureContext struct {
+	Name string
+	// Type is the DogStatsD type of the metric, e.g. "c" or "g".
+	Type string
+	// Tags are the sorted tags sent by the client, without the tags added by origin detection.
+	Tags []string
+	// Messages is the number of messages of the context.
+	Messages uint64
+}
+
+// MetricRate is the number of messages per second received for a metric name.
+type MetricRate struct {
+	Name     string
+	Contexts int
+	Messages uint64
+	Rate     float64
+}
+
+// CaptureSummary summarizes the messages of a capture.
+type CaptureSummary struct {
+	Packets  uint64
+	Messages uint64
+	Events   uint64
+	// ServiceChecks is the number of service check messages.
+	ServiceChecks uint64
+	// Duration is the time elapsed between the first and the last packet.
+	Duration time.Duration
+	// Contexts are the metric contexts, sorted by name, type and tags.
+	Contexts []*CaptureContext
+}
+
+// TopMetrics returns the n metric names with the most messages per second.
+func (s *CaptureSummary) TopMetrics(n int) []MetricRate {
+	byName := make(map[string]*MetricRate)
+	for _, c := range s.Contexts {
+		m, ok := byName[c.Name]
+		if !ok {
+			m = &MetricRate{Name: c.Name}
+			byName[c.Name] = m
+		}
+		m.Contexts++
+		m.Messages += c.Messages
+	}
+
+	seconds := s.Duration.Seconds()
+	if seconds < 1 {
+		seconds = 1
+	}
+	rates := make([]MetricRate, 0, len(byName))
+	for _, m := range byName {
+		m.Rate = float64(m.Messages) / seconds
+		rates = append(rates, *m)
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].Messages == rates[j].Messages {
+			return rates[i].Name < rates[j].Name
+		}
+		return rates[i].Messages > rates[j].Messages
+	})
+
+	if n > 0 && len(rates) > n {
+		rates = rates[:n]
+	}
+	return rates
+}
+
+// timestampResolution returns the resolution of the timestamps of the capture packets.
+func (tc *TrafficCaptureReader) timestampResolution() time.Duration {
+	if tc.Version < minNanoVersion {
+		return time.Second
+	}
+	return time.Nanosecond
+}
+
+// ForEachMessage calls fn with each DogStatsD message of the capture selected by the
+// filter, along with the packet it was read from. It reads the capture from the start
+// and must not be called during a replay.
+func (tc *TrafficCaptureReader) ForEachMessage(filter MessageFilter, fn func(packet *pb.UnixDogstatsdMsg, message []byte) error) error {
+	tc.Seek(0)
+	for {
+		packet, err := tc.ReadNext()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if !filter.matchPid(packet.Pid) {
+			continue
+		}
+
+		payload := packet.Payload[:packet.PayloadSize]
+		for len(payload) > 0 {
+			var message []byte
+			if idx := bytes.IndexByte(payload, '\n'); idx >= 0 {
+				message, payload = payload[:idx], payload[idx+1:]
+			} else {
+				message, payload = payload, nil
+			}
+			message = bytes.TrimSuffix(message, []byte{'\r'})
+
+			if len(message) == 0 || !filter.matchMessage(message) {
+				continue
+			}
+			if err := fn(packet, message); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// SummarizeCapture returns the summary of the messages of the capture selected by the filter.
+func SummarizeCapture(tc *TrafficCaptureReader, filter MessageFilter) (*CaptureSummary, error) {
+	summary := &CaptureSummary{}
+	contexts := make(map[string]*CaptureContext)
+
+	var lastPacket *pb.UnixDogstatsdMsg
+	var first, last int64
+	err := tc.ForEachMessage(filter, func(packet *pb.UnixDogstatsdMsg, message []byte) error {
+		if packet != lastPacket {
+			lastPacket = packet
+			summary.Packets++
+			if summary.Packets == 1 {
+				first = packet.Timestamp
+			}
+			last = packet.Timestamp
+		}
+		summary.Messages++
+
+		msgType, name := parseMessageName(message)
+		switch msgType {
+		case EventMessageType:
+			summary.Events++
+			return nil
+		case ServiceCheckMessageType:
+			summary.ServiceChecks++
+			return nil
+		}
+
+		tags := parseMetricTags(message)
+		key := name + "|" + msgType + "|" + strings.Join(tags, ",")
+		c, ok := contexts[key]
+		if !ok {
+			c = &CaptureContext{Name: name, Type: msgType, Tags: tags}
+			contexts[key] = c
+		}
+		c.Messages++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary.Duration = time.Duration(last-first) * tc.timestampResolution()
+	summary.Contexts = make([]*CaptureContext, 0, len(contexts))
+	for _, c := range contexts {
+		summary.Contexts = append(summary.Contexts, c)
+	}
+	sort.Slice(summary.Contexts, func(i, j int) bool {
+		a, b := summary.Contexts[i], summary.Contexts[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return strings.Join(a.Tags, ",") < strings.Join(b.Tags, ",")
+	})
+	return summary, nil
+}
+
+// FilterCapture writes the messages of the capture selected by the filter to a new
+// capture, along with the tagger state of their clients. The packets without any
+// selected message are dropped. It returns the number of messages written.
+func FilterCapture(tc *TrafficCaptureReader, w io.Writer, filter MessageFilter, compressed bool) (uint64, error) {
+	cw, err := newCaptureFileWriter(w, compressed)
+	if err != nil {
+		return 0, err
+	}
+
+	var count uint64
+	var current *pb.UnixDogstatsdMsg
+	var payload []byte
+	pids := make(map[int32]struct{})
+
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		pids[current.Pid] = struct{}{}
+		filtered := &pb.UnixDogstatsdMsg{
+			// the capture is written in the current version, with timestamps in nanoseconds
+			Timestamp:     current.Timestamp * int64(tc.timestampResolution()),
+			PayloadSize:   int32(len(payload)),
+			Payload:       payload,
+			Pid:           current.Pid,
+			AncillarySize: current.AncillarySize,
+			Ancillary:     current.Ancillary,
+		}
+		current, payload = nil, nil
+		return cw.writeMessage(filtered)
+	}
+
+	err = tc.ForEachMessage(filter, func(packet *pb.UnixDogstatsdMsg, message []byte) error {
+		if packet != current {
+			if err := flush(); err != nil {
+				return err
+			}
+			current = packet
+		}
+		if len(payload) > 0 {
+			payload = append(payload, '\n')
+		}
+		payload = append(payload, message...)
+		count++
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return count, err
+	}
+
+	state := &pb.TaggerState{}
+	if tc.Version >= minStateVersion {
+		pidMap, entities, err := tc.ReadState()
+		if err != nil {
+			return count, err
+		}
+		state = filterTaggerState(pidMap, entities, pids)
+	}
+	return count, cw.close(state)
+}
+
+// filterTaggerState returns the tagger state of the given clients.
+func filterTaggerState(pidMap map[int32]string, entities map[string]*pb.Entity, pids map[int32]struct{}) *pb.TaggerState {
+	state := &pb.TaggerState{
+		State:  make(map[string]*pb.Entity),
+		PidMap: make(map[int32]string),
+	}
+	for pid, id := range pidMap {
+		if _, ok := pids[pid]; !ok {
+			continue
+		}
+		state.PidMap[pid] = id
+		if entity, ok := entities[id]; ok {
+			state.State[id] = entity
+		}
+	}
+	return state
+}
+
+// ExportCapture writes the messages of the capture selected by the filter to w, one per
+// line, in the plain-text DogStatsD format. It returns the number of messages written.
+func ExportCapture(tc *TrafficCaptureReader, w io.Writer, filter MessageFilter) (uint64, error) {
+	bw := bufio.NewWriter(w)
+	var count uint64
+	err := tc.ForEachMessage(filter, func(_ *pb.UnixDogstatsdMsg, message []byte) error {
+		count++
+		if _, err := bw.Write(message); err != nil {
+			return err
+		}
+		return bw.WriteByte('\n')
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, bw.Flush()
+}
+
+// captureFileWriter writes the messages of a capture to a new capture file.
+type captureFileWriter struct {
+	zWriter *zstd.Writer
+	writer  *bufio.Writer
+}
+
+// newCaptureFileWriter returns a writer of capture file, once its header is written.
+func newCaptureFileWriter(w io.Writer, compressed bool) (*captureFileWriter, error) {
+	cw := &captureFileWriter{}
+	if compressed {
+		cw.zWriter = zstd.NewWriter(w)
+		cw.writer = bufio.NewWriter(cw.zWriter)
+	} else {
+		cw.writer = bufio.NewWriter(w)
+	}
+
+	if err := WriteHeader(cw.writer); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *captureFileWriter) writeMessage(msg *pb.UnixDogstatsdMsg) error {
+	buff, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = writeRecord(cw.writer, buff)
+	return err
+}
+
+// close writes the tagger state and flushes the capture file.
+func (cw *captureFileWriter) close(state *pb.TaggerState) error {
+	if state == nil {
+		state = &pb.TaggerState{}
+	}
+	if _, err := writeTaggerState(cw.writer, state); err != nil {
+		return err
+	}
+	if err := cw.writer.Flush(); err != nil {
+		return err
+	}
+	if cw.zWriter != nil {
+		return cw.zWriter.Close()
+	}
+	return nil
+}
+
+// parseMessageName returns the type and the name of a DogStatsD message. The type of
+// the metrics is their DogStatsD type, and the name of the events is their title.
+func parseMessageName(message []byte) (string, string) {
+	if bytes.HasPrefix(message, []byte("_sc|")) {
+		name, _, _ := strings.Cut(string(message[len("_sc|"):]), "|")
+		return ServiceCheckMessageType, name
+	}
+	if bytes.HasPrefix(message, []byte("_e{")) {
+		return EventMessageType, parseEventTitle(message)
+	}
+
+	name, rest, _ := strings.Cut(string(message), ":")
+	_, rest, _ = strings.Cut(rest, "|")
+	msgType, _, _ := strings.Cut(rest, "|")
+	return msgType, name
+}
+
+// parseEventTitle returns the title of an event message, e.g. `_e{5,4}:title|text`.
+func parseEventTitle(message []byte) string {
+	lengths, rest, found := strings.Cut(string(message[len("_e{"):]), "}:")
+	if !found {
+		return ""
+	}
+	titleLength, _, _ := strings.Cut(lengths, ",")
+	n, err := strconv.Atoi(titleLength)
+	if err != nil || n < 0 || n > len(rest) {
+		return ""
+	}
+	return rest[:n]
+}
+
+// parseMetricTags returns the sorted tags of a metric message.
+func parseMetricTags(message []byte) []string {
+	for _, field := range strings.Split(string(message), "|")[1:] {
+		if strings.HasPrefix(field, "#") && len(field) > 1 {
+			tags := strings.Split(field[1:], ",")
+			sort.Strings(tags)
+			return tags
+		}
+	}
+	return nil
+}
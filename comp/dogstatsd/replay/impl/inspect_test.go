@@ -0,0 +1,183 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+// writeTestCapture writes a capture with the given payloads, sent one second apart
+// by the pids 100, 200, 100, ...
+func writeTestCapture(t *testing.T, state *pb.TaggerState, payloads ...string) *TrafficCaptureReader {
+	var buf bytes.Buffer
+	cw, err := newCaptureFileWriter(&buf, true)
+	require.NoError(t, err)
+	for i, payload := range payloads {
+		require.NoError(t, cw.writeMessage(&pb.UnixDogstatsdMsg{
+			Timestamp:   int64(i) * int64(time.Second),
+			PayloadSize: int32(len(payload)),
+			Payload:     []byte(payload),
+			Pid:         int32(100 * (1 + i%2)),
+		}))
+	}
+	require.NoError(t, cw.close(state))
+
+	return readTestCapture(t, buf.Bytes())
+}
+
+func readTestCapture(t *testing.T, content []byte) *TrafficCaptureReader {
+	path := filepath.Join(t.TempDir(), "capture.dog")
+	require.NoError(t, os.WriteFile(path, content, 0600))
+	tc, err := NewTrafficCaptureReader(path, 1, false)
+	require.NoError(t, err)
+	t.Cleanup(func() { tc.Close() })
+	return tc
+}
+
+func TestParseMessageName(t *testing.T) {
+	for _, tc := range []struct {
+		message, msgType, name string
+	}{
+		{"my.metric:1|c|#a:b", "c", "my.metric"},
+		{"my.metric:1:2:3|d|@0.5", "d", "my.metric"},
+		{"_sc|my.check|0|#a:b", ServiceCheckMessageType, "my.check"},
+		{"_e{5,4}:title|text|#a:b", EventMessageType, "title"},
+		{"_e{50,4}:title|text", EventMessageType, ""},
+	} {
+		msgType, name := parseMessageName([]byte(tc.message))
+		assert.Equal(t, tc.msgType, msgType, tc.message)
+		assert.Equal(t, tc.name, name, tc.message)
+	}
+	assert.Equal(t, []string{"a:b", "c:d"}, parseMetricTags([]byte("my.metric:1|c|@0.5|#c:d,a:b|T1657100430")))
+	assert.Nil(t, parseMetricTags([]byte("my.metric:1|c")))
+}
+
+func TestSummarizeCapture(t *testing.T) {
+	tc := writeTestCapture(t, nil,
+		"api.requests:1|c|#env:prod,code:200\napi.latency:3|d",
+		"api.requests:1|c|#code:200,env:prod\n_sc|api.up|0",
+		"api.requests:1|c|#code:500,env:prod\r\n\n_e{5,4}:title|text",
+		"db.queries:1|c",
+	)
+
+	summary, err := SummarizeCapture(tc, MessageFilter{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, summary.Packets)
+	assert.EqualValues(t, 7, summary.Messages)
+	assert.EqualValues(t, 1, summary.Events)
+	assert.EqualValues(t, 1, summary.ServiceChecks)
+	assert.Equal(t, 3*time.Second, summary.Duration)
+	assert.Equal(t, []*CaptureContext{
+		{Name: "api.latency", Type: "d", Messages: 1},
+		{Name: "api.requests", Type: "c", Tags: []string{"code:200", "env:prod"}, Messages: 2},
+		{Name: "api.requests", Type: "c", Tags: []string{"code:500", "env:prod"}, Messages: 1},
+		{Name: "db.queries", Type: "c", Messages: 1},
+	}, summary.Contexts)
+
+	assert.Equal(t, []MetricRate{
+		{Name: "api.requests", Contexts: 2, Messages: 3, Rate: 1},
+		{Name: "api.latency", Contexts: 1, Messages: 1, Rate: 1.0 / 3},
+	}, summary.TopMetrics(2))
+
+	summary, err = SummarizeCapture(tc, MessageFilter{Pids: []int32{200}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, summary.Packets)
+	assert.EqualValues(t, 3, summary.Messages)
+	assert.Equal(t, 2*time.Second, summary.Duration)
+}
+
+func TestFilterCapture(t *testing.T) {
+	tc := writeTestCapture(t, &pb.TaggerState{
+		PidMap: map[int32]string{100: "container_id://abc", 200: "container_id://def"},
+		State: map[string]*pb.Entity{
+			"container_id://abc": {LowCardinalityTags: []string{"image:abc"}},
+			"container_id://def": {LowCardinalityTags: []string{"image:def"}},
+		},
+	},
+		"api.requests:1|c\ndb.queries:1|c",
+		"db.queries:1|c",
+		"api.requests:2|c\n_sc|api.up|0\n_e{5,4}:title|text",
+	)
+
+	var buf bytes.Buffer
+	count, err := FilterCapture(tc, &buf, MessageFilter{Prefixes: []string{"api."}}, false)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, count)
+
+	filtered := readTestCapture(t, buf.Bytes())
+	assert.EqualValues(t, datadogFileVersion, filtered.Version)
+
+	var packets []*pb.UnixDogstatsdMsg
+	require.NoError(t, filtered.ForEachMessage(MessageFilter{}, func(packet *pb.UnixDogstatsdMsg, _ []byte) error {
+		if len(packets) == 0 || packets[len(packets)-1] != packet {
+			packets = append(packets, packet)
+		}
+		return nil
+	}))
+	require.Len(t, packets, 2)
+	assert.Equal(t, "api.requests:1|c", string(packets[0].Payload))
+	assert.EqualValues(t, 0, packets[0].Timestamp)
+	assert.Equal(t, "api.requests:2|c\n_sc|api.up|0", string(packets[1].Payload))
+	assert.EqualValues(t, 2*time.Second, packets[1].Timestamp)
+	assert.EqualValues(t, 100, packets[1].Pid)
+
+	// only the state of the remaining clients is kept
+	pidMap, entities, err := filtered.ReadState()
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]string{100: "container_id://abc"}, pidMap)
+	require.Contains(t, entities, "container_id://abc")
+	assert.Len(t, entities, 1)
+}
+
+func TestFilterCaptureOldVersion(t *testing.T) {
+	tc, err := NewTrafficCaptureReader("resources/test/datadog-capture.dog", 1, false)
+	require.NoError(t, err)
+	defer tc.Close()
+
+	var buf bytes.Buffer
+	count, err := FilterCapture(tc, &buf, MessageFilter{Pids: []int32{2815, 2809}}, true)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+
+	filtered := readTestCapture(t, buf.Bytes())
+	summary, err := SummarizeCapture(filtered, MessageFilter{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, summary.Messages)
+	// the timestamps are converted to nanoseconds
+	assert.Equal(t, time.Second, summary.Duration)
+
+	pidMap, _, err := filtered.ReadState()
+	require.NoError(t, err)
+	assert.Len(t, pidMap, 1)
+}
+
+func TestExportCapture(t *testing.T) {
+	tc := writeTestCapture(t, nil,
+		"api.requests:1|c\ndb.queries:1|c",
+		"api.requests:2|c\n_e{5,4}:title|text",
+	)
+
+	var buf bytes.Buffer
+	count, err := ExportCapture(tc, &buf, MessageFilter{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, count)
+	assert.Equal(t, "api.requests:1|c\ndb.queries:1|c\napi.requests:2|c\n_e{5,4}:title|text\n", buf.String())
+
+	buf.Reset()
+	count, err = ExportCapture(tc, &buf, MessageFilter{Prefixes: []string{"api."}, Pids: []int32{200}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+	assert.Equal(t, "api.requests:2|c\n", buf.String())
+}
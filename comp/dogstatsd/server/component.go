@@ -31,6 +31,9 @@ type Component interface {
 
 	// UDPLocalAddr returns the local address of the UDP statsd listener, if enabled.
 	UDPLocalAddr() string
+
+	// HTTPLocalAddr returns the local address of the HTTP statsd listener, if enabled.
+	HTTPLocalAddr() string
 }
 
 // Mock implements mock-specific methods.
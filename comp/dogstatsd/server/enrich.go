@@ -7,6 +7,7 @@ package server
 
 import (
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/constants"
@@ -30,6 +31,7 @@ type enrichConfig struct {
 	metricPrefix              string
 	metricPrefixBlacklist     []string
 	metricBlocklist           blocklist
+	tagRules                  *atomic.Pointer[tagRules]
 	defaultHostname           string
 	entityIDPrecedenceEnabled bool
 	serverlessMode            bool
@@ -121,6 +123,12 @@ func enrichMetricSample(dest []metrics.MetricSample, ddSample dogstatsdMetricSam
 		return []metrics.MetricSample{}
 	}
 
+	if conf.tagRules != nil {
+		if rules := conf.tagRules.Load(); rules != nil {
+			tags = rules.apply(metricName, tags)
+		}
+	}
+
 	if conf.serverlessMode { // we don't want to set the host while running in serverless mode
 		hostnameFromTags = ""
 	}
@@ -13,6 +13,7 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/fx"
@@ -82,8 +83,10 @@ type dependencies struct {
 type provides struct {
 	fx.Out
 
-	Comp          Component
-	StatsEndpoint api.AgentEndpointProvider
+	Comp                Component
+	StatsEndpoint       api.AgentEndpointProvider
+	CardinalityEndpoint api.AgentEndpointProvider
+	MapperEndpoint      api.AgentEndpointProvider
 }
 
 // When the internal telemetry is enabled, used to tag the origin
@@ -151,6 +154,7 @@ type server struct {
 	ServerlessMode     bool
 	udsListenerRunning bool
 	udpLocalAddr       string
+	httpLocalAddr      string
 
 	// originTelemetry is true if we want to report telemetry per origin.
 	originTelemetry bool
@@ -192,8 +196,10 @@ func newServer(deps dependencies) provides {
 	}
 
 	return provides{
-		Comp:          s,
-		StatsEndpoint: api.NewAgentEndpointProvider(s.writeStats, "/dogstatsd-stats", "GET"),
+		Comp:                s,
+		StatsEndpoint:       api.NewAgentEndpointProvider(s.writeStats, "/dogstatsd-stats", "GET"),
+		CardinalityEndpoint: api.NewAgentEndpointProvider(s.writeCardinalityOffenders, "/dogstatsd-cardinality", "GET"),
+		MapperEndpoint:      api.NewAgentEndpointProvider(s.writeMapperStats, "/dogstatsd-mapper", "GET"),
 	}
 }
 
@@ -223,6 +229,26 @@ func newServerCompat(cfg model.Reader, log log.Component, capture replay.Compone
 		cfg.GetBool("statsd_metric_blocklist_match_prefix"),
 	)
 
+	dogstatsdTagRules := &atomic.Pointer[tagRules]{}
+	if rules, err := getDogstatsdTagRules(cfg); err != nil {
+		log.Errorf("Dogstatsd: ignoring the tag rules: %v", err)
+	} else {
+		dogstatsdTagRules.Store(rules)
+	}
+	// the tag rules can be changed at runtime to fix a cardinality explosion
+	cfg.OnUpdate(func(setting string, _, _ any) {
+		if setting != "dogstatsd_tag_rules" {
+			return
+		}
+		rules, err := getDogstatsdTagRules(cfg)
+		if err != nil {
+			log.Errorf("Dogstatsd: keeping the previous tag rules: %v", err)
+			return
+		}
+		dogstatsdTagRules.Store(rules)
+		log.Infof("Dogstatsd: tag rules updated")
+	})
+
 	defaultHostname, err := hostname.Get(context.TODO())
 	if err != nil {
 		log.Errorf("Dogstatsd: unable to determine default hostname: %s", err.Error())
@@ -296,6 +322,7 @@ func newServerCompat(cfg model.Reader, log log.Component, capture replay.Compone
 			metricPrefix:              metricPrefix,
 			metricPrefixBlacklist:     metricPrefixBlacklist,
 			metricBlocklist:           metricBlocklist,
+			tagRules:                  dogstatsdTagRules,
 			entityIDPrecedenceEnabled: entityIDPrecedenceEnabled,
 			defaultHostname:           defaultHostname,
 			serverlessMode:            serverless,
@@ -400,6 +427,16 @@ func (s *server) start(context.Context) error {
 		}
 	}
 
+	if s.config.GetString("dogstatsd_http_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_http_port") > 0 {
+		httpListener, err := listeners.NewHTTPListener(packetsChannel, sharedPacketPoolManager, s.config, s.listernersTelemetry, s.packetsTelemetry)
+		if err != nil {
+			s.log.Errorf("Can't init HTTP listener: %s", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, httpListener)
+			s.httpLocalAddr = httpListener.LocalAddr()
+		}
+	}
+
 	pipeName := s.config.GetString("dogstatsd_pipe_name")
 	if len(pipeName) > 0 {
 		namedPipeListener, err := listeners.NewNamedPipeListener(pipeName, packetsChannel, sharedPacketPoolManager, s.config, s.tCapture, s.listernersTelemetry, s.packetsTelemetry, s.telemetry)
@@ -533,6 +570,10 @@ func (s *server) UDPLocalAddr() string {
 	return s.udpLocalAddr
 }
 
+func (s *server) HTTPLocalAddr() string {
+	return s.httpLocalAddr
+}
+
 func (s *server) forwarder(fcon net.Conn) {
 	for {
 		select {
@@ -761,9 +802,17 @@ func (s *server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 	if s.mapper != nil {
 		mapResult := s.mapper.Map(sample.name)
 		if mapResult != nil {
+			if mapResult.Drop {
+				s.log.Tracef("Dogstatsd mapper: metric %q dropped", sample.name)
+				if len(sample.values) > 0 {
+					s.sharedFloat64List.put(sample.values)
+				}
+				return metricSamples, nil
+			}
 			s.log.Tracef("Dogstatsd mapper: metric mapped from %q to %q with tags %v", sample.name, mapResult.Name, mapResult.Tags)
 			sample.name = mapResult.Name
 			sample.tags = append(sample.tags, mapResult.Tags...)
+			applyMapperConversions(&sample, mapResult)
 		}
 	}
 
@@ -832,6 +881,32 @@ func getBuckets(cfg model.Reader, logger log.Component, option string) []float64
 	return buckets
 }
 
+// mapperMetricTypes are the metric types the mapper can convert the metrics to.
+var mapperMetricTypes = map[string]metricType{
+	"gauge":        gaugeType,
+	"count":        countType,
+	"distribution": distributionType,
+	"histogram":    histogramType,
+	"timing":       timingType,
+}
+
+// applyMapperConversions converts the type and scales the values of a mapped metric.
+// The sets are left untouched: their values can't be converted.
+func applyMapperConversions(sample *dogstatsdMetricSample, mapResult *mapper.MapResult) {
+	if sample.metricType == setType {
+		return
+	}
+	if mtype, ok := mapperMetricTypes[mapResult.Type]; ok {
+		sample.metricType = mtype
+	}
+	if mapResult.Scale != 0 {
+		sample.value *= mapResult.Scale
+		for i := range sample.values {
+			sample.values[i] *= mapResult.Scale
+		}
+	}
+}
+
 func getDogstatsdMappingProfiles(cfg model.Reader) ([]mapper.MappingProfileConfig, error) {
 	var mappings []mapper.MappingProfileConfig
 	if cfg.IsSet("dogstatsd_mapper_profiles") {
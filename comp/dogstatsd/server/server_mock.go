@@ -70,6 +70,11 @@ func (s *serverMock) UDPLocalAddr() string {
 	return ""
 }
 
+// HTTPLocalAddr is a mocked function but HTTP isn't enabled on the mock
+func (s *serverMock) HTTPLocalAddr() string {
+	return ""
+}
+
 // ServerlessFlush is a noop mocked function
 func (s *serverMock) ServerlessFlush(time.Duration) {}
 
@@ -9,9 +9,11 @@ package server
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"runtime"
 	"sort"
 	"strings"
@@ -504,6 +506,44 @@ func TestUDPReceive(t *testing.T) {
 	testReceive(t, conn, demux)
 }
 
+func TestHTTPReceive(t *testing.T) {
+	cfg := make(map[string]interface{})
+
+	cfg["dogstatsd_port"] = 0
+	cfg["dogstatsd_http_port"] = listeners.RandomPortName
+	cfg["dogstatsd_http_tokens"] = map[string]string{"my-app": "secret"}
+	cfg["dogstatsd_no_aggregation_pipeline"] = true // another test may have turned it off
+
+	deps := fulfillDepsWithConfigOverride(t, cfg)
+	demux := deps.Demultiplexer
+	require.NotEmpty(t, deps.Server.HTTPLocalAddr())
+
+	var payload bytes.Buffer
+	gz := gzip.NewWriter(&payload)
+	_, err := gz.Write([]byte("daemon:666|g|#sometag1:somevalue1\ndaemon.count:2|c\n"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	req, err := http.NewRequest(http.MethodPost, "http://"+deps.Server.HTTPLocalAddr()+listeners.HTTPPath, &payload)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	samples, timedSamples := demux.WaitForNumberOfSamples(2, 0, time.Second*2)
+	require.Len(t, samples, 2)
+	require.Len(t, timedSamples, 0)
+	assert.Equal(t, "daemon", samples[0].Name)
+	assert.EqualValues(t, 666.0, samples[0].Value)
+	assert.Equal(t, metrics.GaugeType, samples[0].Mtype)
+	assert.Equal(t, []string{"sometag1:somevalue1"}, samples[0].Tags)
+	assert.Equal(t, "daemon.count", samples[1].Name)
+	assert.Equal(t, metrics.CounterType, samples[1].Mtype)
+}
+
 func TestUDPForward(t *testing.T) {
 	cfg := make(map[string]interface{})
 
@@ -870,6 +910,37 @@ dogstatsd_mapper_profiles:
 			},
 			expectedCacheSize: 1000,
 		},
+		{
+			name: "Type conversion, scaling and drop",
+			config: `
+dogstatsd_port: __random__
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.debug.*"
+        action: drop
+      - match: "test.*.response_time_ms"
+        name: "test.response_time"
+        type: distribution
+        scale: 0.001
+        tags:
+          service: "$1"
+      - match: "test.users.*"
+        name: "test.users"
+        type: gauge
+`,
+			packets: []string{
+				"test.debug.foo:1|c",
+				"test.web.response_time_ms:250|ms",
+				"test.users.active:abc|s",
+			},
+			expectedSamples: []MetricSample{
+				{Name: "test.response_time", Tags: []string{"service:web"}, Mtype: metrics.DistributionType, Value: 0.25},
+				{Name: "test.users", Tags: nil, Mtype: metrics.SetType, Value: 0},
+			},
+			expectedCacheSize: 1000,
+		},
 		{
 			name: "Cache size",
 			config: `
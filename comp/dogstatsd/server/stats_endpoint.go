@@ -9,6 +9,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
 )
 
@@ -54,3 +56,32 @@ func (s *server) writeStats(w http.ResponseWriter, _ *http.Request) {
 
 	w.Write(jsonStats)
 }
+
+// writeCardinalityOffenders writes the metrics over their context budget.
+func (s *server) writeCardinalityOffenders(w http.ResponseWriter, _ *http.Request) {
+	jsonOffenders, err := json.Marshal(aggregator.GetCardinalityOffenders())
+	if err != nil {
+		httputils.SetJSONError(w, s.log.Errorf("Error getting marshalled Dogstatsd cardinality offenders: %s", err), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonOffenders)
+}
+
+// writeMapperStats writes the number of metrics matched by each mapping of the mapper.
+func (s *server) writeMapperStats(w http.ResponseWriter, _ *http.Request) {
+	stats := []mapper.MappingStats{}
+	if s.mapper != nil {
+		stats = s.mapper.Stats()
+	}
+
+	jsonStats, err := json.Marshal(stats)
+	if err != nil {
+		httputils.SetJSONError(w, s.log.Errorf("Error getting marshalled Dogstatsd mapper stats: %s", err), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonStats)
+}
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+// TagRuleConfig configures the tags kept or removed from the metrics matching a glob.
+type TagRuleConfig struct {
+	// Metric is a glob matched against the metric names, e.g. `http.*`
+	Metric string `mapstructure:"metric" json:"metric" yaml:"metric"`
+	// ExcludeTags are globs of the tag keys removed from the matching metrics
+	ExcludeTags []string `mapstructure:"exclude_tags" json:"exclude_tags" yaml:"exclude_tags"`
+	// IncludeTags are globs of the tag keys kept on the matching metrics, the other tags are removed
+	IncludeTags []string `mapstructure:"include_tags" json:"include_tags" yaml:"include_tags"`
+}
+
+// tagRules removes tags from the metric samples, before they reach the aggregator.
+// Every rule matching the name of a metric is applied, in order.
+type tagRules struct {
+	rules []TagRuleConfig
+}
+
+// ValidateTagRules returns an error when a glob of the rules is malformed or a rule
+// has no effect.
+func ValidateTagRules(configs []TagRuleConfig) error {
+	for i, rule := range configs {
+		if rule.Metric == "" {
+			return fmt.Errorf("tag rule #%d: the metric glob is required", i)
+		}
+		if len(rule.ExcludeTags) == 0 && len(rule.IncludeTags) == 0 {
+			return fmt.Errorf("tag rule #%d (%s): one of exclude_tags or include_tags is required", i, rule.Metric)
+		}
+		for _, pattern := range append(append([]string{rule.Metric}, rule.ExcludeTags...), rule.IncludeTags...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("tag rule #%d (%s): invalid glob %q: %v", i, rule.Metric, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// newTagRules returns the tag rules, or nil when there is none.
+func newTagRules(configs []TagRuleConfig) (*tagRules, error) {
+	if err := ValidateTagRules(configs); err != nil {
+		return nil, err
+	}
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	return &tagRules{rules: configs}, nil
+}
+
+// getDogstatsdTagRules reads the dogstatsd_tag_rules setting.
+func getDogstatsdTagRules(cfg model.Reader) (*tagRules, error) {
+	var configs []TagRuleConfig
+	if cfg.IsSet("dogstatsd_tag_rules") {
+		if err := cfg.UnmarshalKey("dogstatsd_tag_rules", &configs); err != nil {
+			return nil, fmt.Errorf("Could not parse dogstatsd_tag_rules: %v", err)
+		}
+	}
+	return newTagRules(configs)
+}
+
+// apply removes the tags excluded by the rules matching the metric. The tags are
+// filtered in place.
+func (r *tagRules) apply(metricName string, tags []string) []string {
+	for i := range r.rules {
+		rule := &r.rules[i]
+		if !globMatch(rule.Metric, metricName) {
+			continue
+		}
+
+		n := 0
+		for _, tag := range tags {
+			key := tag
+			if idx := strings.IndexByte(tag, ':'); idx >= 0 {
+				key = tag[:idx]
+			}
+			if matchesAny(rule.ExcludeTags, key) || (len(rule.IncludeTags) > 0 && !matchesAny(rule.IncludeTags, key)) {
+				continue
+			}
+			tags[n] = tag
+			n++
+		}
+		tags = tags[:n]
+	}
+	return tags
+}
+
+func matchesAny(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if globMatch(pattern, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch matches a name against a glob validated by ValidateTagRules.
+func globMatch(pattern, name string) bool {
+	matched, _ := path.Match(pattern, name)
+	return matched
+}
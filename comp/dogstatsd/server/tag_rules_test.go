@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+func TestValidateTagRules(t *testing.T) {
+	assert.NoError(t, ValidateTagRules(nil))
+	assert.NoError(t, ValidateTagRules([]TagRuleConfig{{Metric: "http.*", ExcludeTags: []string{"user_*"}}}))
+	assert.NoError(t, ValidateTagRules([]TagRuleConfig{{Metric: "queue.depth", IncludeTags: []string{"queue"}}}))
+
+	assert.Error(t, ValidateTagRules([]TagRuleConfig{{ExcludeTags: []string{"user_id"}}}))
+	assert.Error(t, ValidateTagRules([]TagRuleConfig{{Metric: "http.*"}}))
+	assert.Error(t, ValidateTagRules([]TagRuleConfig{{Metric: "http.[", ExcludeTags: []string{"user_id"}}}))
+	assert.Error(t, ValidateTagRules([]TagRuleConfig{{Metric: "http.*", ExcludeTags: []string{"user_[id"}}}))
+}
+
+func TestTagRulesApply(t *testing.T) {
+	rules, err := newTagRules([]TagRuleConfig{
+		{Metric: "http.*", ExcludeTags: []string{"user_id", "session_*"}},
+		{Metric: "http.requests", IncludeTags: []string{"env", "status*"}},
+	})
+	require.NoError(t, err)
+
+	tags := []string{"env:prod", "user_id:42", "session_id:abc", "status_code:200", "route:/"}
+	assert.Equal(t, []string{"env:prod", "status_code:200", "route:/"}, rules.apply("http.latency", append([]string{}, tags...)))
+	assert.Equal(t, []string{"env:prod", "status_code:200"}, rules.apply("http.requests", append([]string{}, tags...)))
+	assert.Equal(t, tags, rules.apply("db.latency", append([]string{}, tags...)))
+
+	// tags without a value are matched by their name
+	assert.Equal(t, []string{"env:prod"}, rules.apply("http.latency", []string{"user_id", "env:prod"}))
+
+	rules, err = newTagRules(nil)
+	require.NoError(t, err)
+	assert.Nil(t, rules)
+}
+
+func TestEnrichMetricSampleWithTagRules(t *testing.T) {
+	rules, err := newTagRules([]TagRuleConfig{{Metric: "custom.*", ExcludeTags: []string{"user_id"}}})
+	require.NoError(t, err)
+	conf := enrichConfig{defaultHostname: "default", tagRules: &atomic.Pointer[tagRules]{}}
+	conf.tagRules.Store(rules)
+
+	sample, err := parseAndEnrichSingleMetricMessage(t, []byte("custom.metric:1|c|#env:prod,user_id:42,host:my-host"), conf)
+	require.NoError(t, err)
+	assert.Equal(t, "custom.metric", sample.Name)
+	assert.Equal(t, "my-host", sample.Host)
+	assert.Equal(t, []string{"env:prod"}, sample.Tags)
+
+	sample, err = parseAndEnrichSingleMetricMessage(t, []byte("other.metric:1|c|#env:prod,user_id:42"), conf)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"env:prod", "user_id:42"}, sample.Tags)
+}
+
+func TestTagRulesReloadedAtRuntime(t *testing.T) {
+	deps := fulfillDepsWithConfigOverride(t, map[string]interface{}{
+		"dogstatsd_tag_rules": []interface{}{
+			map[string]interface{}{"metric": "http.*", "exclude_tags": []interface{}{"user_id"}},
+		},
+	})
+	s := deps.Server.(*server)
+
+	rules := s.enrichConfig.tagRules.Load()
+	require.NotNil(t, rules)
+	assert.Equal(t, []TagRuleConfig{{Metric: "http.*", ExcludeTags: []string{"user_id"}}}, rules.rules)
+
+	deps.Config.Set("dogstatsd_tag_rules", []interface{}{
+		map[string]interface{}{"metric": "db.*", "include_tags": []interface{}{"env"}},
+	}, model.SourceCLI)
+	rules = s.enrichConfig.tagRules.Load()
+	require.NotNil(t, rules)
+	assert.Equal(t, []TagRuleConfig{{Metric: "db.*", IncludeTags: []string{"env"}}}, rules.rules)
+
+	// invalid rules are ignored
+	deps.Config.Set("dogstatsd_tag_rules", []interface{}{
+		map[string]interface{}{"metric": "db.["},
+	}, model.SourceCLI)
+	assert.Equal(t, rules, s.enrichConfig.tagRules.Load())
+
+	deps.Config.Set("dogstatsd_tag_rules", []interface{}{}, model.SourceCLI)
+	assert.Nil(t, s.enrichConfig.tagRules.Load())
+}
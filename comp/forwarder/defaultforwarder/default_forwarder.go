@@ -243,6 +243,7 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 	var optionalRemovalPolicy *retry.FileRemovalPolicy
 	storageMaxSize := config.GetInt64("forwarder_storage_max_size_in_bytes")
 	var diskUsageLimit *retry.DiskUsageLimit
+	var optionalEncryption *retry.FileEncryption
 
 	// Disk Persistence is a core-only feature for now.
 	if storageMaxSize == 0 {
@@ -270,6 +271,15 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 		diskRatio := config.GetFloat64("forwarder_storage_max_disk_ratio")
 		diskUsageLimit = retry.NewDiskUsageLimit(storagePath, filesystem.NewDisk(), storageMaxSize, diskRatio)
 
+		optionalEncryption, err = buildRetryFileEncryption(config)
+		if err != nil {
+			// Storing the transactions in clear text when the encryption is required is not an option.
+			log.Errorf("Retry queue storage on disk is disabled because the encryption cannot be initialized: %v", err)
+			diskUsageLimit = nil
+		} else if optionalEncryption != nil {
+			log.Infof("Retry queue files are encrypted")
+		}
+
 	} else {
 		log.Infof("Retry queue storage on disk is disabled because the feature is unavailable for this process.")
 	}
@@ -279,6 +289,7 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 	transactionContainerSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: false}
 
 	for domain, resolver := range options.DomainResolvers {
+		bandwidthBudget := getBandwidthBudget(config, log, domain)
 		isMRF := false
 		if config.GetBool("multi_region_failover.enabled") {
 			log.Infof("MRF is enabled, checking site: %v ", domain)
@@ -313,6 +324,7 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 				flushToDiskMemRatio,
 				domainFolderPath,
 				diskUsageLimit,
+				optionalEncryption,
 				transactionContainerSort,
 				resolver,
 				pointCountTelemetry)
@@ -326,7 +338,8 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 				options.NumberOfWorkers,
 				options.ConnectionResetInterval,
 				domainForwarderSort,
-				pointCountTelemetry)
+				pointCountTelemetry,
+				bandwidthBudget)
 			f.domainForwarders[domain] = fwd
 			// Register all alternate domains for each forwarder
 			for _, v := range resolver.GetAlternateDomains() {
@@ -378,6 +391,42 @@ func getAgentName(options *Options) string {
 	return ""
 }
 
+// buildRetryFileEncryption returns the encryption of the retry files, or nil when
+// no encryption key is configured. `forwarder_storage_encryption_key` can be
+// retrieved from the secrets backend. It takes precedence over the first key of
+// `forwarder_storage_encryption_key_file`, the other keys of the file and
+// `forwarder_storage_encryption_previous_keys` are only used to read the files
+// written before a key rotation.
+func buildRetryFileEncryption(config config.Component) (*retry.FileEncryption, error) {
+	var keys [][]byte
+	if encodedKey := config.GetString("forwarder_storage_encryption_key"); encodedKey != "" {
+		key, err := retry.ParseEncryptionKey(encodedKey)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if keyFile := config.GetString("forwarder_storage_encryption_key_file"); keyFile != "" {
+		fileKeys, err := retry.ReadEncryptionKeyFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fileKeys...)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	for _, encodedKey := range config.GetStringSlice("forwarder_storage_encryption_previous_keys") {
+		key, err := retry.ParseEncryptionKey(encodedKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key in forwarder_storage_encryption_previous_keys: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return retry.NewFileEncryption(keys[0], keys[1:])
+}
+
 // Start initialize and runs the forwarder.
 func (f *DefaultForwarder) Start() error {
 	// Lock so we can't stop a Forwarder while is starting
@@ -626,37 +675,37 @@ func (f *DefaultForwarder) submitV1IntakeWithTransactionsFactory(
 
 // SubmitProcessChecks sends process checks
 func (f *DefaultForwarder) SubmitProcessChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
-	return f.submitProcessLikePayload(endpoints.ProcessesEndpoint, payload, extra, true)
+	return f.submitProcessLikePayload(endpoints.ProcessesEndpoint, payload, extra, transaction.Process, true)
 }
 
 // SubmitProcessDiscoveryChecks sends process discovery checks
 func (f *DefaultForwarder) SubmitProcessDiscoveryChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
-	return f.submitProcessLikePayload(endpoints.ProcessDiscoveryEndpoint, payload, extra, true)
+	return f.submitProcessLikePayload(endpoints.ProcessDiscoveryEndpoint, payload, extra, transaction.Process, true)
 }
 
 // SubmitProcessEventChecks sends process events checks
 func (f *DefaultForwarder) SubmitProcessEventChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
-	return f.submitProcessLikePayload(endpoints.ProcessLifecycleEndpoint, payload, extra, true)
+	return f.submitProcessLikePayload(endpoints.ProcessLifecycleEndpoint, payload, extra, transaction.Process, true)
 }
 
 // SubmitRTProcessChecks sends real time process checks
 func (f *DefaultForwarder) SubmitRTProcessChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
-	return f.submitProcessLikePayload(endpoints.RtProcessesEndpoint, payload, extra, false)
+	return f.submitProcessLikePayload(endpoints.RtProcessesEndpoint, payload, extra, transaction.Process, false)
 }
 
 // SubmitContainerChecks sends container checks
 func (f *DefaultForwarder) SubmitContainerChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
-	return f.submitProcessLikePayload(endpoints.ContainerEndpoint, payload, extra, true)
+	return f.submitProcessLikePayload(endpoints.ContainerEndpoint, payload, extra, transaction.Process, true)
 }
 
 // SubmitRTContainerChecks sends real time container checks
 func (f *DefaultForwarder) SubmitRTContainerChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
-	return f.submitProcessLikePayload(endpoints.RtContainerEndpoint, payload, extra, false)
+	return f.submitProcessLikePayload(endpoints.RtContainerEndpoint, payload, extra, transaction.Process, false)
 }
 
 // SubmitConnectionChecks sends connection checks
 func (f *DefaultForwarder) SubmitConnectionChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
-	return f.submitProcessLikePayload(endpoints.ConnectionsEndpoint, payload, extra, true)
+	return f.submitProcessLikePayload(endpoints.ConnectionsEndpoint, payload, extra, transaction.Process, true)
 }
 
 // SubmitOrchestratorChecks sends orchestrator checks
@@ -668,17 +717,17 @@ func (f *DefaultForwarder) SubmitOrchestratorChecks(payload transaction.BytesPay
 		endpoint = endpoints.LegacyOrchestratorEndpoint
 	}
 
-	return f.submitProcessLikePayload(endpoint, payload, extra, true)
+	return f.submitProcessLikePayload(endpoint, payload, extra, transaction.Orchestrator, true)
 }
 
 // SubmitOrchestratorManifests sends orchestrator manifests
 func (f *DefaultForwarder) SubmitOrchestratorManifests(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
 	transactionsOrchestratorManifest.Add(1)
-	return f.submitProcessLikePayload(endpoints.OrchestratorManifestEndpoint, payload, extra, true)
+	return f.submitProcessLikePayload(endpoints.OrchestratorManifestEndpoint, payload, extra, transaction.Orchestrator, true)
 }
 
-func (f *DefaultForwarder) submitProcessLikePayload(ep transaction.Endpoint, payload transaction.BytesPayloads, extra http.Header, retryable bool) (chan Response, error) {
-	transactions := f.createHTTPTransactions(ep, payload, transaction.Process, extra)
+func (f *DefaultForwarder) submitProcessLikePayload(ep transaction.Endpoint, payload transaction.BytesPayloads, extra http.Header, kind transaction.Kind, retryable bool) (chan Response, error) {
+	transactions := f.createHTTPTransactions(ep, payload, kind, extra)
 	results := make(chan Response, len(transactions))
 	internalResults := make(chan Response, len(transactions))
 	expectedResponses := len(transactions)
@@ -45,6 +45,10 @@ type domainForwarder struct {
 	transactionPrioritySorter retry.TransactionPrioritySorter
 	blockedList               *blockedEndpoints
 	pointCountTelemetry       *retry.PointCountTelemetry
+	bandwidthBudget           int                   // in bytes per second, 0 means unlimited
+	scheduler                 *transactionScheduler // nil when the transactions are not scheduled
+	stopDispatch              chan struct{}
+	dispatchStopped           chan struct{}
 }
 
 func newDomainForwarder(
@@ -56,7 +60,8 @@ func newDomainForwarder(
 	numberOfWorkers int,
 	connectionResetInterval time.Duration,
 	transactionPrioritySorter retry.TransactionPrioritySorter,
-	pointCountTelemetry *retry.PointCountTelemetry) *domainForwarder {
+	pointCountTelemetry *retry.PointCountTelemetry,
+	bandwidthBudget int) *domainForwarder {
 	return &domainForwarder{
 		config:                    config,
 		log:                       log,
@@ -70,6 +75,7 @@ func newDomainForwarder(
 		blockedList:               newBlockedEndpoints(config, log),
 		transactionPrioritySorter: transactionPrioritySorter,
 		pointCountTelemetry:       pointCountTelemetry,
+		bandwidthBudget:           bandwidthBudget,
 	}
 }
 
@@ -98,12 +104,11 @@ func (f *domainForwarder) retryTransactions(_ time.Time) {
 	for _, t := range transactions {
 		transactionEndpointName := t.GetEndpointName()
 		if !f.blockedList.isBlock(t.GetTarget()) {
-			select {
-			case f.lowPrio <- t:
+			if f.queueTransaction(t, true) {
 				transactionsRetriedByEndpoint.Add(transactionEndpointName, 1)
 				transactionsRetried.Add(1)
 				tlmTxRetried.Inc(f.domain, transactionEndpointName)
-			default:
+			} else {
 				dropCount := f.addToTransactionRetryQueue(t)
 				tlmTxRequeued.Inc(f.domain, transactionEndpointName)
 				droppedWorkerBusy += dropCount
@@ -150,6 +155,83 @@ func (f *domainForwarder) requeueTransaction(t transaction.Transaction) {
 	tlmTxRetryQueueSize.Set(float64(retryQueueSize), f.domain)
 }
 
+// queueTransaction hands a transaction to the workers, through the scheduler
+// when it's enabled. It returns false when the input queue is full.
+func (f *domainForwarder) queueTransaction(t transaction.Transaction, retry bool) bool {
+	if f.scheduler != nil {
+		return f.scheduler.add(t, retry)
+	}
+
+	queue := f.highPrio
+	if retry {
+		queue = f.lowPrio
+	}
+	select {
+	case queue <- t:
+		return true
+	default:
+		return false
+	}
+}
+
+// dispatchTransactions hands the transactions of the scheduler to the workers,
+// in the order chosen by the scheduler and within the bandwidth budget.
+func (f *domainForwarder) dispatchTransactions() {
+	defer close(f.dispatchStopped)
+
+	for {
+		t := f.scheduler.next()
+		if t == nil {
+			select {
+			case <-f.scheduler.ready:
+				continue
+			case <-f.stopDispatch:
+				return
+			}
+		}
+
+		if delay := f.scheduler.reserveBandwidth(t.GetPayloadSize()); delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-f.stopDispatch:
+				timer.Stop()
+				f.requeueTransaction(t)
+				return
+			}
+		}
+
+		select {
+		case f.highPrio <- t:
+		case <-f.stopDispatch:
+			f.requeueTransaction(t)
+			return
+		}
+	}
+}
+
+// stopDispatching stops the dispatch of the scheduled transactions. The new
+// transactions are sent right away if purgeHighPrio is true, the others are
+// moved to the retry queue.
+func (f *domainForwarder) stopDispatching(purgeHighPrio bool) {
+	close(f.stopDispatch)
+	<-f.dispatchStopped
+	unregisterScheduler(f.domain)
+
+	fresh, retries := f.scheduler.drain()
+	if purgeHighPrio && len(f.workers) > 0 {
+		for _, t := range fresh {
+			f.log.Debugf("Flushing one new transaction before stopping the domainForwarder")
+			f.highPrio <- t
+		}
+	} else {
+		retries = append(retries, fresh...)
+	}
+	for _, t := range retries {
+		f.requeueTransaction(t)
+	}
+}
+
 func (f *domainForwarder) handleFailedTransactions() {
 	ticker := time.NewTicker(flushInterval)
 	for {
@@ -188,6 +270,15 @@ func (f *domainForwarder) init() {
 	lowPrioBuffSize := f.config.GetInt("forwarder_low_prio_buffer_size")
 	requeuedTransactionBuffSize := f.config.GetInt("forwarder_requeue_buffer_size")
 
+	f.scheduler = nil
+	if f.config.GetBool("forwarder_weighted_scheduling") || f.bandwidthBudget > 0 {
+		// the scheduler queues the transactions in place of the workers channels
+		f.scheduler = newTransactionScheduler(getSchedulingWeights(f.config, f.log), highPrioBuffSize, lowPrioBuffSize, f.bandwidthBudget)
+		highPrioBuffSize, lowPrioBuffSize = 0, 0
+		f.stopDispatch = make(chan struct{})
+		f.dispatchStopped = make(chan struct{})
+	}
+
 	f.highPrio = make(chan transaction.Transaction, highPrioBuffSize)
 	f.lowPrio = make(chan transaction.Transaction, lowPrioBuffSize)
 	f.requeuedTransaction = make(chan transaction.Transaction, requeuedTransactionBuffSize)
@@ -215,6 +306,10 @@ func (f *domainForwarder) Start() error {
 		f.workers = append(f.workers, w)
 	}
 	go f.handleFailedTransactions()
+	if f.scheduler != nil {
+		registerScheduler(f.domain, f.scheduler)
+		go f.dispatchTransactions()
+	}
 	if f.connectionResetInterval != 0 {
 		go f.scheduleConnectionResets()
 	}
@@ -238,6 +333,9 @@ func (f *domainForwarder) Stop(purgeHighPrio bool) {
 		f.stopConnectionReset <- true
 	}
 	f.stopRetry <- true
+	if f.scheduler != nil {
+		f.stopDispatching(purgeHighPrio)
+	}
 	for _, w := range f.workers {
 		w.Stop(purgeHighPrio)
 	}
@@ -301,9 +399,7 @@ func (f *domainForwarder) sendHTTPTransactions(t transaction.Transaction) {
 	}
 
 	// We don't want to block the collector if the highPrio queue is full
-	select {
-	case f.highPrio <- t:
-	default:
+	if !f.queueTransaction(t, false) {
 		f.addToTransactionRetryQueue(t)
 		highPriorityQueueFull.Add(1)
 		tlmTxHighPriorityQueueFull.Inc(f.domain, t.GetEndpointName())
@@ -6,10 +6,14 @@
 package defaultforwarder
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -62,6 +66,38 @@ func TestNewDefaultForwarder(t *testing.T) {
 	assert.Equal(t, forwarder.State(), forwarder.internalState.Load())
 }
 
+func TestBuildRetryFileEncryption(t *testing.T) {
+	mockConfig := mock.New(t)
+	encryption, err := buildRetryFileEncryption(mockConfig)
+	require.NoError(t, err)
+	assert.Nil(t, encryption)
+
+	oldKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	fileKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+	configKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{3}, 32))
+	keyFile := filepath.Join(t.TempDir(), "keys")
+	require.NoError(t, os.WriteFile(keyFile, []byte(fileKey+"\n"), 0600))
+
+	// the files encrypted with any of the keys are readable
+	mockConfig.SetWithoutSource("forwarder_storage_encryption_key_file", keyFile)
+	mockConfig.SetWithoutSource("forwarder_storage_encryption_previous_keys", []string{oldKey})
+	encryption, err = buildRetryFileEncryption(mockConfig)
+	require.NoError(t, err)
+	encrypted, err := encryption.Encrypt([]byte("content"))
+	require.NoError(t, err)
+
+	mockConfig.SetWithoutSource("forwarder_storage_encryption_key", configKey)
+	encryption, err = buildRetryFileEncryption(mockConfig)
+	require.NoError(t, err)
+	content, err := encryption.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("content"), content)
+
+	mockConfig.SetWithoutSource("forwarder_storage_encryption_key", "invalid")
+	_, err = buildRetryFileEncryption(mockConfig)
+	assert.Error(t, err)
+}
+
 func TestFeature(t *testing.T) {
 	var featureSet Features
 
@@ -592,7 +628,7 @@ func TestProcessLikePayloadResponseTimeout(t *testing.T) {
 	transactions := f.createHTTPTransactions(endpoints.SeriesEndpoint, payload, transaction.Series, headers)
 	require.Len(t, transactions, 1)
 
-	responses, err := f.submitProcessLikePayload(endpoints.SeriesEndpoint, payload, headers, true)
+	responses, err := f.submitProcessLikePayload(endpoints.SeriesEndpoint, payload, headers, transaction.Process, true)
 	require.NoError(t, err)
 
 	_, ok := <-responses
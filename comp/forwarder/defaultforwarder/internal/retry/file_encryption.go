@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package retry
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// encryptedFileMagic starts the encrypted retry files. 0xfe is never the first
+// byte of a serialized `HttpTransactionProtoCollection` (it would be the tag of a
+// field with an invalid wire type), so the files written in clear text before
+// the encryption was enabled are still recognized.
+var encryptedFileMagic = []byte("\xfeENC")
+
+const (
+	encryptedFileVersion = 1
+	encryptionKeyIDSize  = 8
+	encryptionKeySize    = 32
+)
+
+// encryptedFileHeaderSize is the size of the magic, the version and the key ID,
+// which are authenticated along with the content.
+var encryptedFileHeaderSize = len(encryptedFileMagic) + 1 + encryptionKeyIDSize
+
+var (
+	errNoEncryptionKey      = errors.New("the file is encrypted but no encryption key is configured")
+	errUnknownEncryptionKey = errors.New("the file is encrypted with an unknown key")
+)
+
+type encryptionKey struct {
+	id   []byte
+	aead cipher.AEAD
+}
+
+// FileEncryption encrypts the retry files with AES-256-GCM. The files are
+// encrypted with the current key and decrypted with the key they were encrypted
+// with, so that the files written before a key rotation remain readable as long
+// as the former key is in the previous keys.
+type FileEncryption struct {
+	current *encryptionKey
+	keys    []*encryptionKey
+}
+
+// NewFileEncryption creates a new instance of FileEncryption. The keys are 32
+// bytes long.
+func NewFileEncryption(currentKey []byte, previousKeys [][]byte) (*FileEncryption, error) {
+	current, err := newEncryptionKey(currentKey)
+	if err != nil {
+		return nil, err
+	}
+	e := &FileEncryption{current: current, keys: []*encryptionKey{current}}
+	for _, k := range previousKeys {
+		key, err := newEncryptionKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid previous key: %w", err)
+		}
+		e.keys = append(e.keys, key)
+	}
+	return e, nil
+}
+
+func newEncryptionKey(key []byte) (*encryptionKey, error) {
+	if len(key) != encryptionKeySize {
+		return nil, fmt.Errorf("the encryption key must be %d bytes long, got %d bytes", encryptionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// The key ID only identifies the key, it doesn't leak it.
+	id := sha256.Sum256(key)
+	return &encryptionKey{id: id[:encryptionKeyIDSize], aead: aead}, nil
+}
+
+// ParseEncryptionKey decodes a base64 encoded encryption key.
+func ParseEncryptionKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("the encryption key is not valid base64: %w", err)
+	}
+	return key, nil
+}
+
+// ReadEncryptionKeyFile reads a file containing one base64 encoded key per line.
+// The first key is the current key, the others are the previous keys.
+func ReadEncryptionKeyFile(path string) ([][]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys [][]byte
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, err := ParseEncryptionKey(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no encryption key in %s", path)
+	}
+	return keys, nil
+}
+
+// Encrypt encrypts the content of a retry file with the current key.
+func (e *FileEncryption) Encrypt(content []byte) ([]byte, error) {
+	aead := e.current.aead
+	data := make([]byte, 0, encryptedFileHeaderSize+aead.NonceSize()+len(content)+aead.Overhead())
+	data = append(data, encryptedFileMagic...)
+	data = append(data, encryptedFileVersion)
+	data = append(data, e.current.id...)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	header := data
+	data = append(data, nonce...)
+	return aead.Seal(data, nonce, content, header), nil
+}
+
+// isEncryptedFile returns whether the content of a retry file is encrypted.
+func isEncryptedFile(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedFileMagic)
+}
+
+// Decrypt decrypts the content of a retry file. e may be nil when the encryption
+// is disabled, the files in clear text are returned as they are.
+func (e *FileEncryption) Decrypt(data []byte) ([]byte, error) {
+	if !isEncryptedFile(data) {
+		return data, nil
+	}
+	if e == nil {
+		return nil, errNoEncryptionKey
+	}
+	if len(data) < encryptedFileHeaderSize {
+		return nil, errors.New("truncated encrypted file")
+	}
+	if version := data[len(encryptedFileMagic)]; version != encryptedFileVersion {
+		return nil, fmt.Errorf("unsupported encrypted file version %d", version)
+	}
+
+	header := data[:encryptedFileHeaderSize]
+	id := header[len(encryptedFileMagic)+1:]
+	for _, key := range e.keys {
+		if !bytes.Equal(key.id, id) {
+			continue
+		}
+		nonceSize := key.aead.NonceSize()
+		if len(data) < encryptedFileHeaderSize+nonceSize {
+			return nil, errors.New("truncated encrypted file")
+		}
+		nonce := data[encryptedFileHeaderSize : encryptedFileHeaderSize+nonceSize]
+		content, err := key.aead.Open(nil, nonce, data[encryptedFileHeaderSize+nonceSize:], header)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decrypt the file, it is probably corrupted: %w", err)
+		}
+		return content, nil
+	}
+	return nil, errUnknownEncryptionKey
+}
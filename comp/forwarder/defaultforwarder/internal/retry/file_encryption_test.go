@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package retry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEncryptionKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, encryptionKeySize)
+}
+
+func TestFileEncryption(t *testing.T) {
+	e, err := NewFileEncryption(newTestEncryptionKey(1), nil)
+	require.NoError(t, err)
+
+	content := []byte("transactions with an API key")
+	data, err := e.Encrypt(content)
+	require.NoError(t, err)
+	assert.True(t, isEncryptedFile(data))
+	assert.NotContains(t, string(data), "API key")
+
+	decrypted, err := e.Decrypt(data)
+	require.NoError(t, err)
+	assert.Equal(t, content, decrypted)
+
+	// the same content is never encrypted twice the same way
+	data2, err := e.Encrypt(content)
+	require.NoError(t, err)
+	assert.NotEqual(t, data, data2)
+
+	// the files in clear text are returned as they are
+	decrypted, err = e.Decrypt(content)
+	require.NoError(t, err)
+	assert.Equal(t, content, decrypted)
+
+	var disabled *FileEncryption
+	decrypted, err = disabled.Decrypt(content)
+	require.NoError(t, err)
+	assert.Equal(t, content, decrypted)
+	_, err = disabled.Decrypt(data)
+	assert.ErrorIs(t, err, errNoEncryptionKey)
+}
+
+func TestFileEncryptionKeyRotation(t *testing.T) {
+	oldEncryption, err := NewFileEncryption(newTestEncryptionKey(1), nil)
+	require.NoError(t, err)
+	data, err := oldEncryption.Encrypt([]byte("content"))
+	require.NoError(t, err)
+
+	e, err := NewFileEncryption(newTestEncryptionKey(2), [][]byte{newTestEncryptionKey(1)})
+	require.NoError(t, err)
+	decrypted, err := e.Decrypt(data)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("content"), decrypted)
+
+	// the new files are encrypted with the current key
+	data, err = e.Encrypt([]byte("content"))
+	require.NoError(t, err)
+	_, err = oldEncryption.Decrypt(data)
+	assert.ErrorIs(t, err, errUnknownEncryptionKey)
+}
+
+func TestFileEncryptionCorruption(t *testing.T) {
+	e, err := NewFileEncryption(newTestEncryptionKey(1), nil)
+	require.NoError(t, err)
+	data, err := e.Encrypt([]byte("content"))
+	require.NoError(t, err)
+
+	corrupted := bytes.Clone(data)
+	corrupted[len(corrupted)-1] ^= 1
+	_, err = e.Decrypt(corrupted)
+	assert.Error(t, err)
+
+	// the header is authenticated too
+	corrupted = bytes.Clone(data)
+	corrupted[len(encryptedFileMagic)] = 2
+	_, err = e.Decrypt(corrupted)
+	assert.Error(t, err)
+
+	for _, size := range []int{len(encryptedFileMagic), encryptedFileHeaderSize + 1} {
+		_, err = e.Decrypt(data[:size])
+		assert.Error(t, err)
+	}
+}
+
+func TestNewFileEncryptionInvalidKeys(t *testing.T) {
+	_, err := NewFileEncryption([]byte("too short"), nil)
+	assert.Error(t, err)
+	_, err = NewFileEncryption(newTestEncryptionKey(1), [][]byte{[]byte("too short")})
+	assert.Error(t, err)
+}
+
+func TestReadEncryptionKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	content := "# current key\n" + base64.StdEncoding.EncodeToString(newTestEncryptionKey(2)) + "\n\n" +
+		base64.StdEncoding.EncodeToString(newTestEncryptionKey(1)) + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	keys, err := ReadEncryptionKeyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{newTestEncryptionKey(2), newTestEncryptionKey(1)}, keys)
+
+	require.NoError(t, os.WriteFile(path, []byte("# no key\n"), 0600))
+	_, err = ReadEncryptionKeyFile(path)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("not base64!\n"), 0600))
+	_, err = ReadEncryptionKeyFile(path)
+	assert.ErrorContains(t, err, path+":1")
+}
@@ -24,6 +24,7 @@ const retryFileFormat = "2006_01_02__15_04_05_"
 type onDiskRetryQueue struct {
 	log                 log.Component
 	serializer          *HTTPTransactionsSerializer
+	encryption          *FileEncryption // nil when the files are stored in clear text
 	storagePath         string
 	diskUsageLimit      *DiskUsageLimit
 	filenames           []string
@@ -35,6 +36,7 @@ type onDiskRetryQueue struct {
 func newOnDiskRetryQueue(
 	log log.Component,
 	serializer *HTTPTransactionsSerializer,
+	encryption *FileEncryption,
 	storagePath string,
 	diskUsageLimit *DiskUsageLimit,
 	telemetry onDiskRetryQueueTelemetry,
@@ -47,6 +49,7 @@ func newOnDiskRetryQueue(
 	storage := &onDiskRetryQueue{
 		log:                 log,
 		serializer:          serializer,
+		encryption:          encryption,
 		storagePath:         storagePath,
 		diskUsageLimit:      diskUsageLimit,
 		telemetry:           telemetry,
@@ -82,6 +85,11 @@ func (s *onDiskRetryQueue) Store(transactions []transaction.Transaction) error {
 	if err != nil {
 		return err
 	}
+	if s.encryption != nil {
+		if bytes, err = s.encryption.Encrypt(bytes); err != nil {
+			return err
+		}
+	}
 	bufferSize := int64(len(bytes))
 
 	if err := s.makeRoomFor(bufferSize); err != nil {
@@ -130,6 +138,10 @@ func (s *onDiskRetryQueue) ExtractLast() ([]transaction.Transaction, error) {
 		return nil, err
 	}
 
+	if bytes, err = s.decrypt(bytes); err != nil {
+		return nil, fmt.Errorf("cannot decrypt the file %v: %w", path, err)
+	}
+
 	transactions, errorsCount, err := s.serializer.Deserialize(bytes)
 	if err != nil {
 		return nil, err
@@ -167,6 +179,9 @@ func (s *onDiskRetryQueue) makeRoomFor(bufferSize int64) error {
 		s.log.Errorf("Maximum disk space for retry transactions is reached. Removing %s", filename)
 
 		bytes, err := os.ReadFile(filename)
+		if err == nil {
+			bytes, err = s.decrypt(bytes)
+		}
 		if err != nil {
 			s.log.Errorf("Cannot read the file %v: %v", filename, err)
 		} else if transactions, _, errDeserialize := s.serializer.Deserialize(bytes); errDeserialize == nil {
@@ -188,6 +203,15 @@ func (s *onDiskRetryQueue) makeRoomFor(bufferSize int64) error {
 	return nil
 }
 
+// decrypt returns the content of a retry file in clear text.
+func (s *onDiskRetryQueue) decrypt(bytes []byte) ([]byte, error) {
+	content, err := s.encryption.Decrypt(bytes)
+	if err != nil {
+		s.telemetry.addDecryptionErrorsCount()
+	}
+	return content, err
+}
+
 func (s *onDiskRetryQueue) onPointDropped(count int) {
 	s.telemetry.addPointDroppedCount(count)
 	s.pointCountTelemetry.OnPointDropped(count)
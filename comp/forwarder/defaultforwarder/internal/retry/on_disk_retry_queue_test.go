@@ -8,6 +8,7 @@
 package retry
 
 import (
+	"os"
 	"strconv"
 	"testing"
 
@@ -102,6 +103,96 @@ func TestOnDiskRetryQueueReloadExistingRetryFiles(t *testing.T) {
 	a.Equal([]string{"endpoint1", "endpoint2"}, getEndpointsFromTransactions(transactions))
 }
 
+func TestOnDiskRetryQueueEncryption(t *testing.T) {
+	a := assert.New(t)
+	path := t.TempDir()
+
+	oldEncryption, err := NewFileEncryption(newTestEncryptionKey(1), nil)
+	a.NoError(err)
+	q := newTestEncryptedOnDiskRetryQueue(t, a, path, 1000, oldEncryption)
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint1")))
+	for _, filename := range q.filenames {
+		content, err := os.ReadFile(filename)
+		a.NoError(err)
+		a.True(isEncryptedFile(content))
+		a.NotContains(string(content), "endpoint1")
+	}
+
+	// the files written before the key rotation are still readable
+	encryption, err := NewFileEncryption(newTestEncryptionKey(2), [][]byte{newTestEncryptionKey(1)})
+	a.NoError(err)
+	q = newTestEncryptedOnDiskRetryQueue(t, a, path, 1000, encryption)
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint2")))
+
+	transactions, err := q.ExtractLast()
+	a.NoError(err)
+	a.Equal([]string{"endpoint2"}, getEndpointsFromTransactions(transactions))
+	transactions, err = q.ExtractLast()
+	a.NoError(err)
+	a.Equal([]string{"endpoint1"}, getEndpointsFromTransactions(transactions))
+}
+
+func TestOnDiskRetryQueueEncryptionClearTextFiles(t *testing.T) {
+	a := assert.New(t)
+	path := t.TempDir()
+
+	// the files written before the encryption was enabled are still readable
+	q := newTestOnDiskRetryQueue(t, a, path, 1000)
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint1")))
+
+	encryption, err := NewFileEncryption(newTestEncryptionKey(1), nil)
+	a.NoError(err)
+	q = newTestEncryptedOnDiskRetryQueue(t, a, path, 1000, encryption)
+	transactions, err := q.ExtractLast()
+	a.NoError(err)
+	a.Equal([]string{"endpoint1"}, getEndpointsFromTransactions(transactions))
+}
+
+func TestOnDiskRetryQueueEncryptionCorruptedFile(t *testing.T) {
+	a := assert.New(t)
+	path := t.TempDir()
+
+	encryption, err := NewFileEncryption(newTestEncryptionKey(1), nil)
+	a.NoError(err)
+	q := newTestEncryptedOnDiskRetryQueue(t, a, path, 1000, encryption)
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint1")))
+	a.NoError(q.Store(createHTTPTransactionCollectionTests("endpoint2")))
+
+	corruptedFile := q.filenames[1]
+	content, err := os.ReadFile(corruptedFile)
+	a.NoError(err)
+	content[len(content)-1] ^= 1
+	a.NoError(os.WriteFile(corruptedFile, content, 0600))
+
+	decryptionErrors := decryptionErrorsCountTelemetry.expvar.Value()
+	_, err = q.ExtractLast()
+	a.Error(err)
+	a.Equal(decryptionErrors+1, decryptionErrorsCountTelemetry.expvar.Value())
+	a.NoFileExists(corruptedFile)
+
+	// the other files are not affected
+	transactions, err := q.ExtractLast()
+	a.NoError(err)
+	a.Equal([]string{"endpoint1"}, getEndpointsFromTransactions(transactions))
+	a.Equal(int64(0), q.GetDiskSpaceUsed())
+}
+
+func TestOnDiskRetryQueueEncryptionMaxSize(t *testing.T) {
+	a := assert.New(t)
+	path := t.TempDir()
+
+	encryption, err := NewFileEncryption(newTestEncryptionKey(1), nil)
+	a.NoError(err)
+	q := newTestEncryptedOnDiskRetryQueue(t, a, path, 300, encryption)
+	pointDropped := fileStoragePointDroppedCountTelemetry.expvar.Value()
+
+	// the points of the files removed to make room are still counted
+	for i := 0; q.getFilesCount() == i; i++ {
+		a.NoError(q.Store(createHTTPTransactionCollectionTests(strconv.Itoa(i))))
+	}
+	a.Equal(pointDropped+1, fileStoragePointDroppedCountTelemetry.expvar.Value())
+}
+
 func createHTTPTransactionCollectionTests(endpoints ...string) []transaction.Transaction {
 	var transactions []transaction.Transaction
 
@@ -126,6 +217,10 @@ func getEndpointsFromTransactions(transactions []transaction.Transaction) []stri
 }
 
 func newTestOnDiskRetryQueue(t *testing.T, a *assert.Assertions, path string, maxSizeInBytes int64) *onDiskRetryQueue {
+	return newTestEncryptedOnDiskRetryQueue(t, a, path, maxSizeInBytes, nil)
+}
+
+func newTestEncryptedOnDiskRetryQueue(t *testing.T, a *assert.Assertions, path string, maxSizeInBytes int64, encryption *FileEncryption) *onDiskRetryQueue {
 	telemetry := newOnDiskRetryQueueTelemetry("domain")
 	disk := diskUsageRetrieverMock{
 		diskUsage: &filesystem.DiskUsage{
@@ -134,7 +229,7 @@ func newTestOnDiskRetryQueue(t *testing.T, a *assert.Assertions, path string, ma
 		}}
 	diskUsageLimit := NewDiskUsageLimit("", disk, maxSizeInBytes, 1)
 	log := logmock.New(t)
-	storage, err := newOnDiskRetryQueue(log, NewHTTPTransactionsSerializer(log, resolver.NewSingleDomainResolver(domainName, nil)), path, diskUsageLimit, telemetry, NewPointCountTelemetryMock())
+	storage, err := newOnDiskRetryQueue(log, NewHTTPTransactionsSerializer(log, resolver.NewSingleDomainResolver(domainName, nil)), encryption, path, diskUsageLimit, telemetry, NewPointCountTelemetryMock())
 	a.NoError(err)
 	return storage
 }
@@ -80,6 +80,7 @@ var (
 	fileStoragePointDroppedCountTelemetry   *counterExpvar
 	deserializeErrorsCountTelemetry         *counterExpvar
 	deserializeTransactionsCountTelemetry   *counterExpvar
+	decryptionErrorsCountTelemetry          *counterExpvar
 )
 
 func init() {
@@ -205,6 +206,12 @@ func init() {
 		domainTag,
 		"The number of transactions read from the disk",
 		&fileStorageExpvar)
+	decryptionErrorsCountTelemetry = newCounterExpvar(
+		"file_storage",
+		"decryption_errors_count",
+		domainTag,
+		"The number of retry files which cannot be decrypted",
+		&fileStorageExpvar)
 }
 
 // FileRemovalPolicyTelemetry handles the telemetry for FileRemovalPolicy.
@@ -307,6 +314,10 @@ func (t onDiskRetryQueueTelemetry) addDeserializeTransactionsCount(count int) {
 	deserializeTransactionsCountTelemetry.add(float64(count), t.domainName)
 }
 
+func (t onDiskRetryQueueTelemetry) addDecryptionErrorsCount() {
+	decryptionErrorsCountTelemetry.add(1, t.domainName)
+}
+
 func toCamelCase(s string) string {
 	caser := cases.Title(language.English)
 	parts := strings.Split(s, "_")
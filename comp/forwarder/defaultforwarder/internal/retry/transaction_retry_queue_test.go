@@ -183,6 +183,7 @@ func newOnDiskRetryQueueTest(t *testing.T, a *assert.Assertions) *onDiskRetryQue
 	q, err := newOnDiskRetryQueue(
 		log,
 		NewHTTPTransactionsSerializer(log, resolver.NewSingleDomainResolver("", nil)),
+		nil,
 		path,
 		diskUsageLimit,
 		newOnDiskRetryQueueTelemetry("domain"),
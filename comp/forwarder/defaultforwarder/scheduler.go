@@ -0,0 +1,373 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package defaultforwarder
+
+import (
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+)
+
+// schedulerQuantum is the number of bytes a scheduling class of weight 1 may
+// send each time its turn comes.
+const schedulerQuantum = 64 * 1024
+
+// schedulingKinds are the transaction kinds having their own scheduling class.
+// The other kinds are scheduled with the series.
+var schedulingKinds = []transaction.Kind{
+	transaction.Series,
+	transaction.Sketches,
+	transaction.ServiceChecks,
+	transaction.Events,
+	transaction.CheckRuns,
+	transaction.Metadata,
+	transaction.Process,
+	transaction.Orchestrator,
+}
+
+var (
+	schedulers = struct {
+		sync.Mutex
+		byDomain map[string]*transactionScheduler
+	}{byDomain: map[string]*transactionScheduler{}}
+)
+
+func init() {
+	transaction.ForwarderExpvars.Set("Scheduling", expvar.Func(schedulingStatus))
+}
+
+// bandwidthBudgetOverride sets the bandwidth budget of a single domain.
+type bandwidthBudgetOverride struct {
+	Domain         string `mapstructure:"domain" json:"domain"`
+	BytesPerSecond int    `mapstructure:"bytes_per_second" json:"bytes_per_second"`
+}
+
+// getBandwidthBudget returns the number of bytes per second the forwarder may
+// send to a domain, 0 meaning unlimited. The overrides are matched against the
+// domain as configured, without the agent version.
+func getBandwidthBudget(config config.Component, log log.Component, configuredDomain string) int {
+	budget := config.GetInt("forwarder_bandwidth_budget")
+
+	var overrides []bandwidthBudgetOverride
+	if err := config.UnmarshalKey("forwarder_bandwidth_budget_overrides", &overrides); err != nil {
+		log.Errorf("Could not parse forwarder_bandwidth_budget_overrides: %v", err)
+	}
+	for _, o := range overrides {
+		if o.Domain == configuredDomain {
+			budget = o.BytesPerSecond
+		}
+	}
+
+	if budget < 0 {
+		log.Warnf("Invalid bandwidth budget %d for %s, the bandwidth is not limited", budget, configuredDomain)
+		return 0
+	}
+	return budget
+}
+
+// getSchedulingWeights returns the weight of every scheduling class, indexed
+// by transaction kind.
+func getSchedulingWeights(config config.Component, log log.Component) map[transaction.Kind]int {
+	weights := make(map[transaction.Kind]int, len(schedulingKinds))
+	for _, kind := range schedulingKinds {
+		key := "forwarder_scheduling_weights." + kind.String()
+		weight := config.GetInt(key)
+		if weight < 1 {
+			log.Warnf("Invalid weight %d for %s, using 1", weight, key)
+			weight = 1
+		}
+		weights[kind] = weight
+	}
+	return weights
+}
+
+// schedulingClass holds the transactions of a kind waiting to be sent. New
+// transactions are sent before the retried ones.
+type schedulingClass struct {
+	kind            transaction.Kind
+	weight          int
+	deficit         int
+	fresh           []transaction.Transaction
+	retries         []transaction.Transaction
+	dispatched      int64
+	dispatchedBytes int64
+}
+
+func (c *schedulingClass) head() transaction.Transaction {
+	if len(c.fresh) > 0 {
+		return c.fresh[0]
+	}
+	if len(c.retries) > 0 {
+		return c.retries[0]
+	}
+	return nil
+}
+
+// pop removes the head of the class and returns whether it was a retry.
+func (c *schedulingClass) pop() bool {
+	if len(c.fresh) > 0 {
+		c.fresh[0] = nil
+		c.fresh = c.fresh[1:]
+		return false
+	}
+	c.retries[0] = nil
+	c.retries = c.retries[1:]
+	return true
+}
+
+// bandwidthBudget is a token bucket limiting the number of bytes per second
+// sent to a domain, allowing bursts of one second worth of bytes. Transactions
+// larger than the bucket are sent as well: the bucket goes into debt and the
+// following transactions wait until it's paid back.
+type bandwidthBudget struct {
+	bytesPerSecond int
+	tokens         float64
+	last           time.Time
+	throttled      time.Duration
+	now            func() time.Time
+}
+
+func newBandwidthBudget(bytesPerSecond int) *bandwidthBudget {
+	return &bandwidthBudget{
+		bytesPerSecond: bytesPerSecond,
+		tokens:         float64(bytesPerSecond),
+		last:           time.Now(),
+		now:            time.Now,
+	}
+}
+
+// reserve takes size bytes from the budget and returns how long the caller must
+// wait before sending them.
+func (b *bandwidthBudget) reserve(size int) time.Duration {
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * float64(b.bytesPerSecond)
+	b.tokens = min(b.tokens, float64(b.bytesPerSecond))
+	b.last = now
+
+	b.tokens -= float64(size)
+	if b.tokens >= 0 {
+		return 0
+	}
+	delay := time.Duration(-b.tokens / float64(b.bytesPerSecond) * float64(time.Second))
+	b.throttled += delay
+	return delay
+}
+
+// transactionScheduler orders the transactions of a domain before they are
+// handed to the workers. Every kind of transaction has its own class and the
+// classes share the bandwidth according to their weight (deficit round robin
+// on the payload sizes), so that a burst of one kind can't delay the others.
+// The scheduler optionally enforces a bandwidth budget.
+type transactionScheduler struct {
+	m          sync.Mutex
+	classes    []*schedulingClass
+	byKind     map[transaction.Kind]*schedulingClass
+	cursor     int
+	credited   bool // whether the class under the cursor got its quantum for this round
+	freshCount int
+	retryCount int
+	maxFresh   int
+	maxRetries int
+	budget     *bandwidthBudget // nil when the bandwidth is not limited
+
+	// ready is notified when a transaction is added
+	ready chan struct{}
+}
+
+func newTransactionScheduler(weights map[transaction.Kind]int, maxFresh int, maxRetries int, bytesPerSecond int) *transactionScheduler {
+	s := &transactionScheduler{
+		byKind:     make(map[transaction.Kind]*schedulingClass, len(schedulingKinds)),
+		maxFresh:   maxFresh,
+		maxRetries: maxRetries,
+		ready:      make(chan struct{}, 1),
+	}
+	for _, kind := range schedulingKinds {
+		c := &schedulingClass{kind: kind, weight: max(weights[kind], 1)}
+		s.classes = append(s.classes, c)
+		s.byKind[kind] = c
+	}
+	if bytesPerSecond > 0 {
+		s.budget = newBandwidthBudget(bytesPerSecond)
+	}
+	return s
+}
+
+func (s *transactionScheduler) classOf(t transaction.Transaction) *schedulingClass {
+	if c, found := s.byKind[t.GetKind()]; found {
+		return c
+	}
+	return s.byKind[transaction.Series]
+}
+
+// add queues a transaction and returns false if the queue of the new or of the
+// retried transactions is full.
+func (s *transactionScheduler) add(t transaction.Transaction, retry bool) bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	c := s.classOf(t)
+	if retry {
+		if s.retryCount >= s.maxRetries {
+			return false
+		}
+		c.retries = append(c.retries, t)
+		s.retryCount++
+	} else {
+		if s.freshCount >= s.maxFresh {
+			return false
+		}
+		c.fresh = append(c.fresh, t)
+		s.freshCount++
+	}
+
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// next returns the next transaction to send, or nil if there is none.
+func (s *transactionScheduler) next() transaction.Transaction {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.freshCount+s.retryCount == 0 {
+		return nil
+	}
+
+	for {
+		c := s.classes[s.cursor]
+		t := c.head()
+		if t == nil {
+			c.deficit = 0
+			s.advance()
+			continue
+		}
+		if !s.credited {
+			c.deficit += c.weight * schedulerQuantum
+			s.credited = true
+		}
+
+		size := t.GetPayloadSize()
+		if size > c.deficit {
+			s.advance()
+			continue
+		}
+
+		c.deficit -= size
+		if c.pop() {
+			s.retryCount--
+		} else {
+			s.freshCount--
+		}
+		c.dispatched++
+		c.dispatchedBytes += int64(size)
+		if c.head() == nil {
+			// an idle class doesn't accumulate credit
+			c.deficit = 0
+			s.advance()
+		}
+		return t
+	}
+}
+
+func (s *transactionScheduler) advance() {
+	s.cursor = (s.cursor + 1) % len(s.classes)
+	s.credited = false
+}
+
+// reserveBandwidth returns how long to wait before sending size bytes.
+func (s *transactionScheduler) reserveBandwidth(size int) time.Duration {
+	if s.budget == nil {
+		return 0
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.budget.reserve(size)
+}
+
+// drain empties the scheduler and returns the new and the retried transactions
+// it contained.
+func (s *transactionScheduler) drain() ([]transaction.Transaction, []transaction.Transaction) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	var fresh, retries []transaction.Transaction
+	for _, c := range s.classes {
+		fresh = append(fresh, c.fresh...)
+		retries = append(retries, c.retries...)
+		c.fresh, c.retries, c.deficit = nil, nil, 0
+	}
+	s.freshCount, s.retryCount = 0, 0
+	return fresh, retries
+}
+
+// schedulingClassStats is the status of a scheduling class.
+type schedulingClassStats struct {
+	Weight          int
+	Queued          int
+	QueuedRetries   int
+	Dispatched      int64
+	DispatchedBytes int64
+}
+
+// schedulingStats is the status of the scheduler of a domain.
+type schedulingStats struct {
+	BandwidthBudget  int
+	ThrottledSeconds float64
+	Classes          map[string]schedulingClassStats
+}
+
+func (s *transactionScheduler) stats() schedulingStats {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	stats := schedulingStats{Classes: make(map[string]schedulingClassStats, len(s.classes))}
+	if s.budget != nil {
+		stats.BandwidthBudget = s.budget.bytesPerSecond
+		stats.ThrottledSeconds = s.budget.throttled.Seconds()
+	}
+	for _, c := range s.classes {
+		stats.Classes[c.kind.String()] = schedulingClassStats{
+			Weight:          c.weight,
+			Queued:          len(c.fresh),
+			QueuedRetries:   len(c.retries),
+			Dispatched:      c.dispatched,
+			DispatchedBytes: c.dispatchedBytes,
+		}
+	}
+	return stats
+}
+
+func registerScheduler(domain string, s *transactionScheduler) {
+	schedulers.Lock()
+	defer schedulers.Unlock()
+	schedulers.byDomain[domain] = s
+}
+
+func unregisterScheduler(domain string) {
+	schedulers.Lock()
+	defer schedulers.Unlock()
+	delete(schedulers.byDomain, domain)
+}
+
+// schedulingStatus returns the status of the schedulers of all the domains,
+// exposed in the forwarder expvars.
+func schedulingStatus() interface{} {
+	schedulers.Lock()
+	defer schedulers.Unlock()
+
+	status := make(map[string]schedulingStats, len(schedulers.byDomain))
+	for domain, s := range schedulers.byDomain {
+		status[domain] = s.stats()
+	}
+	return status
+}
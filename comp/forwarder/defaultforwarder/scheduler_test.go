@@ -0,0 +1,211 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package defaultforwarder
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	logmock "github.com/DataDog/datadog-agent/comp/core/log/mock"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	mock "github.com/DataDog/datadog-agent/pkg/config/mock"
+)
+
+func newScheduledTransaction(kind transaction.Kind, size int) *testTransaction {
+	tr := newTestTransactionWithKind(kind)
+	tr.On("GetPayloadSize").Return(size)
+	return tr
+}
+
+func requireNextTransactions(t *testing.T, s *transactionScheduler, expected ...transaction.Transaction) {
+	for i, tr := range expected {
+		require.Equal(t, tr, s.next(), "transaction %d", i)
+	}
+	require.Nil(t, s.next())
+}
+
+func TestSchedulerWeights(t *testing.T) {
+	s := newTransactionScheduler(map[transaction.Kind]int{transaction.Series: 2}, 10, 10, 0)
+
+	series := make([]*testTransaction, 4)
+	for i := range series {
+		series[i] = newScheduledTransaction(transaction.Series, schedulerQuantum)
+		require.True(t, s.add(series[i], false))
+	}
+	events := make([]*testTransaction, 2)
+	for i := range events {
+		events[i] = newScheduledTransaction(transaction.Events, schedulerQuantum)
+		require.True(t, s.add(events[i], false))
+	}
+
+	// the series get twice the bandwidth of the events
+	requireNextTransactions(t, s, series[0], series[1], events[0], series[2], series[3], events[1])
+
+	stats := s.stats()
+	assert.Equal(t, schedulingClassStats{Weight: 2, Dispatched: 4, DispatchedBytes: 4 * schedulerQuantum}, stats.Classes["series"])
+	assert.Equal(t, schedulingClassStats{Weight: 1, Dispatched: 2, DispatchedBytes: 2 * schedulerQuantum}, stats.Classes["events"])
+	assert.Equal(t, schedulingClassStats{Weight: 1}, stats.Classes["orchestrator"])
+}
+
+func TestSchedulerLargeTransactions(t *testing.T) {
+	s := newTransactionScheduler(nil, 10, 10, 0)
+
+	// a transaction larger than the quantum waits for its class to get enough credit
+	large := newScheduledTransaction(transaction.Series, 2*schedulerQuantum)
+	small := newScheduledTransaction(transaction.Sketches, 10)
+	small2 := newScheduledTransaction(transaction.Sketches, 10)
+	require.True(t, s.add(large, false))
+	require.True(t, s.add(small, false))
+	require.True(t, s.add(small2, false))
+
+	requireNextTransactions(t, s, small, small2, large)
+}
+
+func TestSchedulerRetries(t *testing.T) {
+	s := newTransactionScheduler(nil, 1, 1, 0)
+
+	retry := newScheduledTransaction(transaction.Metadata, 10)
+	fresh := newScheduledTransaction(transaction.Metadata, 10)
+	require.True(t, s.add(retry, true))
+	require.True(t, s.add(fresh, false))
+
+	// the queues are full
+	assert.False(t, s.add(newScheduledTransaction(transaction.Series, 10), false))
+	assert.False(t, s.add(newScheduledTransaction(transaction.Series, 10), true))
+
+	stats := s.stats()
+	assert.Equal(t, 1, stats.Classes["metadata"].Queued)
+	assert.Equal(t, 1, stats.Classes["metadata"].QueuedRetries)
+
+	// the new transactions are sent first
+	requireNextTransactions(t, s, fresh, retry)
+
+	require.True(t, s.add(retry, true))
+	require.True(t, s.add(fresh, false))
+	freshTransactions, retries := s.drain()
+	assert.Equal(t, []transaction.Transaction{fresh}, freshTransactions)
+	assert.Equal(t, []transaction.Transaction{retry}, retries)
+	assert.Nil(t, s.next())
+}
+
+func TestBandwidthBudget(t *testing.T) {
+	now := time.Now()
+	b := newBandwidthBudget(1000)
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	assert.Equal(t, time.Duration(0), b.reserve(500))
+	assert.Equal(t, 100*time.Millisecond, b.reserve(600))
+
+	// the debt is paid back over time
+	now = now.Add(time.Second)
+	assert.Equal(t, time.Duration(0), b.reserve(900))
+
+	// the unused budget doesn't accumulate over more than a second
+	now = now.Add(time.Hour)
+	assert.Equal(t, time.Duration(0), b.reserve(1000))
+	assert.Equal(t, time.Second, b.reserve(1000))
+	assert.Equal(t, 1100*time.Millisecond, b.throttled)
+}
+
+func TestGetBandwidthBudget(t *testing.T) {
+	mockConfig := mock.New(t)
+	log := logmock.New(t)
+	mockConfig.SetConfigType("yaml")
+	require.NoError(t, mockConfig.ReadConfig(bytes.NewBufferString(`
+forwarder_bandwidth_budget: 1000
+forwarder_bandwidth_budget_overrides:
+  - domain: https://app.datadoghq.eu
+    bytes_per_second: 0
+  - domain: https://app.datadoghq.com
+    bytes_per_second: 5000
+`)))
+
+	assert.Equal(t, 5000, getBandwidthBudget(mockConfig, log, "https://app.datadoghq.com"))
+	assert.Equal(t, 0, getBandwidthBudget(mockConfig, log, "https://app.datadoghq.eu"))
+	assert.Equal(t, 1000, getBandwidthBudget(mockConfig, log, "https://custom.example.com"))
+}
+
+func TestGetSchedulingWeights(t *testing.T) {
+	mockConfig := mock.New(t)
+	log := logmock.New(t)
+	mockConfig.SetWithoutSource("forwarder_scheduling_weights.series", 4)
+	mockConfig.SetWithoutSource("forwarder_scheduling_weights.events", -1)
+
+	weights := getSchedulingWeights(mockConfig, log)
+	assert.Equal(t, 4, weights[transaction.Series])
+	assert.Equal(t, 1, weights[transaction.Events])
+	assert.Equal(t, 1, weights[transaction.Orchestrator])
+	assert.Len(t, weights, len(schedulingKinds))
+}
+
+func TestDomainForwarderScheduling(t *testing.T) {
+	mockConfig := mock.New(t)
+	mockConfig.SetWithoutSource("forwarder_weighted_scheduling", true)
+	log := logmock.New(t)
+	forwarder := newDomainForwarderForTest(mockConfig, log, 0, false)
+
+	forwarder.Start()
+	require.NotNil(t, forwarder.scheduler)
+	assert.Contains(t, schedulingStatus(), "test")
+
+	// Stopping the worker to read the dispatched transactions
+	forwarder.workers[0].Stop(false)
+	forwarder.workers = nil
+
+	series := make([]*testTransaction, 3)
+	for i := range series {
+		series[i] = newScheduledTransaction(transaction.Series, schedulerQuantum)
+		forwarder.sendHTTPTransactions(series[i])
+	}
+	event := newScheduledTransaction(transaction.Events, schedulerQuantum)
+	forwarder.sendHTTPTransactions(event)
+
+	for _, expected := range []transaction.Transaction{series[0], event, series[1], series[2]} {
+		select {
+		case tr := <-forwarder.highPrio:
+			assert.Equal(t, expected, tr)
+		case <-time.After(2 * time.Second):
+			require.FailNow(t, "timeout waiting for a transaction")
+		}
+	}
+
+	// the transactions still scheduled are moved to the retry queue when stopping
+	forwarder.sendHTTPTransactions(newScheduledTransaction(transaction.Series, 1))
+	forwarder.sendHTTPTransactions(newScheduledTransaction(transaction.Events, 1))
+	forwarder.Stop(false)
+	requireLenForwarderRetryQueue(t, forwarder, 2)
+	assert.NotContains(t, schedulingStatus(), "test")
+}
+
+func TestDomainForwarderBandwidthBudget(t *testing.T) {
+	mockConfig := mock.New(t)
+	log := logmock.New(t)
+	forwarder := newDomainForwarderForTest(mockConfig, log, 0, false)
+	forwarder.bandwidthBudget = 1000
+
+	// a budget enables the scheduler
+	forwarder.Start()
+	defer forwarder.Stop(false)
+	require.NotNil(t, forwarder.scheduler)
+	require.NotNil(t, forwarder.scheduler.budget)
+	forwarder.workers[0].Stop(false)
+	forwarder.workers = nil
+
+	start := time.Now()
+	forwarder.sendHTTPTransactions(newScheduledTransaction(transaction.Series, 1000))
+	forwarder.sendHTTPTransactions(newScheduledTransaction(transaction.Series, 200))
+	<-forwarder.highPrio
+	<-forwarder.highPrio
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	assert.Equal(t, 1000, forwarder.scheduler.stats().BandwidthBudget)
+}
@@ -85,3 +85,21 @@ func TestHTML(t *testing.T) {
 
 	assert.NotEqual(t, "", b.String())
 }
+
+func TestTextScheduling(t *testing.T) {
+	config := config.NewMock(t)
+
+	provider := statusProvider{
+		config: config,
+	}
+
+	registerScheduler("https://app.datadoghq.com", newTransactionScheduler(nil, 1, 1, 1000))
+	defer unregisterScheduler("https://app.datadoghq.com")
+
+	b := new(bytes.Buffer)
+	provider.Text(false, b)
+
+	assert.Contains(t, b.String(), "https://app.datadoghq.com:")
+	assert.Contains(t, b.String(), "Bandwidth budget: 1,000 bytes/s")
+	assert.Contains(t, b.String(), "series (weight 1): 0 sent (0 bytes), 0 queued, 0 retries queued")
+}
@@ -192,8 +192,34 @@ const (
 	Metadata
 	// Process is the transaction type for live-process monitoring payloads
 	Process
+	// Orchestrator is the transaction type for orchestrator checks and manifests
+	Orchestrator
 )
 
+// String returns the name of the transaction kind
+func (k Kind) String() string {
+	switch k {
+	case Series:
+		return "series"
+	case Sketches:
+		return "sketches"
+	case ServiceChecks:
+		return "service_checks"
+	case Events:
+		return "events"
+	case CheckRuns:
+		return "check_runs"
+	case Metadata:
+		return "metadata"
+	case Process:
+		return "process"
+	case Orchestrator:
+		return "orchestrator"
+	default:
+		return "unknown"
+	}
+}
+
 // Destination indicates which regions the transaction should be sent to
 type Destination int
 
@@ -246,6 +246,9 @@ func BuildHTTPEndpointsWithConfig(coreConfig pkgconfigmodel.Reader, logsConfig *
 		e.IsMRF = true
 		e.UseCompression = main.UseCompression
 		e.CompressionLevel = main.CompressionLevel
+		e.CompressionKind = main.CompressionKind
+		e.GzipCompressionLevel = main.GzipCompressionLevel
+		e.ZstdDictionaryPath = main.ZstdDictionaryPath
 		e.BackoffBase = main.BackoffBase
 		e.BackoffMax = main.BackoffMax
 		e.BackoffFactor = main.BackoffFactor
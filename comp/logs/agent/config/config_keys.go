@@ -7,6 +7,7 @@ package config
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
 
 	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
@@ -112,6 +113,27 @@ func (l *LogsConfigKeys) compressionLevel() int {
 	return l.getConfig().GetInt(l.getConfigKey("compression_level"))
 }
 
+func (l *LogsConfigKeys) compressionKind() string {
+	kind := strings.ToLower(l.getConfig().GetString(l.getConfigKey("compression_kind")))
+	switch kind {
+	case GzipCompressionKind, ZstdCompressionKind:
+		return kind
+	case "":
+		return GzipCompressionKind
+	default:
+		log.Warnf("Invalid %s: %s, falling back to %s", l.getConfigKey("compression_kind"), kind, GzipCompressionKind)
+		return GzipCompressionKind
+	}
+}
+
+func (l *LogsConfigKeys) zstdCompressionLevel() int {
+	return l.getConfig().GetInt(l.getConfigKey("zstd_compression_level"))
+}
+
+func (l *LogsConfigKeys) zstdDictionaryPath() string {
+	return l.getConfig().GetString(l.getConfigKey("zstd_dictionary_path"))
+}
+
 func (l *LogsConfigKeys) useCompression() bool {
 	return l.getConfig().GetBool(l.getConfigKey("use_compression"))
 }
@@ -11,6 +11,7 @@ import (
 
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	pkgconfigutils "github.com/DataDog/datadog-agent/pkg/config/utils"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // EPIntakeVersion is the events platform intake API version
@@ -33,6 +34,28 @@ const (
 	EPIntakeVersion2
 )
 
+const (
+	// GzipCompressionKind compresses payloads with gzip
+	GzipCompressionKind = "gzip"
+	// ZstdCompressionKind compresses payloads with zstd
+	ZstdCompressionKind = "zstd"
+)
+
+// EndpointFormat is the format of the payloads sent to an endpoint.
+type EndpointFormat string
+
+const (
+	// DatadogEndpointFormat sends the payloads in the format of the Datadog intake
+	DatadogEndpointFormat EndpointFormat = ""
+	// OTLPProtobufEndpointFormat sends the logs to an OTLP/HTTP receiver, encoded in protobuf
+	OTLPProtobufEndpointFormat EndpointFormat = "otlp_proto"
+	// OTLPJSONEndpointFormat sends the logs to an OTLP/HTTP receiver, encoded in JSON
+	OTLPJSONEndpointFormat EndpointFormat = "otlp_json"
+)
+
+// otlpHTTPDefaultPort is the default port of the OTLP/HTTP receivers.
+const otlpHTTPDefaultPort = 4318
+
 // Endpoint holds all the organization and network parameters to send logs to Datadog.
 type Endpoint struct {
 	apiKeyGetter func() string
@@ -43,6 +66,9 @@ type Endpoint struct {
 	Port                    int
 	UseCompression          bool `mapstructure:"use_compression" json:"use_compression"`
 	CompressionLevel        int  `mapstructure:"compression_level" json:"compression_level"`
+	CompressionKind         string
+	GzipCompressionLevel    int // level of the gzip fallback of the zstd kind, whose CompressionLevel is the zstd level
+	ZstdDictionaryPath      string
 	ProxyAddress            string
 	IsMRF                   bool `mapstructure:"-" json:"-"`
 	ConnectionResetInterval time.Duration
@@ -57,6 +83,8 @@ type Endpoint struct {
 	TrackType IntakeTrackType
 	Protocol  IntakeProtocol
 	Origin    IntakeOrigin
+
+	Format EndpointFormat `mapstructure:"format" json:"format"`
 }
 
 // unmarshalEndpoint is used to load additional endpoints from the configuration which stored as JSON/mapstructure.
@@ -96,10 +124,19 @@ func NewTCPEndpoint(logsConfig *LogsConfigKeys) Endpoint {
 // NewHTTPEndpoint returns a new HTTP Endpoint based on LogsConfigKeys The endpoint is by default reliable and will use
 // the settings related to HTTP from the configuration (compression, Backoff, recovery, ...).
 func NewHTTPEndpoint(logsConfig *LogsConfigKeys) Endpoint {
+	compressionKind := logsConfig.compressionKind()
+	compressionLevel := logsConfig.compressionLevel()
+	if compressionKind == ZstdCompressionKind {
+		compressionLevel = logsConfig.zstdCompressionLevel()
+	}
+
 	return Endpoint{
 		apiKeyGetter:            logsConfig.getAPIKeyGetter(),
 		UseCompression:          logsConfig.useCompression(),
-		CompressionLevel:        logsConfig.compressionLevel(),
+		CompressionLevel:        compressionLevel,
+		CompressionKind:         compressionKind,
+		GzipCompressionLevel:    logsConfig.compressionLevel(),
+		ZstdDictionaryPath:      logsConfig.zstdDictionaryPath(),
 		ConnectionResetInterval: logsConfig.connectionResetInterval(),
 		BackoffBase:             logsConfig.senderBackoffBase(),
 		BackoffMax:              logsConfig.senderBackoffMax(),
@@ -156,6 +193,9 @@ func loadHTTPAdditionalEndpoints(main Endpoint, l *LogsConfigKeys, intakeTrackTy
 
 		newE.UseCompression = main.UseCompression
 		newE.CompressionLevel = main.CompressionLevel
+		newE.CompressionKind = main.CompressionKind
+		newE.GzipCompressionLevel = main.GzipCompressionLevel
+		newE.ZstdDictionaryPath = main.ZstdDictionaryPath
 		newE.ProxyAddress = e.ProxyAddress
 		newE.isReliable = e.IsReliable == nil || *e.IsReliable
 		newE.ConnectionResetInterval = e.ConnectionResetInterval
@@ -168,6 +208,20 @@ func loadHTTPAdditionalEndpoints(main Endpoint, l *LogsConfigKeys, intakeTrackTy
 		newE.TrackType = e.TrackType
 		newE.Protocol = e.Protocol
 		newE.Origin = e.Origin
+		newE.Format = e.Format
+
+		if !newE.Format.isValid() {
+			log.Warnf("Ignoring the logs additional endpoint %s with the unknown format %q", e.Host, e.Format)
+			continue
+		}
+		if newE.IsOTLP() {
+			// OTLP receivers only support gzip
+			newE.CompressionKind = GzipCompressionKind
+			newE.CompressionLevel = main.GzipCompressionLevel
+			if newE.Port == 0 {
+				newE.Port = otlpHTTPDefaultPort
+			}
+		}
 
 		if e.UseSSL != nil {
 			newE.useSSL = *e.UseSSL
@@ -189,6 +243,19 @@ func loadHTTPAdditionalEndpoints(main Endpoint, l *LogsConfigKeys, intakeTrackTy
 	return newEndpoints
 }
 
+// IsOTLP returns whether the endpoint is an OTLP/HTTP receiver.
+func (e *Endpoint) IsOTLP() bool {
+	return e.Format == OTLPProtobufEndpointFormat || e.Format == OTLPJSONEndpointFormat
+}
+
+func (f EndpointFormat) isValid() bool {
+	switch f {
+	case DatadogEndpointFormat, OTLPProtobufEndpointFormat, OTLPJSONEndpointFormat:
+		return true
+	}
+	return false
+}
+
 // GetAPIKey returns the latest API Key for the Endpoint, including when the configuration gets updated at runtime
 func (e *Endpoint) GetAPIKey() string {
 	return e.apiKeyGetter()
@@ -204,13 +271,22 @@ func (e *Endpoint) GetStatus(prefix string, useHTTP bool) string {
 	compression := "uncompressed"
 	if e.UseCompression {
 		compression = "compressed"
+		if e.CompressionKind == ZstdCompressionKind {
+			compression = "zstd compressed"
+		}
 	}
 
 	host := e.Host
 	port := e.Port
 
 	var protocol string
-	if useHTTP {
+	if useHTTP && e.IsOTLP() {
+		if e.UseSSL() {
+			protocol = "OTLP/HTTPS"
+		} else {
+			protocol = "OTLP/HTTP"
+		}
+	} else if useHTTP {
 		if e.UseSSL() {
 			protocol = "HTTPS"
 			if port == 0 {
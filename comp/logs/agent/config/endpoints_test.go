@@ -168,6 +168,41 @@ func (suite *EndpointsTestSuite) TestBuildEndpointsShouldSucceedWithValidHTTPCon
 	suite.Equal(endpoint.CompressionLevel, 1)
 }
 
+func (suite *EndpointsTestSuite) TestBuildEndpointsShouldSucceedWithValidHTTPConfigAndZstdCompression() {
+	var endpoints *Endpoints
+	var endpoint Endpoint
+	var err error
+
+	suite.config.SetWithoutSource("logs_config.use_http", true)
+	suite.config.SetWithoutSource("logs_config.use_compression", true)
+	suite.config.SetWithoutSource("logs_config.compression_kind", "zstd")
+	suite.config.SetWithoutSource("logs_config.zstd_compression_level", 3)
+	suite.config.SetWithoutSource("logs_config.zstd_dictionary_path", "/etc/datadog-agent/logs.dict")
+
+	endpoints, err = BuildEndpoints(suite.config, HTTPConnectivityFailure, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.True(endpoints.UseHTTP)
+
+	endpoint = endpoints.Main
+	suite.True(endpoint.UseCompression)
+	suite.Equal(ZstdCompressionKind, endpoint.CompressionKind)
+	suite.Equal(3, endpoint.CompressionLevel)
+	suite.Equal(6, endpoint.GzipCompressionLevel)
+	suite.Equal("/etc/datadog-agent/logs.dict", endpoint.ZstdDictionaryPath)
+}
+
+func (suite *EndpointsTestSuite) TestBuildEndpointsShouldFallbackToGzipWithInvalidCompressionKind() {
+	suite.config.SetWithoutSource("logs_config.use_http", true)
+	suite.config.SetWithoutSource("logs_config.use_compression", true)
+	suite.config.SetWithoutSource("logs_config.compression_kind", "lz4")
+
+	endpoints, err := BuildEndpoints(suite.config, HTTPConnectivityFailure, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+
+	suite.Equal(GzipCompressionKind, endpoints.Main.CompressionKind)
+	suite.Equal(6, endpoints.Main.CompressionLevel)
+}
+
 func (suite *EndpointsTestSuite) TestBuildEndpointsShouldSucceedWithValidHTTPConfigAndOverride() {
 	var endpoints *Endpoints
 	var endpoint Endpoint
@@ -766,6 +801,37 @@ func (suite *EndpointsTestSuite) TestloadHTTPAdditionalEndpoints() {
 	compareEndpoint(suite.T(), expected2, endpoints[1])
 }
 
+func (suite *EndpointsTestSuite) TestOTLPAdditionalEndpoints() {
+	suite.config.SetWithoutSource("logs_config.use_http", true)
+	suite.config.SetWithoutSource("logs_config.compression_kind", ZstdCompressionKind)
+	suite.config.SetWithoutSource("logs_config.additional_endpoints", []map[string]interface{}{
+		{
+			"host":        "otel-collector",
+			"format":      "otlp_proto",
+			"use_ssl":     false,
+			"is_reliable": false,
+		},
+		{
+			"host":   "unknown",
+			"format": "syslog",
+		},
+	})
+
+	endpoints, err := BuildEndpoints(suite.config, HTTPConnectivitySuccess, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.Len(endpoints.Endpoints, 2)
+
+	endpoint := endpoints.Endpoints[1]
+	suite.Equal("otel-collector", endpoint.Host)
+	suite.True(endpoint.IsOTLP())
+	suite.Equal(OTLPProtobufEndpointFormat, endpoint.Format)
+	suite.Equal(4318, endpoint.Port)
+	suite.Equal(GzipCompressionKind, endpoint.CompressionKind)
+	suite.Equal(6, endpoint.CompressionLevel)
+	suite.False(endpoint.IsReliable())
+	suite.Equal("Sending compressed logs in OTLP/HTTP to otel-collector on port 4318", endpoint.GetStatus("", true))
+}
+
 func TestEndpointsTestSuite(t *testing.T) {
 	suite.Run(t, new(EndpointsTestSuite))
 }
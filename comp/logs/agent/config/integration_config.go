@@ -8,6 +8,7 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"sync"
 
@@ -32,6 +33,18 @@ const (
 	UTF16LE string = "utf-16-le"
 	// SHIFTJIS for Shift JIS (Japanese) encoding
 	SHIFTJIS string = "shift-jis"
+
+	// SyslogFormat for syslog messages in the RFC5424 or RFC3164 formats
+	SyslogFormat string = "syslog"
+
+	// KubeAuditLogFormat for the audit events of the kube-apiserver
+	KubeAuditLogFormat string = "kube_audit"
+	// NginxLogFormat for the access logs of nginx in the combined log format
+	NginxLogFormat string = "nginx"
+	// ApacheLogFormat for the access logs of apache in the combined log format
+	ApacheLogFormat string = "apache"
+	// LogfmtLogFormat for logfmt lines
+	LogfmtLogFormat string = "logfmt"
 )
 
 // LogsConfig represents a log source config, which can be for instance
@@ -43,12 +56,17 @@ type LogsConfig struct {
 
 	Port        int    // Network
 	IdleTimeout string `mapstructure:"idle_timeout" json:"idle_timeout"` // Network
+	Format      string `mapstructure:"format" json:"format"`             // Network
 	Path        string // File, Journald
 
 	Encoding     string   `mapstructure:"encoding" json:"encoding"`             // File
+	LogFormat    string   `mapstructure:"log_format" json:"log_format"`         // File
 	ExcludePaths []string `mapstructure:"exclude_paths" json:"exclude_paths"`   // File
 	TailingMode  string   `mapstructure:"start_position" json:"start_position"` // File
 
+	TailRotatedFiles    bool   `mapstructure:"tail_rotated_files" json:"tail_rotated_files"`       // File
+	RotatedFilesPattern string `mapstructure:"rotated_files_pattern" json:"rotated_files_pattern"` // File
+
 	//nolint:revive // TODO(AML) Fix revive linter
 	ConfigId           string   `mapstructure:"config_id" json:"config_id"`                   // Journald
 	IncludeSystemUnits []string `mapstructure:"include_units" json:"include_units"`           // Journald
@@ -114,15 +132,20 @@ func (c *LogsConfig) Dump(multiline bool) string {
 	case TCPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+		fmt.Fprintf(&b, ws("Format: %#v,"), c.Format)
 	case UDPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+		fmt.Fprintf(&b, ws("Format: %#v,"), c.Format)
 	case FileType:
 		fmt.Fprintf(&b, ws("Path: %#v,"), c.Path)
 		fmt.Fprintf(&b, ws("Encoding: %#v,"), c.Encoding)
+		fmt.Fprintf(&b, ws("LogFormat: %#v,"), c.LogFormat)
 		fmt.Fprintf(&b, ws("Identifier: %#v,"), c.Identifier)
 		fmt.Fprintf(&b, ws("ExcludePaths: %#v,"), c.ExcludePaths)
 		fmt.Fprintf(&b, ws("TailingMode: %#v,"), c.TailingMode)
+		fmt.Fprintf(&b, ws("TailRotatedFiles: %t,"), c.TailRotatedFiles)
+		fmt.Fprintf(&b, ws("RotatedFilesPattern: %#v,"), c.RotatedFilesPattern)
 	case DockerType, ContainerdType:
 		fmt.Fprintf(&b, ws("Image: %#v,"), c.Image)
 		fmt.Fprintf(&b, ws("Label: %#v,"), c.Label)
@@ -172,12 +195,16 @@ func (c *LogsConfig) PublicJSON() ([]byte, error) {
 	// Export only fields that are explicitly documented in the public documentation
 	return json.Marshal(&struct {
 		Type            string            `json:"type,omitempty"`
-		Port            int               `json:"port,omitempty"`           // Network
-		Path            string            `json:"path,omitempty"`           // File, Journald
-		Encoding        string            `json:"encoding,omitempty"`       // File
-		ExcludePaths    []string          `json:"exclude_paths,omitempty"`  // File
-		TailingMode     string            `json:"start_position,omitempty"` // File
-		ChannelPath     string            `json:"channel_path,omitempty"`   // Windows Event
+		Port            int               `json:"port,omitempty"`                  // Network
+		Format          string            `json:"format,omitempty"`                // Network
+		Path            string            `json:"path,omitempty"`                  // File, Journald
+		Encoding        string            `json:"encoding,omitempty"`              // File
+		LogFormat       string            `json:"log_format,omitempty"`            // File
+		ExcludePaths    []string          `json:"exclude_paths,omitempty"`         // File
+		TailingMode     string            `json:"start_position,omitempty"`        // File
+		TailRotated     bool              `json:"tail_rotated_files,omitempty"`    // File
+		RotatedPattern  string            `json:"rotated_files_pattern,omitempty"` // File
+		ChannelPath     string            `json:"channel_path,omitempty"`          // Windows Event
 		Service         string            `json:"service,omitempty"`
 		Source          string            `json:"source,omitempty"`
 		Tags            []string          `json:"tags,omitempty"`
@@ -186,10 +213,14 @@ func (c *LogsConfig) PublicJSON() ([]byte, error) {
 	}{
 		Type:            c.Type,
 		Port:            c.Port,
+		Format:          c.Format,
 		Path:            c.Path,
 		Encoding:        c.Encoding,
+		LogFormat:       c.LogFormat,
 		ExcludePaths:    c.ExcludePaths,
 		TailingMode:     c.TailingMode,
+		TailRotated:     c.TailRotatedFiles,
+		RotatedPattern:  c.RotatedFilesPattern,
 		ChannelPath:     c.ChannelPath,
 		Service:         c.Service,
 		Source:          c.Source,
@@ -256,10 +287,18 @@ func (c *LogsConfig) Validate() error {
 		if err != nil {
 			return err
 		}
+		if _, err := filepath.Match(c.RotatedFilesPattern, ""); err != nil {
+			return fmt.Errorf("invalid rotated_files_pattern '%v' for %v: %v", c.RotatedFilesPattern, c.Path, err)
+		}
+		if !isValidLogFormat(c.LogFormat) {
+			return fmt.Errorf("invalid log_format '%v' for %v, the supported formats are '%v', '%v', '%v' and '%v'", c.LogFormat, c.Path, KubeAuditLogFormat, NginxLogFormat, ApacheLogFormat, LogfmtLogFormat)
+		}
 	case c.Type == TCPType && c.Port == 0:
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case (c.Type == TCPType || c.Type == UDPType) && c.Format != "" && c.Format != SyslogFormat:
+		return fmt.Errorf("invalid format '%v' for %v source, the supported format is '%v'", c.Format, c.Type, SyslogFormat)
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
@@ -268,6 +307,15 @@ func (c *LogsConfig) Validate() error {
 	return CompileProcessingRules(c.ProcessingRules)
 }
 
+func isValidLogFormat(format string) bool {
+	switch format {
+	case "", KubeAuditLogFormat, NginxLogFormat, ApacheLogFormat, LogfmtLogFormat:
+		return true
+	default:
+		return false
+	}
+}
+
 func (c *LogsConfig) validateTailingMode() error {
 	mode, found := TailingModeFromString(c.TailingMode)
 	if !found && c.TailingMode != "" {
@@ -16,6 +16,28 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	RemapFields    = "remap_fields"
+	Sample         = "sample"
+	RateLimit      = "rate_limit"
+	GenerateMetric = "generate_metric"
+)
+
+// Metric types supported by the generate_metric processing rule
+const (
+	GeneratedMetricCount        = "count"
+	GeneratedMetricDistribution = "distribution"
+)
+
+// Keys supported by the sample and rate_limit processing rules
+const (
+	SamplingKeyStatus       = "status"
+	SamplingKeyCaptureGroup = "capture_group"
+)
+
+// Formats supported by the remap_fields processing rule
+const (
+	RemapFormatJSON   = "json"
+	RemapFormatLogfmt = "logfmt"
 )
 
 // ProcessingRule defines an exclusion or a masking rule to
@@ -25,6 +47,24 @@ type ProcessingRule struct {
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+	// Remapping settings, only used by the remap_fields rule
+	Format          string            `mapstructure:"format" json:"format"`
+	StatusField     string            `mapstructure:"status_field" json:"status_field"`
+	ServiceField    string            `mapstructure:"service_field" json:"service_field"`
+	TimestampField  string            `mapstructure:"timestamp_field" json:"timestamp_field"`
+	TimestampLayout string            `mapstructure:"timestamp_layout" json:"timestamp_layout"`
+	TagFields       []string          `mapstructure:"tag_fields" json:"tag_fields"`
+	DropFields      []string          `mapstructure:"drop_fields" json:"drop_fields"`
+	RenameFields    map[string]string `mapstructure:"rename_fields" json:"rename_fields"`
+	// Sampling settings, only used by the sample and rate_limit rules
+	SampleRate     float64 `mapstructure:"sample_rate" json:"sample_rate"`
+	LinesPerSecond float64 `mapstructure:"lines_per_second" json:"lines_per_second"`
+	Burst          int     `mapstructure:"burst" json:"burst"`
+	Key            string  `mapstructure:"key" json:"key"`
+	// Metric settings, only used by the generate_metric rule
+	MetricName string `mapstructure:"metric_name" json:"metric_name"`
+	MetricType string `mapstructure:"metric_type" json:"metric_type"`
+	ValueGroup string `mapstructure:"value_group" json:"value_group"`
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
@@ -34,7 +74,7 @@ type ProcessingRule struct {
 // Each processing rule must have:
 // - a valid name
 // - a valid type
-// - a valid pattern that compiles
+// - a valid pattern that compiles, optional for remap_fields, sample and rate_limit rules
 func ValidateProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
 		if rule.Name == "" {
@@ -44,6 +84,21 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		switch rule.Type {
 		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
 			break
+		case RemapFields:
+			if err := validateRemapFields(rule); err != nil {
+				return err
+			}
+			continue
+		case Sample, RateLimit:
+			if err := validateSampling(rule); err != nil {
+				return err
+			}
+			continue
+		case GenerateMetric:
+			if err := validateGenerateMetric(rule); err != nil {
+				return err
+			}
+			continue
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
@@ -64,12 +119,15 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		if rule.Pattern == "" && (rule.Type == RemapFields || rule.Type == Sample || rule.Type == RateLimit) {
+			continue
+		}
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, RemapFields, Sample, RateLimit, GenerateMetric:
 			rule.Regex = re
 		case MaskSequences:
 			rule.Regex = re
@@ -83,3 +141,89 @@ func CompileProcessingRules(rules []*ProcessingRule) error {
 	}
 	return nil
 }
+
+// validateRemapFields validates a remap_fields rule, its pattern is optional and
+// restricts the rule to the matching lines when set.
+func validateRemapFields(rule *ProcessingRule) error {
+	switch rule.Format {
+	case RemapFormatJSON, RemapFormatLogfmt:
+	case "":
+		return fmt.Errorf("format must be set for processing rule `%s`", rule.Name)
+	default:
+		return fmt.Errorf("format %s is not supported for processing rule `%s`", rule.Format, rule.Name)
+	}
+
+	if rule.StatusField == "" && rule.ServiceField == "" && rule.TimestampField == "" &&
+		len(rule.TagFields) == 0 && len(rule.DropFields) == 0 && len(rule.RenameFields) == 0 {
+		return fmt.Errorf("no field to remap provided for processing rule: %s", rule.Name)
+	}
+
+	if rule.Pattern != "" {
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
+		}
+	}
+	return nil
+}
+
+// validateSampling validates a sample or rate_limit rule, its pattern is optional and
+// restricts the rule to the matching lines when set.
+func validateSampling(rule *ProcessingRule) error {
+	if rule.Type == Sample && (rule.SampleRate < 0 || rule.SampleRate > 1) {
+		return fmt.Errorf("sample_rate must be between 0 and 1 for processing rule: %s", rule.Name)
+	}
+	if rule.Type == RateLimit && rule.LinesPerSecond <= 0 {
+		return fmt.Errorf("lines_per_second must be greater than 0 for processing rule: %s", rule.Name)
+	}
+	if rule.Burst < 0 {
+		return fmt.Errorf("burst can't be negative for processing rule: %s", rule.Name)
+	}
+
+	var re *regexp.Regexp
+	if rule.Pattern != "" {
+		var err error
+		if re, err = regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
+		}
+	}
+
+	switch rule.Key {
+	case "", SamplingKeyStatus:
+	case SamplingKeyCaptureGroup:
+		if re == nil || re.NumSubexp() == 0 {
+			return fmt.Errorf("a pattern with a capture group is required by the key %s for processing rule: %s", rule.Key, rule.Name)
+		}
+	default:
+		return fmt.Errorf("key %s is not supported for processing rule: %s", rule.Key, rule.Name)
+	}
+	return nil
+}
+
+// validateGenerateMetric validates a generate_metric rule. The named capture groups of its pattern
+// are used as tags, except for the value_group which holds the value of the distributions.
+func validateGenerateMetric(rule *ProcessingRule) error {
+	if rule.MetricName == "" {
+		return fmt.Errorf("metric_name must be set for processing rule: %s", rule.Name)
+	}
+	if rule.Pattern == "" {
+		return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
+	}
+	re, err := regexp.Compile(rule.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
+	}
+
+	switch rule.MetricType {
+	case "", GeneratedMetricCount:
+		if rule.ValueGroup != "" {
+			return fmt.Errorf("value_group is only supported by distributions for processing rule: %s", rule.Name)
+		}
+	case GeneratedMetricDistribution:
+		if rule.ValueGroup == "" || re.SubexpIndex(rule.ValueGroup) < 0 {
+			return fmt.Errorf("value_group must be a named capture group of the pattern for processing rule: %s", rule.Name)
+		}
+	default:
+		return fmt.Errorf("metric_type %s is not supported for processing rule: %s", rule.MetricType, rule.Name)
+	}
+	return nil
+}
@@ -33,3 +33,69 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestValidateRemapFieldsRules(t *testing.T) {
+	valid := []*ProcessingRule{
+		{Name: "json", Type: RemapFields, Format: RemapFormatJSON, StatusField: "level"},
+		{Name: "logfmt", Type: RemapFields, Format: RemapFormatLogfmt, Pattern: "^level=", DropFields: []string{"token"}},
+	}
+	assert.Nil(t, ValidateProcessingRules(valid))
+	assert.Nil(t, CompileProcessingRules(valid))
+	assert.Nil(t, valid[0].Regex)
+	assert.NotNil(t, valid[1].Regex)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no format", Type: RemapFields, StatusField: "level"},
+		{Name: "unknown format", Type: RemapFields, Format: "xml", StatusField: "level"},
+		{Name: "no field", Type: RemapFields, Format: RemapFormatJSON},
+		{Name: "invalid pattern", Type: RemapFields, Format: RemapFormatJSON, StatusField: "level", Pattern: "(?=abf)"},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateSamplingRules(t *testing.T) {
+	valid := []*ProcessingRule{
+		{Name: "sample", Type: Sample, SampleRate: 0.1},
+		{Name: "sample by status", Type: Sample, SampleRate: 0.5, Key: SamplingKeyStatus},
+		{Name: "rate limit", Type: RateLimit, LinesPerSecond: 100, Burst: 200},
+		{Name: "rate limit by user", Type: RateLimit, LinesPerSecond: 10, Pattern: "user=(\\w+)", Key: SamplingKeyCaptureGroup},
+	}
+	assert.Nil(t, ValidateProcessingRules(valid))
+	assert.Nil(t, CompileProcessingRules(valid))
+	assert.Nil(t, valid[0].Regex)
+	assert.NotNil(t, valid[3].Regex)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "invalid rate", Type: Sample, SampleRate: 1.5},
+		{Name: "no limit", Type: RateLimit},
+		{Name: "negative burst", Type: RateLimit, LinesPerSecond: 10, Burst: -1},
+		{Name: "no capture group", Type: RateLimit, LinesPerSecond: 10, Pattern: "user=\\w+", Key: SamplingKeyCaptureGroup},
+		{Name: "unknown key", Type: Sample, SampleRate: 0.5, Key: "hostname"},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateGenerateMetricRules(t *testing.T) {
+	valid := []*ProcessingRule{
+		{Name: "count", Type: GenerateMetric, MetricName: "app.errors", Pattern: "status=(?P<status>5\\d\\d)"},
+		{Name: "distribution", Type: GenerateMetric, MetricName: "app.latency", MetricType: GeneratedMetricDistribution, Pattern: "took=(?P<took>\\d+)ms", ValueGroup: "took"},
+	}
+	assert.Nil(t, ValidateProcessingRules(valid))
+	assert.Nil(t, CompileProcessingRules(valid))
+	assert.NotNil(t, valid[0].Regex)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no name", Type: GenerateMetric, Pattern: "error"},
+		{Name: "no pattern", Type: GenerateMetric, MetricName: "app.errors"},
+		{Name: "unknown type", Type: GenerateMetric, MetricName: "app.errors", MetricType: "gauge", Pattern: "error"},
+		{Name: "count with value", Type: GenerateMetric, MetricName: "app.errors", Pattern: "(?P<v>\\d+)", ValueGroup: "v"},
+		{Name: "unknown group", Type: GenerateMetric, MetricName: "app.latency", MetricType: GeneratedMetricDistribution, Pattern: "took=(?P<took>\\d+)", ValueGroup: "duration"},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
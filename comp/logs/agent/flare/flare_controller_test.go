@@ -7,6 +7,7 @@ package flare
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,7 +16,7 @@ import (
 )
 
 func TestFillFlare(t *testing.T) {
-	file, err := os.Create("test.log")
+	file, err := os.Create(filepath.Join(t.TempDir(), "test.log"))
 	assert.Nil(t, err)
 	fi, err := os.Stat(file.Name())
 	assert.Nil(t, err)
@@ -59,6 +59,26 @@ func TestParseRepaceRules(t *testing.T) {
 }
 
 // TestSplitTag tests various split-tagging scenarios
+func TestCompileTailSamplingPolicies(t *testing.T) {
+	for _, tt := range []struct {
+		policy *traceconfig.TailSamplingPolicy
+		err    string
+	}{
+		{policy: &traceconfig.TailSamplingPolicy{Type: "probabilistic", SamplingPercentage: 10}},
+		{policy: &traceconfig.TailSamplingPolicy{Type: "attribute", Key: "env"}},
+		{policy: &traceconfig.TailSamplingPolicy{Type: "attribute", Value: "prod"}, err: `all attribute policies must have a "key"`},
+		{policy: &traceconfig.TailSamplingPolicy{Type: "attribute", Key: "env", Value: "("}, err: `key "env": error parsing regexp: missing closing ): ` + "`(`"},
+		{policy: &traceconfig.TailSamplingPolicy{Type: "slow"}, err: `unknown policy type "slow"`},
+	} {
+		err := compileTailSamplingPolicies([]*traceconfig.TailSamplingPolicy{tt.policy})
+		if tt.err == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tt.err)
+		}
+	}
+}
+
 func TestSplitTag(t *testing.T) {
 	for _, tt := range []struct {
 		tag string
@@ -682,6 +702,13 @@ func TestFullYamlConfig(t *testing.T) {
 		},
 	}, cfg.ReplaceTags)
 
+	assert.True(t, cfg.TailSamplingEnabled)
+	assert.Equal(t, 5*time.Second, cfg.TailSamplingDecisionWait)
+	assert.Equal(t, []*traceconfig.TailSamplingPolicy{
+		{Type: "error"},
+		{Name: "checkout", Type: "attribute", Key: "http.route", Value: "^/checkout", Re: regexp.MustCompile("^/checkout")},
+	}, cfg.TailSamplingPolicies)
+
 	assert.EqualValues(t, []string{"/health", "/500"}, cfg.Ignore["resource"])
 
 	o := cfg.Obfuscation
@@ -1164,6 +1191,108 @@ func TestLoadEnv(t *testing.T) {
 		assert.Contains(t, cfg.ReplaceTags, rule2)
 	})
 
+	env = "DD_APM_SPAN_FILTERS"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, `[{"expression":"resource == \"GET /health\"","scope":"trace"}, {"expression":"name == \"redis.command\" && duration < 1ms"}]`)
+
+		c := fxutil.Test[Component](t, fx.Options(
+			corecomp.MockModule(),
+			fx.Replace(corecomp.MockParams{
+				Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+			}),
+			MockModule(),
+		))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, []*traceconfig.SpanFilterRule{
+			{Expression: `resource == "GET /health"`, Scope: "trace"},
+			{Expression: `name == "redis.command" && duration < 1ms`},
+		}, cfg.SpanFilters)
+	})
+
+	env = "DD_APM_REDACTION_RULES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv("DD_APM_REDACTION_HASH_KEY", "secret")
+		t.Setenv(env, `[{"tag":"user.email","action":"hash"}, {"span_type":"sql","tag":"db.statement","action":"truncate","length":200}]`)
+
+		c := fxutil.Test[Component](t, fx.Options(
+			corecomp.MockModule(),
+			fx.Replace(corecomp.MockParams{
+				Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+			}),
+			MockModule(),
+		))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, "secret", cfg.RedactionHashKey)
+		assert.Equal(t, []*traceconfig.RedactionRule{
+			{Tag: "user.email", Action: "hash"},
+			{SpanType: "sql", Tag: "db.statement", Action: "truncate", Length: 200},
+		}, cfg.RedactionRules)
+	})
+
+	env = "DD_APM_TAIL_SAMPLING_POLICIES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv("DD_APM_TAIL_SAMPLING_ENABLED", "true")
+		t.Setenv("DD_APM_TAIL_SAMPLING_DECISION_WAIT", "30")
+		t.Setenv("DD_APM_TAIL_SAMPLING_MAX_BUFFER_BYTES", "1000000")
+		t.Setenv(env, `[{"type":"error"}, {"name":"slow","type":"latency","threshold_ms":500}, {"type":"attribute","key":"http.status_code","value":"^5"}]`)
+
+		c := fxutil.Test[Component](t, fx.Options(
+			corecomp.MockModule(),
+			fx.Replace(corecomp.MockParams{
+				Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+			}),
+			MockModule(),
+		))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, cfg.TailSamplingEnabled)
+		assert.Equal(t, 30*time.Second, cfg.TailSamplingDecisionWait)
+		assert.Equal(t, 1000000, cfg.TailSamplingMaxBufferBytes)
+		require.Len(t, cfg.TailSamplingPolicies, 3)
+		assert.Equal(t, &traceconfig.TailSamplingPolicy{Type: "error"}, cfg.TailSamplingPolicies[0])
+		assert.Equal(t, &traceconfig.TailSamplingPolicy{Name: "slow", Type: "latency", ThresholdMs: 500}, cfg.TailSamplingPolicies[1])
+		assert.Equal(t, "http.status_code", cfg.TailSamplingPolicies[2].Key)
+		assert.True(t, cfg.TailSamplingPolicies[2].Re.MatchString("503"))
+	})
+
+	env = "DD_APM_OTLP_EXPORT_ENDPOINT"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv("DD_APM_OTLP_EXPORT_ENABLED", "true")
+		t.Setenv("DD_APM_OTLP_EXPORT_PROTOCOL", "HTTP")
+		t.Setenv("DD_APM_OTLP_EXPORT_HEADERS", `{"x-api-key":"secret"}`)
+		t.Setenv("DD_APM_OTLP_EXPORT_TIMEOUT", "5")
+		t.Setenv("DD_APM_OTLP_EXPORT_QUEUE_SIZE", "10")
+		t.Setenv(env, "https://collector:4318")
+
+		c := fxutil.Test[Component](t, fx.Options(
+			corecomp.MockModule(),
+			fx.Replace(corecomp.MockParams{
+				Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+			}),
+			MockModule(),
+		))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, &traceconfig.OTLPExport{
+			Enabled:   true,
+			Endpoint:  "https://collector:4318",
+			Protocol:  traceconfig.OTLPExportProtocolHTTP,
+			Headers:   map[string]string{"x-api-key": "secret"},
+			Timeout:   5 * time.Second,
+			QueueSize: 10,
+		}, cfg.OTLPExport)
+	})
+
 	env = "DD_APM_FILTER_TAGS_REQUIRE"
 	t.Run(env, func(t *testing.T) {
 		t.Setenv(env, `important1 important2:value1`)
@@ -23,7 +23,7 @@ func remote(c corecompcfg.Component, ipcAddress string) (config.RemoteClient, er
 		coreconfig.GetIPCPort(),
 		func() (string, error) { return security.FetchAuthToken(c) },
 		rc.WithAgent(rcClientName, version.AgentVersion),
-		rc.WithProducts(state.ProductAPMSampling, state.ProductAgentConfig),
+		rc.WithProducts(state.ProductAPMSampling, state.ProductAgentConfig, state.ProductAPMRedaction),
 		rc.WithPollInterval(rcClientPollInterval),
 		rc.WithDirectorRootOverride(c.GetString("site"), c.GetString("remote_configuration.director_root")),
 	)
@@ -28,6 +28,7 @@ import (
 	coreconfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/config/env"
 	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
 	"github.com/DataDog/datadog-agent/pkg/config/utils"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
@@ -273,6 +274,33 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		c.ProbabilisticSamplerHashSeed = uint32(core.GetInt("apm_config.probabilistic_sampler.hash_seed"))
 	}
 
+	if core.IsSet("apm_config.tail_sampling.enabled") {
+		c.TailSamplingEnabled = core.GetBool("apm_config.tail_sampling.enabled")
+	}
+	if k := "apm_config.tail_sampling.decision_wait"; core.IsSet(k) {
+		if wait := core.GetInt(k); wait > 0 {
+			c.TailSamplingDecisionWait = getDuration(wait)
+		} else {
+			log.Warnf("Invalid %s: %d, using the default of %s", k, wait, c.TailSamplingDecisionWait)
+		}
+	}
+	if core.IsSet("apm_config.tail_sampling.max_buffer_bytes") {
+		c.TailSamplingMaxBufferBytes = core.GetInt("apm_config.tail_sampling.max_buffer_bytes")
+	}
+	if k := "apm_config.tail_sampling.policies"; core.IsSet(k) {
+		policies := make([]*config.TailSamplingPolicy, 0)
+		// structure.UnmarshalKey reads the policies set in DD_APM_TAIL_SAMPLING_POLICIES
+		// even if no other tail sampling setting is in the configuration file.
+		if err := structure.UnmarshalKey(core, k, &policies); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"type\": \"error\"},{\"type\": \"latency\",\"threshold_ms\": 500}]', error: %v", k, err)
+		} else {
+			if err := compileTailSamplingPolicies(policies); err != nil {
+				return fmt.Errorf("tail_sampling.policies: %s", err)
+			}
+			c.TailSamplingPolicies = policies
+		}
+	}
+
 	if core.IsSet("apm_config.max_remote_traces_per_second") {
 		c.MaxRemoteTPS = core.GetFloat64("apm_config.max_remote_traces_per_second")
 	}
@@ -323,6 +351,27 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		}
 	}
 
+	if k := "apm_config.span_filters"; core.IsSet(k) {
+		filters := make([]*config.SpanFilterRule, 0)
+		if err := structure.UnmarshalKey(core, k, &filters); err != nil {
+			log.Errorf("Bad format for %q it should be a list of objects with an \"expression\" and an optional \"scope\", error: %v", k, err)
+		} else {
+			c.SpanFilters = filters
+		}
+	}
+
+	if k := "apm_config.redaction.rules"; core.IsSet(k) {
+		rules := make([]*config.RedactionRule, 0)
+		if err := structure.UnmarshalKey(core, k, &rules); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"span_type\": \"web\",\"tag\": \"user.*\",\"action\": \"hash\"}]', error: %v", k, err)
+		} else {
+			c.RedactionRules = rules
+		}
+	}
+	if core.IsSet("apm_config.redaction.hash_key") {
+		c.RedactionHashKey = core.GetString("apm_config.redaction.hash_key")
+	}
+
 	if core.IsSet("bind_host") || core.IsSet("apm_config.apm_non_local_traffic") {
 		if core.IsSet("bind_host") {
 			host := core.GetString("bind_host")
@@ -367,6 +416,10 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		AttributesTranslator:   attributesTranslator,
 	}
 
+	if err := loadOTLPExport(c, core); err != nil {
+		return fmt.Errorf("otlp_export: %s", err)
+	}
+
 	if core.IsSet("apm_config.install_id") {
 		c.InstallSignature.Found = true
 		c.InstallSignature.InstallID = core.GetString("apm_config.install_id")
@@ -654,6 +707,7 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		c.EVPProxy.ReceiverTimeout = core.GetInt(k)
 	}
 	c.DebugServerPort = core.GetInt("apm_config.debug.port")
+	c.DebugRecentTraces = core.GetInt("apm_config.debug.recent_traces")
 	return nil
 }
 
@@ -721,6 +775,72 @@ func compileReplaceRules(rules []*config.ReplaceRule) error {
 	return nil
 }
 
+// loadOTLPExport loads the settings of the export of the sampled traces to an OTLP endpoint.
+func loadOTLPExport(c *config.AgentConfig, core corecompcfg.Component) error {
+	ecfg := c.OTLPExport
+	if core.IsSet("apm_config.otlp_export.enabled") {
+		ecfg.Enabled = core.GetBool("apm_config.otlp_export.enabled")
+	}
+	if core.IsSet("apm_config.otlp_export.endpoint") {
+		ecfg.Endpoint = core.GetString("apm_config.otlp_export.endpoint")
+	}
+	if core.IsSet("apm_config.otlp_export.protocol") {
+		ecfg.Protocol = strings.ToLower(core.GetString("apm_config.otlp_export.protocol"))
+	}
+	if core.IsSet("apm_config.otlp_export.headers") {
+		ecfg.Headers = core.GetStringMapString("apm_config.otlp_export.headers")
+	}
+	if core.IsSet("apm_config.otlp_export.insecure") {
+		ecfg.Insecure = core.GetBool("apm_config.otlp_export.insecure")
+	}
+	if k := "apm_config.otlp_export.timeout"; core.IsSet(k) {
+		if timeout := core.GetInt(k); timeout > 0 {
+			ecfg.Timeout = getDuration(timeout)
+		} else {
+			log.Warnf("Invalid %s: %d, using the default of %s", k, timeout, ecfg.Timeout)
+		}
+	}
+	if core.IsSet("apm_config.otlp_export.queue_size") {
+		ecfg.QueueSize = core.GetInt("apm_config.otlp_export.queue_size")
+	}
+	if !ecfg.Enabled {
+		return nil
+	}
+	if ecfg.Endpoint == "" {
+		return errors.New("an endpoint is required to export the traces")
+	}
+	if ecfg.Protocol != config.OTLPExportProtocolGRPC && ecfg.Protocol != config.OTLPExportProtocolHTTP {
+		return fmt.Errorf("unknown protocol %q, it must be %q or %q", ecfg.Protocol, config.OTLPExportProtocolGRPC, config.OTLPExportProtocolHTTP)
+	}
+	return nil
+}
+
+// compileTailSamplingPolicies validates the tail sampling policies and compiles
+// the regular expressions of the attribute policies. If it fails it returns the
+// first error.
+func compileTailSamplingPolicies(policies []*config.TailSamplingPolicy) error {
+	for _, p := range policies {
+		switch p.Type {
+		case config.TailSamplingPolicyError, config.TailSamplingPolicyLatency, config.TailSamplingPolicyProbabilistic:
+		case config.TailSamplingPolicyAttribute:
+			if p.Key == "" {
+				return errors.New(`all attribute policies must have a "key"`)
+			}
+			if p.Value == "" {
+				continue
+			}
+			re, err := regexp.Compile(p.Value)
+			if err != nil {
+				return fmt.Errorf("key %q: %s", p.Key, err)
+			}
+			p.Re = re
+		default:
+			return fmt.Errorf("unknown policy type %q", p.Type)
+		}
+	}
+	return nil
+}
+
 // getDuration returns the duration of the provided value in seconds
 func getDuration(seconds int) time.Duration {
 	return time.Duration(seconds) * time.Second
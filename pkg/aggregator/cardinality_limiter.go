@@ -0,0 +1,204 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cast"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// CardinalityOverflowTag is added to the contexts created for the samples of a metric
+// over its context budget, in place of the tags dropped from these samples.
+const CardinalityOverflowTag = "cardinality_overflow:true"
+
+const (
+	limitGlobal    = "global"
+	limitPerMetric = "per_metric"
+
+	// maxCardinalityOffenders bounds the number of offenders reported when the global
+	// budget is exhausted and every new metric is over budget.
+	maxCardinalityOffenders = 1000
+)
+
+// dogstatsdCardinalityLimiter is shared by the DogStatsD time samplers, it is nil when
+// no context budget is configured.
+var dogstatsdCardinalityLimiter *cardinalityLimiter
+
+// CardinalityOffender describes a metric whose new contexts were folded into an overflow
+// context because a context budget was exhausted.
+type CardinalityOffender struct {
+	Name         string    `json:"name"`
+	Limit        string    `json:"limit"`
+	Contexts     int       `json:"contexts"`
+	Overflows    uint64    `json:"overflows"`
+	LastOverflow time.Time `json:"last_overflow"`
+}
+
+// cardinalityLimiter enforces a global and a per-metric budget of DogStatsD contexts.
+//
+// It is shared by all the time samplers since the contexts of a metric are spread across
+// them. It is only called when a sampler creates or expires a context, never for the
+// samples of the contexts already tracked, so its lock is not contended.
+type cardinalityLimiter struct {
+	mu sync.Mutex
+
+	maxContexts          int
+	maxContextsPerMetric int
+	overrides            map[string]int
+	// overflowTags are the names of the tags dropped from the samples over budget, all the
+	// tags, including the tags added by the tagger, are dropped when it is empty.
+	overflowTags map[string]struct{}
+
+	contexts         int
+	contextsByMetric map[string]int
+	offenders        map[string]*CardinalityOffender
+}
+
+// newCardinalityLimiter returns a limiter configured with the dogstatsd_max_contexts* settings,
+// or nil when no budget is set.
+func newCardinalityLimiter(cfg model.Reader) *cardinalityLimiter {
+	overrides := map[string]int{}
+	for name, value := range cfg.GetStringMap("dogstatsd_max_contexts_per_metric_overrides") {
+		limit, err := cast.ToIntE(value)
+		if err != nil || limit < 0 {
+			log.Warnf("Ignoring the invalid context budget %v of the metric %s", value, name)
+			continue
+		}
+		overrides[name] = limit
+	}
+
+	l := &cardinalityLimiter{
+		maxContexts:          cfg.GetInt("dogstatsd_max_contexts"),
+		maxContextsPerMetric: cfg.GetInt("dogstatsd_max_contexts_per_metric"),
+		overrides:            overrides,
+		overflowTags:         map[string]struct{}{},
+		contextsByMetric:     map[string]int{},
+		offenders:            map[string]*CardinalityOffender{},
+	}
+	if l.maxContexts <= 0 && l.maxContextsPerMetric <= 0 && len(l.overrides) == 0 {
+		return nil
+	}
+	for _, tag := range cfg.GetStringSlice("dogstatsd_cardinality_overflow_tags") {
+		l.overflowTags[tag] = struct{}{}
+	}
+	return l
+}
+
+// admit reserves a context of the metric and returns true when the metric is within
+// its budgets. Otherwise the overflow is recorded and false is returned.
+func (l *cardinalityLimiter) admit(name string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := l.contextsByMetric[name]
+	limit := l.maxContextsPerMetric
+	if override, ok := l.overrides[name]; ok {
+		limit = override
+	}
+
+	switch {
+	case limit > 0 && count >= limit:
+		l.recordOverflow(name, limitPerMetric, count)
+		return false
+	case l.maxContexts > 0 && l.contexts >= l.maxContexts:
+		l.recordOverflow(name, limitGlobal, count)
+		return false
+	}
+
+	l.contexts++
+	l.contextsByMetric[name] = count + 1
+	return true
+}
+
+// release frees a context reserved by admit.
+func (l *cardinalityLimiter) release(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.contexts--
+	if count := l.contextsByMetric[name]; count > 1 {
+		l.contextsByMetric[name] = count - 1
+	} else {
+		delete(l.contextsByMetric, name)
+	}
+}
+
+func (l *cardinalityLimiter) recordOverflow(name, limit string, contexts int) {
+	tlmDogstatsdCardinalityOverflow.Inc(limit)
+
+	offender, ok := l.offenders[name]
+	if !ok {
+		if len(l.offenders) >= maxCardinalityOffenders {
+			return
+		}
+		log.Warnf("The metric %s exceeds its %s DogStatsD context budget, its new contexts are tagged with %s", name, limit, CardinalityOverflowTag)
+		offender = &CardinalityOffender{Name: name}
+		l.offenders[name] = offender
+	}
+	offender.Limit = limit
+	offender.Contexts = contexts
+	offender.Overflows++
+	offender.LastOverflow = time.Now()
+}
+
+// overflow returns the metric tags of a sample over budget: the overflow tags, or all of
+// them, are replaced by CardinalityOverflowTag.
+func (l *cardinalityLimiter) overflow(tags []string) []string {
+	return append(l.keep(tags, 1), CardinalityOverflowTag)
+}
+
+// keep returns a copy of tags without the overflow tags, or an empty slice when all of them
+// are dropped. extra is the capacity reserved for the tags appended by the caller.
+func (l *cardinalityLimiter) keep(tags []string, extra int) []string {
+	kept := make([]string, 0, len(tags)+extra)
+	if len(l.overflowTags) == 0 {
+		return kept
+	}
+	for _, tag := range tags {
+		tagName := tag
+		if i := strings.IndexByte(tag, ':'); i >= 0 {
+			tagName = tag[:i]
+		}
+		if _, drop := l.overflowTags[tagName]; !drop {
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
+
+// getOffenders returns the metrics over budget, the most frequent offenders first.
+func (l *cardinalityLimiter) getOffenders() []CardinalityOffender {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	offenders := make([]CardinalityOffender, 0, len(l.offenders))
+	for _, offender := range l.offenders {
+		offenders = append(offenders, *offender)
+	}
+	sort.Slice(offenders, func(i, j int) bool {
+		if offenders[i].Overflows != offenders[j].Overflows {
+			return offenders[i].Overflows > offenders[j].Overflows
+		}
+		return offenders[i].Name < offenders[j].Name
+	})
+	return offenders
+}
+
+// GetCardinalityOffenders returns the DogStatsD metrics whose new contexts were folded into
+// an overflow context because a context budget was exhausted.
+func GetCardinalityOffenders() []CardinalityOffender {
+	if dogstatsdCardinalityLimiter == nil {
+		return []CardinalityOffender{}
+	}
+	return dogstatsdCardinalityLimiter.getOffenders()
+}
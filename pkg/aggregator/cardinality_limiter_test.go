@@ -0,0 +1,168 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	pkgconfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func setCardinalityLimits(t *testing.T, settings map[string]interface{}) {
+	for key, value := range settings {
+		previous := pkgconfig.Datadog().Get(key)
+		pkgconfig.Datadog().SetWithoutSource(key, value)
+		t.Cleanup(func() { pkgconfig.Datadog().SetWithoutSource(key, previous) })
+	}
+
+	dogstatsdCardinalityLimiter = newCardinalityLimiter(pkgconfig.Datadog())
+	t.Cleanup(func() { dogstatsdCardinalityLimiter = nil })
+}
+
+func TestCardinalityLimiterDisabledByDefault(t *testing.T) {
+	assert.Nil(t, newCardinalityLimiter(pkgconfig.Datadog()))
+	assert.Empty(t, GetCardinalityOffenders())
+}
+
+func TestCardinalityLimiterBudgets(t *testing.T) {
+	setCardinalityLimits(t, map[string]interface{}{
+		"dogstatsd_max_contexts":                      5,
+		"dogstatsd_max_contexts_per_metric":           2,
+		"dogstatsd_max_contexts_per_metric_overrides": map[string]interface{}{"allowed": 3, "unlimited": 0},
+	})
+	l := dogstatsdCardinalityLimiter
+	require.NotNil(t, l)
+
+	assert.True(t, l.admit("limited"))
+	assert.True(t, l.admit("limited"))
+	assert.False(t, l.admit("limited"))
+
+	assert.True(t, l.admit("allowed"))
+	assert.True(t, l.admit("allowed"))
+	assert.True(t, l.admit("allowed"))
+	assert.False(t, l.admit("allowed"))
+
+	// the global budget is exhausted
+	assert.False(t, l.admit("unlimited"))
+
+	l.release("limited")
+	assert.True(t, l.admit("unlimited"))
+	assert.False(t, l.admit("limited"))
+
+	offenders := GetCardinalityOffenders()
+	require.Len(t, offenders, 3)
+	assert.Equal(t, "limited", offenders[0].Name)
+	assert.Equal(t, limitGlobal, offenders[0].Limit)
+	assert.EqualValues(t, 2, offenders[0].Overflows)
+	assert.Equal(t, "allowed", offenders[1].Name)
+	assert.Equal(t, limitPerMetric, offenders[1].Limit)
+	assert.Equal(t, 3, offenders[1].Contexts)
+	assert.Equal(t, "unlimited", offenders[2].Name)
+}
+
+func TestCardinalityLimiterOverflowTags(t *testing.T) {
+	setCardinalityLimits(t, map[string]interface{}{"dogstatsd_max_contexts": 1})
+	assert.Equal(t, []string{CardinalityOverflowTag}, dogstatsdCardinalityLimiter.overflow([]string{"env:prod", "user_id:42"}))
+
+	setCardinalityLimits(t, map[string]interface{}{"dogstatsd_cardinality_overflow_tags": []string{"user_id", "request"}})
+	assert.Equal(t, []string{"env:prod", CardinalityOverflowTag}, dogstatsdCardinalityLimiter.overflow([]string{"env:prod", "user_id:42", "request"}))
+}
+
+func testTimeSamplerCardinalityLimit(t *testing.T, store *tags.Store) {
+	setCardinalityLimits(t, map[string]interface{}{
+		"dogstatsd_max_contexts_per_metric":   2,
+		"dogstatsd_cardinality_overflow_tags": []string{"user_id"},
+	})
+	sampler := NewTimeSampler(TimeSamplerID(0), 10, store, dogstatsdCardinalityLimiter, "host")
+
+	for i := 0; i < 5; i++ {
+		sampler.sample(&metrics.MetricSample{
+			Name:       "my.counter",
+			Value:      1,
+			Mtype:      metrics.CounterType,
+			Tags:       []string{"env:prod", fmt.Sprintf("user_id:%d", i)},
+			SampleRate: 1,
+		}, 12345.0)
+	}
+	assert.Equal(t, 3, sampler.contextResolver.length())
+
+	series, _ := flushSerie(sampler, 12360.0)
+	valuesByTags := map[string]float64{}
+	for _, serie := range series {
+		tags := serie.Tags.UnsafeToReadOnlySliceString()
+		sort.Strings(tags)
+		valuesByTags[fmt.Sprint(tags)] = serie.Points[0].Value
+	}
+	assert.Equal(t, map[string]float64{
+		"[env:prod user_id:0]":                 0.1,
+		"[env:prod user_id:1]":                 0.1,
+		"[cardinality_overflow:true env:prod]": 0.3,
+	}, valuesByTags)
+
+	offenders := GetCardinalityOffenders()
+	require.Len(t, offenders, 1)
+	assert.Equal(t, "my.counter", offenders[0].Name)
+	assert.EqualValues(t, 3, offenders[0].Overflows)
+
+	// the budget is released when the contexts expire
+	flushSerie(sampler, 12345.0+float64(pkgconfig.Datadog().GetInt64("dogstatsd_context_expiry_seconds")+pkgconfig.Datadog().GetInt64("dogstatsd_expiry_seconds"))+20)
+	assert.Equal(t, 0, sampler.contextResolver.length())
+	assert.Empty(t, dogstatsdCardinalityLimiter.contextsByMetric)
+}
+
+func TestTimeSamplerCardinalityLimit(t *testing.T) {
+	testWithTagsStore(t, testTimeSamplerCardinalityLimit)
+}
+
+func testContextResolverCardinalityLimitWithOriginTags(t *testing.T, store *tags.Store) {
+	for _, tc := range []struct {
+		name         string
+		overflowTags []string
+		expected     []string
+	}{
+		{"all the tags dropped", nil, []string{CardinalityOverflowTag}},
+		{"overflow tags dropped", []string{"pod_name", "user_id"}, []string{"kube_namespace:default", CardinalityOverflowTag}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setCardinalityLimits(t, map[string]interface{}{
+				"dogstatsd_max_contexts_per_metric":   1,
+				"dogstatsd_cardinality_overflow_tags": tc.overflowTags,
+			})
+			resolver := newTimestampContextResolver(store, "test", 10, 10, dogstatsdCardinalityLimiter)
+
+			resolver.trackContext(&mockSample{"my.gauge", []string{"kube_namespace:default", "pod_name:a"}, []string{"user_id:0"}}, 0)
+			for i := 1; i < 4; i++ {
+				pod := fmt.Sprintf("pod_name:%d", i)
+				resolver.trackContext(&mockSample{"my.gauge", []string{"kube_namespace:default", pod}, []string{fmt.Sprintf("user_id:%d", i)}}, 0)
+			}
+			// the samples over budget of all the pods are folded into a single context
+			require.Equal(t, 2, resolver.length())
+
+			var overflowContext *Context
+			for _, entry := range resolver.resolver.contextsByKey {
+				if entry.context.overflow {
+					overflowContext = entry.context
+				}
+			}
+			require.NotNil(t, overflowContext)
+			metrics.AssertCompositeTagsEqual(t, tagset.CompositeTagsFromSlice(tc.expected), overflowContext.Tags())
+		})
+	}
+}
+
+func TestContextResolverCardinalityLimitWithOriginTags(t *testing.T) {
+	testWithTagsStore(t, testContextResolverCardinalityLimitWithOriginTags)
+}
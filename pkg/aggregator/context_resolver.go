@@ -27,6 +27,9 @@ type Context struct {
 	metricTags *tags.Entry
 	noIndex    bool
 	source     metrics.MetricSource
+	// overflow is true for the contexts created for the samples over a context budget,
+	// they are not counted against the budgets.
+	overflow bool
 }
 
 type resolverEntry struct {
@@ -75,6 +78,8 @@ type contextResolver struct {
 	keyGenerator     *ckey.KeyGenerator
 	taggerBuffer     *tagset.HashingTagsAccumulator
 	metricBuffer     *tagset.HashingTagsAccumulator
+	// limiter enforces the context budgets, it is nil when the contexts are not limited
+	limiter *cardinalityLimiter
 }
 
 // generateContextKey generates the contextKey associated with the context of the metricSample
@@ -105,6 +110,20 @@ func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSample
 
 	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
 
+	overflow := false
+	if _, ok := cr.contextsByKey[contextKey]; !ok && cr.limiter != nil && !cr.limiter.admit(metricSampleContext.GetName()) {
+		// the metric is over budget: fold the sample into an overflow context, the origin
+		// tags added by the tagger are dropped as well
+		taggerTags := cr.limiter.keep(cr.taggerBuffer.Get(), 0)
+		metricTags := cr.limiter.overflow(cr.metricBuffer.Get())
+		cr.taggerBuffer.Reset()
+		cr.taggerBuffer.Append(taggerTags...)
+		cr.metricBuffer.Reset()
+		cr.metricBuffer.Append(metricTags...)
+		contextKey, taggerKey, metricKey = cr.generateContextKey(metricSampleContext)
+		overflow = true
+	}
+
 	if entry, ok := cr.contextsByKey[contextKey]; !ok {
 		mtype := metricSampleContext.GetMetricType()
 		context := &Context{
@@ -115,6 +134,7 @@ func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSample
 			mtype:      mtype,
 			noIndex:    metricSampleContext.IsNoIndex(),
 			source:     metricSampleContext.GetSource(),
+			overflow:   overflow,
 		}
 		cr.contextsByKey[contextKey] = resolverEntry{
 			lastSeen: timestamp,
@@ -153,6 +173,9 @@ func (cr *contextResolver) remove(expiredContextKey ckey.ContextKey) {
 		cr.countsByMtype[context.mtype]--
 		cr.bytesByMtype[context.mtype] -= uint64(context.SizeInBytes())
 		cr.dataBytesByMtype[context.mtype] -= uint64(context.DataSizeInBytes())
+		if cr.limiter != nil && !context.overflow {
+			cr.limiter.release(context.Name)
+		}
 		context.release()
 	}
 }
@@ -176,6 +199,9 @@ func (cr *contextResolver) updateMetrics(countsByMTypeGauge telemetry.Gauge, byt
 
 func (cr *contextResolver) release() {
 	for _, c := range cr.contextsByKey {
+		if cr.limiter != nil && !c.context.overflow {
+			cr.limiter.release(c.context.Name)
+		}
 		c.context.release()
 	}
 }
@@ -217,11 +243,17 @@ type timestampContextResolver struct {
 
 	contextExpireTime int64
 	counterExpireTime int64
+
+	// extraTTL, when set, returns how long a context must be kept in addition to its
+	// expiry time, e.g. until the end of the rollup interval it is aggregated on
+	extraTTL func(*Context) int64
 }
 
-func newTimestampContextResolver(cache *tags.Store, id string, contextExpireTime, counterExpireTime int64) *timestampContextResolver {
+func newTimestampContextResolver(cache *tags.Store, id string, contextExpireTime, counterExpireTime int64, limiter *cardinalityLimiter) *timestampContextResolver {
+	resolver := newContextResolver(cache, id)
+	resolver.limiter = limiter
 	return &timestampContextResolver{
-		resolver: newContextResolver(cache, id),
+		resolver: resolver,
 
 		contextExpireTime: contextExpireTime,
 		counterExpireTime: counterExpireTime,
@@ -253,6 +285,9 @@ func (cr *timestampContextResolver) expireContexts(timestamp int64) {
 		if entry.context.mtype == metrics.CounterType {
 			ttl = cr.counterExpireTime
 		}
+		if cr.extraTTL != nil {
+			ttl += cr.extraTTL(entry.context)
+		}
 		if entry.lastSeen+ttl < timestamp {
 			cr.resolver.remove(ck)
 		}
@@ -138,7 +138,7 @@ func testExpireContexts(t *testing.T, store *tags.Store) {
 		Tags:       []string{"foo"},
 		SampleRate: 1,
 	}
-	contextResolver := newTimestampContextResolver(store, "test", 2, 4)
+	contextResolver := newTimestampContextResolver(store, "test", 2, 4, nil)
 
 	// Track the 2 contexts
 	contextKey1 := contextResolver.trackContext(&mSample1, 4) // expires after 6
@@ -89,11 +89,18 @@ type flushTrigger struct {
 	seriesSink   metrics.SerieSink
 }
 
+// flushSink receives a copy of the series and sketches flushed to the serializer.
+type flushSink interface {
+	AddSerie(*metrics.Serie)
+	AddSketch(*metrics.SketchSeries)
+}
+
 func createIterableMetrics(
 	flushAndSerializeInParallel FlushAndSerializeInParallel,
 	serializer serializer.MetricSerializer,
 	logPayloads bool,
 	isServerless bool,
+	sink flushSink,
 ) (*metrics.IterableSeries, *metrics.IterableSketches) {
 	var series *metrics.IterableSeries
 	var sketches *metrics.IterableSketches
@@ -104,6 +111,9 @@ func createIterableMetrics(
 				log.Debugf("Flushing serie: %s", se)
 			}
 			tagsetTlm.updateHugeSerieTelemetry(se)
+			if sink != nil {
+				sink.AddSerie(se)
+			}
 		}, flushAndSerializeInParallel.BufferSize, flushAndSerializeInParallel.ChannelSize)
 	}
 
@@ -116,6 +126,9 @@ func createIterableMetrics(
 				log.DebugfServerless("Sending sketches payload : %s", sketch.String())
 			}
 			tagsetTlm.updateHugeSketchesTelemetry(sketch)
+			if sink != nil {
+				sink.AddSketch(sketch)
+			}
 		}, flushAndSerializeInParallel.BufferSize, flushAndSerializeInParallel.ChannelSize)
 	}
 	return series, sketches
@@ -9,6 +9,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"strconv"
 	"sync"
 	"time"
 
@@ -18,6 +20,7 @@ import (
 	orchestratorforwarder "github.com/DataDog/datadog-agent/comp/forwarder/orchestrator"
 	"github.com/DataDog/datadog-agent/comp/serializer/compression"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/openmetrics"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
 	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
 	"github.com/DataDog/datadog-agent/pkg/config"
@@ -65,6 +68,9 @@ type AgentDemultiplexer struct {
 
 	// sharded statsd time samplers
 	statsd
+
+	// openMetricsExporter exposes the flushed metrics locally, it is nil when disabled
+	openMetricsExporter *openmetrics.Exporter
 }
 
 // AgentDemultiplexerOptions are the options used to initialize a Demultiplexer.
@@ -164,12 +170,13 @@ func initAgentDemultiplexer(
 	log.Debug("the Demultiplexer will use", statsdPipelinesCount, "pipelines")
 
 	statsdWorkers := make([]*timeSamplerWorker, statsdPipelinesCount)
+	dogstatsdCardinalityLimiter = newCardinalityLimiter(config.Datadog())
 
 	for i := 0; i < statsdPipelinesCount; i++ {
 		// the sampler
 		tagsStore := tags.NewStore(config.Datadog().GetBool("aggregator_use_tags_store"), fmt.Sprintf("timesampler #%d", i))
 
-		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, agg.hostname)
+		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, dogstatsdCardinalityLimiter, agg.hostname)
 
 		// its worker (process loop + flush/serialization mechanism)
 
@@ -189,6 +196,16 @@ func initAgentDemultiplexer(
 		)
 	}
 
+	var openMetricsExporter *openmetrics.Exporter
+	if config.Datadog().GetBool("aggregator_openmetrics_exporter.enabled") {
+		openMetricsExporter = openmetrics.NewExporter(config.Datadog().GetDuration("aggregator_openmetrics_exporter.expiry_seconds") * time.Second)
+		addr := net.JoinHostPort(config.Datadog().GetString("aggregator_openmetrics_exporter.host"), strconv.Itoa(config.Datadog().GetInt("aggregator_openmetrics_exporter.port")))
+		if err := openMetricsExporter.Start(addr); err != nil {
+			log.Errorf("Could not expose the aggregated metrics on %s: %v", addr, err)
+			openMetricsExporter = nil
+		}
+	}
+
 	// --
 
 	demux := &AgentDemultiplexer{
@@ -217,6 +234,8 @@ func initAgentDemultiplexer(
 			metricSamplePool:  metricSamplePool,
 			noAggStreamWorker: noAggWorker,
 		},
+
+		openMetricsExporter: openMetricsExporter,
 	}
 
 	return demux
@@ -350,6 +369,10 @@ func (d *AgentDemultiplexer) Stop(flush bool) {
 	}
 	d.aggregator = nil
 
+	if d.openMetricsExporter != nil {
+		d.openMetricsExporter.Stop()
+	}
+
 	// forwarders
 
 	if !d.options.DontStartForwarders {
@@ -400,7 +423,11 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 	}
 
 	logPayloads := config.Datadog().GetBool("log_payloads")
-	series, sketches := createIterableMetrics(d.aggregator.flushAndSerializeInParallel, d.sharedSerializer, logPayloads, false)
+	var sink flushSink
+	if d.openMetricsExporter != nil {
+		sink = d.openMetricsExporter
+	}
+	series, sketches := createIterableMetrics(d.aggregator.flushAndSerializeInParallel, d.sharedSerializer, logPayloads, false, sink)
 
 	metrics.Serialize(
 		series,
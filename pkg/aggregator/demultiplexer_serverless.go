@@ -50,7 +50,8 @@ func InitAndStartServerlessDemultiplexer(keysPerDomain map[string][]string, forw
 	metricSamplePool := metrics.NewMetricSamplePool(MetricSamplePoolBatchSize, utils.IsTelemetryEnabled(config.Datadog()))
 	tagsStore := tags.NewStore(config.Datadog().GetBool("aggregator_use_tags_store"), "timesampler")
 
-	statsdSampler := NewTimeSampler(TimeSamplerID(0), bucketSize, tagsStore, "")
+	dogstatsdCardinalityLimiter = newCardinalityLimiter(config.Datadog())
+	statsdSampler := NewTimeSampler(TimeSamplerID(0), bucketSize, tagsStore, dogstatsdCardinalityLimiter, "")
 	flushAndSerializeInParallel := NewFlushAndSerializeInParallel(config.Datadog())
 	statsdWorker := newTimeSamplerWorker(statsdSampler, DefaultFlushInterval, bufferSize, metricSamplePool, flushAndSerializeInParallel, tagsStore)
 
@@ -105,7 +106,7 @@ func (d *ServerlessDemultiplexer) ForceFlushToSerializer(start time.Time, waitFo
 	defer d.flushLock.Unlock()
 
 	logPayloads := config.Datadog().GetBool("log_payloads")
-	series, sketches := createIterableMetrics(d.flushAndSerializeInParallel, d.serializer, logPayloads, true)
+	series, sketches := createIterableMetrics(d.flushAndSerializeInParallel, d.serializer, logPayloads, true, nil)
 
 	metrics.Serialize(
 		series,
@@ -0,0 +1,252 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package openmetrics exposes the metrics aggregated by the agent on a local endpoint,
+// in the Prometheus text or OpenMetrics exposition formats.
+package openmetrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// MetricsPath is the path of the endpoint exposing the metrics.
+	MetricsPath = "/metrics"
+
+	contentTypePrometheus  = "text/plain; version=0.0.4; charset=utf-8"
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// expireInterval bounds how often the metrics are expired while the series are flushed,
+// it is shorter than the flush interval so that every flush expires the metrics once.
+const expireInterval = 5 * time.Second
+
+// summaryQuantiles are the quantiles of the distributions exposed as summaries.
+var summaryQuantiles = []float64{0.5, 0.75, 0.95, 0.99}
+
+// Exporter keeps the last value of the series and sketches flushed by the aggregator
+// and exposes them on MetricsPath.
+//
+// The counts are accumulated into counters, as well as the rates, like the DogStatsD counts,
+// which are multiplied by their interval. The gauges are exposed as gauges and the
+// distributions as summaries: their quantiles are the quantiles of the last flush while
+// their sum and count are accumulated. The metrics not flushed for the expiry duration are
+// removed when the series are flushed and when the metrics are scraped.
+type Exporter struct {
+	mu          sync.Mutex
+	families    map[string]*family
+	expiry      time.Duration
+	lastExpired time.Time
+	now         func() time.Time
+
+	server   *http.Server
+	listener net.Listener
+}
+
+// NewExporter returns an exporter removing the metrics not flushed for the expiry duration.
+func NewExporter(expiry time.Duration) *Exporter {
+	return &Exporter{
+		families: make(map[string]*family),
+		expiry:   expiry,
+		now:      time.Now,
+	}
+}
+
+// AddSerie records the points of a flushed serie.
+func (e *Exporter) AddSerie(serie *metrics.Serie) {
+	if len(serie.Points) == 0 {
+		return
+	}
+
+	var kind metricKind
+	var value float64
+	switch serie.MType {
+	case metrics.APICountType:
+		kind = kindCounter
+		for _, point := range serie.Points {
+			value += point.Value
+		}
+	case metrics.APIRateType:
+		// the rates are per second values over the interval of the serie, which is not set
+		// for the series of the checks, flushed every second
+		kind = kindCounter
+		interval := float64(serie.Interval)
+		if interval <= 0 {
+			interval = 1
+		}
+		for _, point := range serie.Points {
+			value += point.Value * interval
+		}
+	default:
+		kind = kindGauge
+		last := serie.Points[0]
+		for _, point := range serie.Points[1:] {
+			if point.Ts >= last.Ts {
+				last = point
+			}
+		}
+		value = last.Value
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expireOnFlush()
+
+	sample := e.getSample(serie.Name, kind, serie.Tags.UnsafeToReadOnlySliceString(), serie.Host)
+	if sample == nil {
+		return
+	}
+	if kind == kindCounter {
+		sample.value += value
+	} else {
+		sample.value = value
+	}
+}
+
+// AddSketch records the points of a flushed distribution.
+func (e *Exporter) AddSketch(sketch *metrics.SketchSeries) {
+	merged := &quantile.Sketch{}
+	for _, point := range sketch.Points {
+		if point.Sketch != nil {
+			merged.Merge(quantile.Default(), point.Sketch)
+		}
+	}
+	if merged.Basic.Cnt == 0 {
+		return
+	}
+
+	quantiles := make([]float64, len(summaryQuantiles))
+	for i, q := range summaryQuantiles {
+		quantiles[i] = merged.Quantile(quantile.Default(), q)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expireOnFlush()
+
+	sample := e.getSample(sketch.Name, kindSummary, sketch.Tags.UnsafeToReadOnlySliceString(), sketch.Host)
+	if sample == nil {
+		return
+	}
+	sample.quantiles = quantiles
+	sample.sum += merged.Basic.Sum
+	sample.count += uint64(merged.Basic.Cnt)
+}
+
+// getSample returns the sample of the metric with the given tags, creating it if needed.
+// It returns nil when the metric name is already used by a metric of another type.
+func (e *Exporter) getSample(name string, kind metricKind, tags []string, host string) *sample {
+	familyName := sanitizeMetricName(name)
+	f, ok := e.families[familyName]
+	if !ok {
+		f = &family{name: familyName, kind: kind, samples: make(map[string]*sample)}
+		e.families[familyName] = f
+	} else if f.kind != kind {
+		log.Debugf("Not exposing the %s metric %s: its name is already used by a %s", kind, name, f.kind)
+		return nil
+	}
+
+	labels := formatLabels(tags, host)
+	s, ok := f.samples[labels]
+	if !ok {
+		s = &sample{labels: labels}
+		f.samples[labels] = s
+	}
+	s.lastSeen = e.now()
+	return s
+}
+
+// expireOnFlush expires the metrics at most every expireInterval, so that the metrics no
+// longer flushed are removed even when the endpoint is not scraped.
+func (e *Exporter) expireOnFlush() {
+	if e.now().Sub(e.lastExpired) >= expireInterval {
+		e.expire()
+	}
+}
+
+// expire removes the metrics not flushed for the expiry duration.
+func (e *Exporter) expire() {
+	if e.expiry <= 0 {
+		return
+	}
+	now := e.now()
+	e.lastExpired = now
+	deadline := now.Add(-e.expiry)
+	for name, f := range e.families {
+		for labels, s := range f.samples {
+			if s.lastSeen.Before(deadline) {
+				delete(f.samples, labels)
+			}
+		}
+		if len(f.samples) == 0 {
+			delete(e.families, name)
+		}
+	}
+}
+
+// ServeHTTP writes the metrics in the OpenMetrics format when the scraper accepts it,
+// and in the Prometheus text format otherwise.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	openMetrics := strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text")
+
+	e.mu.Lock()
+	e.expire()
+	body := e.render(openMetrics)
+	e.mu.Unlock()
+
+	if openMetrics {
+		w.Header().Set("Content-Type", contentTypeOpenMetrics)
+	} else {
+		w.Header().Set("Content-Type", contentTypePrometheus)
+	}
+	w.Write(body) //nolint:errcheck
+}
+
+// Start exposes the metrics on the given address.
+func (e *Exporter) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	e.listener = listener
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, e)
+	e.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := e.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Error while serving the aggregated metrics on %s: %v", addr, err)
+		}
+	}()
+	log.Infof("Exposing the aggregated metrics on http://%s%s", listener.Addr(), MetricsPath)
+	return nil
+}
+
+// Stop stops exposing the metrics.
+func (e *Exporter) Stop() {
+	if e.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.server.Shutdown(ctx); err != nil {
+		log.Debugf("Error while stopping the aggregated metrics endpoint: %v", err)
+	}
+	e.server = nil
+}
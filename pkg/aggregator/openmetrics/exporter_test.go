@@ -0,0 +1,195 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package openmetrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func scrape(t *testing.T, e *Exporter, accept string) (string, string) {
+	req := httptest.NewRequest(http.MethodGet, MetricsPath, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	body, err := io.ReadAll(rec.Result().Body)
+	require.NoError(t, err)
+	return rec.Result().Header.Get("Content-Type"), string(body)
+}
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "system_cpu_user", sanitizeMetricName("system.cpu.user"))
+	assert.Equal(t, "_5xx_errors:rate", sanitizeMetricName("5xx-errors:rate"))
+	assert.Equal(t, "kube_pod_name", sanitizeLabelName("kube:pod.name"))
+	assert.Equal(t, `a\\b\"c\n`, escapeLabelValue("a\\b\"c\n"))
+}
+
+func TestFormatLabels(t *testing.T) {
+	assert.Equal(t, `env="prod",host="my-host",role="db,web",ssl="true"`,
+		formatLabels([]string{"role:web", "env:prod", "ssl", "role:db"}, "my-host"))
+	assert.Equal(t, "", formatLabels(nil, ""))
+}
+
+func TestExporterPrometheusFormat(t *testing.T) {
+	e := NewExporter(0)
+
+	e.AddSerie(&metrics.Serie{
+		Name:   "system.load.1",
+		Points: []metrics.Point{{Ts: 10, Value: 1}, {Ts: 20, Value: 2}},
+		Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod"}),
+		Host:   "my-host",
+		MType:  metrics.APIGaugeType,
+	})
+	for i := 0; i < 2; i++ {
+		e.AddSerie(&metrics.Serie{
+			Name:   "http.requests",
+			Points: []metrics.Point{{Ts: 10, Value: 3}},
+			Tags:   tagset.CompositeTagsFromSlice([]string{"status:200"}),
+			MType:  metrics.APICountType,
+		})
+	}
+
+	contentType, body := scrape(t, e, "")
+	assert.Equal(t, contentTypePrometheus, contentType)
+	assert.Equal(t, `# TYPE http_requests_total counter
+http_requests_total{status="200"} 6
+# TYPE system_load_1 gauge
+system_load_1{env="prod",host="my-host"} 2
+`, body)
+}
+
+func TestExporterSummary(t *testing.T) {
+	e := NewExporter(0)
+	for i := 0; i < 2; i++ {
+		sketch := &quantile.Sketch{}
+		for v := 1; v <= 100; v++ {
+			sketch.Insert(quantile.Default(), float64(v))
+		}
+		e.AddSketch(&metrics.SketchSeries{
+			Name:   "http.latency",
+			Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod"}),
+			Points: []metrics.SketchPoint{{Ts: 10, Sketch: sketch}},
+		})
+	}
+
+	s := e.families["http_latency"].samples[`env="prod"`]
+	require.NotNil(t, s)
+	require.Len(t, s.quantiles, len(summaryQuantiles))
+	for i, q := range summaryQuantiles {
+		assert.InEpsilon(t, q*100, s.quantiles[i], 0.05)
+	}
+	// the sum and count are accumulated across flushes
+	assert.Equal(t, float64(2*5050), s.sum)
+	assert.EqualValues(t, 200, s.count)
+
+	_, body := scrape(t, e, "")
+	assert.Contains(t, body, "# TYPE http_latency summary\n")
+	assert.Contains(t, body, `http_latency{env="prod",quantile="0.99"} `)
+	assert.Contains(t, body, "http_latency_sum{env=\"prod\"} 10100\nhttp_latency_count{env=\"prod\"} 200\n")
+}
+
+func TestExporterOpenMetricsFormat(t *testing.T) {
+	e := NewExporter(0)
+	e.AddSerie(&metrics.Serie{
+		Name:   "jobs.total",
+		Points: []metrics.Point{{Ts: 10, Value: 1}},
+		MType:  metrics.APICountType,
+	})
+
+	contentType, body := scrape(t, e, "application/openmetrics-text;version=1.0.0,text/plain;q=0.5")
+	assert.Equal(t, contentTypeOpenMetrics, contentType)
+	assert.Equal(t, `# TYPE jobs counter
+jobs_total 1
+# EOF
+`, body)
+}
+
+func TestExporterTypeConflict(t *testing.T) {
+	e := NewExporter(0)
+	e.AddSerie(&metrics.Serie{Name: "my.metric", Points: []metrics.Point{{Ts: 10, Value: 1}}, MType: metrics.APIGaugeType})
+	e.AddSerie(&metrics.Serie{Name: "my.metric", Points: []metrics.Point{{Ts: 10, Value: 5}}, MType: metrics.APICountType})
+
+	_, body := scrape(t, e, "")
+	assert.Equal(t, "# TYPE my_metric gauge\nmy_metric 1\n", body)
+}
+
+func TestExporterExpiry(t *testing.T) {
+	now := time.Now()
+	e := NewExporter(time.Minute)
+	e.now = func() time.Time { return now }
+
+	e.AddSerie(&metrics.Serie{Name: "old", Points: []metrics.Point{{Ts: 10, Value: 1}}, MType: metrics.APIGaugeType})
+	now = now.Add(45 * time.Second)
+	e.AddSerie(&metrics.Serie{Name: "recent", Points: []metrics.Point{{Ts: 10, Value: 1}}, MType: metrics.APIGaugeType})
+	now = now.Add(30 * time.Second)
+
+	_, body := scrape(t, e, "")
+	assert.Equal(t, "# TYPE recent gauge\nrecent 1\n", body)
+}
+
+func TestExporterRatesAsCounters(t *testing.T) {
+	e := NewExporter(0)
+	for i := 0; i < 2; i++ {
+		// a DogStatsD count flushed as a per second rate over its interval
+		e.AddSerie(&metrics.Serie{
+			Name:     "dsd.requests",
+			Points:   []metrics.Point{{Ts: 10, Value: 0.5}},
+			MType:    metrics.APIRateType,
+			Interval: 10,
+		})
+	}
+	e.AddSerie(&metrics.Serie{Name: "check.rate", Points: []metrics.Point{{Ts: 10, Value: 3}}, MType: metrics.APIRateType})
+
+	_, body := scrape(t, e, "")
+	assert.Equal(t, `# TYPE check_rate_total counter
+check_rate_total 3
+# TYPE dsd_requests_total counter
+dsd_requests_total 10
+`, body)
+}
+
+func TestExporterExpiryOnFlush(t *testing.T) {
+	now := time.Now()
+	e := NewExporter(time.Minute)
+	e.now = func() time.Time { return now }
+
+	e.AddSerie(&metrics.Serie{Name: "old", Points: []metrics.Point{{Ts: 10, Value: 1}}, MType: metrics.APIGaugeType})
+	now = now.Add(75 * time.Second)
+	e.AddSerie(&metrics.Serie{Name: "recent", Points: []metrics.Point{{Ts: 10, Value: 1}}, MType: metrics.APIGaugeType})
+
+	// the metrics are expired without being scraped
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	assert.NotContains(t, e.families, "old")
+	assert.Contains(t, e.families, "recent")
+}
+
+func TestExporterStartStop(t *testing.T) {
+	e := NewExporter(0)
+	e.AddSerie(&metrics.Serie{Name: "up", Points: []metrics.Point{{Ts: 10, Value: 1}}, MType: metrics.APIGaugeType})
+
+	require.NoError(t, e.Start("127.0.0.1:0"))
+	defer e.Stop()
+
+	resp, err := http.Get("http://" + e.listener.Addr().String() + MetricsPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "# TYPE up gauge\nup 1\n", string(body))
+}
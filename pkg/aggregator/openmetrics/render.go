@@ -0,0 +1,212 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package openmetrics
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type metricKind int
+
+const (
+	kindGauge metricKind = iota
+	kindCounter
+	kindSummary
+)
+
+func (k metricKind) String() string {
+	switch k {
+	case kindCounter:
+		return "counter"
+	case kindSummary:
+		return "summary"
+	default:
+		return "gauge"
+	}
+}
+
+// family groups the samples of a metric, keyed by their formatted labels.
+type family struct {
+	name    string
+	kind    metricKind
+	samples map[string]*sample
+}
+
+type sample struct {
+	labels   string
+	lastSeen time.Time
+
+	// value of the gauges and counters
+	value float64
+
+	// quantiles, sum and count of the summaries
+	quantiles []float64
+	sum       float64
+	count     uint64
+}
+
+// sanitizeMetricName replaces the characters not allowed in a metric name by underscores.
+func sanitizeMetricName(name string) string {
+	return sanitizeName(name, true)
+}
+
+// sanitizeLabelName replaces the characters not allowed in a label name by underscores.
+func sanitizeLabelName(name string) string {
+	return sanitizeName(name, false)
+}
+
+func sanitizeName(name string, allowColon bool) string {
+	var b strings.Builder
+	b.Grow(len(name) + 1)
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', allowColon && r == ':':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// formatLabels converts the tags and the host to sorted labels, formatted as `k="v",...`.
+// Tags without a value are exposed with the "true" value and the values of the tags
+// sharing the same name are joined by commas.
+func formatLabels(tags []string, host string) string {
+	values := make(map[string][]string, len(tags)+1)
+	for _, tag := range tags {
+		name, value := tag, "true"
+		if idx := strings.IndexByte(tag, ':'); idx >= 0 {
+			name, value = tag[:idx], tag[idx+1:]
+		}
+		name = sanitizeLabelName(name)
+		values[name] = append(values[name], value)
+	}
+	if host != "" {
+		values["host"] = append(values["host"], host)
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		labelValues := values[name]
+		sort.Strings(labelValues)
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(strings.Join(labelValues, ",")))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// render writes the metrics sorted by name and labels. The counters are suffixed by
+// `_total`; in the OpenMetrics format the suffix is only added to the samples and the
+// exposition is terminated by `# EOF`.
+func (e *Exporter) render(openMetrics bool) []byte {
+	names := make([]string, 0, len(e.families))
+	for name := range e.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		f := e.families[name]
+
+		familyName, sampleName := f.name, f.name
+		if f.kind == kindCounter {
+			sampleName = strings.TrimSuffix(f.name, "_total") + "_total"
+			familyName = sampleName
+			if openMetrics {
+				familyName = strings.TrimSuffix(f.name, "_total")
+			}
+		}
+		buf.WriteString("# TYPE ")
+		buf.WriteString(familyName)
+		buf.WriteByte(' ')
+		buf.WriteString(f.kind.String())
+		buf.WriteByte('\n')
+
+		labels := make([]string, 0, len(f.samples))
+		for l := range f.samples {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+
+		for _, l := range labels {
+			s := f.samples[l]
+			if f.kind != kindSummary {
+				writeSample(&buf, sampleName, s.labels, "", s.value)
+				continue
+			}
+			for i, q := range summaryQuantiles {
+				writeSample(&buf, sampleName, s.labels, `quantile="`+formatFloat(q)+`"`, s.quantiles[i])
+			}
+			writeSample(&buf, sampleName+"_sum", s.labels, "", s.sum)
+			writeSample(&buf, sampleName+"_count", s.labels, "", float64(s.count))
+		}
+	}
+
+	if openMetrics {
+		buf.WriteString("# EOF\n")
+	}
+	return buf.Bytes()
+}
+
+func writeSample(buf *bytes.Buffer, name, labels, extraLabel string, value float64) {
+	buf.WriteString(name)
+	if labels != "" || extraLabel != "" {
+		buf.WriteByte('{')
+		buf.WriteString(labels)
+		if labels != "" && extraLabel != "" {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(extraLabel)
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(formatFloat(value))
+	buf.WriteByte('\n')
+}
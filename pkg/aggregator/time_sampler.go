@@ -35,6 +35,10 @@ type TimeSampler struct {
 	lastCutOffTime     int64
 	sketchMap          sketchMap
 
+	// rollups aggregate the metrics matching their prefix on longer intervals
+	rollups      []*rollup
+	rollupByName map[string]*rollup
+
 	// id is a number to differentiate multiple time samplers
 	// since we start running more than one with the demultiplexer introduction
 	id       TimeSamplerID
@@ -43,8 +47,9 @@ type TimeSampler struct {
 	hostname string
 }
 
-// NewTimeSampler returns a newly initialized TimeSampler
-func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, hostname string) *TimeSampler {
+// NewTimeSampler returns a newly initialized TimeSampler, limiter enforces the DogStatsD context
+// budgets and is nil when the contexts are not limited.
+func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, limiter *cardinalityLimiter, hostname string) *TimeSampler {
 	if interval == 0 {
 		interval = bucketSize
 	}
@@ -57,13 +62,24 @@ func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, hostnam
 
 	s := &TimeSampler{
 		interval:           interval,
-		contextResolver:    newTimestampContextResolver(cache, idString, contextExpireTime, counterExpireTime),
+		contextResolver:    newTimestampContextResolver(cache, idString, contextExpireTime, counterExpireTime, limiter),
 		metricsByTimestamp: map[int64]metrics.ContextMetrics{},
 		sketchMap:          make(sketchMap),
+		rollups:            getRollups(config.Datadog(), interval),
+		rollupByName:       make(map[string]*rollup),
 		id:                 id,
 		idString:           idString,
 		hostname:           hostname,
 	}
+	if len(s.rollups) > 0 {
+		// keep the contexts of the rollups until their bucket is flushed
+		s.contextResolver.extraTTL = func(c *Context) int64 {
+			if r := s.getRollup(c.Name); r != nil {
+				return r.interval
+			}
+			return 0
+		}
+	}
 
 	return s
 }
@@ -72,10 +88,6 @@ func (s *TimeSampler) calculateBucketStart(timestamp float64) int64 {
 	return int64(timestamp) - int64(timestamp)%s.interval
 }
 
-func (s *TimeSampler) isBucketStillOpen(bucketStartTimestamp, timestamp int64) bool {
-	return bucketStartTimestamp+s.interval > timestamp
-}
-
 func (s *TimeSampler) sample(metricSample *metrics.MetricSample, timestamp float64) {
 	// use the timestamp provided in the sample if any
 	if metricSample.Timestamp > 0 {
@@ -84,26 +96,34 @@ func (s *TimeSampler) sample(metricSample *metrics.MetricSample, timestamp float
 
 	// Keep track of the context
 	contextKey := s.contextResolver.trackContext(metricSample, int64(timestamp))
-	bucketStart := s.calculateBucketStart(timestamp)
+
+	interval, metricsByTimestamp, sketches := s.interval, s.metricsByTimestamp, s.sketchMap
+	var bucketStart int64
+	if r := s.getRollup(metricSample.Name); r != nil {
+		interval, metricsByTimestamp, sketches = r.interval, r.metricsByTimestamp, r.sketchMap
+		bucketStart = r.calculateBucketStart(timestamp)
+	} else {
+		bucketStart = s.calculateBucketStart(timestamp)
+	}
 
 	switch metricSample.Mtype {
 	case metrics.DistributionType:
-		s.sketchMap.insert(bucketStart, contextKey, metricSample.Value, metricSample.SampleRate)
+		sketches.insert(bucketStart, contextKey, metricSample.Value, metricSample.SampleRate)
 	default:
 		// If it's a new bucket, initialize it
-		bucketMetrics, ok := s.metricsByTimestamp[bucketStart]
+		bucketMetrics, ok := metricsByTimestamp[bucketStart]
 		if !ok {
 			bucketMetrics = metrics.MakeContextMetrics()
-			s.metricsByTimestamp[bucketStart] = bucketMetrics
+			metricsByTimestamp[bucketStart] = bucketMetrics
 		}
 		// Add sample to bucket
-		if err := bucketMetrics.AddSample(contextKey, metricSample, timestamp, s.interval, nil, config.Datadog()); err != nil {
+		if err := bucketMetrics.AddSample(contextKey, metricSample, timestamp, interval, nil, config.Datadog()); err != nil {
 			log.Debugf("TimeSampler #%d Ignoring sample '%s' on host '%s' and tags '%s': %s", s.id, metricSample.Name, metricSample.Host, metricSample.Tags, err)
 		}
 	}
 }
 
-func (s *TimeSampler) newSketchSeries(ck ckey.ContextKey, points []metrics.SketchPoint) *metrics.SketchSeries {
+func (s *TimeSampler) newSketchSeries(ck ckey.ContextKey, points []metrics.SketchPoint, interval int64) *metrics.SketchSeries {
 	ctx, ok := s.contextResolver.get(ck)
 	if !ok {
 		return nil
@@ -113,7 +133,7 @@ func (s *TimeSampler) newSketchSeries(ck ckey.ContextKey, points []metrics.Sketc
 		Name:       ctx.Name,
 		Tags:       ctx.Tags(),
 		Host:       ctx.Host,
-		Interval:   s.interval,
+		Interval:   interval,
 		Points:     points,
 		ContextKey: ck,
 		Source:     ctx.source,
@@ -124,38 +144,60 @@ func (s *TimeSampler) newSketchSeries(ck ckey.ContextKey, points []metrics.Sketc
 }
 
 func (s *TimeSampler) flushSeries(cutoffTime int64, series metrics.SerieSink) {
+	// serieBySignature is reused for each call of dedupSerieBySerieSignature to avoid allocations.
+	serieBySignature := make(map[SerieSignature]*metrics.Serie)
+
+	s.flushBuckets(cutoffTime, s.lastCutOffTime, s.interval, s.metricsByTimestamp, nil, series, serieBySignature)
+
+	// the buckets of the rollups are flushed once their whole interval has passed
+	for _, r := range s.rollups {
+		rollupCutoffTime := r.calculateBucketStart(float64(cutoffTime))
+		s.flushBuckets(rollupCutoffTime, r.lastCutOffTime, r.interval, r.metricsByTimestamp, r, series, serieBySignature)
+		r.lastCutOffTime = rollupCutoffTime
+	}
+}
+
+// flushBuckets flushes the buckets of the given interval ended before cutoffTime. r is the
+// rollup the buckets belong to, nil for the buckets of the sampler interval.
+func (s *TimeSampler) flushBuckets(
+	cutoffTime int64,
+	lastCutOffTime int64,
+	interval int64,
+	metricsByTimestamp map[int64]metrics.ContextMetrics,
+	r *rollup,
+	series metrics.SerieSink,
+	serieBySignature map[SerieSignature]*metrics.Serie,
+) {
 	// Map to hold the expired contexts that will need to be deleted after the flush so that we stop sending zeros
 	contextMetricsFlusher := metrics.NewContextMetricsFlusher()
 
-	if len(s.metricsByTimestamp) > 0 {
-		for bucketTimestamp, contextMetrics := range s.metricsByTimestamp {
+	if len(metricsByTimestamp) > 0 {
+		for bucketTimestamp, contextMetrics := range metricsByTimestamp {
 			// disregard when the timestamp is too recent
-			if s.isBucketStillOpen(bucketTimestamp, cutoffTime) {
+			if bucketTimestamp+interval > cutoffTime {
 				continue
 			}
 
 			// Add a 0 sample to all the counters that are not expired.
 			// It is ok to add 0 samples to a counter that was already sampled for real in the bucket, since it won't change its value
-			s.countersSampleZeroValue(bucketTimestamp, contextMetrics)
+			s.countersSampleZeroValue(bucketTimestamp, contextMetrics, interval, r)
 			contextMetricsFlusher.Append(float64(bucketTimestamp), contextMetrics)
 
-			delete(s.metricsByTimestamp, bucketTimestamp)
+			delete(metricsByTimestamp, bucketTimestamp)
 		}
-	} else if s.lastCutOffTime+s.interval <= cutoffTime {
+	} else if lastCutOffTime+interval <= cutoffTime {
 		// Even if there is no metric in this flush, recreate empty counters,
 		// but only if we've passed an interval since the last flush
 
 		contextMetrics := metrics.MakeContextMetrics()
 
-		s.countersSampleZeroValue(cutoffTime-s.interval, contextMetrics)
-		contextMetricsFlusher.Append(float64(cutoffTime-s.interval), contextMetrics)
+		s.countersSampleZeroValue(cutoffTime-interval, contextMetrics, interval, r)
+		contextMetricsFlusher.Append(float64(cutoffTime-interval), contextMetrics)
 	}
 
-	// serieBySignature is reused for each call of dedupSerieBySerieSignature to avoid allocations.
-	serieBySignature := make(map[SerieSignature]*metrics.Serie)
 	s.flushContextMetrics(contextMetricsFlusher, func(rawSeries []*metrics.Serie) {
 		// Note: rawSeries is reused at each call
-		s.dedupSerieBySerieSignature(rawSeries, series, serieBySignature)
+		s.dedupSerieBySerieSignature(rawSeries, series, serieBySignature, interval)
 	})
 }
 
@@ -163,6 +205,7 @@ func (s *TimeSampler) dedupSerieBySerieSignature(
 	rawSeries []*metrics.Serie,
 	serieSink metrics.SerieSink,
 	serieBySignature map[SerieSignature]*metrics.Serie,
+	interval int64,
 ) {
 	// clear the map. Reuse serieBySignature
 	for k := range serieBySignature {
@@ -186,7 +229,7 @@ func (s *TimeSampler) dedupSerieBySerieSignature(
 			serie.Tags = context.Tags()
 			serie.Host = context.Host
 			serie.NoIndex = context.noIndex
-			serie.Interval = s.interval
+			serie.Interval = interval
 			serie.Source = context.source
 
 			serieBySignature[serieSignature] = serie
@@ -199,16 +242,26 @@ func (s *TimeSampler) dedupSerieBySerieSignature(
 }
 
 func (s *TimeSampler) flushSketches(cutoffTime int64, sketchesSink metrics.SketchesSink) {
+	s.flushSketchMap(s.sketchMap, cutoffTime, s.interval, sketchesSink)
+
+	for _, r := range s.rollups {
+		// a rollup bucket is flushed once its whole interval has passed
+		s.flushSketchMap(r.sketchMap, r.calculateBucketStart(float64(cutoffTime))-r.interval+1, r.interval, sketchesSink)
+	}
+}
+
+// flushSketchMap flushes the sketches of the buckets starting before beforeTs.
+func (s *TimeSampler) flushSketchMap(sketches sketchMap, beforeTs int64, interval int64, sketchesSink metrics.SketchesSink) {
 	pointsByCtx := make(map[ckey.ContextKey][]metrics.SketchPoint)
 
-	s.sketchMap.flushBefore(cutoffTime, func(ck ckey.ContextKey, p metrics.SketchPoint) {
+	sketches.flushBefore(beforeTs, func(ck ckey.ContextKey, p metrics.SketchPoint) {
 		if p.Sketch == nil {
 			return
 		}
 		pointsByCtx[ck] = append(pointsByCtx[ck], p)
 	})
 	for ck, points := range pointsByCtx {
-		ss := s.newSketchSeries(ck, points)
+		ss := s.newSketchSeries(ck, points, interval)
 		if ss == nil {
 			log.Errorf("TimeSampler #%d Ignoring all metrics on context key '%v': inconsistent context resolver state: the context is not tracked", s.id, ck)
 			continue
@@ -238,7 +291,11 @@ func (s *TimeSampler) updateMetrics() {
 	totalContexts := s.contextResolver.length()
 	aggregatorDogstatsdContexts.Set(int64(totalContexts))
 	tlmDogstatsdContexts.Set(float64(totalContexts), s.idString)
-	tlmDogstatsdTimeBuckets.Set(float64(len(s.metricsByTimestamp)), s.idString)
+	timeBuckets := len(s.metricsByTimestamp)
+	for _, r := range s.rollups {
+		timeBuckets += len(r.metricsByTimestamp)
+	}
+	tlmDogstatsdTimeBuckets.Set(float64(timeBuckets), s.idString)
 
 	countByMtype := s.contextResolver.countsByMtype()
 	for i := 0; i < int(metrics.NumMetricTypes); i++ {
@@ -263,10 +320,12 @@ func (s *TimeSampler) flushContextMetrics(contextMetricsFlusher *metrics.Context
 	}
 }
 
-func (s *TimeSampler) countersSampleZeroValue(timestamp int64, contextMetrics metrics.ContextMetrics) {
+// countersSampleZeroValue adds a 0 sample to the counters aggregated on the interval of
+// the rollup r, or of the sampler when r is nil.
+func (s *TimeSampler) countersSampleZeroValue(timestamp int64, contextMetrics metrics.ContextMetrics, interval int64, r *rollup) {
 	expirySeconds := config.Datadog().GetInt64("dogstatsd_expiry_seconds")
 	for counterContext, entry := range s.contextResolver.resolver.contextsByKey {
-		if entry.lastSeen+expirySeconds > timestamp && entry.context.mtype == metrics.CounterType {
+		if entry.lastSeen+expirySeconds > timestamp && entry.context.mtype == metrics.CounterType && s.getRollup(entry.context.Name) == r {
 			sample := &metrics.MetricSample{
 				Name:       "",
 				Value:      0.0,
@@ -279,7 +338,7 @@ func (s *TimeSampler) countersSampleZeroValue(timestamp int64, contextMetrics me
 			}
 			// Add a zero value sample to the counter
 			// It is ok to add a 0 sample to a counter that was already sampled in the bucket, it won't change its value
-			contextMetrics.AddSample(counterContext, sample, float64(timestamp), s.interval, nil, config.Datadog()) //nolint:errcheck
+			contextMetrics.AddSample(counterContext, sample, float64(timestamp), interval, nil, config.Datadog()) //nolint:errcheck
 		}
 	}
 }
@@ -0,0 +1,109 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// maxRollupCacheSize bounds the number of metric names whose rollup is cached by a TimeSampler.
+const maxRollupCacheSize = 100000
+
+// rollupConfig configures the interval the metrics with a given prefix are aggregated on.
+type rollupConfig struct {
+	Prefix   string `mapstructure:"prefix" json:"prefix" yaml:"prefix"`
+	Interval int64  `mapstructure:"interval" json:"interval" yaml:"interval"`
+}
+
+// rollup holds the buckets of the metrics aggregated on a longer interval than the
+// one of the TimeSampler.
+type rollup struct {
+	prefix             string
+	interval           int64
+	metricsByTimestamp map[int64]metrics.ContextMetrics
+	sketchMap          sketchMap
+	lastCutOffTime     int64
+}
+
+// getRollups reads the dogstatsd_rollup_intervals setting. The intervals must be
+// multiples of the interval of the sampler, the invalid rollups are skipped. The
+// rollups are sorted by decreasing prefix length so that the most specific prefix
+// matches first.
+func getRollups(cfg model.Reader, samplerInterval int64) []*rollup {
+	if !cfg.IsSet("dogstatsd_rollup_intervals") {
+		return nil
+	}
+
+	var configs []rollupConfig
+	if err := cfg.UnmarshalKey("dogstatsd_rollup_intervals", &configs); err != nil {
+		log.Errorf("Could not parse dogstatsd_rollup_intervals: %v", err)
+		return nil
+	}
+
+	rollups := make([]*rollup, 0, len(configs))
+	for _, c := range configs {
+		if err := validateRollup(c, samplerInterval); err != nil {
+			log.Errorf("Ignoring the dogstatsd_rollup_intervals entry for %q: %v", c.Prefix, err)
+			continue
+		}
+		rollups = append(rollups, &rollup{
+			prefix:             c.Prefix,
+			interval:           c.Interval,
+			metricsByTimestamp: map[int64]metrics.ContextMetrics{},
+			sketchMap:          make(sketchMap),
+		})
+	}
+
+	sort.SliceStable(rollups, func(i, j int) bool {
+		return len(rollups[i].prefix) > len(rollups[j].prefix)
+	})
+	return rollups
+}
+
+func validateRollup(c rollupConfig, samplerInterval int64) error {
+	if c.Prefix == "" {
+		return fmt.Errorf("the prefix is required")
+	}
+	if c.Interval <= samplerInterval || c.Interval%samplerInterval != 0 {
+		return fmt.Errorf("the interval must be a multiple of %d seconds greater than %d, got %d", samplerInterval, samplerInterval, c.Interval)
+	}
+	return nil
+}
+
+func (r *rollup) calculateBucketStart(timestamp float64) int64 {
+	return int64(timestamp) - int64(timestamp)%r.interval
+}
+
+// getRollup returns the rollup of the metric, nil when it's aggregated on the sampler interval.
+func (s *TimeSampler) getRollup(name string) *rollup {
+	if len(s.rollups) == 0 {
+		return nil
+	}
+
+	if r, ok := s.rollupByName[name]; ok {
+		return r
+	}
+
+	var match *rollup
+	for _, r := range s.rollups {
+		if strings.HasPrefix(name, r.prefix) {
+			match = r
+			break
+		}
+	}
+
+	if len(s.rollupByName) >= maxRollupCacheSize {
+		s.rollupByName = make(map[string]*rollup)
+	}
+	s.rollupByName[name] = match
+	return match
+}
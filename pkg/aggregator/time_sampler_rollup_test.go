@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	pkgconfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func setRollupIntervals(t *testing.T, rollups []interface{}) {
+	previous := pkgconfig.Datadog().Get("dogstatsd_rollup_intervals")
+	pkgconfig.Datadog().SetWithoutSource("dogstatsd_rollup_intervals", rollups)
+	t.Cleanup(func() { pkgconfig.Datadog().SetWithoutSource("dogstatsd_rollup_intervals", previous) })
+}
+
+func TestGetRollups(t *testing.T) {
+	setRollupIntervals(t, []interface{}{
+		map[string]interface{}{"prefix": "batch.", "interval": 60},
+		map[string]interface{}{"prefix": "batch.nightly.", "interval": 300},
+		map[string]interface{}{"prefix": "", "interval": 60},
+		map[string]interface{}{"prefix": "invalid.", "interval": 15},
+		map[string]interface{}{"prefix": "same.", "interval": 10},
+	})
+
+	rollups := getRollups(pkgconfig.Datadog(), 10)
+	require.Len(t, rollups, 2)
+	assert.Equal(t, "batch.nightly.", rollups[0].prefix)
+	assert.EqualValues(t, 300, rollups[0].interval)
+	assert.Equal(t, "batch.", rollups[1].prefix)
+	assert.EqualValues(t, 60, rollups[1].interval)
+
+	sampler := testTimeSampler(tags.NewStore(true, "test"))
+	assert.Equal(t, sampler.rollups[1], sampler.getRollup("batch.size"))
+	assert.Equal(t, sampler.rollups[0], sampler.getRollup("batch.nightly.size"))
+	assert.Nil(t, sampler.getRollup("api.requests"))
+}
+
+func testTimeSamplerRollup(t *testing.T, store *tags.Store) {
+	setRollupIntervals(t, []interface{}{
+		map[string]interface{}{"prefix": "batch.", "interval": 60},
+	})
+	sampler := testTimeSampler(store)
+
+	sampleAt := func(name string, mtype metrics.MetricType, value float64, timestamp float64) {
+		sampler.sample(&metrics.MetricSample{Name: name, Value: value, Mtype: mtype, SampleRate: 1}, timestamp)
+	}
+	sampleAt("batch.size", metrics.GaugeType, 1, 12301)
+	sampleAt("batch.size", metrics.GaugeType, 2, 12355)
+	sampleAt("batch.size", metrics.GaugeType, 3, 12365)
+	sampleAt("batch.jobs", metrics.CounterType, 3, 12301)
+	sampleAt("batch.jobs", metrics.CounterType, 3, 12355)
+	sampleAt("batch.latency", metrics.DistributionType, 5, 12301)
+	sampleAt("api.requests", metrics.GaugeType, 1, 12345)
+
+	// the metrics of the rollup are kept until their 60s bucket ends
+	series, sketches := flushSerie(sampler, 12355)
+	require.Len(t, series, 1)
+	assert.Equal(t, "api.requests", series[0].Name)
+	assert.EqualValues(t, 10, series[0].Interval)
+	assert.Empty(t, sketches)
+	assert.Len(t, sampler.rollups[0].metricsByTimestamp, 2)
+
+	series, sketches = flushSerie(sampler, 12365)
+	byName := map[string]*metrics.Serie{}
+	for _, serie := range series {
+		byName[serie.Name] = serie
+	}
+	require.Contains(t, byName, "batch.size")
+	assert.EqualValues(t, 60, byName["batch.size"].Interval)
+	assert.Equal(t, []metrics.Point{{Ts: 12300, Value: 2}}, byName["batch.size"].Points)
+
+	// the counters are normalized on the rollup interval
+	require.Contains(t, byName, "batch.jobs")
+	assert.EqualValues(t, 60, byName["batch.jobs"].Interval)
+	assert.Equal(t, []metrics.Point{{Ts: 12300, Value: 0.1}}, byName["batch.jobs"].Points)
+
+	require.Len(t, sketches, 1)
+	assert.Equal(t, "batch.latency", sketches[0].Name)
+	assert.EqualValues(t, 60, sketches[0].Interval)
+	require.Len(t, sketches[0].Points, 1)
+	assert.EqualValues(t, 12300, sketches[0].Points[0].Ts)
+
+	// the sample of the next bucket is still pending
+	assert.Len(t, sampler.rollups[0].metricsByTimestamp, 1)
+}
+
+func TestTimeSamplerRollup(t *testing.T) {
+	testWithTagsStore(t, testTimeSamplerRollup)
+}
@@ -32,7 +32,7 @@ func generateSerieContextKey(serie *metrics.Serie) ckey.ContextKey {
 }
 
 func testTimeSampler(store *tags.Store) *TimeSampler {
-	sampler := NewTimeSampler(TimeSamplerID(0), 10, store, "host")
+	sampler := NewTimeSampler(TimeSamplerID(0), 10, store, nil, "host")
 	return sampler
 }
 
@@ -533,7 +533,7 @@ func TestFlushMissingContext(t *testing.T) {
 }
 
 func benchmarkTimeSampler(b *testing.B, store *tags.Store) {
-	sampler := NewTimeSampler(TimeSamplerID(0), 10, store, "host")
+	sampler := NewTimeSampler(TimeSamplerID(0), 10, store, nil, "host")
 
 	sample := metrics.MetricSample{
 		Name:       "my.metric.name",
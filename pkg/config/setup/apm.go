@@ -112,6 +112,17 @@ func setupAPM(config pkgconfigmodel.Setup) {
 	config.BindEnv("apm_config.probabilistic_sampler.enabled", "DD_APM_PROBABILISTIC_SAMPLER_ENABLED")
 	config.BindEnv("apm_config.probabilistic_sampler.sampling_percentage", "DD_APM_PROBABILISTIC_SAMPLER_SAMPLING_PERCENTAGE")
 	config.BindEnv("apm_config.probabilistic_sampler.hash_seed", "DD_APM_PROBABILISTIC_SAMPLER_HASH_SEED")
+	config.BindEnv("apm_config.tail_sampling.enabled", "DD_APM_TAIL_SAMPLING_ENABLED")
+	config.BindEnv("apm_config.tail_sampling.decision_wait", "DD_APM_TAIL_SAMPLING_DECISION_WAIT")
+	config.BindEnv("apm_config.tail_sampling.max_buffer_bytes", "DD_APM_TAIL_SAMPLING_MAX_BUFFER_BYTES")
+	config.BindEnv("apm_config.tail_sampling.policies", "DD_APM_TAIL_SAMPLING_POLICIES")
+	config.BindEnv("apm_config.otlp_export.enabled", "DD_APM_OTLP_EXPORT_ENABLED")
+	config.BindEnv("apm_config.otlp_export.endpoint", "DD_APM_OTLP_EXPORT_ENDPOINT")
+	config.BindEnv("apm_config.otlp_export.protocol", "DD_APM_OTLP_EXPORT_PROTOCOL")
+	config.BindEnv("apm_config.otlp_export.headers", "DD_APM_OTLP_EXPORT_HEADERS")
+	config.BindEnv("apm_config.otlp_export.insecure", "DD_APM_OTLP_EXPORT_INSECURE")
+	config.BindEnv("apm_config.otlp_export.timeout", "DD_APM_OTLP_EXPORT_TIMEOUT")
+	config.BindEnv("apm_config.otlp_export.queue_size", "DD_APM_OTLP_EXPORT_QUEUE_SIZE")
 
 	config.BindEnv("apm_config.max_memory", "DD_APM_MAX_MEMORY")
 	config.BindEnv("apm_config.max_cpu_percent", "DD_APM_MAX_CPU_PERCENT")
@@ -125,6 +136,9 @@ func setupAPM(config pkgconfigmodel.Setup) {
 	config.BindEnv("apm_config.profiling_additional_endpoints", "DD_APM_PROFILING_ADDITIONAL_ENDPOINTS")
 	config.BindEnv("apm_config.additional_endpoints", "DD_APM_ADDITIONAL_ENDPOINTS")
 	config.BindEnv("apm_config.replace_tags", "DD_APM_REPLACE_TAGS")
+	config.BindEnv("apm_config.span_filters", "DD_APM_SPAN_FILTERS")
+	config.BindEnv("apm_config.redaction.rules", "DD_APM_REDACTION_RULES")
+	config.BindEnv("apm_config.redaction.hash_key", "DD_APM_REDACTION_HASH_KEY")
 	config.BindEnv("apm_config.analyzed_spans", "DD_APM_ANALYZED_SPANS")
 	config.BindEnv("apm_config.ignore_resources", "DD_APM_IGNORE_RESOURCES", "DD_IGNORE_RESOURCE")
 	config.BindEnv("apm_config.receiver_socket", "DD_APM_RECEIVER_SOCKET")
@@ -153,6 +167,7 @@ func setupAPM(config pkgconfigmodel.Setup) {
 	config.BindEnv("apm_config.obfuscation.credit_cards.enabled", "DD_APM_OBFUSCATION_CREDIT_CARDS_ENABLED")
 	config.BindEnv("apm_config.obfuscation.credit_cards.luhn", "DD_APM_OBFUSCATION_CREDIT_CARDS_LUHN")
 	config.BindEnvAndSetDefault("apm_config.debug.port", 5012, "DD_APM_DEBUG_PORT")
+	config.BindEnvAndSetDefault("apm_config.debug.recent_traces", 0, "DD_APM_DEBUG_RECENT_TRACES")
 	config.BindEnv("apm_config.features", "DD_APM_FEATURES")
 	config.ParseEnvAsStringSlice("apm_config.features", func(s string) []string {
 		// Either commas or spaces can be used as separators.
@@ -192,6 +207,30 @@ func setupAPM(config pkgconfigmodel.Setup) {
 		return out
 	})
 
+	config.ParseEnvAsSlice("apm_config.span_filters", func(in string) []interface{} {
+		var out []interface{}
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			log.Warnf(`"apm_config.span_filters" can not be parsed: %v`, err)
+		}
+		return out
+	})
+
+	config.ParseEnvAsSlice("apm_config.redaction.rules", func(in string) []interface{} {
+		var out []interface{}
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			log.Warnf(`"apm_config.redaction.rules" can not be parsed: %v`, err)
+		}
+		return out
+	})
+
+	config.ParseEnvAsSlice("apm_config.tail_sampling.policies", func(in string) []interface{} {
+		var out []interface{}
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			log.Warnf(`"apm_config.tail_sampling.policies" can not be parsed: %v`, err)
+		}
+		return out
+	})
+
 	config.ParseEnvAsMapStringInterface("apm_config.analyzed_spans", func(in string) map[string]interface{} {
 		out, err := parseAnalyzedSpans(in)
 		if err != nil {
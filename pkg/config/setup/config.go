@@ -1266,6 +1266,10 @@ func aggregator(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("basic_telemetry_add_container_tags", false) // configure adding the agent container tags to the basic agent telemetry metrics (e.g. `datadog.agent.running`)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_chan_size", 200)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_buffer_size", 4000)
+	config.BindEnvAndSetDefault("aggregator_openmetrics_exporter.enabled", false)
+	config.BindEnvAndSetDefault("aggregator_openmetrics_exporter.host", "localhost")
+	config.BindEnvAndSetDefault("aggregator_openmetrics_exporter.port", 5004)
+	config.BindEnvAndSetDefault("aggregator_openmetrics_exporter.expiry_seconds", 300)
 }
 
 func serverless(config pkgconfigmodel.Setup) {
@@ -1304,11 +1308,29 @@ func forwarder(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("forwarder_storage_max_size_in_bytes", 0)                // 0 means disabled. This is a BETA feature.
 	config.BindEnvAndSetDefault("forwarder_storage_max_disk_ratio", 0.80)                // Do not store transactions on disk when the disk usage exceeds 80% of the disk capacity. Use 80% as some applications do not behave well when the disk space is very small.
 	config.BindEnvAndSetDefault("forwarder_retry_queue_capacity_time_interval_sec", 900) // 15 mins
+	config.BindEnvAndSetDefault("forwarder_storage_encryption_key", "")                  // base64 encoded AES-256 key, may be a secret handle
+	config.BindEnvAndSetDefault("forwarder_storage_encryption_key_file", "")             // one base64 encoded key per line, the first one is the current key
+	config.BindEnvAndSetDefault("forwarder_storage_encryption_previous_keys", []string{})
 
 	// Forwarder channels buffer size
 	config.BindEnvAndSetDefault("forwarder_high_prio_buffer_size", 100)
 	config.BindEnvAndSetDefault("forwarder_low_prio_buffer_size", 100)
 	config.BindEnvAndSetDefault("forwarder_requeue_buffer_size", 100)
+
+	// Forwarder scheduling
+	config.BindEnvAndSetDefault("forwarder_weighted_scheduling", false)
+	for _, class := range []string{"series", "sketches", "service_checks", "events", "check_runs", "metadata", "process", "orchestrator"} {
+		config.BindEnvAndSetDefault("forwarder_scheduling_weights."+class, 1)
+	}
+	config.BindEnvAndSetDefault("forwarder_bandwidth_budget", 0) // in bytes per second, 0 means unlimited
+	config.BindEnv("forwarder_bandwidth_budget_overrides")
+	config.ParseEnvAsSlice("forwarder_bandwidth_budget_overrides", func(in string) []interface{} {
+		var overrides []interface{}
+		if err := json.Unmarshal([]byte(in), &overrides); err != nil {
+			log.Errorf(`"forwarder_bandwidth_budget_overrides" can not be parsed: %v`, err)
+		}
+		return overrides
+	})
 }
 
 func dogstatsd(config pkgconfigmodel.Setup) {
@@ -1316,6 +1338,10 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("use_dogstatsd", true)
 	config.BindEnvAndSetDefault("dogstatsd_port", 8125)    // Notice: 0 means UDP port closed
 	config.BindEnvAndSetDefault("dogstatsd_pipe_name", "") // experimental and not officially supported for now.
+	config.BindEnvAndSetDefault("dogstatsd_http_port", 0)  // Notice: 0 means HTTP listener disabled
+	config.BindEnvAndSetDefault("dogstatsd_http_tokens", map[string]string{})
+	config.BindEnvAndSetDefault("dogstatsd_http_allow_unauthenticated", false)
+	config.BindEnvAndSetDefault("dogstatsd_http_max_payload_size", 4*1024*1024)
 	// Experimental and not officially supported for now.
 	// Options are: udp, uds, named_pipe
 	config.BindEnvAndSetDefault("dogstatsd_eol_required", []string{})
@@ -1349,6 +1375,11 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("dogstatsd_expiry_seconds", 300)
 	// Control how long we keep dogstatsd contexts in memory.
 	config.BindEnvAndSetDefault("dogstatsd_context_expiry_seconds", 20)
+	// Control how many dogstatsd contexts are kept in memory, 0 means no limit.
+	config.BindEnvAndSetDefault("dogstatsd_max_contexts", 0)
+	config.BindEnvAndSetDefault("dogstatsd_max_contexts_per_metric", 0)
+	config.BindEnvAndSetDefault("dogstatsd_max_contexts_per_metric_overrides", map[string]int{})
+	config.BindEnvAndSetDefault("dogstatsd_cardinality_overflow_tags", []string{})
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection", false) // Only supported for socket traffic
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection_client", false)
 	config.BindEnvAndSetDefault("dogstatsd_origin_optout_enabled", true)
@@ -1395,6 +1426,23 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 		return mappings
 	})
 
+	config.BindEnv("dogstatsd_tag_rules")
+	config.ParseEnvAsSlice("dogstatsd_tag_rules", func(in string) []interface{} {
+		var rules []interface{}
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"dogstatsd_tag_rules" can not be parsed: %v`, err)
+		}
+		return rules
+	})
+	config.BindEnv("dogstatsd_rollup_intervals")
+	config.ParseEnvAsSlice("dogstatsd_rollup_intervals", func(in string) []interface{} {
+		var rollups []interface{}
+		if err := json.Unmarshal([]byte(in), &rollups); err != nil {
+			log.Errorf(`"dogstatsd_rollup_intervals" can not be parsed: %v`, err)
+		}
+		return rollups
+	})
+
 	config.BindEnvAndSetDefault("statsd_forward_host", "")
 	config.BindEnvAndSetDefault("statsd_forward_port", 0)
 	config.BindEnvAndSetDefault("statsd_metric_namespace", "")
@@ -1406,6 +1454,7 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("histogram_copy_to_distribution_prefix", "")
 	config.BindEnvAndSetDefault("histogram_aggregates", []string{"max", "median", "avg", "count"})
 	config.BindEnvAndSetDefault("histogram_percentiles", []string{"0.95"})
+	config.BindEnvAndSetDefault("histogram_use_sketch", false)
 }
 
 func logsagent(config pkgconfigmodel.Setup) {
@@ -1463,6 +1512,9 @@ func logsagent(config pkgconfigmodel.Setup) {
 	// This field lets you increase the read timeout to prevent the client from
 	// timing out too early in such a situation. Value in seconds.
 	config.BindEnvAndSetDefault("logs_config.docker_client_read_timeout", 30)
+	// Max size of the payloads buffered on disk when the logs intake can't be reached, 0 disables the disk buffer.
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_max_size_bytes", 0)
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_path", "")
 	// Internal Use Only: avoid modifying those configuration parameters, this could lead to unexpected results.
 	config.BindEnvAndSetDefault("logs_config.run_path", defaultRunPath)
 	// DEPRECATED in favor of `logs_config.force_use_http`.
@@ -1488,6 +1540,8 @@ func logsagent(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("logs_config.stop_grace_period", 30)
 	// maximum time that the unix tailer will hold a log file open after it has been rotated
 	config.BindEnvAndSetDefault("logs_config.close_timeout", 60)
+	// number of bytes at the beginning of the files used to identify them instead of their path, 0 disables it
+	config.BindEnvAndSetDefault("logs_config.fingerprint_size_bytes", 0)
 	// maximum time that the windows tailer will hold a log file open, while waiting for
 	// the downstream logs pipeline to be ready to accept more data
 	config.BindEnvAndSetDefault("logs_config.windows_open_file_timeout", 5)
@@ -2328,6 +2382,9 @@ func bindEnvAndSetLogsConfigKeys(config pkgconfigmodel.Setup, prefix string) {
 	config.BindEnv(prefix + "additional_endpoints")
 	config.BindEnvAndSetDefault(prefix+"use_compression", true)
 	config.BindEnvAndSetDefault(prefix+"compression_level", 6) // Default level for the gzip/deflate algorithm
+	config.BindEnvAndSetDefault(prefix+"compression_kind", "gzip")
+	config.BindEnvAndSetDefault(prefix+"zstd_compression_level", DefaultZstdCompressionLevel)
+	config.BindEnvAndSetDefault(prefix+"zstd_dictionary_path", "")
 	config.BindEnvAndSetDefault(prefix+"batch_wait", DefaultBatchWait)
 	config.BindEnvAndSetDefault(prefix+"connection_reset_interval", 0) // in seconds, 0 means disabled
 	config.BindEnvAndSetDefault(prefix+"logs_no_ssl", false)
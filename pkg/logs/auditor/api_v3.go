@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package auditor
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// v3: In the fourth version of the auditor, the files can be identified by a fingerprint of their first bytes
+// instead of their path. The format is the same as v2, the version is only written when fingerprinting is enabled
+// and tells that the entries of the files have already been migrated to their fingerprint.
+
+func unmarshalRegistryV3(b []byte) (map[string]*RegistryEntry, error) {
+	return unmarshalRegistryV2(b)
+}
+
+// migrateToFingerprints moves the entries of the files identified by their path to the fingerprint of their
+// content, so that enabling fingerprinting does not send the files again. The entries of the files which
+// can't be fingerprinted yet are kept, the tailers of these files keep using their path until they are
+// long enough.
+func migrateToFingerprints(registry map[string]*RegistryEntry, fingerprintSize int) {
+	for identifier, entry := range registry {
+		if !strings.HasPrefix(identifier, fileIdentifierPrefix) {
+			continue
+		}
+		path := strings.TrimPrefix(identifier, fileIdentifierPrefix)
+		fingerprint, ok, err := FileFingerprint(path, fingerprintSize)
+		if err != nil || !ok {
+			continue
+		}
+		if _, exists := registry[fingerprint]; !exists {
+			registry[fingerprint] = entry
+		}
+		delete(registry, identifier)
+		log.Debugf("Migrated the registry entry of %s to %s", path, fingerprint)
+	}
+}
@@ -0,0 +1,106 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package auditor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/status/health"
+)
+
+func TestFileFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	path1 := filepath.Join(dir, "1.log")
+	path2 := filepath.Join(dir, "2.log")
+	require.NoError(t, os.WriteFile(path1, []byte("0123456789 first file"), 0600))
+	require.NoError(t, os.WriteFile(path2, []byte("0123456789 second file"), 0600))
+
+	fingerprint1, ok, err := FileFingerprint(path1, 10)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, IsFingerprintIdentifier(fingerprint1))
+
+	// only the first bytes are hashed
+	fingerprint2, ok, err := FileFingerprint(path2, 10)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, fingerprint1, fingerprint2)
+
+	fingerprint2, _, err = FileFingerprint(path2, 12)
+	require.NoError(t, err)
+	assert.NotEqual(t, fingerprint1, fingerprint2)
+
+	// too short to be identified by its content
+	_, ok, err = FileFingerprint(path1, 1024)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, err = FileFingerprint(filepath.Join(dir, "missing.log"), 10)
+	assert.Error(t, err)
+}
+
+func TestAuditorMigratesRegistryV2ToFingerprints(t *testing.T) {
+	dir := t.TempDir()
+	longPath := filepath.Join(dir, "long.log")
+	shortPath := filepath.Join(dir, "short.log")
+	require.NoError(t, os.WriteFile(longPath, []byte(strings.Repeat("a", 64)), 0600))
+	require.NoError(t, os.WriteFile(shortPath, []byte("a"), 0600))
+
+	input := fmt.Sprintf(`{
+	    "Registry": {
+	        "file:%s": {"Offset": "42", "TailingMode": "end", "LastUpdated": "2006-01-12T01:01:01.000000001Z"},
+	        "file:%s": {"Offset": "1", "LastUpdated": "2006-01-12T01:01:01.000000001Z"},
+	        "file:%s": {"Offset": "3", "LastUpdated": "2006-01-12T01:01:01.000000001Z"},
+	        "journald:default": {"Offset": "cursor", "LastUpdated": "2006-01-12T01:01:01.000000001Z"}
+	    },
+	    "Version": 2
+	}`, longPath, shortPath, filepath.Join(dir, "removed.log"))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, DefaultRegistryFilename), []byte(input), 0644))
+
+	a := New(dir, DefaultRegistryFilename, time.Hour, 32, health.RegisterLiveness("fake"))
+	registry := a.recoverRegistry()
+
+	fingerprint, ok, err := FileFingerprint(longPath, 32)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Contains(t, registry, fingerprint)
+	assert.Equal(t, "42", registry[fingerprint].Offset)
+	assert.Equal(t, "end", registry[fingerprint].TailingMode)
+	assert.NotContains(t, registry, "file:"+longPath)
+	// the files which can't be fingerprinted are still identified by their path
+	assert.Equal(t, "1", registry["file:"+shortPath].Offset)
+	assert.Equal(t, "3", registry["file:"+filepath.Join(dir, "removed.log")].Offset)
+	assert.Equal(t, "cursor", registry["journald:default"].Offset)
+
+	// the migrated registry is written with the fingerprinting version
+	a.registry = registry
+	require.NoError(t, a.flushRegistry())
+	content, err := os.ReadFile(filepath.Join(dir, DefaultRegistryFilename))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"Version":3`)
+	assert.Equal(t, registry, a.recoverRegistry())
+}
+
+func TestAuditorDoesNotMigrateWithoutFingerprints(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "long.log")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("a", 64)), 0600))
+	input := fmt.Sprintf(`{"Registry": {"file:%s": {"Offset": "42"}}, "Version": 2}`, path)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, DefaultRegistryFilename), []byte(input), 0644))
+
+	a := New(dir, DefaultRegistryFilename, time.Hour, 0, health.RegisterLiveness("fake"))
+	registry := a.recoverRegistry()
+	assert.Equal(t, "42", registry["file:"+path].Offset)
+	assert.Len(t, registry, 1)
+}
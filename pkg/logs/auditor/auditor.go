@@ -27,12 +27,17 @@ const defaultFlushPeriod = 1 * time.Second
 const defaultCleanupPeriod = 300 * time.Second
 
 // latest version of the API used by the auditor to retrieve the registry from disk.
-const registryAPIVersion = 2
+const registryAPIVersion = 3
+
+// registryAPIVersionWithoutFingerprints is the version written when fingerprinting is disabled, so
+// that the registry can still be read by the versions of the agent which don't support it.
+const registryAPIVersionWithoutFingerprints = 2
 
 // Registry holds a list of offsets.
 type Registry interface {
 	GetOffset(identifier string) string
 	GetTailingMode(identifier string) string
+	GetLastUpdated(identifier string) time.Time
 }
 
 // A RegistryEntry represents an entry in the registry where we keep track
@@ -70,17 +75,20 @@ type RegistryAuditor struct {
 	registryTmpFile string
 	registryMutex   sync.Mutex
 	entryTTL        time.Duration
+	fingerprintSize int
 	done            chan struct{}
 }
 
-// New returns an initialized Auditor
-func New(runPath string, filename string, ttl time.Duration, health *health.Handle) *RegistryAuditor {
+// New returns an initialized Auditor, fingerprintSize is the number of bytes used to identify
+// the files by their content, 0 disables fingerprinting.
+func New(runPath string, filename string, ttl time.Duration, fingerprintSize int, health *health.Handle) *RegistryAuditor {
 	return &RegistryAuditor{
 		health:          health,
 		registryPath:    filepath.Join(runPath, filename),
 		registryDirPath: runPath,
 		registryTmpFile: filepath.Base(filename) + ".tmp",
 		entryTTL:        ttl,
+		fingerprintSize: fingerprintSize,
 	}
 }
 
@@ -152,6 +160,17 @@ func (a *RegistryAuditor) GetTailingMode(identifier string) string {
 	return entry.TailingMode
 }
 
+// GetLastUpdated returns the last time an offset was committed for a given identifier,
+// returns the zero time if it does not exist.
+func (a *RegistryAuditor) GetLastUpdated(identifier string) time.Time {
+	r := a.readOnlyRegistryCopy()
+	entry, exists := r[identifier]
+	if !exists {
+		return time.Time{}
+	}
+	return entry.LastUpdated
+}
+
 // run keeps up to date the registry depending on different events
 func (a *RegistryAuditor) run() {
 	cleanUpTicker := time.NewTicker(defaultCleanupPeriod)
@@ -300,8 +319,12 @@ func (a *RegistryAuditor) flushRegistry() error {
 
 // marshalRegistry marshals a registry
 func (a *RegistryAuditor) marshalRegistry(registry map[string]RegistryEntry) ([]byte, error) {
+	version := registryAPIVersion
+	if a.fingerprintSize <= 0 {
+		version = registryAPIVersionWithoutFingerprints
+	}
 	r := JSONRegistry{
-		Version:  registryAPIVersion,
+		Version:  version,
 		Registry: registry,
 	}
 	return json.Marshal(r)
@@ -319,14 +342,21 @@ func (a *RegistryAuditor) unmarshalRegistry(b []byte) (map[string]*RegistryEntry
 		return nil, fmt.Errorf("registry retrieved from disk must have a version number")
 	}
 	// ensure backward compatibility
+	var registry map[string]*RegistryEntry
 	switch int(version) {
+	case 3:
+		return unmarshalRegistryV3(b)
 	case 2:
-		return unmarshalRegistryV2(b)
+		registry, err = unmarshalRegistryV2(b)
 	case 1:
-		return unmarshalRegistryV1(b)
+		registry, err = unmarshalRegistryV1(b)
 	case 0:
-		return unmarshalRegistryV0(b)
+		registry, err = unmarshalRegistryV0(b)
 	default:
 		return nil, fmt.Errorf("invalid registry version number")
 	}
+	if err == nil && a.fingerprintSize > 0 {
+		migrateToFingerprints(registry, a.fingerprintSize)
+	}
+	return registry, err
 }
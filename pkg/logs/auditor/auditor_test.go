@@ -36,7 +36,7 @@ func (suite *AuditorTestSuite) SetupTest() {
 
 	suite.testRegistryPath = filepath.Join(suite.testRunPathDir, "registry.json")
 
-	suite.a = New(suite.testRunPathDir, DefaultRegistryFilename, time.Hour, health.RegisterLiveness("fake"))
+	suite.a = New(suite.testRunPathDir, DefaultRegistryFilename, time.Hour, 0, health.RegisterLiveness("fake"))
 	suite.source = sources.NewLogSource("", &config.LogsConfig{Path: testpath})
 }
 
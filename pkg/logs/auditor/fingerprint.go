@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package auditor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+)
+
+const (
+	// fingerprintIdentifierPrefix is the prefix of the identifiers of the files identified by their content.
+	fingerprintIdentifierPrefix = "fingerprint:"
+	// fileIdentifierPrefix is the prefix of the identifiers of the files identified by their path.
+	fileIdentifierPrefix = "file:"
+)
+
+// FileFingerprint returns the identifier of a file computed from a hash of its first size bytes.
+// ok is false when the file is shorter than size bytes: it can't be identified by its content yet
+// since its first bytes are still being written. Only the first size bytes are hashed: files with
+// identical first bytes, like a header written on creation, share the same identifier.
+func FileFingerprint(path string, size int) (identifier string, ok bool, err error) {
+	f, err := filesystem.OpenShared(path)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+
+	head := make([]byte, size)
+	if _, err := io.ReadFull(f, head); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return Fingerprint(head), true, nil
+}
+
+// Fingerprint returns the identifier of a file starting with the given bytes.
+func Fingerprint(head []byte) string {
+	sum := sha256.Sum256(head)
+	return fingerprintIdentifierPrefix + hex.EncodeToString(sum[:16])
+}
+
+// IsFingerprintIdentifier returns true if the identifier is a fingerprint returned by FileFingerprint.
+func IsFingerprintIdentifier(identifier string) bool {
+	return strings.HasPrefix(identifier, fingerprintIdentifierPrefix)
+}
@@ -6,10 +6,13 @@
 //nolint:revive // TODO(AML) Fix revive linter
 package mock
 
+import "time"
+
 // Registry does nothing
 type Registry struct {
 	offset      string
 	tailingMode string
+	lastUpdated time.Time
 }
 
 // NewRegistry returns a new registry.
@@ -36,3 +39,13 @@ func (r *Registry) GetTailingMode(_ string) string {
 func (r *Registry) SetTailingMode(tailingMode string) {
 	r.tailingMode = tailingMode
 }
+
+// GetLastUpdated returns the last update time.
+func (r *Registry) GetLastUpdated(_ string) time.Time {
+	return r.lastUpdated
+}
+
+// SetLastUpdated sets the last update time.
+func (r *Registry) SetLastUpdated(lastUpdated time.Time) {
+	r.lastUpdated = lastUpdated
+}
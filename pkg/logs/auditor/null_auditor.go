@@ -6,6 +6,8 @@
 package auditor
 
 import (
+	"time"
+
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 )
 
@@ -36,6 +38,9 @@ func (a *NullAuditor) GetOffset(_ string) string { return "" }
 //nolint:revive // TODO(AML) Fix revive linter
 func (a *NullAuditor) GetTailingMode(_ string) string { return "" }
 
+// GetLastUpdated returns the zero time.
+func (a *NullAuditor) GetLastUpdated(_ string) time.Time { return time.Time{} }
+
 // Start starts the NullAuditor main loop.
 func (a *NullAuditor) Start() {
 	go a.run()
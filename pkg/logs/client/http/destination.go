@@ -22,6 +22,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/otlp"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
@@ -68,6 +69,7 @@ type Destination struct {
 	protocol            config.IntakeProtocol
 	origin              config.IntakeOrigin
 	isMRF               bool
+	otlpEncoder         *otlp.Encoder // set when the endpoint is an OTLP/HTTP receiver
 
 	// Concurrency
 	climit chan struct{} // semaphore for limiting concurrent background sends
@@ -127,6 +129,12 @@ func newDestination(endpoint config.Endpoint,
 		endpoint.RecoveryReset,
 	)
 
+	var otlpEncoder *otlp.Encoder
+	if endpoint.IsOTLP() {
+		otlpEncoder = otlp.NewEncoder(endpoint)
+		contentType = otlpEncoder.ContentType()
+	}
+
 	expVars := &expvar.Map{}
 	expVars.AddFloat(expVarIdleMsMapKey, 0)
 	expVars.AddFloat(expVarInUseMsMapKey, 0)
@@ -152,6 +160,7 @@ func newDestination(endpoint config.Endpoint,
 		expVars:             expVars,
 		telemetryName:       telemetryName,
 		isMRF:               endpoint.IsMRF,
+		otlpEncoder:         otlpEncoder,
 	}
 }
 
@@ -276,34 +285,46 @@ func (d *Destination) unconditionalSend(payload *message.Payload) (err error) {
 	if err != nil {
 		return err
 	}
+	body, encoding := payload.Encoded, payload.Encoding
+	if d.otlpEncoder != nil {
+		// OTLP receivers don't understand the Datadog intake format, the messages are encoded again
+		if body, encoding, err = d.otlpEncoder.Encode(payload); err != nil {
+			tlmDropped.Inc()
+			return err
+		}
+	}
+
 	metrics.BytesSent.Add(int64(payload.UnencodedSize))
 	metrics.TlmBytesSent.Add(float64(payload.UnencodedSize))
-	metrics.EncodedBytesSent.Add(int64(len(payload.Encoded)))
-	metrics.TlmEncodedBytesSent.Add(float64(len(payload.Encoded)))
+	metrics.EncodedBytesSent.Add(int64(len(body)))
+	metrics.TlmEncodedBytesSent.Add(float64(len(body)))
 
-	req, err := http.NewRequest("POST", d.url, bytes.NewReader(payload.Encoded))
+	req, err := http.NewRequest("POST", d.url, bytes.NewReader(body))
 	if err != nil {
 		// the request could not be built,
 		// this can happen when the method or the url are valid.
 		return err
 	}
-	req.Header.Set("DD-API-KEY", d.endpoint.GetAPIKey())
 	req.Header.Set("Content-Type", d.contentType)
 	req.Header.Set("User-Agent", fmt.Sprintf("datadog-agent/%s", version.AgentVersion))
 
-	if payload.Encoding != "" {
-		req.Header.Set("Content-Encoding", payload.Encoding)
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
 	}
-	if d.protocol != "" {
-		req.Header.Set("DD-PROTOCOL", string(d.protocol))
-	}
-	if d.origin != "" {
-		req.Header.Set("DD-EVP-ORIGIN", string(d.origin))
-		req.Header.Set("DD-EVP-ORIGIN-VERSION", version.AgentVersion)
-	}
-	req.Header.Set("dd-message-timestamp", strconv.FormatInt(getMessageTimestamp(payload.Messages), 10))
 	then := time.Now()
-	req.Header.Set("dd-current-timestamp", strconv.FormatInt(then.UnixMilli(), 10))
+	// the Datadog headers, and the API key, are never sent to OTLP receivers
+	if d.otlpEncoder == nil {
+		req.Header.Set("DD-API-KEY", d.endpoint.GetAPIKey())
+		if d.protocol != "" {
+			req.Header.Set("DD-PROTOCOL", string(d.protocol))
+		}
+		if d.origin != "" {
+			req.Header.Set("DD-EVP-ORIGIN", string(d.origin))
+			req.Header.Set("DD-EVP-ORIGIN-VERSION", version.AgentVersion)
+		}
+		req.Header.Set("dd-message-timestamp", strconv.FormatInt(getMessageTimestamp(payload.Messages), 10))
+		req.Header.Set("dd-current-timestamp", strconv.FormatInt(then.UnixMilli(), 10))
+	}
 
 	req = req.WithContext(ctx)
 	resp, err := d.client.Do(req)
@@ -403,7 +424,9 @@ func buildURL(endpoint config.Endpoint) string {
 		Scheme: scheme,
 		Host:   address,
 	}
-	if endpoint.Version == config.EPIntakeVersion2 && endpoint.TrackType != "" {
+	if endpoint.IsOTLP() {
+		url.Path = otlp.LogsPath
+	} else if endpoint.Version == config.EPIntakeVersion2 && endpoint.TrackType != "" {
 		url.Path = fmt.Sprintf("/api/v2/%s", endpoint.TrackType)
 	} else {
 		url.Path = "/v1/input"
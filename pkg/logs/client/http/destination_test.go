@@ -7,6 +7,9 @@ package http
 
 import (
 	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"regexp"
 	"strconv"
 	"strings"
@@ -14,9 +17,12 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/otlp"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
@@ -41,6 +47,13 @@ func TestBuildURLShouldReturnAddressWithPortWhenDefined(t *testing.T) {
 	assert.Equal(t, "http://foo:1234/v1/input", url)
 }
 
+func TestBuildURLShouldReturnLogsPathForOTLP(t *testing.T) {
+	e := config.NewEndpoint("bar", "foo", 4318, false)
+	e.Format = config.OTLPJSONEndpointFormat
+	url := buildURL(e)
+	assert.Equal(t, "http://foo:4318/v1/logs", url)
+}
+
 func TestBuildURLShouldReturnAddressForVersion2(t *testing.T) {
 	e := config.NewEndpoint("bar", "foo", 0, false)
 	e.Version = config.EPIntakeVersion2
@@ -220,6 +233,41 @@ func TestDestinationSendsUserAgent(t *testing.T) {
 	assert.Regexp(t, regexp.MustCompile("datadog-agent/.*"), server.request.Header.Values("user-agent"))
 }
 
+func TestDestinationSendsOTLPRequests(t *testing.T) {
+	var request *http.Request
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer ts.Close()
+
+	url := strings.Split(ts.URL, ":")
+	port, _ := strconv.Atoi(url[2])
+	endpoint := config.NewEndpoint("secret", strings.TrimPrefix(url[1], "//"), port, false)
+	endpoint.Format = config.OTLPProtobufEndpointFormat
+	destCtx := client.NewDestinationsContext()
+	destCtx.Start()
+	defer destCtx.Stop()
+	destination := NewDestination(endpoint, JSONContentType, destCtx, 0, true, "", getNewConfig())
+
+	msg := message.NewMessage(nil, message.NewOrigin(sources.NewLogSource("", &config.LogsConfig{Service: "web"})), message.StatusError, 0)
+	msg.SetEncoded([]byte(`{"message":"boom","timestamp":1700000000000,"hostname":"host-a"}`))
+	err := destination.unconditionalSend(&message.Payload{Messages: []*message.Message{msg}, Encoded: []byte("datadog payload")})
+	assert.Nil(t, err)
+
+	assert.Equal(t, "/v1/logs", request.URL.Path)
+	assert.Equal(t, otlp.ProtobufContentType, request.Header.Get("Content-Type"))
+	assert.Empty(t, request.Header.Get("DD-API-KEY"))
+	assert.Empty(t, request.Header.Get("dd-message-timestamp"))
+
+	exportRequest := plogotlp.NewExportRequest()
+	assert.Nil(t, exportRequest.UnmarshalProto(body))
+	resource := exportRequest.Logs().ResourceLogs().At(0)
+	assert.Equal(t, "web", resource.Resource().Attributes().AsRaw()[otlp.ServiceAttribute])
+	assert.Equal(t, "boom", resource.ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+}
+
 func TestDestinationConcurrentSends(t *testing.T) {
 	cfg := getNewConfig()
 	// make the server return 500, so the payloads get stuck retrying
@@ -0,0 +1,219 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package otlp converts the logs payloads to OTLP export requests, so that they can be sent to an OTLP/HTTP receiver.
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// Content types of the OTLP/HTTP requests.
+const (
+	ProtobufContentType = "application/x-protobuf"
+	JSONContentType     = "application/json"
+)
+
+// LogsPath is the path of the OTLP/HTTP logs receivers.
+const LogsPath = "/v1/logs"
+
+// Attributes set on the resources of the log records.
+const (
+	HostNameAttribute  = "host.name"
+	ServiceAttribute   = "service.name"
+	LogSourceAttribute = "datadog.log.source"
+)
+
+// Encoder converts the payloads to OTLP export requests.
+type Encoder struct {
+	json             bool
+	useCompression   bool
+	compressionLevel int
+}
+
+// NewEncoder returns an encoder for the format and the compression settings of the endpoint.
+func NewEncoder(endpoint config.Endpoint) *Encoder {
+	return &Encoder{
+		json:             endpoint.Format == config.OTLPJSONEndpointFormat,
+		useCompression:   endpoint.UseCompression,
+		compressionLevel: endpoint.CompressionLevel,
+	}
+}
+
+// ContentType returns the content type of the encoded requests.
+func (e *Encoder) ContentType() string {
+	if e.json {
+		return JSONContentType
+	}
+	return ProtobufContentType
+}
+
+// Encode returns the body of the export request of the messages of the payload along with its content encoding.
+func (e *Encoder) Encode(payload *message.Payload) ([]byte, string, error) {
+	request := plogotlp.NewExportRequestFromLogs(ToLogs(payload.Messages))
+
+	var body []byte
+	var err error
+	if e.json {
+		body, err = request.MarshalJSON()
+	} else {
+		body, err = request.MarshalProto()
+	}
+	if err != nil || !e.useCompression {
+		return body, "", err
+	}
+
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, e.compressionLevel)
+	if err != nil {
+		writer = gzip.NewWriter(&buf)
+	}
+	if _, err := writer.Write(body); err != nil {
+		return nil, "", err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "gzip", nil
+}
+
+// encodedMessage is the JSON representation of a message built by the processor for the HTTP intake.
+type encodedMessage struct {
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+	Hostname  string `json:"hostname"`
+}
+
+type resourceKey struct {
+	hostname string
+	service  string
+	source   string
+	tags     string
+}
+
+// ToLogs maps the messages to OTLP log records. The messages sharing the same hostname, service, source
+// and tags are grouped under the same resource, the tags being added as resource attributes.
+func ToLogs(messages []*message.Message) plog.Logs {
+	logs := plog.NewLogs()
+	scopes := make(map[resourceKey]plog.ScopeLogs)
+
+	for _, msg := range messages {
+		content := msg.GetContent()
+		body := string(content)
+		hostname := msg.Hostname
+		timestamp := msg.ServerlessExtra.Timestamp
+		var encoded encodedMessage
+		if msg.State == message.StateEncoded && json.Unmarshal(content, &encoded) == nil {
+			body = encoded.Message
+			if encoded.Hostname != "" {
+				hostname = encoded.Hostname
+			}
+			if encoded.Timestamp > 0 {
+				timestamp = time.UnixMilli(encoded.Timestamp)
+			}
+		}
+
+		var service, source string
+		var tags []string
+		if msg.Origin != nil {
+			service = msg.Origin.Service()
+			source = msg.Origin.Source()
+			tags = msg.Tags()
+		} else {
+			tags = msg.ProcessingTags
+		}
+
+		key := resourceKey{hostname: hostname, service: service, source: source, tags: strings.Join(tags, ",")}
+		scope, exists := scopes[key]
+		if !exists {
+			resourceLogs := logs.ResourceLogs().AppendEmpty()
+			setResourceAttributes(resourceLogs.Resource().Attributes(), key, tags)
+			scope = resourceLogs.ScopeLogs().AppendEmpty()
+			scopes[key] = scope
+		}
+
+		record := scope.LogRecords().AppendEmpty()
+		record.Body().SetStr(body)
+		if !timestamp.IsZero() {
+			record.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+		}
+		if msg.IngestionTimestamp > 0 {
+			record.SetObservedTimestamp(pcommon.Timestamp(msg.IngestionTimestamp))
+		}
+		status := msg.GetStatus()
+		record.SetSeverityText(status)
+		record.SetSeverityNumber(severityNumber(status))
+	}
+	return logs
+}
+
+// setResourceAttributes sets the attributes of a resource, a tag without value is added with an empty value
+// and the values of the tags sharing the same key are grouped in a slice.
+func setResourceAttributes(attributes pcommon.Map, key resourceKey, tags []string) {
+	for _, tag := range tags {
+		name, value, _ := strings.Cut(tag, ":")
+		if name == "" {
+			continue
+		}
+		existing, exists := attributes.Get(name)
+		switch {
+		case !exists:
+			attributes.PutStr(name, value)
+		case existing.Type() == pcommon.ValueTypeSlice:
+			existing.Slice().AppendEmpty().SetStr(value)
+		default:
+			previous := existing.Str()
+			values := attributes.PutEmptySlice(name)
+			values.AppendEmpty().SetStr(previous)
+			values.AppendEmpty().SetStr(value)
+		}
+	}
+
+	// the reserved attributes take precedence over the tags
+	if key.hostname != "" {
+		attributes.PutStr(HostNameAttribute, key.hostname)
+	}
+	if key.service != "" {
+		attributes.PutStr(ServiceAttribute, key.service)
+	}
+	if key.source != "" {
+		attributes.PutStr(LogSourceAttribute, key.source)
+	}
+}
+
+// severityNumber maps the status of a message to an OTLP severity.
+func severityNumber(status string) plog.SeverityNumber {
+	switch status {
+	case message.StatusEmergency:
+		return plog.SeverityNumberFatal4
+	case message.StatusAlert:
+		return plog.SeverityNumberFatal3
+	case message.StatusCritical:
+		return plog.SeverityNumberFatal
+	case message.StatusError:
+		return plog.SeverityNumberError
+	case message.StatusWarning:
+		return plog.SeverityNumberWarn
+	case message.StatusNotice:
+		return plog.SeverityNumberInfo2
+	case message.StatusInfo:
+		return plog.SeverityNumberInfo
+	case message.StatusDebug:
+		return plog.SeverityNumberDebug
+	default:
+		return plog.SeverityNumberUnspecified
+	}
+}
@@ -0,0 +1,132 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newEncodedMessage(source *sources.LogSource, encoded string, status string) *message.Message {
+	msg := message.NewMessage(nil, message.NewOrigin(source), status, 1700000000123456789)
+	msg.SetEncoded([]byte(encoded))
+	return msg
+}
+
+func TestToLogs(t *testing.T) {
+	source := sources.NewLogSource("nginx", &config.LogsConfig{
+		Service: "web",
+		Source:  "nginx",
+		Tags:    []string{"env:prod", "team:a", "team:b", "canary"},
+	})
+	other := sources.NewLogSource("redis", &config.LogsConfig{Service: "cache", Source: "redis"})
+
+	messages := []*message.Message{
+		newEncodedMessage(source, `{"message":"GET /","status":"info","timestamp":1700000000000,"hostname":"host-a","service":"web","ddsource":"nginx","ddtags":"env:prod"}`, message.StatusInfo),
+		newEncodedMessage(other, `{"message":"OOM","status":"error","timestamp":1700000001000,"hostname":"host-a","service":"cache","ddsource":"redis","ddtags":""}`, message.StatusError),
+		newEncodedMessage(source, `{"message":"GET /health","status":"warn","timestamp":1700000002000,"hostname":"host-a","service":"web","ddsource":"nginx","ddtags":"env:prod"}`, message.StatusWarning),
+	}
+
+	logs := ToLogs(messages)
+	require.Equal(t, 2, logs.ResourceLogs().Len())
+	assert.Equal(t, 3, logs.LogRecordCount())
+
+	web := logs.ResourceLogs().At(0)
+	attributes := web.Resource().Attributes().AsRaw()
+	assert.Equal(t, "host-a", attributes[HostNameAttribute])
+	assert.Equal(t, "web", attributes[ServiceAttribute])
+	assert.Equal(t, "nginx", attributes[LogSourceAttribute])
+	assert.Equal(t, "prod", attributes["env"])
+	assert.Equal(t, []interface{}{"a", "b"}, attributes["team"])
+	assert.Equal(t, "", attributes["canary"])
+
+	records := web.ScopeLogs().At(0).LogRecords()
+	require.Equal(t, 2, records.Len())
+	assert.Equal(t, "GET /", records.At(0).Body().Str())
+	assert.Equal(t, time.UnixMilli(1700000000000).UTC(), records.At(0).Timestamp().AsTime())
+	assert.Equal(t, int64(1700000000123456789), records.At(0).ObservedTimestamp().AsTime().UnixNano())
+	assert.Equal(t, "info", records.At(0).SeverityText())
+	assert.Equal(t, plog.SeverityNumberInfo, records.At(0).SeverityNumber())
+	assert.Equal(t, "GET /health", records.At(1).Body().Str())
+	assert.Equal(t, plog.SeverityNumberWarn, records.At(1).SeverityNumber())
+
+	redis := logs.ResourceLogs().At(1)
+	assert.Equal(t, "cache", redis.Resource().Attributes().AsRaw()[ServiceAttribute])
+	record := redis.ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, "OOM", record.Body().Str())
+	assert.Equal(t, plog.SeverityNumberError, record.SeverityNumber())
+}
+
+func TestToLogsUnencodedContent(t *testing.T) {
+	msg := message.NewMessage([]byte("raw line"), message.NewOrigin(sources.NewLogSource("", &config.LogsConfig{})), message.StatusDebug, 0)
+	msg.Hostname = "host-b"
+
+	logs := ToLogs([]*message.Message{msg})
+	require.Equal(t, 1, logs.LogRecordCount())
+	resource := logs.ResourceLogs().At(0)
+	assert.Equal(t, map[string]interface{}{HostNameAttribute: "host-b"}, resource.Resource().Attributes().AsRaw())
+	record := resource.ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, "raw line", record.Body().Str())
+	assert.Equal(t, plog.SeverityNumberDebug, record.SeverityNumber())
+}
+
+func TestEncode(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{Service: "web"})
+	payload := &message.Payload{Messages: []*message.Message{
+		newEncodedMessage(source, `{"message":"hello","timestamp":1700000000000,"hostname":"host-a"}`, message.StatusInfo),
+	}}
+
+	t.Run("protobuf", func(t *testing.T) {
+		endpoint := config.NewEndpoint("", "collector", 4318, false)
+		endpoint.Format = config.OTLPProtobufEndpointFormat
+		encoder := NewEncoder(endpoint)
+		assert.Equal(t, ProtobufContentType, encoder.ContentType())
+
+		body, encoding, err := encoder.Encode(payload)
+		require.NoError(t, err)
+		assert.Empty(t, encoding)
+
+		request := plogotlp.NewExportRequest()
+		require.NoError(t, request.UnmarshalProto(body))
+		assert.Equal(t, "hello", request.Logs().ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+	})
+
+	t.Run("compressed json", func(t *testing.T) {
+		endpoint := config.NewEndpoint("", "collector", 4318, false)
+		endpoint.Format = config.OTLPJSONEndpointFormat
+		endpoint.UseCompression = true
+		endpoint.CompressionLevel = 6
+		encoder := NewEncoder(endpoint)
+		assert.Equal(t, JSONContentType, encoder.ContentType())
+
+		body, encoding, err := encoder.Encode(payload)
+		require.NoError(t, err)
+		assert.Equal(t, "gzip", encoding)
+
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		require.NoError(t, err)
+		decompressed, err := io.ReadAll(reader)
+		require.NoError(t, err)
+
+		request := plogotlp.NewExportRequest()
+		require.NoError(t, request.UnmarshalJSON(decompressed))
+		resource := request.Logs().ResourceLogs().At(0)
+		assert.Equal(t, "web", resource.Resource().Attributes().AsRaw()[ServiceAttribute])
+		assert.Equal(t, "hello", resource.ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+	})
+}
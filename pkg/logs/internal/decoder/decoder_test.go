@@ -269,6 +269,24 @@ func TestDecoderWithDecodingParser(t *testing.T) {
 	d.Stop()
 }
 
+func TestDecoderFromSourceWithLogFormat(t *testing.T) {
+	source := sources.NewLogSource("config", &config.LogsConfig{Type: config.FileType, Encoding: config.UTF16LE, LogFormat: config.LogfmtLogFormat})
+
+	info := status.NewInfoRegistry()
+	d := NewDecoderFromSource(sources.NewReplaceableSource(source), info)
+	d.Start()
+
+	input := []byte{'l', 0x0, 'v', 0x0, 'l', 0x0, '=', 0x0, 'e', 0x0, 'r', 0x0, 'r', 0x0, '\n', 0x0}
+	d.InputChan <- NewInput(input)
+
+	output := <-d.OutputChan
+	assert.Equal(t, `{"lvl":"err"}`, string(output.GetContent()))
+	assert.Equal(t, message.StatusError, output.Status)
+	assert.Equal(t, len(input), output.RawDataLen)
+
+	d.Stop()
+}
+
 func TestDecoderWithSinglelineKubernetes(t *testing.T) {
 	var output *message.Message
 	var line []byte
@@ -12,11 +12,15 @@ import (
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/framer"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/accesslog"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/dockerfile"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/encodedtext"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/integrations"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/kubeaudit"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/kubernetes"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/logfmt"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/noop"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
 )
@@ -65,7 +69,50 @@ func NewDecoderFromSourceWithPattern(source *sources.ReplaceableSource, multiLin
 			encodingInfo.SetMessage("Encoding", "utf-8")
 		}
 		tailerInfo.Register(encodingInfo)
+
+		if formatParser := logFormatParser(source.Config().LogFormat); formatParser != nil {
+			lineParser = &chainedParser{decoding: lineParser, format: formatParser}
+			formatInfo := status.NewMappedInfo("Log Format")
+			formatInfo.SetMessage("Log Format", source.Config().LogFormat)
+			tailerInfo.Register(formatInfo)
+		}
 	}
 
 	return NewDecoderWithFraming(source, lineParser, framing, multiLinePattern, tailerInfo)
 }
+
+// logFormatParser returns the structured parser of the log_format of a source, or nil when the
+// lines are not structured.
+func logFormatParser(logFormat string) parsers.Parser {
+	switch logFormat {
+	case config.KubeAuditLogFormat:
+		return kubeaudit.New()
+	case config.NginxLogFormat, config.ApacheLogFormat:
+		return accesslog.New()
+	case config.LogfmtLogFormat:
+		return logfmt.New()
+	default:
+		return nil
+	}
+}
+
+// chainedParser decodes the lines with the parser of the encoding of the source, then parses
+// the decoded lines with the parser of their format.
+type chainedParser struct {
+	decoding parsers.Parser
+	format   parsers.Parser
+}
+
+// Parse implements Parser#Parse
+func (p *chainedParser) Parse(msg *message.Message) (*message.Message, error) {
+	msg, err := p.decoding.Parse(msg)
+	if err != nil {
+		return msg, err
+	}
+	return p.format.Parse(msg)
+}
+
+// SupportsPartialLine implements Parser#SupportsPartialLine
+func (p *chainedParser) SupportsPartialLine() bool {
+	return false
+}
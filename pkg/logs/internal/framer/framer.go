@@ -42,6 +42,10 @@ const (
 	// headers are included in the log frame.  The size in those headers is not
 	// consulted.  The result does not include the trailing newlines.
 	DockerStream
+
+	// Syslog messages sent over TCP, either octet-counted or newline-terminated
+	// as described in RFC6587.
+	SyslogOctetCounting
 )
 
 // Framer gets chunks of bytes (via Process(..)) and uses an
@@ -101,6 +105,8 @@ func NewFramer(
 		matcher = &dockerStreamMatcher{contentLenLimit}
 	case NoFraming:
 		matcher = &noFramingMatcher{}
+	case SyslogOctetCounting:
+		matcher = &syslogMatcher{newline: oneByteNewLineMatcher{contentLenLimit}}
 	default:
 		panic(fmt.Sprintf("unknown framing %d", framing))
 	}
@@ -158,10 +164,21 @@ func (fr *Framer) Process(input *message.Message) {
 		buf := fr.buffer.Bytes()[framed:]
 
 		content, rawDataLen := fr.matcher.FindFrame(buf, seen-framed)
+		if content == nil && rawDataLen > 0 {
+			// the matcher discarded these bytes
+			framed += rawDataLen
+			seen = framed
+			continue
+		}
 		if content == nil {
 			// if the matcher was asked to match more than contentLenLimit,
-			// chop off contentLenLimit raw bytes and output them
-			if len(buf) >= contentLenLimit {
+			// chop off contentLenLimit raw bytes and output them. The header
+			// of the frame doesn't count in the limit.
+			limit := contentLenLimit
+			if hm, ok := fr.matcher.(frameHeaderMatcher); ok {
+				limit += hm.headerLen(buf)
+			}
+			if len(buf) >= limit {
 				content, rawDataLen = buf[:contentLenLimit], contentLenLimit
 			} else {
 				// matcher didn't find a frame, so leave the remainder in
@@ -47,6 +47,28 @@ func chunk(input []byte, size int) [][]byte {
 	return rv
 }
 
+func TestSyslogOctetCountingOversizedFrame(t *testing.T) {
+	// the first frame is longer than the limit, its remaining bytes must not be taken for frames
+	oversized := "<13>" + strings.Repeat("a", 21)
+	input := []byte("25 " + oversized + "5 <13>b")
+	for size := 1; size <= len(input); size++ {
+		t.Run(fmt.Sprintf("%d-byte chunks", size), func(t *testing.T) {
+			var lines []string
+			var lens []int
+			outputFn := func(msg *message.Message, rawDataLen int) {
+				lines = append(lines, string(msg.GetContent()))
+				lens = append(lens, rawDataLen)
+			}
+			framer := NewFramer(outputFn, SyslogOctetCounting, 10)
+			for i := 0; i < len(input); i += size {
+				framer.Process(message.NewMessage(input[i:min(i+size, len(input))], nil, "", 0))
+			}
+			assert.Equal(t, []string{oversized[:10], "<13>b"}, lines)
+			assert.Equal(t, []int{13, 7}, lens)
+		})
+	}
+}
+
 func TestLineBreaking(t *testing.T) {
 	test := func(framing Framing, chunks [][]byte, lines []string, rawLens []int) func(*testing.T) {
 		return func(t *testing.T) {
@@ -138,6 +160,19 @@ func TestLineBreaking(t *testing.T) {
 		}
 	})
 
+	t.Run("SyslogOctetCounting", func(t *testing.T) {
+		input := []byte("11 <34>1 - - -15 <13>hello\nworld<13>newline\n")
+		lines := []string{"<34>1 - - -", "<13>hello\nworld", "<13>newline"}
+		lens := []int{14, 18, 12}
+		framing := SyslogOctetCounting
+		t.Run("one chunk", test(framing, [][]byte{input}, lines, lens))
+		oneByteChunks := [][]byte{}
+		for i := range input {
+			oneByteChunks = append(oneByteChunks, input[i:i+1])
+		}
+		t.Run("one-byte chunks", test(framing, oneByteChunks, lines, lens))
+	})
+
 	t.Run("DockerStream(headers)", func(t *testing.T) {
 		input := []byte{}
 		lines := []string{}
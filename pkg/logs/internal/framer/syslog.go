@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import "strconv"
+
+// maxOctetCountDigits is the maximum number of digits of the length of an octet-counted frame.
+const maxOctetCountDigits = 10
+
+// syslogMatcher matches the syslog frames sent over TCP as described in RFC6587: octet-counted
+// frames ('MSG-LEN SP SYSLOG-MSG') and, for the senders using the non-transparent framing, frames
+// terminated by a newline.
+//
+// The octet-counted frames longer than contentLenLimit are truncated, and the rest of their
+// bytes is discarded as it is received so that the next frame is found after them.
+type syslogMatcher struct {
+	newline oneByteNewLineMatcher
+	// discard is the number of bytes left to discard from a truncated frame.
+	discard int
+}
+
+// octetCountingHeader returns the length of the 'MSG-LEN SP' header at the start of buf and the
+// length of the frame. ok is false when buf doesn't start with a header, wait is true when
+// more bytes are needed to know.
+func octetCountingHeader(buf []byte) (start, length int, ok, wait bool) {
+	digits := 0
+	for digits < len(buf) && digits <= maxOctetCountDigits && buf[digits] >= '0' && buf[digits] <= '9' {
+		digits++
+	}
+	if digits == 0 || digits > maxOctetCountDigits {
+		return 0, 0, false, false
+	}
+	if digits == len(buf) {
+		// wait for the end of the length
+		return 0, 0, false, true
+	}
+	if buf[digits] != ' ' {
+		return 0, 0, false, false
+	}
+	length, err := strconv.Atoi(string(buf[:digits]))
+	if err != nil {
+		return 0, 0, false, false
+	}
+	return digits + 1, length, true, false
+}
+
+// FindFrame implements EndLineMatcher#FindFrame.
+func (m *syslogMatcher) FindFrame(buf []byte, seen int) ([]byte, int) {
+	if m.discard > 0 {
+		n := min(m.discard, len(buf))
+		m.discard -= n
+		return nil, n
+	}
+
+	start, length, ok, wait := octetCountingHeader(buf)
+	if wait {
+		return nil, 0
+	}
+	if !ok {
+		return m.newline.FindFrame(buf, seen)
+	}
+	// limit the returned frame to contentLenLimit bytes, the rest is discarded
+	if limit := m.newline.contentLenLimit; length > limit {
+		if len(buf) < start+limit {
+			return nil, 0
+		}
+		m.discard = length - limit
+		return buf[start : start+limit], start + limit
+	}
+	if len(buf) < start+length {
+		return nil, 0
+	}
+	return buf[start : start+length], start + length
+}
+
+// headerLen implements frameHeaderMatcher#headerLen.
+func (m *syslogMatcher) headerLen(buf []byte) int {
+	if m.discard > 0 {
+		return 0
+	}
+	start, _, ok, _ := octetCountingHeader(buf)
+	if !ok {
+		return 0
+	}
+	return start
+}
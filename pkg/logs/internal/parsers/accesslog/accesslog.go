@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package accesslog implements a parser for the access logs of nginx and apache, in the
+// combined and common log formats.
+package accesslog
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// timestampLayout is the layout of the '%t' timestamps of apache and '$time_local' of nginx.
+const timestampLayout = "02/Jan/2006:15:04:05 -0700"
+
+// combinedFormat matches '%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-agent}i"', the referer and
+// the user agent being optional in the common log format.
+var combinedFormat = regexp.MustCompile(`^(\S+) (\S+) (\S+) \[([^\]]+)\] "((?:[^"\\]|\\.)*)" (\d{3}) (\d+|-)(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?`)
+
+var errNotAnAccessLog = errors.New("the log line is not in the combined log format")
+
+// New creates a new parser that parses access logs in the combined log format, e.g.
+// `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"`.
+//
+// The line is rendered as a JSON object holding the original line as message and the request
+// attributes, named after the Datadog standard attributes. The status is derived from the
+// response code and the timestamp is the time of the request.
+func New() parsers.Parser {
+	return &accessLogFormat{}
+}
+
+type accessLogFormat struct{}
+
+type accessLog struct {
+	Message    string  `json:"message"`
+	DateAccess string  `json:"date_access"`
+	Network    network `json:"network"`
+	HTTP       http    `json:"http"`
+}
+
+type network struct {
+	Client       client `json:"client"`
+	BytesWritten int64  `json:"bytes_written"`
+}
+
+type client struct {
+	IP string `json:"ip"`
+}
+
+type http struct {
+	Ident      string `json:"ident,omitempty"`
+	Auth       string `json:"auth,omitempty"`
+	Method     string `json:"method,omitempty"`
+	URL        string `json:"url,omitempty"`
+	Version    string `json:"version,omitempty"`
+	StatusCode int    `json:"status_code"`
+	Referer    string `json:"referer,omitempty"`
+	UserAgent  string `json:"useragent,omitempty"`
+}
+
+// Parse implements Parser#Parse
+func (p *accessLogFormat) Parse(msg *message.Message) (*message.Message, error) {
+	line := string(msg.GetContent())
+	match := combinedFormat.FindStringSubmatch(line)
+	if match == nil {
+		return msg, errNotAnAccessLog
+	}
+
+	statusCode, _ := strconv.Atoi(match[6])
+	bytesWritten, _ := strconv.ParseInt(match[7], 10, 64)
+	entry := accessLog{
+		Message:    line,
+		DateAccess: match[4],
+		Network: network{
+			Client:       client{IP: match[1]},
+			BytesWritten: bytesWritten,
+		},
+		HTTP: http{
+			Ident:      nonEmpty(match[2]),
+			Auth:       nonEmpty(match[3]),
+			StatusCode: statusCode,
+			Referer:    nonEmpty(match[8]),
+			UserAgent:  nonEmpty(match[9]),
+		},
+	}
+	// the request line is 'METHOD URL VERSION'
+	if request := strings.Fields(match[5]); len(request) == 3 {
+		entry.HTTP.Method, entry.HTTP.URL, entry.HTTP.Version = request[0], request[1], strings.TrimPrefix(request[2], "HTTP/")
+	}
+
+	rendered, err := json.Marshal(entry)
+	if err != nil {
+		return msg, err
+	}
+	msg.SetContent(rendered)
+	msg.Status = parsers.StatusFromHTTPCode(statusCode)
+	if ts, err := time.Parse(timestampLayout, match[4]); err == nil {
+		msg.ServerlessExtra.Timestamp = ts.UTC()
+	}
+	return msg, nil
+}
+
+// SupportsPartialLine implements Parser#SupportsPartialLine
+func (p *accessLogFormat) SupportsPartialLine() bool {
+	return false
+}
+
+// nonEmpty returns an empty string for the '-' placeholder of the missing values.
+func nonEmpty(value string) string {
+	if value == "-" {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package accesslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestParseCombinedLogFormat(t *testing.T) {
+	line := `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 404 2326 "http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"`
+	msg, err := New().Parse(message.NewMessage([]byte(line), nil, message.StatusInfo, 0))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"message": "127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] \"GET /apache_pb.gif HTTP/1.0\" 404 2326 \"http://www.example.com/start.html\" \"Mozilla/4.08 [en] (Win98; I ;Nav)\"",
+		"date_access": "10/Oct/2000:13:55:36 -0700",
+		"network": {"client": {"ip": "127.0.0.1"}, "bytes_written": 2326},
+		"http": {
+			"auth": "frank",
+			"method": "GET",
+			"url": "/apache_pb.gif",
+			"version": "1.0",
+			"status_code": 404,
+			"referer": "http://www.example.com/start.html",
+			"useragent": "Mozilla/4.08 [en] (Win98; I ;Nav)"
+		}
+	}`, string(msg.GetContent()))
+	assert.Equal(t, message.StatusWarning, msg.Status)
+	assert.Equal(t, time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC), msg.ServerlessExtra.Timestamp)
+}
+
+func TestParseCommonLogFormat(t *testing.T) {
+	line := `10.0.0.2 - - [02/May/2024:10:11:12 +0000] "POST /api/v1/orders HTTP/1.1" 502 -`
+	msg, err := New().Parse(message.NewMessage([]byte(line), nil, message.StatusInfo, 0))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"message": "10.0.0.2 - - [02/May/2024:10:11:12 +0000] \"POST /api/v1/orders HTTP/1.1\" 502 -",
+		"date_access": "02/May/2024:10:11:12 +0000",
+		"network": {"client": {"ip": "10.0.0.2"}, "bytes_written": 0},
+		"http": {"method": "POST", "url": "/api/v1/orders", "version": "1.1", "status_code": 502}
+	}`, string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.Status)
+}
+
+func TestParseInvalidAccessLog(t *testing.T) {
+	line := `2024/05/02 10:11:12 [error] 42#42: *1 open() "/usr/share/nginx/html/favicon.ico" failed`
+	msg, err := New().Parse(message.NewMessage([]byte(line), nil, message.StatusInfo, 0))
+	assert.Error(t, err)
+	assert.Equal(t, line, string(msg.GetContent()))
+	assert.Equal(t, message.StatusInfo, msg.Status)
+	assert.True(t, msg.ServerlessExtra.Timestamp.IsZero())
+}
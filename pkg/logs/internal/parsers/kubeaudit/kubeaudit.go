@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package kubeaudit implements a parser for the audit events logged by the kube-apiserver.
+package kubeaudit
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+const panicStage = "Panic"
+
+var errNotAnAuditEvent = errors.New("the log line is not a Kubernetes audit event")
+
+// New creates a new parser that parses the audit events written by the kube-apiserver log backend,
+// one JSON object per line.
+//
+// The content is left untouched, the status is derived from the response code, the timestamp is the
+// timestamp of the stage and the verb, stage, resource and namespace are added as tags.
+func New() parsers.Parser {
+	return &kubeAuditFormat{}
+}
+
+type kubeAuditFormat struct{}
+
+// auditEvent holds the fields of an audit.k8s.io/v1 event used by the parser.
+type auditEvent struct {
+	Kind           string    `json:"kind"`
+	Stage          string    `json:"stage"`
+	Verb           string    `json:"verb"`
+	StageTimestamp time.Time `json:"stageTimestamp"`
+	ObjectRef      *struct {
+		Resource  string `json:"resource"`
+		Namespace string `json:"namespace"`
+	} `json:"objectRef"`
+	ResponseStatus *struct {
+		Code int `json:"code"`
+	} `json:"responseStatus"`
+}
+
+// Parse implements Parser#Parse
+func (p *kubeAuditFormat) Parse(msg *message.Message) (*message.Message, error) {
+	var event auditEvent
+	if err := json.Unmarshal(msg.GetContent(), &event); err != nil {
+		return msg, err
+	}
+	if event.Kind != "Event" {
+		return msg, errNotAnAuditEvent
+	}
+
+	switch {
+	case event.Stage == panicStage:
+		msg.Status = message.StatusError
+	case event.ResponseStatus != nil:
+		msg.Status = parsers.StatusFromHTTPCode(event.ResponseStatus.Code)
+	default:
+		msg.Status = message.StatusInfo
+	}
+	if !event.StageTimestamp.IsZero() {
+		msg.ServerlessExtra.Timestamp = event.StageTimestamp.UTC()
+	}
+
+	if event.Verb != "" {
+		msg.ParsingExtra.Tags = append(msg.ParsingExtra.Tags, "audit_verb:"+event.Verb)
+	}
+	if event.Stage != "" {
+		msg.ParsingExtra.Tags = append(msg.ParsingExtra.Tags, "audit_stage:"+event.Stage)
+	}
+	if event.ObjectRef != nil {
+		if event.ObjectRef.Resource != "" {
+			msg.ParsingExtra.Tags = append(msg.ParsingExtra.Tags, "audit_resource:"+event.ObjectRef.Resource)
+		}
+		if event.ObjectRef.Namespace != "" {
+			msg.ParsingExtra.Tags = append(msg.ParsingExtra.Tags, "kube_namespace:"+event.ObjectRef.Namespace)
+		}
+	}
+	return msg, nil
+}
+
+// SupportsPartialLine implements Parser#SupportsPartialLine
+func (p *kubeAuditFormat) SupportsPartialLine() bool {
+	return false
+}
@@ -0,0 +1,57 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kubeaudit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+const event = `{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"4d2c2b2e","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/default/pods","verb":"create","user":{"username":"admin"},"objectRef":{"resource":"pods","namespace":"default","apiVersion":"v1"},"responseStatus":{"metadata":{},"code":403},"requestReceivedTimestamp":"2024-05-02T10:11:12.123456Z","stageTimestamp":"2024-05-02T10:11:12.234567Z"}`
+
+func TestParseAuditEvent(t *testing.T) {
+	msg, err := New().Parse(message.NewMessage([]byte(event), nil, message.StatusInfo, 0))
+	require.NoError(t, err)
+
+	assert.Equal(t, event, string(msg.GetContent()))
+	assert.Equal(t, message.StatusWarning, msg.Status)
+	assert.Equal(t, time.Date(2024, 5, 2, 10, 11, 12, 234567000, time.UTC), msg.ServerlessExtra.Timestamp)
+	assert.Equal(t, []string{"audit_verb:create", "audit_stage:ResponseComplete", "audit_resource:pods", "kube_namespace:default"}, msg.ParsingExtra.Tags)
+}
+
+func TestParseAuditEventStatus(t *testing.T) {
+	for _, test := range []struct {
+		event  string
+		status string
+	}{
+		{`{"kind":"Event","stage":"ResponseComplete","responseStatus":{"code":200}}`, message.StatusInfo},
+		{`{"kind":"Event","stage":"ResponseComplete","responseStatus":{"code":503}}`, message.StatusError},
+		{`{"kind":"Event","stage":"Panic"}`, message.StatusError},
+		{`{"kind":"Event","stage":"RequestReceived"}`, message.StatusInfo},
+	} {
+		msg, err := New().Parse(message.NewMessage([]byte(test.event), nil, "", 0))
+		require.NoError(t, err)
+		assert.Equal(t, test.status, msg.Status, test.event)
+	}
+}
+
+func TestParseInvalidAuditEvent(t *testing.T) {
+	for _, content := range []string{
+		`I0502 10:11:12.123456       1 main.go:42] not an audit event`,
+		`{"kind":"Pod"}`,
+	} {
+		msg, err := New().Parse(message.NewMessage([]byte(content), nil, message.StatusInfo, 0))
+		assert.Error(t, err)
+		assert.Equal(t, content, string(msg.GetContent()))
+		assert.Equal(t, message.StatusInfo, msg.Status)
+		assert.Empty(t, msg.ParsingExtra.Tags)
+	}
+}
@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package logfmt implements a parser for logfmt lines.
+package logfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/util/logfmt"
+)
+
+// levelKeys and timestampKeys are the usual keys of the level and the timestamp of the lines.
+var (
+	levelKeys     = []string{"level", "lvl", "severity"}
+	timestampKeys = []string{"time", "ts", "timestamp"}
+)
+
+// New creates a new parser that parses logfmt lines, e.g. `level=info msg="hello world" took=3ms`.
+//
+// The line is rendered as a JSON object keeping the order of the keys, the status is taken from
+// the level and the timestamp from the time of the line, in RFC3339 or as a Unix epoch. Lines
+// which don't start with a key=value pair are left unchanged.
+func New() parsers.Parser {
+	return &logfmtFormat{}
+}
+
+type logfmtFormat struct{}
+
+// Parse implements Parser#Parse
+func (p *logfmtFormat) Parse(msg *message.Message) (*message.Message, error) {
+	pairs, err := logfmt.Parse(msg.GetContent())
+	if err != nil {
+		return msg, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, pair := range pairs {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(pair.Key)
+		value, _ := json.Marshal(pair.Value)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+
+		if contains(levelKeys, pair.Key) {
+			if status := parsers.StatusFromLevel(pair.Value); status != "" {
+				msg.Status = status
+			}
+		}
+		if contains(timestampKeys, pair.Key) {
+			if ts, ok := parseTimestamp(pair.Value); ok {
+				msg.ServerlessExtra.Timestamp = ts
+			}
+		}
+	}
+	buf.WriteByte('}')
+	msg.SetContent(buf.Bytes())
+	return msg, nil
+}
+
+// SupportsPartialLine implements Parser#SupportsPartialLine
+func (p *logfmtFormat) SupportsPartialLine() bool {
+	return false
+}
+
+// parseTimestamp parses a RFC3339 timestamp, or an epoch in seconds or milliseconds.
+func parseTimestamp(value string) (time.Time, bool) {
+	if ts, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return ts.UTC(), true
+	}
+	epoch, err := strconv.ParseFloat(value, 64)
+	if err != nil || epoch <= 0 {
+		return time.Time{}, false
+	}
+	if epoch >= 1e11 {
+		return time.UnixMilli(int64(epoch)).UTC(), true
+	}
+	return time.Unix(0, int64(epoch*1e9)).UTC(), true
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package logfmt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestParseLogfmt(t *testing.T) {
+	line := `ts=2024-05-02T10:11:12.5Z level=warn msg="disk \"/data\" almost full" used=93% dry_run`
+	msg, err := New().Parse(message.NewMessage([]byte(line), nil, message.StatusInfo, 0))
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"ts":"2024-05-02T10:11:12.5Z","level":"warn","msg":"disk \"/data\" almost full","used":"93%","dry_run":""}`, string(msg.GetContent()))
+	assert.Equal(t, message.StatusWarning, msg.Status)
+	assert.Equal(t, time.Date(2024, 5, 2, 10, 11, 12, 500000000, time.UTC), msg.ServerlessExtra.Timestamp)
+}
+
+func TestParseLogfmtEpochTimestamp(t *testing.T) {
+	for _, ts := range []string{"1714644672", "1714644672000"} {
+		msg, err := New().Parse(message.NewMessage([]byte("time="+ts+" lvl=EROR"), nil, message.StatusInfo, 0))
+		require.NoError(t, err)
+		assert.Equal(t, message.StatusError, msg.Status)
+		assert.Equal(t, time.Unix(1714644672, 0).UTC(), msg.ServerlessExtra.Timestamp)
+	}
+}
+
+func TestParseLogfmtUnknownLevel(t *testing.T) {
+	msg, err := New().Parse(message.NewMessage([]byte("level=verbose msg=hello"), nil, message.StatusInfo, 0))
+	require.NoError(t, err)
+	assert.Equal(t, message.StatusInfo, msg.Status)
+}
+
+func TestParseInvalidLogfmt(t *testing.T) {
+	for _, line := range []string{``, `=value`, `msg="unterminated`, `Starting the server`, `listening on port=8080`} {
+		msg, err := New().Parse(message.NewMessage([]byte(line), nil, message.StatusInfo, 0))
+		assert.Error(t, err, line)
+		assert.Equal(t, line, string(msg.GetContent()))
+	}
+}
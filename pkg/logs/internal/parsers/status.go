@@ -0,0 +1,49 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package parsers
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// StatusFromHTTPCode returns the status of a message reporting an HTTP response with the given code.
+func StatusFromHTTPCode(code int) string {
+	switch {
+	case code >= 500:
+		return message.StatusError
+	case code >= 400:
+		return message.StatusWarning
+	default:
+		return message.StatusInfo
+	}
+}
+
+// StatusFromLevel returns the status matching the usual names of the log levels, or an empty
+// string when the level is unknown.
+func StatusFromLevel(level string) string {
+	switch strings.ToLower(level) {
+	case "emerg", "emergency", "panic":
+		return message.StatusEmergency
+	case "alert":
+		return message.StatusAlert
+	case "crit", "critical", "fatal":
+		return message.StatusCritical
+	case "err", "eror", "error":
+		return message.StatusError
+	case "warn", "warning":
+		return message.StatusWarning
+	case "notice":
+		return message.StatusNotice
+	case "info", "information", "informational":
+		return message.StatusInfo
+	case "debug", "dbug", "trace":
+		return message.StatusDebug
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,270 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package syslog implements a parser for syslog messages in the RFC5424 and RFC3164 formats.
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// Tags added to the parsed messages.
+const (
+	FacilityTag = "syslog_facility"
+	AppNameTag  = "syslog_appname"
+	ProcIDTag   = "syslog_procid"
+	MsgIDTag    = "syslog_msgid"
+)
+
+// nilValue is the value of the empty fields in RFC5424.
+const nilValue = "-"
+
+var (
+	errMissingPriority = errors.New("the syslog message doesn't start with a priority")
+	errInvalidPriority = errors.New("invalid syslog priority")
+
+	utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+)
+
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var severities = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// New creates a new parser that parses syslog messages.
+//
+// The RFC5424 messages follow the pattern
+// '<PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG', and the RFC3164
+// messages the pattern '<PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG'.
+//
+// The severity is used as the message status, the hostname as the message hostname, and the facility,
+// app-name, procid, msgid and structured data are added as tags. The messages which can't be parsed are
+// left untouched.
+func New() parsers.Parser {
+	return &syslogFormat{}
+}
+
+type syslogFormat struct{}
+
+// Parse implements Parser#Parse
+func (p *syslogFormat) Parse(msg *message.Message) (*message.Message, error) {
+	content := bytes.TrimRight(msg.GetContent(), "\r\n\x00")
+	msg.SetContent(content)
+
+	priority, rest, err := parsePriority(content)
+	if err != nil {
+		return msg, err
+	}
+
+	var parsed syslogMessage
+	if len(rest) > 1 && rest[0] == '1' && rest[1] == ' ' {
+		parsed = parseRFC5424(rest[2:])
+	} else {
+		parsed = parseRFC3164(rest)
+	}
+
+	msg.SetContent(parsed.content)
+	msg.Status = severities[priority%8]
+	if parsed.hostname != "" {
+		msg.Hostname = parsed.hostname
+	}
+	if parsed.timestamp != "" {
+		msg.ParsingExtra.Timestamp = parsed.timestamp
+	}
+	msg.ParsingExtra.Tags = append(msg.ParsingExtra.Tags, FacilityTag+":"+facilities[priority/8])
+	msg.ParsingExtra.Tags = append(msg.ParsingExtra.Tags, parsed.tags...)
+	return msg, nil
+}
+
+// SupportsPartialLine implements Parser#SupportsPartialLine
+func (p *syslogFormat) SupportsPartialLine() bool {
+	return false
+}
+
+type syslogMessage struct {
+	timestamp string
+	hostname  string
+	tags      []string
+	content   []byte
+}
+
+// parsePriority parses the '<PRI>' header, the priority is the facility multiplied by 8 plus the severity.
+func parsePriority(content []byte) (int, []byte, error) {
+	if len(content) < 3 || content[0] != '<' {
+		return 0, nil, errMissingPriority
+	}
+	end := bytes.IndexByte(content[:min(len(content), 5)], '>')
+	if end < 2 {
+		return 0, nil, errInvalidPriority
+	}
+	priority, err := strconv.Atoi(string(content[1:end]))
+	if err != nil || priority < 0 || priority >= len(facilities)*8 {
+		return 0, nil, errInvalidPriority
+	}
+	return priority, content[end+1:], nil
+}
+
+// parseRFC5424 parses the message following the version of a RFC5424 message.
+func parseRFC5424(content []byte) syslogMessage {
+	var parsed syslogMessage
+	var fields [4]string
+	for i := range fields {
+		fields[i], content = nextField(content)
+	}
+	parsed.timestamp = nonNil(fields[0])
+	parsed.hostname = nonNil(fields[1])
+	if appName := nonNil(fields[2]); appName != "" {
+		parsed.tags = append(parsed.tags, AppNameTag+":"+appName)
+	}
+	if procID := nonNil(fields[3]); procID != "" {
+		parsed.tags = append(parsed.tags, ProcIDTag+":"+procID)
+	}
+	var msgID string
+	msgID, content = nextField(content)
+	if msgID = nonNil(msgID); msgID != "" {
+		parsed.tags = append(parsed.tags, MsgIDTag+":"+msgID)
+	}
+
+	if bytes.HasPrefix(content, []byte(nilValue)) {
+		content = content[1:]
+	} else {
+		var tags []string
+		tags, content = parseStructuredData(content)
+		parsed.tags = append(parsed.tags, tags...)
+	}
+	content = bytes.TrimPrefix(content, []byte(" "))
+	parsed.content = bytes.TrimPrefix(content, utf8BOM)
+	return parsed
+}
+
+// parseStructuredData parses the structured data elements, e.g. '[exampleSDID@32473 iut="3" eventSource="App"]',
+// into tags such as 'exampleSDID@32473.iut:3'.
+func parseStructuredData(content []byte) ([]string, []byte) {
+	var tags []string
+	for len(content) > 0 && content[0] == '[' {
+		i := 1
+		for i < len(content) && content[i] != ' ' && content[i] != ']' {
+			i++
+		}
+		id := string(content[1:i])
+		for i < len(content) && content[i] != ']' {
+			// skip the spaces between the parameters
+			for i < len(content) && content[i] == ' ' {
+				i++
+			}
+			nameStart := i
+			for i < len(content) && content[i] != '=' && content[i] != ']' {
+				i++
+			}
+			if i+1 >= len(content) || content[i] != '=' || content[i+1] != '"' {
+				break
+			}
+			name := string(content[nameStart:i])
+			i += 2
+
+			var value strings.Builder
+			for i < len(content) && content[i] != '"' {
+				// '"', '\' and ']' are escaped in the parameter values
+				if content[i] == '\\' && i+1 < len(content) && (content[i+1] == '"' || content[i+1] == '\\' || content[i+1] == ']') {
+					i++
+				}
+				value.WriteByte(content[i])
+				i++
+			}
+			if i < len(content) {
+				// skip the closing quote
+				i++
+			}
+			tags = append(tags, id+"."+name+":"+value.String())
+		}
+		if i < len(content) {
+			// skip the closing bracket
+			i++
+		}
+		content = content[i:]
+	}
+	return tags, content
+}
+
+// parseRFC3164 parses the message following the priority of a RFC3164 message. As the format
+// is loosely followed, the timestamp and the hostname are optional.
+func parseRFC3164(content []byte) syslogMessage {
+	var parsed syslogMessage
+
+	// Mmm dd hh:mm:ss
+	const timestampLen = len("Jan _2 15:04:05")
+	if len(content) > timestampLen && content[timestampLen] == ' ' && content[3] == ' ' && content[9] == ':' && content[12] == ':' {
+		parsed.timestamp = string(content[:timestampLen])
+		content = content[timestampLen+1:]
+
+		// the hostname is followed by the tag, which ends with ':' or '['
+		if space := bytes.IndexByte(content, ' '); space > 0 && !bytes.ContainsAny(content[:space], ":[") {
+			parsed.hostname = string(content[:space])
+			content = content[space+1:]
+		}
+	}
+
+	// TAG[PID]: MSG
+	end := 0
+	for end < len(content) && end <= 32 && isTagChar(content[end]) {
+		end++
+	}
+	if end > 0 && end < len(content) && (content[end] == ':' || content[end] == '[') {
+		parsed.tags = append(parsed.tags, AppNameTag+":"+string(content[:end]))
+		rest := content[end:]
+		if rest[0] == '[' {
+			if closing := bytes.IndexByte(rest, ']'); closing > 0 {
+				parsed.tags = append(parsed.tags, ProcIDTag+":"+string(rest[1:closing]))
+				rest = rest[closing+1:]
+			}
+		}
+		if len(rest) > 0 && rest[0] == ':' {
+			content = bytes.TrimPrefix(rest[1:], []byte(" "))
+		} else {
+			// not a tag after all
+			parsed.tags = nil
+		}
+	}
+	parsed.content = content
+	return parsed
+}
+
+func isTagChar(c byte) bool {
+	return c > ' ' && c <= '~' && c != ':' && c != '[' && c != ']'
+}
+
+// nextField returns the next field delimited by a space, and the remaining content.
+func nextField(content []byte) (string, []byte) {
+	space := bytes.IndexByte(content, ' ')
+	if space == -1 {
+		return string(content), nil
+	}
+	return string(content[:space]), content[space+1:]
+}
+
+func nonNil(field string) string {
+	if field == nilValue {
+		return ""
+	}
+	return field
+}
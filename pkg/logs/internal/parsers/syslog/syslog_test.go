@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func parse(t *testing.T, content string) *message.Message {
+	msg, err := New().Parse(message.NewMessage([]byte(content), nil, "", 0))
+	require.NoError(t, err)
+	return msg
+}
+
+func TestParseRFC5424(t *testing.T) {
+	msg := parse(t, `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="App\"lication\]"][origin ip="192.0.2.1"] `+"\xEF\xBB\xBF"+`An application event log entry...`+"\n")
+
+	assert.Equal(t, "An application event log entry...", string(msg.GetContent()))
+	assert.Equal(t, message.StatusNotice, msg.Status)
+	assert.Equal(t, "mymachine.example.com", msg.Hostname)
+	assert.Equal(t, "2003-10-11T22:14:15.003Z", msg.ParsingExtra.Timestamp)
+	assert.Equal(t, []string{
+		"syslog_facility:local4",
+		"syslog_appname:evntslog",
+		"syslog_procid:1234",
+		"syslog_msgid:ID47",
+		"exampleSDID@32473.iut:3",
+		`exampleSDID@32473.eventSource:App"lication]`,
+		"origin.ip:192.0.2.1",
+	}, msg.ParsingExtra.Tags)
+}
+
+func TestParseRFC5424WithNilValues(t *testing.T) {
+	msg := parse(t, `<34>1 - - - - - - 'su root' failed for lonvick on /dev/pts/8`)
+
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", string(msg.GetContent()))
+	assert.Equal(t, message.StatusCritical, msg.Status)
+	assert.Empty(t, msg.Hostname)
+	assert.Empty(t, msg.ParsingExtra.Timestamp)
+	assert.Equal(t, []string{"syslog_facility:auth"}, msg.ParsingExtra.Tags)
+}
+
+func TestParseRFC3164(t *testing.T) {
+	msg := parse(t, "<86>Feb  5 17:32:18 10.0.0.99 sshd[4242]: Accepted publickey for root\r\n")
+
+	assert.Equal(t, "Accepted publickey for root", string(msg.GetContent()))
+	assert.Equal(t, message.StatusInfo, msg.Status)
+	assert.Equal(t, "10.0.0.99", msg.Hostname)
+	assert.Equal(t, "Feb  5 17:32:18", msg.ParsingExtra.Timestamp)
+	assert.Equal(t, []string{"syslog_facility:authpriv", "syslog_appname:sshd", "syslog_procid:4242"}, msg.ParsingExtra.Tags)
+}
+
+func TestParseRFC3164WithoutHeader(t *testing.T) {
+	msg := parse(t, "<11>kernel: Out of memory")
+	assert.Equal(t, "Out of memory", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.Status)
+	assert.Empty(t, msg.Hostname)
+	assert.Equal(t, []string{"syslog_facility:user", "syslog_appname:kernel"}, msg.ParsingExtra.Tags)
+
+	msg = parse(t, "<191>just a message: with a colon")
+	assert.Equal(t, "just a message: with a colon", string(msg.GetContent()))
+	assert.Equal(t, message.StatusDebug, msg.Status)
+	assert.Equal(t, []string{"syslog_facility:local7"}, msg.ParsingExtra.Tags)
+}
+
+func TestParseInvalidPriority(t *testing.T) {
+	for _, content := range []string{"no priority", "<>1 - - - - - - msg", "<192>too high", "<abc>msg", "<12"} {
+		msg, err := New().Parse(message.NewMessage([]byte(content), nil, message.StatusInfo, 0))
+		assert.Error(t, err, content)
+		assert.Equal(t, content, string(msg.GetContent()))
+		assert.Equal(t, message.StatusInfo, msg.Status)
+	}
+}
@@ -43,7 +43,7 @@ func TestStartStop(t *testing.T) {
 
 	sp := launchers.NewMockSourceProvider()
 	pl := pipeline.NewMockProvider()
-	reg := auditor.New("/run", "agent", 0, nil)
+	reg := auditor.New("/run", "agent", 0, 0, nil)
 	tailerTracker := tailers.NewTailerTracker()
 	l.Start(sp, pl, reg, tailerTracker)
 
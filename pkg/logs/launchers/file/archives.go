@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+	tailer "github.com/DataDog/datadog-agent/pkg/logs/tailers/file"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// rotatedFile is a sibling of a tailed file left by a rotation.
+type rotatedFile struct {
+	path       string
+	identifier string
+	modTime    time.Time
+}
+
+// startArchiveTailers reads the rotated siblings of a file which are missing from the registry,
+// when the tail_rotated_files option of its source is set. It returns true when the file was
+// rotated since its offset was last committed, in which case the offset registered for its path
+// belongs to a rotated file and the file itself must be read from the beginning.
+//
+// Only the files rotated since the last committed offset of the file or its siblings, and the
+// rotated files with a registered offset, are read: the history of the files seen for the first
+// time is not collected. When the files are identified by their path, the oldest rotated file is
+// the file which was tailed when its offset was committed, it is read from that offset. Compressed
+// files can't be seeked, so the rotated files are read from their start and the lines before their
+// offset are dropped, until their registry entry expires.
+func (s *Launcher) startArchiveTailers(file *tailer.File, liveTailer *tailer.Tailer) bool {
+	identifier, pathIdentifier := liveTailer.Identifier(), liveTailer.PathIdentifier()
+	rotated := s.rotatedFiles(file, liveTailer.FingerprintSize())
+
+	pathUpdated := s.registry.GetLastUpdated(pathIdentifier)
+	lastUpdated := latest(pathUpdated, s.registry.GetLastUpdated(identifier))
+	for _, rf := range rotated {
+		lastUpdated = latest(lastUpdated, s.registry.GetLastUpdated(rf.identifier))
+	}
+	if lastUpdated.IsZero() {
+		return false
+	}
+
+	pathOffsetUsed := false
+	didRotate := false
+	for _, rf := range rotated {
+		value := s.registry.GetOffset(rf.identifier)
+		if value == "" && !rf.modTime.After(lastUpdated) {
+			continue
+		}
+		if rf.modTime.After(lastUpdated) {
+			didRotate = true
+		}
+		if value == "" && !pathOffsetUsed && !pathUpdated.IsZero() && rf.modTime.After(pathUpdated) {
+			value = s.registry.GetOffset(pathIdentifier)
+			pathOffsetUsed = true
+		}
+		if _, isTailed := s.archiveTailers[rf.identifier]; isTailed {
+			continue
+		}
+
+		offset, _ := strconv.ParseInt(value, 10, 64)
+		tailerInfo := status.NewInfoRegistry()
+		archiveFile := tailer.NewFile(rf.path, file.Source.UnderlyingSource(), file.IsWildcardPath)
+		archiveTailer := tailer.NewArchiveTailer(archiveFile, rf.identifier, s.pipelineProvider.NextPipelineChan(), decoder.NewDecoderFromSource(archiveFile.Source, tailerInfo))
+		if err := archiveTailer.Start(offset); err != nil {
+			log.Warnf("Could not read the rotated file %s: %v", rf.path, err)
+			continue
+		}
+		s.archiveTailers[rf.identifier] = archiveTailer
+	}
+
+	// a file identified by its fingerprint with a registered offset was not rotated
+	return didRotate && (identifier == pathIdentifier || s.registry.GetOffset(identifier) == "")
+}
+
+// rotatedFiles returns the siblings of the file matching the rotated_files_pattern of its source,
+// ordered by modification time.
+func (s *Launcher) rotatedFiles(file *tailer.File, fingerprintSize int) []rotatedFile {
+	pattern := file.Source.Config().RotatedFilesPattern
+	if pattern == "" {
+		pattern = filepath.Base(file.Path) + ".*"
+	}
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(file.Path), pattern))
+	if err != nil {
+		log.Warnf("Invalid rotated_files_pattern %q for %s: %v", pattern, file.Path, err)
+		return nil
+	}
+
+	var rotated []rotatedFile
+	for _, path := range matches {
+		if path == file.Path {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		identifier, err := tailer.ArchiveIdentifier(path, fingerprintSize)
+		if err != nil {
+			log.Debugf("Skipping the rotated file %s: %v", path, err)
+			continue
+		}
+		rotated = append(rotated, rotatedFile{path: path, identifier: identifier, modTime: info.ModTime()})
+	}
+	sort.SliceStable(rotated, func(i, j int) bool {
+		return rotated[i].modTime.Before(rotated[j].modTime)
+	})
+	return rotated
+}
+
+// cleanUpArchiveTailers removes the archive tailers which have read their whole file.
+func (s *Launcher) cleanUpArchiveTailers() {
+	for identifier, archiveTailer := range s.archiveTailers {
+		if archiveTailer.IsFinished() {
+			delete(s.archiveTailers, identifier)
+		}
+	}
+}
+
+func latest(t1, t2 time.Time) time.Time {
+	if t1.After(t2) {
+		return t1
+	}
+	return t2
+}
@@ -0,0 +1,202 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows
+
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/tagger/taggerimpl"
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	flareController "github.com/DataDog/datadog-agent/comp/logs/agent/flare"
+	pkgConfig "github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+// fakeRegistry holds an entry per identifier.
+type fakeRegistry map[string]auditor.RegistryEntry
+
+func (r fakeRegistry) GetOffset(identifier string) string { return r[identifier].Offset }
+
+func (r fakeRegistry) GetTailingMode(identifier string) string { return r[identifier].TailingMode }
+
+func (r fakeRegistry) GetLastUpdated(identifier string) time.Time { return r[identifier].LastUpdated }
+
+func TestLauncherReadsFilesRotatedWhileStopped(t *testing.T) {
+	testDir := t.TempDir()
+	fakeTagger := taggerimpl.SetupFakeTagger(t)
+	defer fakeTagger.ResetTagger()
+
+	path := filepath.Join(testDir, "app.log")
+	lastUpdated := time.Now().Add(-time.Hour)
+
+	// rotated before the offset was committed, already sent
+	require.NoError(t, os.WriteFile(path+".3", []byte("old line\n"), 0600))
+	require.NoError(t, os.Chtimes(path+".3", lastUpdated.Add(-time.Hour), lastUpdated.Add(-time.Hour)))
+	// tailed when the offset was committed, then rotated and compressed
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte("sent line\nunsent line\n")) //nolint:errcheck
+	gz.Close()
+	require.NoError(t, os.WriteFile(path+".2.gz", compressed.Bytes(), 0600))
+	require.NoError(t, os.Chtimes(path+".2.gz", lastUpdated.Add(time.Minute), lastUpdated.Add(time.Minute)))
+	// created and rotated while the agent was stopped
+	require.NoError(t, os.WriteFile(path+".1", []byte("rotated line\n"), 0600))
+	require.NoError(t, os.Chtimes(path+".1", lastUpdated.Add(2*time.Minute), lastUpdated.Add(2*time.Minute)))
+	require.NoError(t, os.WriteFile(path, []byte("live line\n"), 0600))
+
+	launcher := NewLauncher(10, 20*time.Millisecond, false, 10*time.Second, "by_name", flareController.NewFlareController(), fakeTagger)
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.registry = fakeRegistry{
+		"file:" + path: {Offset: "10", TailingMode: "end", LastUpdated: lastUpdated},
+	}
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	defer launcher.cleanup()
+
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, TailRotatedFiles: true})
+	launcher.addSource(source)
+	assert.Len(t, launcher.archiveTailers, 2)
+
+	var lines []string
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-outputChan:
+			lines = append(lines, string(msg.GetContent()))
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timeout waiting for the lines", "got %v", lines)
+		}
+	}
+	assert.ElementsMatch(t, []string{"unsent line", "rotated line", "live line"}, lines)
+
+	select {
+	case msg := <-outputChan:
+		assert.Failf(t, "unexpected line", "%s", msg.GetContent())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestLauncherIgnoresRotatedFilesOfNewFiles(t *testing.T) {
+	testDir := t.TempDir()
+	fakeTagger := taggerimpl.SetupFakeTagger(t)
+	defer fakeTagger.ResetTagger()
+
+	path := filepath.Join(testDir, "app.log")
+	require.NoError(t, os.WriteFile(path+".1", []byte("rotated line\n"), 0600))
+	require.NoError(t, os.WriteFile(path, nil, 0600))
+
+	launcher := NewLauncher(10, 20*time.Millisecond, false, 10*time.Second, "by_name", flareController.NewFlareController(), fakeTagger)
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.registry = fakeRegistry{}
+	defer launcher.cleanup()
+
+	launcher.addSource(sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, TailRotatedFiles: true}))
+	assert.Empty(t, launcher.archiveTailers)
+	assert.Equal(t, 1, launcher.tailers.Count())
+}
+
+func TestLauncherReadsFilesRotatedWhileStoppedWithFingerprints(t *testing.T) {
+	testDir := t.TempDir()
+	fakeTagger := taggerimpl.SetupFakeTagger(t)
+	defer fakeTagger.ResetTagger()
+	pkgConfig.Datadog().SetWithoutSource("logs_config.fingerprint_size_bytes", 8)
+	defer pkgConfig.Datadog().SetWithoutSource("logs_config.fingerprint_size_bytes", 0)
+
+	path := filepath.Join(testDir, "app.log")
+	lastUpdated := time.Now().Add(-time.Hour)
+
+	// tailed when the offset was committed, then rotated and compressed
+	content := []byte("sent line\nunsent line\n")
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write(content) //nolint:errcheck
+	gz.Close()
+	require.NoError(t, os.WriteFile(path+".1.gz", compressed.Bytes(), 0600))
+	require.NoError(t, os.WriteFile(path, []byte("live line\n"), 0600))
+
+	launcher := NewLauncher(10, 20*time.Millisecond, false, 10*time.Second, "by_name", flareController.NewFlareController(), fakeTagger)
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.registry = fakeRegistry{
+		auditor.Fingerprint(content[:8]): {Offset: "10", TailingMode: "end", LastUpdated: lastUpdated},
+	}
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	defer launcher.cleanup()
+
+	launcher.addSource(sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, TailRotatedFiles: true, TailingMode: "beginning"}))
+	assert.Len(t, launcher.archiveTailers, 1)
+
+	var lines []string
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-outputChan:
+			lines = append(lines, string(msg.GetContent()))
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timeout waiting for the lines", "got %v", lines)
+		}
+	}
+	assert.ElementsMatch(t, []string{"unsent line", "live line"}, lines)
+}
+
+func TestLauncherIgnoresThePathEntryOfFingerprintedFiles(t *testing.T) {
+	testDir := t.TempDir()
+	fakeTagger := taggerimpl.SetupFakeTagger(t)
+	defer fakeTagger.ResetTagger()
+	pkgConfig.Datadog().SetWithoutSource("logs_config.fingerprint_size_bytes", 8)
+	defer pkgConfig.Datadog().SetWithoutSource("logs_config.fingerprint_size_bytes", 0)
+
+	// the offset registered for the path belongs to a file this one replaced
+	path := filepath.Join(testDir, "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("first line\nsecond line\n"), 0600))
+
+	launcher := NewLauncher(10, 20*time.Millisecond, false, 10*time.Second, "by_name", flareController.NewFlareController(), fakeTagger)
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.registry = fakeRegistry{
+		"file:" + path: {Offset: "11", TailingMode: "beginning", LastUpdated: time.Now()},
+	}
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	defer launcher.cleanup()
+
+	launcher.addSource(sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, TailingMode: "beginning"}))
+	msg := <-outputChan
+	assert.Equal(t, "first line", string(msg.GetContent()))
+	assert.True(t, auditor.IsFingerprintIdentifier(msg.Origin.Identifier))
+	msg = <-outputChan
+	assert.Equal(t, "second line", string(msg.GetContent()))
+}
+
+func TestLauncherUsesThePathEntryOfFilesTooShortToBeFingerprinted(t *testing.T) {
+	testDir := t.TempDir()
+	fakeTagger := taggerimpl.SetupFakeTagger(t)
+	defer fakeTagger.ResetTagger()
+	pkgConfig.Datadog().SetWithoutSource("logs_config.fingerprint_size_bytes", 64)
+	defer pkgConfig.Datadog().SetWithoutSource("logs_config.fingerprint_size_bytes", 0)
+
+	path := filepath.Join(testDir, "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("sent\nunsent line\n"), 0600))
+
+	launcher := NewLauncher(10, 20*time.Millisecond, false, 10*time.Second, "by_name", flareController.NewFlareController(), fakeTagger)
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.registry = fakeRegistry{
+		"file:" + path: {Offset: "5", TailingMode: "beginning", LastUpdated: time.Now()},
+	}
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	defer launcher.cleanup()
+
+	launcher.addSource(sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path, TailingMode: "beginning"}))
+	msg := <-outputChan
+	assert.Equal(t, "unsent line", string(msg.GetContent()))
+	assert.Equal(t, "file:"+path, msg.Origin.Identifier)
+}
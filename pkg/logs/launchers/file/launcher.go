@@ -7,6 +7,7 @@
 package file
 
 import (
+	"io"
 	"regexp"
 	"time"
 
@@ -43,6 +44,7 @@ type Launcher struct {
 	fileProvider        *fileprovider.FileProvider
 	tailers             *tailers.TailerContainer[*tailer.Tailer]
 	rotatedTailers      []*tailer.Tailer
+	archiveTailers      map[string]*tailer.ArchiveTailer
 	registry            auditor.Registry
 	tailerSleepDuration time.Duration
 	stop                chan struct{}
@@ -75,6 +77,7 @@ func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePo
 		fileProvider:           fileprovider.NewFileProvider(tailingLimit, wildcardStrategy),
 		tailers:                tailers.NewTailerContainer[*tailer.Tailer](),
 		rotatedTailers:         []*tailer.Tailer{},
+		archiveTailers:         make(map[string]*tailer.ArchiveTailer),
 		tailerSleepDuration:    tailerSleepDuration,
 		stop:                   make(chan struct{}),
 		done:                   make(chan struct{}),
@@ -117,6 +120,7 @@ func (s *Launcher) run() {
 			s.removeSource(source)
 		case <-scanTicker.C:
 			s.cleanUpRotatedTailers()
+			s.cleanUpArchiveTailers()
 			// check if there are new files to tail, tailers to stop and tailer to restart because of file rotation
 			s.scan()
 		case <-s.stop:
@@ -136,6 +140,11 @@ func (s *Launcher) cleanup() {
 	}
 	s.rotatedTailers = []*tailer.Tailer{}
 
+	for identifier, archiveTailer := range s.archiveTailers {
+		stopper.Add(archiveTailer)
+		delete(s.archiveTailers, identifier)
+	}
+
 	for _, tailer := range s.tailers.All() {
 		stopper.Add(tailer)
 		s.tailers.Remove(tailer)
@@ -313,12 +322,23 @@ func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode) bool
 
 	var offset int64
 	var whence int
-	mode := s.handleTailingModeChange(tailer.Identifier(), m)
-	offset, whence, err := Position(s.registry, tailer.Identifier(), mode)
+	// the file is identified by its path only when it is too short to be fingerprinted: the entry
+	// registered for its path otherwise belongs to the file it replaced, it is left to the archive
+	// tailers and the file is tailed according to the tailing mode of its source
+	identifier := tailer.Identifier()
+	mode := s.handleTailingModeChange(identifier, m)
+	offset, whence, err := Position(s.registry, identifier, mode)
 	if err != nil {
 		log.Warnf("Could not recover offset for file with path %v: %v", file.Path, err)
 	}
 
+	if file.Source.Config().TailRotatedFiles && mode != config.ForceBeginning && mode != config.ForceEnd {
+		if s.startArchiveTailers(file, tailer) {
+			log.Infof("%s was rotated since its offset was committed, tailing it from the beginning", file.Path)
+			offset, whence = 0, io.SeekStart
+		}
+	}
+
 	log.Infof("Starting a new tailer for: %s (offset: %d, whence: %d) for tailer key %s", file.Path, offset, whence, file.GetScanKey())
 	err = tailer.Start(offset, whence)
 	if err != nil {
@@ -61,7 +61,7 @@ func (s *MockJournalFactory) NewJournalFromPath(path string) (tailer.Journal, er
 
 func newTestLauncher() *Launcher {
 	launcher := NewLauncherWithFactory(&MockJournalFactory{}, flare.NewFlareController())
-	launcher.Start(launchers.NewMockSourceProvider(), pipeline.NewMockProvider(), auditor.New("", "registry.json", time.Hour, health.RegisterLiveness("fake")), tailers.NewTailerTracker())
+	launcher.Start(launchers.NewMockSourceProvider(), pipeline.NewMockProvider(), auditor.New("", "registry.json", time.Hour, 0, health.RegisterLiveness("fake")), tailers.NewTailerTracker())
 	return launcher
 }
 
@@ -25,6 +25,11 @@ var (
 	// TlmLogsProcessed is the total number of processed logs.
 	TlmLogsProcessed = telemetry.NewCounter("logs", "processed",
 		nil, "Total number of processed logs")
+	// LogsSampledOut is the total number of logs dropped by the sample and rate_limit processing rules.
+	LogsSampledOut = expvar.Int{}
+	// TlmLogsSampledOut is the total number of logs dropped by the sample and rate_limit processing rules.
+	TlmLogsSampledOut = telemetry.NewCounter("logs", "sampled_out",
+		[]string{"rule_type"}, "Total number of logs dropped by sampling and rate limiting processing rules")
 
 	// LogsSent is the total number of sent logs.
 	LogsSent = expvar.Int{}
@@ -79,6 +84,7 @@ func init() {
 	LogsExpvars = expvar.NewMap("logs-agent")
 	LogsExpvars.Set("LogsDecoded", &LogsDecoded)
 	LogsExpvars.Set("LogsProcessed", &LogsProcessed)
+	LogsExpvars.Set("LogsSampledOut", &LogsSampledOut)
 	LogsExpvars.Set("LogsSent", &LogsSent)
 	LogsExpvars.Set("DestinationErrors", &DestinationErrors)
 	LogsExpvars.Set("DestinationLogsDropped", &DestinationLogsDropped)
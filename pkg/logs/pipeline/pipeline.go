@@ -9,6 +9,8 @@ package pipeline
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/DataDog/datadog-agent/comp/core/hostname/hostnameinterface"
@@ -22,6 +24,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 	"github.com/DataDog/datadog-agent/pkg/logs/status/statusinterface"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // Pipeline processes and sends messages to the backend
@@ -43,6 +46,7 @@ func NewPipeline(outputChan chan *message.Payload,
 	diagnosticMessageReceiver diagnostic.MessageReceiver,
 	serverless bool,
 	pipelineID int,
+	numberOfPipelines int,
 	status statusinterface.Status,
 	hostname hostnameinterface.Component,
 	cfg pkgconfigmodel.Reader) *Pipeline {
@@ -74,7 +78,11 @@ func NewPipeline(outputChan chan *message.Payload,
 	}
 
 	strategy := getStrategy(strategyInput, senderInput, flushChan, endpoints, serverless, flushWg, pipelineID)
-	logsSender = sender.NewSender(cfg, senderInput, outputChan, mainDestinations, config.DestinationPayloadChanSize, senderDoneChan, flushWg)
+	var diskBuffer *sender.DiskBuffer
+	if !serverless && cfg != nil {
+		diskBuffer = getDiskBuffer(cfg, endpoints, pipelineID, numberOfPipelines)
+	}
+	logsSender = sender.NewSender(cfg, senderInput, outputChan, mainDestinations, config.DestinationPayloadChanSize, senderDoneChan, flushWg, diskBuffer)
 
 	inputChan := make(chan *message.Message, config.ChanSize)
 
@@ -150,13 +158,34 @@ func getDestinations(endpoints *config.Endpoints, destinationsContext *client.De
 	return client.NewDestinations(reliable, additionals)
 }
 
+// getDiskBuffer returns the disk buffer of the pipeline, or nil when it is disabled. The configured size is
+// shared by the numberOfPipelines pipelines, each one stores its payloads in its own directory.
+func getDiskBuffer(cfg pkgconfigmodel.Reader, endpoints *config.Endpoints, pipelineID int, numberOfPipelines int) *sender.DiskBuffer {
+	maxSizeBytes := cfg.GetInt64("logs_config.disk_buffer_max_size_bytes")
+	if maxSizeBytes <= 0 {
+		return nil
+	}
+
+	path := cfg.GetString("logs_config.disk_buffer_path")
+	if path == "" {
+		path = filepath.Join(cfg.GetString("logs_config.run_path"), "logs_buffer")
+	}
+	path = filepath.Join(path, fmt.Sprintf("%s_%d", diskBufferDirReplacer.Replace(endpoints.Main.Host), pipelineID))
+
+	diskBuffer, err := sender.NewDiskBuffer(path, maxSizeBytes/int64(numberOfPipelines))
+	if err != nil {
+		log.Errorf("Unable to create the logs disk buffer in %s, payloads will not be buffered on disk: %v", path, err)
+		return nil
+	}
+	return diskBuffer
+}
+
+var diskBufferDirReplacer = strings.NewReplacer("/", "_", "\\", "_", ":", "_")
+
 //nolint:revive // TODO(AML) Fix revive linter
 func getStrategy(inputChan chan *message.Message, outputChan chan *message.Payload, flushChan chan struct{}, endpoints *config.Endpoints, serverless bool, flushWg *sync.WaitGroup, _ int) sender.Strategy {
 	if endpoints.UseHTTP || serverless {
-		encoder := sender.IdentityContentType
-		if endpoints.Main.UseCompression {
-			encoder = sender.NewGzipContentEncoding(endpoints.Main.CompressionLevel)
-		}
+		encoder := sender.NewContentEncoding(endpoints.Main)
 		return sender.NewBatchStrategy(inputChan, outputChan, flushChan, serverless, flushWg, sender.ArraySerializer, endpoints.BatchWait, endpoints.BatchMaxSize, endpoints.BatchMaxContentSize, "logs", encoder)
 	}
 	return sender.NewStreamStrategy(inputChan, outputChan, sender.IdentityContentType)
@@ -24,7 +24,7 @@ type ProviderTestSuite struct {
 }
 
 func (suite *ProviderTestSuite) SetupTest() {
-	suite.a = auditor.New("", auditor.DefaultRegistryFilename, time.Hour, health.RegisterLiveness("fake"))
+	suite.a = auditor.New(suite.T().TempDir(), auditor.DefaultRegistryFilename, time.Hour, 0, health.RegisterLiveness("fake"))
 	suite.p = &provider{
 		numberOfPipelines:    3,
 		auditor:              suite.a,
@@ -25,6 +25,7 @@ replace (
 	github.com/DataDog/datadog-agent/pkg/logs/sources => ../sources
 	github.com/DataDog/datadog-agent/pkg/logs/status/statusinterface => ../status/statusinterface
 	github.com/DataDog/datadog-agent/pkg/logs/status/utils => ../status/utils
+	github.com/DataDog/datadog-agent/pkg/logs/util/logfmt => ../util/logfmt
 	github.com/DataDog/datadog-agent/pkg/logs/util/testutils => ../util/testutils
 	github.com/DataDog/datadog-agent/pkg/telemetry => ../../telemetry
 	github.com/DataDog/datadog-agent/pkg/util/executable => ../../util/executable
@@ -53,6 +54,7 @@ require (
 	github.com/DataDog/datadog-agent/pkg/logs/metrics v0.56.0-rc.3
 	github.com/DataDog/datadog-agent/pkg/logs/sds v0.56.0-rc.3
 	github.com/DataDog/datadog-agent/pkg/logs/sources v0.56.0-rc.3
+	github.com/DataDog/datadog-agent/pkg/logs/util/logfmt v0.56.0-rc.3
 	github.com/DataDog/datadog-agent/pkg/util/log v0.56.0-rc.3
 	github.com/stretchr/testify v1.9.0
 )
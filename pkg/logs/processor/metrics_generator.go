@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// GeneratedMetricsSenderID is the ID of the aggregator sender used to report the metrics generated from logs.
+const GeneratedMetricsSenderID = "logs-agent-generated-metrics"
+
+// generatedMetricsCommitInterval is the interval at which the generated metrics are committed to the aggregator.
+const generatedMetricsCommitInterval = 10 * time.Second
+
+// MetricSender is the subset of the aggregator sender.Sender interface used to report the metrics
+// generated by the generate_metric processing rules.
+type MetricSender interface {
+	Count(metric string, value float64, hostname string, tags []string)
+	Distribution(metric string, value float64, hostname string, tags []string)
+	Commit()
+}
+
+// metricsGenerator reports the metrics of the generate_metric rules, it is shared by all the processors.
+type metricsGenerator struct {
+	mu      sync.Mutex
+	sender  MetricSender
+	pending bool
+	once    sync.Once
+}
+
+var generator = &metricsGenerator{}
+
+// RegisterMetricSender registers the sender used to report the metrics generated from logs, the
+// generate_metric rules are no-ops until a sender is registered.
+func RegisterMetricSender(sender MetricSender) {
+	generator.mu.Lock()
+	generator.sender = sender
+	generator.mu.Unlock()
+
+	generator.once.Do(func() {
+		go generator.commitLoop(generatedMetricsCommitInterval)
+	})
+}
+
+// generateMetric reports the metric of the rule when the content matches its pattern. The named capture
+// groups are used as tags, except for the value group of the distributions.
+func (g *metricsGenerator) generateMetric(rule *config.ProcessingRule, msg *message.Message, content []byte) {
+	submatches := rule.Regex.FindSubmatch(content)
+	if submatches == nil {
+		return
+	}
+
+	value := 1.0
+	var tags []string
+	for i, name := range rule.Regex.SubexpNames() {
+		if name == "" || submatches[i] == nil {
+			continue
+		}
+		if name == rule.ValueGroup {
+			var err error
+			if value, err = strconv.ParseFloat(string(submatches[i]), 64); err != nil {
+				log.Debugf("Invalid value %q for the metric %s generated from logs: %v", submatches[i], rule.MetricName, err)
+				return
+			}
+			continue
+		}
+		tags = append(tags, name+":"+string(submatches[i]))
+	}
+	if service := msg.Origin.Service(); service != "" {
+		tags = append(tags, "service:"+service)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.sender == nil {
+		return
+	}
+	if rule.MetricType == config.GeneratedMetricDistribution {
+		g.sender.Distribution(rule.MetricName, value, "", tags)
+	} else {
+		g.sender.Count(rule.MetricName, value, "", tags)
+	}
+	g.pending = true
+}
+
+func (g *metricsGenerator) commitLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		g.commit()
+	}
+}
+
+// commit flushes the metrics reported since the last commit to the aggregator.
+func (g *metricsGenerator) commit() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.sender == nil || !g.pending {
+		return
+	}
+	g.sender.Commit()
+	g.pending = false
+}
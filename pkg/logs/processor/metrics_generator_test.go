@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+type generatedMetric struct {
+	kind  string
+	name  string
+	value float64
+	tags  []string
+}
+
+type fakeMetricSender struct {
+	metrics []generatedMetric
+	commits int
+}
+
+func (s *fakeMetricSender) Count(metric string, value float64, _ string, tags []string) {
+	s.metrics = append(s.metrics, generatedMetric{"count", metric, value, tags})
+}
+
+func (s *fakeMetricSender) Distribution(metric string, value float64, _ string, tags []string) {
+	s.metrics = append(s.metrics, generatedMetric{"distribution", metric, value, tags})
+}
+
+func (s *fakeMetricSender) Commit() {
+	s.commits++
+}
+
+func withFakeMetricSender(t *testing.T) *fakeMetricSender {
+	sender := &fakeMetricSender{}
+	previous := generator
+	generator = &metricsGenerator{sender: sender}
+	t.Cleanup(func() { generator = previous })
+	return sender
+}
+
+func newGenerateMetricRule(name, metricType, pattern, valueGroup string) *config.ProcessingRule {
+	return &config.ProcessingRule{
+		Type:       config.GenerateMetric,
+		Name:       name,
+		MetricName: name,
+		MetricType: metricType,
+		ValueGroup: valueGroup,
+		Pattern:    pattern,
+		Regex:      regexp.MustCompile(pattern),
+	}
+}
+
+func TestGenerateMetricBeforeExclusion(t *testing.T) {
+	sender := withFakeMetricSender(t)
+	p := &Processor{}
+	source := sources.NewLogSource("", &config.LogsConfig{
+		Service: "web",
+		ProcessingRules: []*config.ProcessingRule{
+			newProcessingRule(config.ExcludeAtMatch, "", "status="),
+			newGenerateMetricRule("http.errors", "", `status=(?P<status>5\d\d)`, ""),
+			newGenerateMetricRule("http.latency", config.GeneratedMetricDistribution, `took=(?P<took>\d+)ms path=(?P<path>\S+)`, "took"),
+		},
+	})
+
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("status=503 took=12ms path=/api"), source, "")))
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("status=200 took=3ms path=/health"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("starting"), source, "")))
+
+	assert.Equal(t, []generatedMetric{
+		{"count", "http.errors", 1, []string{"status:503", "service:web"}},
+		{"distribution", "http.latency", 12, []string{"path:/api", "service:web"}},
+		{"distribution", "http.latency", 3, []string{"path:/health", "service:web"}},
+	}, sender.metrics)
+
+	generator.commit()
+	generator.commit()
+	assert.Equal(t, 1, sender.commits)
+}
+
+func TestGenerateMetricWithoutSender(t *testing.T) {
+	previous := generator
+	generator = &metricsGenerator{}
+	defer func() { generator = previous }()
+
+	p := &Processor{}
+	source := sources.NewLogSource("", &config.LogsConfig{
+		ProcessingRules: []*config.ProcessingRule{newGenerateMetricRule("app.errors", "", "error", "")},
+	})
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("error"), source, "")))
+	generator.commit()
+}
@@ -254,6 +254,15 @@ func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 	// ---------------------------
 
 	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
+
+	// generate the metrics before applying the other rules so that they
+	// also account for the messages dropped by exclusion or sampling
+	for _, rule := range rules {
+		if rule.Type == config.GenerateMetric {
+			generator.generateMetric(rule, msg, content)
+		}
+	}
+
 	for _, rule := range rules {
 		switch rule.Type {
 		case config.ExcludeAtMatch:
@@ -268,6 +277,13 @@ func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 			}
 		case config.MaskSequences:
 			content = rule.Regex.ReplaceAll(content, rule.Placeholder)
+		case config.RemapFields:
+			content = remapFields(rule, msg, content)
+		case config.Sample, config.RateLimit:
+			// if this message is sampled out or over the rate limit, we ignore it
+			if !applySampling(rule, msg, content) {
+				return false
+			}
 		}
 	}
 
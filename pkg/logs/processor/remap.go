@@ -0,0 +1,414 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/util/logfmt"
+)
+
+// remapFields parses the content with the format of the rule, promotes the configured
+// fields to the message status, service, tags and timestamp, then drops and renames keys.
+// The content is returned untouched when it can't be parsed.
+func remapFields(rule *config.ProcessingRule, msg *message.Message, content []byte) []byte {
+	if rule.Regex != nil && !rule.Regex.Match(content) {
+		return content
+	}
+
+	var fields *remapObject
+	var err error
+	switch rule.Format {
+	case config.RemapFormatJSON:
+		fields, err = parseJSONObject(content)
+	case config.RemapFormatLogfmt:
+		fields, err = parseLogfmt(content)
+	default:
+		return content
+	}
+	if err != nil {
+		return content
+	}
+
+	if value, ok := fields.lookup(rule.StatusField); ok {
+		if status := normalizeStatus(value); status != "" {
+			msg.Status = status
+		}
+	}
+	if value, ok := fields.lookup(rule.ServiceField); ok {
+		if service := stringify(value); service != "" {
+			msg.Origin.SetService(service)
+		}
+	}
+	if value, ok := fields.lookup(rule.TimestampField); ok {
+		if ts, ok := parseTimestamp(value, rule.TimestampLayout); ok {
+			msg.ServerlessExtra.Timestamp = ts
+		}
+	}
+	for _, field := range rule.TagFields {
+		if value, ok := fields.lookup(field); ok {
+			if tag := stringify(value); tag != "" {
+				msg.ProcessingTags = append(msg.ProcessingTags, field+":"+tag)
+			}
+		}
+	}
+
+	modified := false
+	for _, field := range rule.DropFields {
+		modified = fields.delete(field) || modified
+	}
+	for _, from := range sortedRenames(rule.RenameFields) {
+		modified = fields.rename(from, rule.RenameFields[from]) || modified
+	}
+	if !modified {
+		return content
+	}
+
+	var remapped []byte
+	if rule.Format == config.RemapFormatJSON {
+		remapped, err = fields.marshalJSON()
+	} else {
+		remapped, err = fields.marshalLogfmt()
+	}
+	if err != nil {
+		return content
+	}
+	return remapped
+}
+
+// remapObject is a parsed log line keeping the original order of its keys so
+// that a remapped line stays close to the original one.
+type remapObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newRemapObject() *remapObject {
+	return &remapObject{values: make(map[string]interface{})}
+}
+
+func (o *remapObject) set(key string, value interface{}) {
+	if _, exists := o.values[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// lookup returns the value of the field, nested JSON objects are accessed with a dot
+// separated path (e.g. `http.status_code`).
+func (o *remapObject) lookup(field string) (interface{}, bool) {
+	if field == "" {
+		return nil, false
+	}
+	if value, ok := o.values[field]; ok {
+		return value, true
+	}
+	parent, key, ok := o.parent(field)
+	if !ok {
+		return nil, false
+	}
+	value, ok := parent.values[key]
+	return value, ok
+}
+
+func (o *remapObject) delete(field string) bool {
+	if _, ok := o.values[field]; ok {
+		o.remove(field)
+		return true
+	}
+	parent, key, ok := o.parent(field)
+	if !ok {
+		return false
+	}
+	if _, ok := parent.values[key]; !ok {
+		return false
+	}
+	parent.remove(key)
+	return true
+}
+
+func (o *remapObject) rename(from, to string) bool {
+	value, ok := o.lookup(from)
+	if !ok || from == to {
+		return false
+	}
+	o.delete(from)
+	o.set(to, value)
+	return true
+}
+
+func (o *remapObject) remove(key string) {
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// parent resolves the object holding the last element of a dot separated path.
+func (o *remapObject) parent(field string) (*remapObject, string, bool) {
+	path := strings.Split(field, ".")
+	if len(path) < 2 {
+		return nil, "", false
+	}
+	current := o
+	for _, key := range path[:len(path)-1] {
+		next, ok := current.values[key].(*remapObject)
+		if !ok {
+			return nil, "", false
+		}
+		current = next
+	}
+	return current, path[len(path)-1], true
+}
+
+func (o *remapObject) marshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := o.writeJSON(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (o *remapObject) writeJSON(buf *bytes.Buffer) error {
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		if nested, ok := o.values[key].(*remapObject); ok {
+			if err := nested.writeJSON(buf); err != nil {
+				return err
+			}
+			continue
+		}
+		encodedValue, err := json.Marshal(o.values[key])
+		if err != nil {
+			return err
+		}
+		buf.Write(encodedValue)
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func (o *remapObject) marshalLogfmt() ([]byte, error) {
+	var buf bytes.Buffer
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(key)
+		buf.WriteByte('=')
+		value := stringify(o.values[key])
+		if value == "" || strings.ContainsAny(value, " \"=") {
+			value = strconv.Quote(value)
+		}
+		buf.WriteString(value)
+	}
+	return buf.Bytes(), nil
+}
+
+// parseJSONObject decodes a JSON object, keeping the keys order of the nested objects.
+func parseJSONObject(content []byte) (*remapObject, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("not a JSON object")
+	}
+	object, err := decodeJSONObject(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("trailing data after the JSON object")
+	}
+	return object, nil
+}
+
+func decodeJSONObject(decoder *json.Decoder) (*remapObject, error) {
+	object := newRemapObject()
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid JSON key %v", token)
+		}
+		value, err := decodeJSONValue(decoder)
+		if err != nil {
+			return nil, err
+		}
+		object.set(key, value)
+	}
+	// consume the closing delimiter
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+func decodeJSONValue(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+	switch delim {
+	case '{':
+		return decodeJSONObject(decoder)
+	case '[':
+		var values []interface{}
+		for decoder.More() {
+			value, err := decodeJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if nested, ok := value.(*remapObject); ok {
+				value = nested.toMap()
+			}
+			values = append(values, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("unexpected JSON delimiter %v", delim)
+	}
+}
+
+// toMap converts objects nested in arrays, they are not addressable by a path and
+// don't need to keep their keys order.
+func (o *remapObject) toMap() map[string]interface{} {
+	m := make(map[string]interface{}, len(o.values))
+	for key, value := range o.values {
+		if nested, ok := value.(*remapObject); ok {
+			value = nested.toMap()
+		}
+		m[key] = value
+	}
+	return m
+}
+
+// parseLogfmt parses a logfmt line (e.g. `level=info msg="hello world" took=3ms`).
+func parseLogfmt(content []byte) (*remapObject, error) {
+	pairs, err := logfmt.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	object := newRemapObject()
+	for _, pair := range pairs {
+		object.set(pair.Key, pair.Value)
+	}
+	return object, nil
+}
+
+// stringify returns the string representation of a scalar value, nested objects are
+// not promoted.
+func stringify(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return ""
+	}
+}
+
+// normalizeStatus maps the usual level names to the statuses understood by the intake.
+func normalizeStatus(value interface{}) string {
+	switch strings.ToLower(stringify(value)) {
+	case "emerg", "emergency", "panic":
+		return message.StatusEmergency
+	case "alert":
+		return message.StatusAlert
+	case "crit", "critical", "fatal":
+		return message.StatusCritical
+	case "err", "eror", "error":
+		return message.StatusError
+	case "warn", "warning":
+		return message.StatusWarning
+	case "notice":
+		return message.StatusNotice
+	case "info", "information", "informational":
+		return message.StatusInfo
+	case "debug", "dbug", "trace":
+		return message.StatusDebug
+	default:
+		return ""
+	}
+}
+
+// parseTimestamp parses a timestamp with the given layout, or guesses the format
+// among RFC3339 and epoch timestamps in seconds, milliseconds, microseconds or nanoseconds.
+func parseTimestamp(value interface{}, layout string) (time.Time, bool) {
+	raw := stringify(value)
+	if raw == "" {
+		return time.Time{}, false
+	}
+	if layout != "" {
+		ts, err := time.Parse(layout, raw)
+		return ts.UTC(), err == nil
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+		return ts.UTC(), true
+	}
+	epoch, err := strconv.ParseFloat(raw, 64)
+	if err != nil || epoch <= 0 {
+		return time.Time{}, false
+	}
+	switch {
+	case epoch < 1e11:
+		sec, frac := math.Modf(epoch)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+	case epoch < 1e14:
+		return time.UnixMilli(int64(epoch)).UTC(), true
+	case epoch < 1e17:
+		return time.UnixMicro(int64(epoch)).UTC(), true
+	default:
+		return time.Unix(0, int64(epoch)).UTC(), true
+	}
+}
+
+// sortedRenames returns the rename pairs in a deterministic order.
+func sortedRenames(renames map[string]string) []string {
+	keys := make([]string, 0, len(renames))
+	for key := range renames {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newRemapSource(rule *config.ProcessingRule) *sources.LogSource {
+	rule.Type = config.RemapFields
+	rule.Name = "remap"
+	if rule.Pattern != "" {
+		rule.Regex = regexp.MustCompile(rule.Pattern)
+	}
+	return sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}})
+}
+
+func TestRemapFieldsJSON(t *testing.T) {
+	p := &Processor{}
+	source := newRemapSource(&config.ProcessingRule{
+		Format:         config.RemapFormatJSON,
+		StatusField:    "level",
+		ServiceField:   "app.name",
+		TimestampField: "ts",
+		TagFields:      []string{"env"},
+		DropFields:     []string{"password"},
+		RenameFields:   map[string]string{"msg": "message"},
+	})
+
+	msg := newMessage([]byte(`{"ts":"2024-08-20T10:00:00.5Z","level":"WARNING","app":{"name":"billing","version":2},"env":"prod","password":"hunter2","msg":"hello"}`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+
+	assert.Equal(t, `{"ts":"2024-08-20T10:00:00.5Z","level":"WARNING","app":{"name":"billing","version":2},"env":"prod","message":"hello"}`, string(msg.GetContent()))
+	assert.Equal(t, message.StatusWarning, msg.GetStatus())
+	assert.Equal(t, "billing", msg.Origin.Service())
+	assert.Equal(t, time.Date(2024, 8, 20, 10, 0, 0, 500000000, time.UTC), msg.ServerlessExtra.Timestamp)
+	assert.Equal(t, []string{"env:prod"}, msg.ProcessingTags)
+}
+
+func TestRemapFieldsLogfmt(t *testing.T) {
+	p := &Processor{}
+	source := newRemapSource(&config.ProcessingRule{
+		Format:         config.RemapFormatLogfmt,
+		StatusField:    "level",
+		TimestampField: "time",
+		DropFields:     []string{"token"},
+	})
+
+	msg := newMessage([]byte(`time=1724148000123 level=err token=abc msg="request failed" path=/api retried`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+
+	// the keys without a value are kept with an empty one
+	assert.Equal(t, `time=1724148000123 level=err msg="request failed" path=/api retried=""`, string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, time.UnixMilli(1724148000123).UTC(), msg.ServerlessExtra.Timestamp)
+}
+
+func TestRemapFieldsKeepsUnparsableContent(t *testing.T) {
+	p := &Processor{}
+	source := newRemapSource(&config.ProcessingRule{
+		Format:      config.RemapFormatJSON,
+		StatusField: "level",
+		DropFields:  []string{"password"},
+	})
+
+	msg := newMessage([]byte(`level=error password=hunter2`), source, message.StatusInfo)
+	assert.True(t, p.applyRedactingRules(msg))
+
+	assert.Equal(t, `level=error password=hunter2`, string(msg.GetContent()))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+}
+
+func TestRemapFieldsOnlyAppliesToMatchingLines(t *testing.T) {
+	p := &Processor{}
+	source := newRemapSource(&config.ProcessingRule{
+		Format:      config.RemapFormatJSON,
+		Pattern:     `"kind":"audit"`,
+		StatusField: "level",
+	})
+
+	msg := newMessage([]byte(`{"kind":"access","level":"error"}`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, message.StatusInfo, msg.GetStatus())
+
+	msg = newMessage([]byte(`{"kind":"audit","level":"error"}`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+}
+
+func TestParseTimestamp(t *testing.T) {
+	expected := time.Date(2024, 8, 20, 10, 0, 0, 0, time.UTC)
+	for _, value := range []string{"2024-08-20T10:00:00Z", "1724148000", "1724148000000", "1724148000000000", "1724148000000000000"} {
+		ts, ok := parseTimestamp(value, "")
+		assert.True(t, ok, value)
+		assert.Equal(t, expected, ts, value)
+	}
+
+	ts, ok := parseTimestamp("20/Aug/2024:10:00:00 +0000", "02/Jan/2006:15:04:05 -0700")
+	assert.True(t, ok)
+	assert.Equal(t, expected, ts)
+
+	_, ok = parseTimestamp("yesterday", "")
+	assert.False(t, ok)
+}
@@ -0,0 +1,216 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+// bucketsSweepInterval is the minimum interval between two removals of the idle token buckets
+// and sampling counters.
+const bucketsSweepInterval = time.Minute
+
+// rateLimiters holds the token buckets of the rate_limit rules. It is shared by all the processors
+// since the messages of a single source can be spread over several pipelines.
+var rateLimiters = newRateLimiterRegistry(time.Now)
+
+// samplers holds the counters of the sample rules, shared by all the processors like rateLimiters.
+var samplers = newSamplerRegistry(time.Now)
+
+// applySampling returns whether the message should be kept by the sample or rate_limit rule.
+// Rules with a pattern only apply to the matching messages.
+func applySampling(rule *config.ProcessingRule, msg *message.Message, content []byte) bool {
+	key, applies := samplingKey(rule, msg, content)
+	if !applies {
+		return true
+	}
+
+	var keep bool
+	if rule.Type == config.Sample {
+		keep = samplers.keep(bucketKey{source: msg.Origin.LogSource, rule: rule, key: key}, rule.SampleRate)
+	} else {
+		keep = rateLimiters.allow(bucketKey{source: msg.Origin.LogSource, rule: rule, key: key}, rule.LinesPerSecond, rule.Burst)
+	}
+
+	if !keep {
+		metrics.LogsSampledOut.Add(1)
+		metrics.TlmLogsSampledOut.Inc(rule.Type)
+		if msg.Origin.LogSource != nil {
+			msg.Origin.LogSource.RecordSampledOut(1)
+		}
+	}
+	return keep
+}
+
+// samplingKey returns the key used to sample the message. When no key is configured, a single
+// counter or bucket is used for the whole source.
+func samplingKey(rule *config.ProcessingRule, msg *message.Message, content []byte) (string, bool) {
+	var submatches [][]byte
+	if rule.Regex != nil {
+		if submatches = rule.Regex.FindSubmatch(content); submatches == nil {
+			return "", false
+		}
+	}
+
+	switch rule.Key {
+	case config.SamplingKeyStatus:
+		return msg.GetStatus(), true
+	case config.SamplingKeyCaptureGroup:
+		if len(submatches) > 1 {
+			return string(submatches[1]), true
+		}
+	}
+	return "", true
+}
+
+type bucketKey struct {
+	source *sources.LogSource
+	rule   *config.ProcessingRule
+	key    string
+}
+
+type tokenBucket struct {
+	tokens   float64
+	capacity float64
+	rate     float64
+	last     time.Time
+}
+
+// refill adds the tokens earned since the last update and returns whether the bucket is full.
+func (b *tokenBucket) refill(now time.Time) bool {
+	b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	return b.tokens >= b.capacity
+}
+
+type rateLimiterRegistry struct {
+	mu        sync.Mutex
+	buckets   map[bucketKey]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newRateLimiterRegistry(now func() time.Time) *rateLimiterRegistry {
+	return &rateLimiterRegistry{
+		buckets:   make(map[bucketKey]*tokenBucket),
+		lastSweep: now(),
+		now:       now,
+	}
+}
+
+// allow consumes a token from the bucket of the key, burst defaults to one second worth of lines.
+func (r *rateLimiterRegistry) allow(key bucketKey, linesPerSecond float64, burst int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.sweep(now)
+
+	bucket, exists := r.buckets[key]
+	if !exists {
+		capacity := float64(burst)
+		if capacity == 0 {
+			capacity = math.Max(1, math.Ceil(linesPerSecond))
+		}
+		bucket = &tokenBucket{tokens: capacity, capacity: capacity, rate: linesPerSecond, last: now}
+		r.buckets[key] = bucket
+	} else {
+		bucket.refill(now)
+	}
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// sweep removes the buckets that are full again, they behave exactly like new buckets which
+// prevents keeping the buckets of removed sources or short-lived keys forever.
+func (r *rateLimiterRegistry) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < bucketsSweepInterval {
+		return
+	}
+	r.lastSweep = now
+	for key, bucket := range r.buckets {
+		if bucket.refill(now) {
+			delete(r.buckets, key)
+		}
+	}
+}
+
+// sampleCounter spreads the kept messages of a key evenly: every message adds the sample rate to
+// the credit and a message is kept each time a whole unit of credit is available.
+type sampleCounter struct {
+	credit float64
+	last   time.Time
+}
+
+type samplerRegistry struct {
+	mu        sync.Mutex
+	counters  map[bucketKey]*sampleCounter
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newSamplerRegistry(now func() time.Time) *samplerRegistry {
+	return &samplerRegistry{
+		counters:  make(map[bucketKey]*sampleCounter),
+		lastSweep: now(),
+		now:       now,
+	}
+}
+
+// keep returns whether the message of the key should be kept to keep the given rate of the
+// messages of the key. The first message of a key is always kept, so that rare keys are seen.
+func (r *samplerRegistry) keep(key bucketKey, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.sweep(now)
+
+	counter, exists := r.counters[key]
+	if !exists {
+		r.counters[key] = &sampleCounter{last: now}
+		return true
+	}
+	counter.last = now
+	counter.credit += rate
+	if counter.credit < 1 {
+		return false
+	}
+	counter.credit--
+	return true
+}
+
+// sweep removes the counters which weren't used since the last sweep, so that the counters of
+// removed sources or short-lived keys aren't kept forever.
+func (r *samplerRegistry) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < bucketsSweepInterval {
+		return
+	}
+	r.lastSweep = now
+	for key, counter := range r.counters {
+		if now.Sub(counter.last) >= bucketsSweepInterval {
+			delete(r.counters, key)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newSamplingSource(rule *config.ProcessingRule) *sources.LogSource {
+	rule.Name = "sampling"
+	if rule.Pattern != "" {
+		rule.Regex = regexp.MustCompile(rule.Pattern)
+	}
+	return sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}})
+}
+
+func TestSampleKeepsRateOfIdenticalLines(t *testing.T) {
+	defer func(registry *samplerRegistry) { samplers = registry }(samplers)
+	samplers = newSamplerRegistry(time.Now)
+
+	p := &Processor{}
+	source := newSamplingSource(&config.ProcessingRule{Type: config.Sample, SampleRate: 0.25})
+
+	kept := 0
+	for i := 0; i < 1000; i++ {
+		if p.applyRedactingRules(newMessage([]byte("the same line"), source, "")) {
+			kept++
+		}
+	}
+	assert.InDelta(t, 250, kept, 1)
+}
+
+func TestSampleRateIsPerKey(t *testing.T) {
+	defer func(registry *samplerRegistry) { samplers = registry }(samplers)
+	samplers = newSamplerRegistry(time.Now)
+
+	p := &Processor{}
+	source := newSamplingSource(&config.ProcessingRule{Type: config.Sample, SampleRate: 0.1, Key: config.SamplingKeyStatus})
+
+	kept := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		status := message.StatusInfo
+		if i%10 == 0 {
+			status = message.StatusError
+		}
+		if p.applyRedactingRules(newMessage([]byte(fmt.Sprintf("line %d", i)), source, status)) {
+			kept[status]++
+		}
+	}
+	assert.InDelta(t, 90, kept[message.StatusInfo], 1)
+	assert.InDelta(t, 10, kept[message.StatusError], 1)
+}
+
+func TestSampleByStatus(t *testing.T) {
+	p := &Processor{}
+	source := newSamplingSource(&config.ProcessingRule{Type: config.Sample, SampleRate: 0, Key: config.SamplingKeyStatus, Pattern: "^DEBUG"})
+
+	sampledOut := metrics.LogsSampledOut.Value()
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("DEBUG cache miss"), source, message.StatusDebug)))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("ERROR cache unavailable"), source, message.StatusError)))
+	assert.Equal(t, sampledOut+1, metrics.LogsSampledOut.Value())
+	assert.Equal(t, []string{"1"}, source.GetInfoStatus()["Lines Sampled Out"])
+}
+
+func TestRateLimit(t *testing.T) {
+	now := time.Now()
+	defer func(registry *rateLimiterRegistry) { rateLimiters = registry }(rateLimiters)
+	rateLimiters = newRateLimiterRegistry(func() time.Time { return now })
+
+	p := &Processor{}
+	source := newSamplingSource(&config.ProcessingRule{Type: config.RateLimit, LinesPerSecond: 2, Burst: 3})
+
+	kept := 0
+	for i := 0; i < 10; i++ {
+		if p.applyRedactingRules(newMessage([]byte("hello"), source, "")) {
+			kept++
+		}
+	}
+	assert.Equal(t, 3, kept)
+
+	now = now.Add(time.Second)
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("hello"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("hello"), source, "")))
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("hello"), source, "")))
+	assert.Equal(t, []string{"8"}, source.GetInfoStatus()["Lines Sampled Out"])
+}
+
+func TestRateLimitByCaptureGroup(t *testing.T) {
+	now := time.Now()
+	defer func(registry *rateLimiterRegistry) { rateLimiters = registry }(rateLimiters)
+	rateLimiters = newRateLimiterRegistry(func() time.Time { return now })
+
+	p := &Processor{}
+	source := newSamplingSource(&config.ProcessingRule{Type: config.RateLimit, LinesPerSecond: 1, Pattern: `user=(\w+)`, Key: config.SamplingKeyCaptureGroup})
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("user=alice login"), source, "")))
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("user=alice logout"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("user=bob login"), source, "")))
+	// lines not matching the pattern are not rate limited
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("healthcheck"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("healthcheck"), source, "")))
+}
+
+func TestRateLimiterSweepsFullBuckets(t *testing.T) {
+	now := time.Now()
+	registry := newRateLimiterRegistry(func() time.Time { return now })
+
+	assert.True(t, registry.allow(bucketKey{key: "a"}, 1, 1))
+	assert.False(t, registry.allow(bucketKey{key: "a"}, 1, 1))
+	assert.Len(t, registry.buckets, 1)
+
+	now = now.Add(bucketsSweepInterval)
+	assert.True(t, registry.allow(bucketKey{key: "b"}, 1, 1))
+	assert.Len(t, registry.buckets, 1)
+}
+
+func TestSamplerSweepsIdleCounters(t *testing.T) {
+	now := time.Now()
+	registry := newSamplerRegistry(func() time.Time { return now })
+
+	assert.True(t, registry.keep(bucketKey{key: "a"}, 0.5))
+	assert.False(t, registry.keep(bucketKey{key: "a"}, 0.5))
+	assert.Len(t, registry.counters, 1)
+
+	now = now.Add(bucketsSweepInterval)
+	assert.True(t, registry.keep(bucketKey{key: "b"}, 0.5))
+	assert.Len(t, registry.counters, 1)
+}
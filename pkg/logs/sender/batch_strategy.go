@@ -18,7 +18,8 @@ import (
 )
 
 var (
-	tlmDroppedTooLarge = telemetry.NewCounter("logs_sender_batch_strategy", "dropped_too_large", []string{"pipeline"}, "Number of payloads dropped due to being too large")
+	tlmDroppedTooLarge  = telemetry.NewCounter("logs_sender_batch_strategy", "dropped_too_large", []string{"pipeline"}, "Number of payloads dropped due to being too large")
+	tlmCompressionRatio = telemetry.NewHistogram("logs_sender_batch_strategy", "compression_ratio", []string{"pipeline", "encoding"}, "Ratio between the unencoded and the encoded payload sizes", []float64{1, 2, 4, 6, 8, 10, 15, 20, 30, 50})
 )
 
 // batchStrategy contains all the logic to send logs in batch.
@@ -163,6 +164,9 @@ func (s *batchStrategy) sendMessages(messages []*message.Message, outputChan cha
 		log.Warn("Encoding failed - dropping payload", err)
 		return
 	}
+	if len(encodedPayload) > 0 {
+		tlmCompressionRatio.Observe(float64(len(serializedMessage))/float64(len(encodedPayload)), s.pipelineName, s.contentEncoding.name())
+	}
 
 	if s.serverless {
 		// Increment the wait group so the flush doesn't finish until all payloads are sent to all destinations
@@ -8,6 +8,12 @@ package sender
 import (
 	"bytes"
 	"compress/gzip"
+	"os"
+
+	"github.com/DataDog/zstd"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // ContentEncoding encodes the payload
@@ -16,6 +22,31 @@ type ContentEncoding interface {
 	encode(payload []byte) ([]byte, error)
 }
 
+// NewContentEncoding returns the content encoding configured for the endpoint. It falls back to gzip, at the
+// configured gzip level, when the zstd encoder cannot be created, for instance when the dictionary file cannot be read.
+func NewContentEncoding(endpoint config.Endpoint) ContentEncoding {
+	if !endpoint.UseCompression {
+		return IdentityContentType
+	}
+	if endpoint.CompressionKind == config.ZstdCompressionKind {
+		var dictionary []byte
+		if endpoint.ZstdDictionaryPath != "" {
+			var err error
+			if dictionary, err = os.ReadFile(endpoint.ZstdDictionaryPath); err != nil {
+				log.Warnf("Unable to read zstd dictionary %s, falling back to gzip: %v", endpoint.ZstdDictionaryPath, err)
+				return NewGzipContentEncoding(endpoint.GzipCompressionLevel)
+			}
+		}
+		encoding, err := NewZstdContentEncoding(endpoint.CompressionLevel, dictionary)
+		if err != nil {
+			log.Warnf("Unable to create zstd encoder, falling back to gzip: %v", err)
+			return NewGzipContentEncoding(endpoint.GzipCompressionLevel)
+		}
+		return encoding
+	}
+	return NewGzipContentEncoding(endpoint.CompressionLevel)
+}
+
 // IdentityContentType encodes the payload using the identity function
 var IdentityContentType ContentEncoding = &identityContentType{}
 
@@ -71,3 +102,44 @@ func (c *GzipContentEncoding) encode(payload []byte) ([]byte, error) {
 	}
 	return compressedPayload.Bytes(), nil
 }
+
+// ZstdContentEncoding encodes the payload using the zstd algorithm, optionally
+// primed with a pre-trained dictionary.
+type ZstdContentEncoding struct {
+	level     int
+	processor *zstd.BulkProcessor
+}
+
+// NewZstdContentEncoding creates a new zstd content type. When dictionary is
+// not empty it is used to prime the compressor, the intake must then know the
+// same dictionary to decode the payloads.
+func NewZstdContentEncoding(level int, dictionary []byte) (*ZstdContentEncoding, error) {
+	if level < zstd.BestSpeed {
+		level = zstd.BestSpeed
+	} else if level > zstd.BestCompression {
+		level = zstd.BestCompression
+	}
+
+	encoding := &ZstdContentEncoding{
+		level: level,
+	}
+	if len(dictionary) > 0 {
+		processor, err := zstd.NewBulkProcessor(dictionary, level)
+		if err != nil {
+			return nil, err
+		}
+		encoding.processor = processor
+	}
+	return encoding, nil
+}
+
+func (c *ZstdContentEncoding) name() string {
+	return "zstd"
+}
+
+func (c *ZstdContentEncoding) encode(payload []byte) ([]byte, error) {
+	if c.processor != nil {
+		return c.processor.Compress(nil, payload)
+	}
+	return zstd.CompressLevel(nil, payload, c.level)
+}
@@ -8,9 +8,14 @@ package sender
 import (
 	"bytes"
 	"compress/gzip"
+	"path/filepath"
 	"testing"
 
+	"github.com/DataDog/zstd"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 )
 
 func TestIdentityContentType(t *testing.T) {
@@ -42,6 +47,56 @@ func TestGzipContentEncodingName(t *testing.T) {
 	assert.Equal(t, NewGzipContentEncoding(gzip.BestCompression).name(), "gzip")
 }
 
+func TestZstdContentEncoding(t *testing.T) {
+	payload := []byte("my payload")
+
+	encoding, err := NewZstdContentEncoding(zstd.DefaultCompression, nil)
+	require.NoError(t, err)
+	encodedPayload, err := encoding.encode(payload)
+	assert.Nil(t, err)
+
+	decompressedPayload, err := zstd.Decompress(nil, encodedPayload)
+	assert.Nil(t, err)
+
+	assert.Equal(t, payload, decompressedPayload)
+}
+
+func TestZstdContentEncodingWithDictionary(t *testing.T) {
+	dictionary := bytes.Repeat([]byte(`{"message":"my payload","status":"info"}`), 16)
+	payload := []byte(`{"message":"my payload","status":"error"}`)
+
+	encoding, err := NewZstdContentEncoding(zstd.DefaultCompression, dictionary)
+	require.NoError(t, err)
+	encodedPayload, err := encoding.encode(payload)
+	assert.Nil(t, err)
+
+	processor, err := zstd.NewBulkProcessor(dictionary, zstd.DefaultCompression)
+	require.NoError(t, err)
+	decompressedPayload, err := processor.Decompress(nil, encodedPayload)
+	assert.Nil(t, err)
+
+	assert.Equal(t, payload, decompressedPayload)
+}
+
+func TestZstdContentEncodingName(t *testing.T) {
+	encoding, err := NewZstdContentEncoding(zstd.DefaultCompression, nil)
+	require.NoError(t, err)
+	assert.Equal(t, encoding.name(), "zstd")
+}
+
+func TestNewContentEncodingZstdFallback(t *testing.T) {
+	endpoint := config.NewEndpoint("", "", 0, true)
+	endpoint.UseCompression = true
+	endpoint.CompressionKind = config.ZstdCompressionKind
+	endpoint.CompressionLevel = 19
+	endpoint.GzipCompressionLevel = gzip.BestSpeed
+	endpoint.ZstdDictionaryPath = filepath.Join(t.TempDir(), "missing.dict")
+
+	encoding := NewContentEncoding(endpoint)
+	require.IsType(t, &GzipContentEncoding{}, encoding)
+	assert.Equal(t, gzip.BestSpeed, encoding.(*GzipContentEncoding).level)
+}
+
 func decompress(payload []byte) ([]byte, error) {
 	reader, err := gzip.NewReader(bytes.NewReader(payload))
 	if err != nil {
@@ -0,0 +1,285 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const diskBufferFileExtension = ".payload"
+
+var (
+	tlmDiskBufferSize     = telemetry.NewGauge("logs_sender_disk_buffer", "size_bytes", []string{"path"}, "Size of the payloads stored in the disk buffer")
+	tlmDiskBufferStored   = telemetry.NewCounter("logs_sender_disk_buffer", "payloads_stored", []string{"path"}, "Payloads stored in the disk buffer")
+	tlmDiskBufferReplayed = telemetry.NewCounter("logs_sender_disk_buffer", "payloads_replayed", []string{"path"}, "Payloads sent from the disk buffer")
+	tlmDiskBufferErrors   = telemetry.NewCounter("logs_sender_disk_buffer", "errors", []string{"path"}, "Payloads lost because they could not be written to or read from the disk buffer")
+)
+
+var errDiskBufferFull = errors.New("the disk buffer is full")
+
+// DiskBuffer is a bounded on-disk spool of encoded payloads, filled by the sender when all the
+// reliable destinations are failing and replayed in order once they recover.
+//
+// Each payload is written to its own file along with its messages, so that the destinations which
+// encode the messages themselves, such as the OTLP ones, can send it again. Only the names and the
+// sizes of the files are kept in memory. The messages keep the identifier and the offset of their
+// origin, so their offsets are committed by the destination once the payload is replayed, and not
+// when it is stored. The payloads left from a previous run are replayed on startup, the lines read
+// again since their last committed offset may then be sent twice.
+//
+// A DiskBuffer is not thread safe, it is only used by the goroutine of its sender.
+type DiskBuffer struct {
+	path             string
+	maxSizeBytes     int64
+	currentSizeBytes int64
+	entries          []diskBufferEntry
+	nextID           uint64
+}
+
+type diskBufferEntry struct {
+	filename string
+	size     int64
+}
+
+// diskBufferHeader is written before the encoded bytes of a payload, its messages keep the fields
+// used by the destinations once the payload is encoded.
+type diskBufferHeader struct {
+	Encoding      string              `json:"encoding"`
+	UnencodedSize int                 `json:"unencoded_size"`
+	Messages      []diskBufferMessage `json:"messages"`
+}
+
+type diskBufferMessage struct {
+	Content            []byte   `json:"content"`
+	Encoded            bool     `json:"encoded,omitempty"`
+	Hostname           string   `json:"hostname,omitempty"`
+	Status             string   `json:"status,omitempty"`
+	Service            string   `json:"service,omitempty"`
+	Source             string   `json:"source,omitempty"`
+	Tags               []string `json:"tags,omitempty"`
+	Timestamp          int64    `json:"timestamp,omitempty"`
+	IngestionTimestamp int64    `json:"ingestion_timestamp,omitempty"`
+	Identifier         string   `json:"identifier,omitempty"`
+	Offset             string   `json:"offset,omitempty"`
+	TailingMode        string   `json:"tailing_mode,omitempty"`
+}
+
+// NewDiskBuffer creates a disk buffer storing at most maxSizeBytes of payloads in path, the
+// payloads left from a previous run are replayed first.
+func NewDiskBuffer(path string, maxSizeBytes int64) (*DiskBuffer, error) {
+	if maxSizeBytes <= 0 {
+		return nil, fmt.Errorf("invalid disk buffer size: %d", maxSizeBytes)
+	}
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+
+	// the zero padded IDs of the files keep the payloads in order
+	filenames, err := filepath.Glob(filepath.Join(path, "*"+diskBufferFileExtension))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(filenames)
+
+	b := &DiskBuffer{
+		path:         path,
+		maxSizeBytes: maxSizeBytes,
+	}
+	for _, filename := range filenames {
+		info, err := os.Stat(filename)
+		if err != nil {
+			log.Warnf("Unable to read the logs payload %s left in the disk buffer: %v", filename, err)
+			continue
+		}
+		b.entries = append(b.entries, diskBufferEntry{filename: filename, size: info.Size()})
+		b.currentSizeBytes += info.Size()
+		if id, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(filename), diskBufferFileExtension), 10, 64); err == nil && id >= b.nextID {
+			b.nextID = id + 1
+		}
+	}
+	if len(b.entries) > 0 {
+		log.Infof("Replaying %d logs payloads left in the disk buffer %s", len(b.entries), path)
+	}
+
+	tlmDiskBufferSize.Set(float64(b.currentSizeBytes), path)
+	return b, nil
+}
+
+// Len returns the number of payloads in the buffer.
+func (b *DiskBuffer) Len() int {
+	return len(b.entries)
+}
+
+// SizeBytes returns the size of the payloads stored on disk.
+func (b *DiskBuffer) SizeBytes() int64 {
+	return b.currentSizeBytes
+}
+
+// Store appends the payload to the buffer, it returns errDiskBufferFull when there is no room left.
+func (b *DiskBuffer) Store(payload *message.Payload) error {
+	header, err := json.Marshal(diskBufferHeader{
+		Encoding:      payload.Encoding,
+		UnencodedSize: payload.UnencodedSize,
+		Messages:      toDiskBufferMessages(payload.Messages),
+	})
+	if err != nil {
+		return err
+	}
+	size := int64(4 + len(header) + len(payload.Encoded))
+	if b.currentSizeBytes+size > b.maxSizeBytes {
+		return errDiskBufferFull
+	}
+
+	data := make([]byte, 0, size)
+	data = binary.BigEndian.AppendUint32(data, uint32(len(header)))
+	data = append(data, header...)
+	data = append(data, payload.Encoded...)
+
+	filename := filepath.Join(b.path, fmt.Sprintf("%020d%s", b.nextID, diskBufferFileExtension))
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		_ = os.Remove(filename)
+		tlmDiskBufferErrors.Inc(b.path)
+		return err
+	}
+	b.nextID++
+
+	b.entries = append(b.entries, diskBufferEntry{filename: filename, size: size})
+	b.currentSizeBytes += size
+	tlmDiskBufferStored.Inc(b.path)
+	tlmDiskBufferSize.Set(float64(b.currentSizeBytes), b.path)
+	return nil
+}
+
+// Peek reads the oldest payload of the buffer without removing it. A payload which can't be
+// read is removed from the buffer and the error is returned.
+func (b *DiskBuffer) Peek() (*message.Payload, error) {
+	if len(b.entries) == 0 {
+		return nil, nil
+	}
+	payload, err := readDiskBufferFile(b.entries[0].filename)
+	if err != nil {
+		tlmDiskBufferErrors.Inc(b.path)
+		b.removeOldest()
+		return nil, err
+	}
+	return payload, nil
+}
+
+// Pop removes the oldest payload once it has been sent.
+func (b *DiskBuffer) Pop() {
+	if len(b.entries) == 0 {
+		return
+	}
+	tlmDiskBufferReplayed.Inc(b.path)
+	b.removeOldest()
+}
+
+func (b *DiskBuffer) removeOldest() {
+	entry := b.entries[0]
+	if err := os.Remove(entry.filename); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Unable to remove the logs payload %s from the disk buffer: %v", entry.filename, err)
+	}
+	b.entries[0] = diskBufferEntry{}
+	b.entries = b.entries[1:]
+	b.currentSizeBytes -= entry.size
+	tlmDiskBufferSize.Set(float64(b.currentSizeBytes), b.path)
+}
+
+func readDiskBufferFile(filename string) (*message.Payload, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 || uint64(binary.BigEndian.Uint32(data)) > uint64(len(data)-4) {
+		return nil, fmt.Errorf("invalid logs payload %s", filename)
+	}
+	headerEnd := 4 + int(binary.BigEndian.Uint32(data))
+	var header diskBufferHeader
+	if err := json.Unmarshal(data[4:headerEnd], &header); err != nil {
+		return nil, fmt.Errorf("invalid logs payload %s: %v", filename, err)
+	}
+	return &message.Payload{
+		Messages:      fromDiskBufferMessages(header.Messages),
+		Encoded:       data[headerEnd:],
+		Encoding:      header.Encoding,
+		UnencodedSize: header.UnencodedSize,
+	}, nil
+}
+
+func toDiskBufferMessages(messages []*message.Message) []diskBufferMessage {
+	stored := make([]diskBufferMessage, 0, len(messages))
+	for _, msg := range messages {
+		m := diskBufferMessage{
+			Content:            msg.GetContent(),
+			Encoded:            msg.State == message.StateEncoded,
+			Hostname:           msg.Hostname,
+			Status:             msg.GetStatus(),
+			IngestionTimestamp: msg.IngestionTimestamp,
+		}
+		if !msg.ServerlessExtra.Timestamp.IsZero() {
+			m.Timestamp = msg.ServerlessExtra.Timestamp.UnixNano()
+		}
+		if msg.Origin != nil {
+			m.Service = msg.Origin.Service()
+			m.Source = msg.Origin.Source()
+			m.Tags = msg.Tags()
+			m.Identifier = msg.Origin.Identifier
+			m.Offset = msg.Origin.Offset
+			if msg.Origin.LogSource != nil && msg.Origin.LogSource.Config != nil {
+				m.TailingMode = msg.Origin.LogSource.Config.TailingMode
+			}
+		} else {
+			m.Tags = msg.ProcessingTags
+		}
+		stored = append(stored, m)
+	}
+	return stored
+}
+
+// fromDiskBufferMessages rebuilds the stored messages, their origin has the identifier, the offset
+// and the tailing mode used by the auditor to commit them.
+func fromDiskBufferMessages(stored []diskBufferMessage) []*message.Message {
+	messages := make([]*message.Message, 0, len(stored))
+	sourcesByTailingMode := make(map[string]*sources.LogSource)
+	for _, m := range stored {
+		source, ok := sourcesByTailingMode[m.TailingMode]
+		if !ok {
+			source = sources.NewLogSource("disk_buffer", &config.LogsConfig{TailingMode: m.TailingMode})
+			sourcesByTailingMode[m.TailingMode] = source
+		}
+		origin := message.NewOrigin(source)
+		origin.Identifier = m.Identifier
+		origin.Offset = m.Offset
+		origin.SetService(m.Service)
+		origin.SetSource(m.Source)
+		origin.SetTags(m.Tags)
+		msg := message.NewMessage(m.Content, origin, m.Status, m.IngestionTimestamp)
+		if m.Encoded {
+			msg.SetEncoded(m.Content)
+		}
+		msg.Hostname = m.Hostname
+		if m.Timestamp != 0 {
+			msg.ServerlessExtra.Timestamp = time.Unix(0, m.Timestamp).UTC()
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
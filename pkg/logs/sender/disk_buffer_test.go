@@ -0,0 +1,228 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/otlp"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func diskBufferFiles(t *testing.T, path string) []string {
+	files, err := filepath.Glob(filepath.Join(path, "*"+diskBufferFileExtension))
+	require.NoError(t, err)
+	return files
+}
+
+func TestDiskBufferStoreAndReplayInOrder(t *testing.T) {
+	path := t.TempDir()
+	buffer, err := NewDiskBuffer(path, 1024)
+	require.NoError(t, err)
+
+	source := sources.NewLogSource("", &config.LogsConfig{Service: "web", Source: "nginx", Tags: []string{"env:prod"}, TailingMode: "beginning"})
+	first := newMessage([]byte("first"), source, message.StatusError)
+	first.Messages[0].Origin.Identifier = "file:/var/log/nginx.log"
+	first.Messages[0].Origin.Offset = "42"
+	first.Messages[0].Hostname = "host"
+	require.NoError(t, buffer.Store(first))
+	require.NoError(t, buffer.Store(newMessage([]byte("two"), source, "")))
+	assert.Equal(t, 2, buffer.Len())
+
+	var size int64
+	for _, file := range diskBufferFiles(t, path) {
+		info, err := os.Stat(file)
+		require.NoError(t, err)
+		size += info.Size()
+	}
+	assert.Equal(t, size, buffer.SizeBytes())
+
+	payload, err := buffer.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("first"), payload.Encoded)
+	assert.Equal(t, "identity", payload.Encoding)
+	require.Len(t, payload.Messages, 1)
+	msg := payload.Messages[0]
+	assert.Equal(t, []byte("first"), msg.GetContent())
+	assert.Equal(t, message.StatusError, msg.GetStatus())
+	assert.Equal(t, "host", msg.Hostname)
+	assert.Equal(t, "web", msg.Origin.Service())
+	assert.Equal(t, "nginx", msg.Origin.Source())
+	assert.Equal(t, []string{"env:prod"}, msg.Tags())
+	// the offsets are committed once the payload is replayed
+	assert.Equal(t, "file:/var/log/nginx.log", msg.Origin.Identifier)
+	assert.Equal(t, "42", msg.Origin.Offset)
+	assert.Equal(t, "beginning", msg.Origin.LogSource.Config.TailingMode)
+	buffer.Pop()
+
+	payload, err = buffer.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("two"), payload.Encoded)
+	buffer.Pop()
+
+	assert.Equal(t, 0, buffer.Len())
+	assert.Equal(t, int64(0), buffer.SizeBytes())
+	assert.Empty(t, diskBufferFiles(t, path))
+	payload, err = buffer.Peek()
+	assert.NoError(t, err)
+	assert.Nil(t, payload)
+}
+
+func TestDiskBufferIsFull(t *testing.T) {
+	buffer, err := NewDiskBuffer(t.TempDir(), 200)
+	require.NoError(t, err)
+
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	require.NoError(t, buffer.Store(newMessage([]byte("first"), source, "")))
+	assert.Equal(t, errDiskBufferFull, buffer.Store(newMessage(make([]byte, 200), source, "")))
+	assert.Equal(t, 1, buffer.Len())
+}
+
+func TestDiskBufferDropsUnreadablePayloads(t *testing.T) {
+	path := t.TempDir()
+	buffer, err := NewDiskBuffer(path, 1024)
+	require.NoError(t, err)
+
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	require.NoError(t, buffer.Store(newMessage([]byte("lost"), source, "")))
+	require.NoError(t, buffer.Store(newMessage([]byte("corrupted"), source, "")))
+	require.NoError(t, os.Remove(buffer.entries[0].filename))
+	require.NoError(t, os.WriteFile(buffer.entries[1].filename, []byte("garbage"), 0600))
+
+	_, err = buffer.Peek()
+	assert.Error(t, err)
+	_, err = buffer.Peek()
+	assert.Error(t, err)
+	assert.Equal(t, 0, buffer.Len())
+	assert.Equal(t, int64(0), buffer.SizeBytes())
+	assert.Empty(t, diskBufferFiles(t, path))
+}
+
+func TestDiskBufferReplaysPayloadsOfPreviousRun(t *testing.T) {
+	path := t.TempDir()
+	previous, err := NewDiskBuffer(path, 1024)
+	require.NoError(t, err)
+
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	require.NoError(t, previous.Store(newMessage([]byte("a"), source, "")))
+	require.NoError(t, previous.Store(newMessage([]byte("b"), source, "")))
+
+	buffer, err := NewDiskBuffer(path, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, 2, buffer.Len())
+	assert.Equal(t, previous.SizeBytes(), buffer.SizeBytes())
+	require.NoError(t, buffer.Store(newMessage([]byte("c"), source, "")))
+
+	for _, content := range []string{"a", "b", "c"} {
+		payload, err := buffer.Peek()
+		require.NoError(t, err)
+		assert.Equal(t, []byte(content), payload.Encoded)
+		assert.Equal(t, []byte(content), payload.Messages[0].GetContent())
+		buffer.Pop()
+	}
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func TestDiskBufferReplayToOTLP(t *testing.T) {
+	buffer, err := NewDiskBuffer(t.TempDir(), 1024)
+	require.NoError(t, err)
+
+	source := sources.NewLogSource("", &config.LogsConfig{Service: "web", Source: "nginx"})
+	payload := newMessage(nil, source, message.StatusWarning)
+	payload.Messages[0].SetEncoded([]byte(`{"message":"GET /health","status":"warn","timestamp":1700000002000,"hostname":"host-a"}`))
+	require.NoError(t, buffer.Store(payload))
+
+	replayed, err := buffer.Peek()
+	require.NoError(t, err)
+	logs := otlp.ToLogs(replayed.Messages)
+	require.Equal(t, 1, logs.LogRecordCount())
+	resource := logs.ResourceLogs().At(0)
+	assert.Equal(t, "web", resource.Resource().Attributes().AsRaw()[otlp.ServiceAttribute])
+	assert.Equal(t, "host-a", resource.Resource().Attributes().AsRaw()[otlp.HostNameAttribute])
+	record := resource.ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, "GET /health", record.Body().Str())
+	assert.Equal(t, message.StatusWarning, record.SeverityText())
+	assert.Equal(t, time.UnixMilli(1700000002000).UTC(), record.Timestamp().AsTime())
+}
+
+// failingDestination reports itself as retrying until recovered is closed.
+type failingDestination struct {
+	recovered chan struct{}
+}
+
+func (d *failingDestination) IsMRF() bool {
+	return false
+}
+
+func (d *failingDestination) Target() string {
+	return "failing"
+}
+
+func (d *failingDestination) Start(input chan *message.Payload, output chan *message.Payload, isRetrying chan bool) <-chan struct{} {
+	stopChan := make(chan struct{})
+	go func() {
+		isRetrying <- true
+		<-d.recovered
+		isRetrying <- false
+		for payload := range input {
+			output <- payload
+		}
+		close(stopChan)
+	}()
+	return stopChan
+}
+
+func TestSenderBuffersOnDiskWhileDestinationsFail(t *testing.T) {
+	path := t.TempDir()
+	buffer, err := NewDiskBuffer(path, 1024)
+	require.NoError(t, err)
+
+	input := make(chan *message.Payload, 1)
+	output := make(chan *message.Payload, 3)
+	destination := &failingDestination{recovered: make(chan struct{})}
+	destinations := client.NewDestinations([]client.Destination{destination}, nil)
+
+	sender := NewSender(getNewConfig(), input, output, destinations, 0, nil, nil, buffer)
+	sender.Start()
+
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	for i, content := range []string{"a", "b", "c"} {
+		payload := newMessage([]byte(content), source, "")
+		payload.Messages[0].Origin.Identifier = "file:/var/log/app.log"
+		payload.Messages[0].Origin.Offset = strconv.Itoa(i)
+		input <- payload
+	}
+
+	// the pipeline is not blocked, the payloads are stored on disk
+	assert.Eventually(t, func() bool { return len(diskBufferFiles(t, path)) == 3 }, 5*time.Second, 10*time.Millisecond)
+	// their offsets are not committed until they are delivered
+	assert.Empty(t, output)
+
+	close(destination.recovered)
+	for i, content := range []string{"a", "b", "c"} {
+		select {
+		case payload := <-output:
+			assert.Equal(t, []byte(content), payload.Encoded)
+			assert.Equal(t, "file:/var/log/app.log", payload.Messages[0].Origin.Identifier)
+			assert.Equal(t, strconv.Itoa(i), payload.Messages[0].Origin.Offset)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timeout while waiting for the buffered payloads")
+		}
+	}
+
+	sender.Stop()
+	assert.Empty(t, diskBufferFiles(t, path))
+}
@@ -14,8 +14,13 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
+// diskBufferReplayInterval is the interval at which the sender tries to replay the payloads of
+// its disk buffer when it doesn't receive new payloads.
+const diskBufferReplayInterval = time.Second
+
 var (
 	tlmPayloadsDropped = telemetry.NewCounterWithOpts("logs_sender", "payloads_dropped", []string{"reliable", "destination"}, "Payloads dropped", telemetry.Options{DefaultMetric: true})
 	tlmMessagesDropped = telemetry.NewCounterWithOpts("logs_sender", "messages_dropped", []string{"reliable", "destination"}, "Messages dropped", telemetry.Options{DefaultMetric: true})
@@ -29,6 +34,11 @@ var (
 // one reliable destination is also sending logs. However they do not update
 // the auditor or block the pipeline if they fail. There will always be at
 // least 1 reliable destination (the main destination).
+//
+// When a disk buffer is set, the payloads that no reliable destination can accept are stored
+// on disk instead of blocking the pipeline, and are replayed in order once a reliable
+// destination recovers. The offsets of the stored payloads are only committed once a destination
+// delivers them. The pipeline only blocks again once the disk buffer is full.
 type Sender struct {
 	config         pkgconfigmodel.Reader
 	inputChan      chan *message.Payload
@@ -38,10 +48,11 @@ type Sender struct {
 	bufferSize     int
 	senderDoneChan chan *sync.WaitGroup
 	flushWg        *sync.WaitGroup
+	diskBuffer     *DiskBuffer
 }
 
-// NewSender returns a new sender.
-func NewSender(config pkgconfigmodel.Reader, inputChan chan *message.Payload, outputChan chan *message.Payload, destinations *client.Destinations, bufferSize int, senderDoneChan chan *sync.WaitGroup, flushWg *sync.WaitGroup) *Sender {
+// NewSender returns a new sender. The disk buffer is optional and can be nil.
+func NewSender(config pkgconfigmodel.Reader, inputChan chan *message.Payload, outputChan chan *message.Payload, destinations *client.Destinations, bufferSize int, senderDoneChan chan *sync.WaitGroup, flushWg *sync.WaitGroup, diskBuffer *DiskBuffer) *Sender {
 	return &Sender{
 		config:         config,
 		inputChan:      inputChan,
@@ -51,6 +62,7 @@ func NewSender(config pkgconfigmodel.Reader, inputChan chan *message.Payload, ou
 		bufferSize:     bufferSize,
 		senderDoneChan: senderDoneChan,
 		flushWg:        flushWg,
+		diskBuffer:     diskBuffer,
 	}
 }
 
@@ -72,46 +84,51 @@ func (s *Sender) run() {
 	sink := additionalDestinationsSink(s.bufferSize)
 	unreliableDestinations := buildDestinationSenders(s.config, s.destinations.Unreliable, sink, s.bufferSize)
 
-	for payload := range s.inputChan {
-		var startInUse = time.Now()
-		senderDoneWg := &sync.WaitGroup{}
-
-		sent := false
-		for !sent {
-			for _, destSender := range reliableDestinations {
-				if destSender.Send(payload) {
-					sent = true
-					if s.senderDoneChan != nil {
-						senderDoneWg.Add(1)
-						s.senderDoneChan <- senderDoneWg
-					}
-				}
-			}
+	var replayTicker <-chan time.Time
+	if s.diskBuffer != nil {
+		ticker := time.NewTicker(diskBufferReplayInterval)
+		defer ticker.Stop()
+		replayTicker = ticker.C
+	}
 
-			if !sent {
-				// Throttle the poll loop while waiting for a send to succeed
-				// This will only happen when all reliable destinations
-				// are blocked so logs have no where to go.
-				time.Sleep(100 * time.Millisecond)
+loop:
+	for {
+		select {
+		case payload, isOpen := <-s.inputChan:
+			if !isOpen {
+				break loop
 			}
-		}
-
-		for i, destSender := range reliableDestinations {
-			// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
-			// loss on intermittent failures.
-			if !destSender.lastSendSucceeded {
-				if !destSender.NonBlockingSend(payload) {
-					tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
-					tlmMessagesDropped.Add(float64(len(payload.Messages)), "true", strconv.Itoa(i))
-				}
+			if s.diskBuffer == nil || !s.bufferPayload(payload, reliableDestinations, unreliableDestinations) {
+				s.sendPayload(payload, reliableDestinations, unreliableDestinations, true)
 			}
+		case <-replayTicker:
+			s.replayDiskBuffer(reliableDestinations, unreliableDestinations)
 		}
+	}
 
-		// Attempt to send to unreliable destinations
-		for i, destSender := range unreliableDestinations {
-			if !destSender.NonBlockingSend(payload) {
-				tlmPayloadsDropped.Inc("false", strconv.Itoa(i))
-				tlmMessagesDropped.Add(float64(len(payload.Messages)), "false", strconv.Itoa(i))
+	// Cleanup the destinations
+	for _, destSender := range reliableDestinations {
+		destSender.Stop()
+	}
+	for _, destSender := range unreliableDestinations {
+		destSender.Stop()
+	}
+	close(sink)
+	s.done <- struct{}{}
+}
+
+// sendPayload sends the payload to the reliable destinations, then to the unreliable ones once a reliable
+// destination accepted it. When blocking is false, it returns false instead of waiting for a reliable
+// destination to accept the payload.
+func (s *Sender) sendPayload(payload *message.Payload, reliableDestinations, unreliableDestinations []*DestinationSender, blocking bool) bool {
+	var startInUse = time.Now()
+	senderDoneWg := &sync.WaitGroup{}
+
+	sent := false
+	for !sent {
+		for _, destSender := range reliableDestinations {
+			if destSender.Send(payload) {
+				sent = true
 				if s.senderDoneChan != nil {
 					senderDoneWg.Add(1)
 					s.senderDoneChan <- senderDoneWg
@@ -119,26 +136,94 @@ func (s *Sender) run() {
 			}
 		}
 
-		inUse := float64(time.Since(startInUse) / time.Millisecond)
-		tlmSendWaitTime.Add(inUse)
+		if !sent {
+			if !blocking {
+				return false
+			}
+			// Throttle the poll loop while waiting for a send to succeed
+			// This will only happen when all reliable destinations
+			// are blocked so logs have no where to go.
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	for i, destSender := range reliableDestinations {
+		// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
+		// loss on intermittent failures.
+		if !destSender.lastSendSucceeded {
+			if !destSender.NonBlockingSend(payload) {
+				tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
+				tlmMessagesDropped.Add(float64(len(payload.Messages)), "true", strconv.Itoa(i))
+			}
+		}
+	}
 
-		if s.senderDoneChan != nil && s.flushWg != nil {
-			// Wait for all destinations to finish sending the payload
-			senderDoneWg.Wait()
-			// Decrement the wait group when this payload has been sent
-			s.flushWg.Done()
+	// Attempt to send to unreliable destinations
+	for i, destSender := range unreliableDestinations {
+		if !destSender.NonBlockingSend(payload) {
+			tlmPayloadsDropped.Inc("false", strconv.Itoa(i))
+			tlmMessagesDropped.Add(float64(len(payload.Messages)), "false", strconv.Itoa(i))
+			if s.senderDoneChan != nil {
+				senderDoneWg.Add(1)
+				s.senderDoneChan <- senderDoneWg
+			}
 		}
 	}
 
-	// Cleanup the destinations
-	for _, destSender := range reliableDestinations {
-		destSender.Stop()
+	inUse := float64(time.Since(startInUse) / time.Millisecond)
+	tlmSendWaitTime.Add(inUse)
+
+	if s.senderDoneChan != nil && s.flushWg != nil {
+		// Wait for all destinations to finish sending the payload
+		senderDoneWg.Wait()
+		// Decrement the wait group when this payload has been sent
+		s.flushWg.Done()
 	}
-	for _, destSender := range unreliableDestinations {
-		destSender.Stop()
+	return true
+}
+
+// bufferPayload sends the payload, or stores it in the disk buffer when no reliable destination accepts it.
+// The payloads are always sent in order, so it is stored as long as older payloads are still buffered.
+// It returns false when the disk buffer is full, once the buffered payloads have been sent.
+func (s *Sender) bufferPayload(payload *message.Payload, reliableDestinations, unreliableDestinations []*DestinationSender) bool {
+	s.replayDiskBuffer(reliableDestinations, unreliableDestinations)
+	if s.diskBuffer.Len() == 0 && s.sendPayload(payload, reliableDestinations, unreliableDestinations, false) {
+		return true
 	}
-	close(sink)
-	s.done <- struct{}{}
+
+	err := s.diskBuffer.Store(payload)
+	if err == nil {
+		return true
+	}
+	if err != errDiskBufferFull {
+		log.Warnf("Unable to store a logs payload in the disk buffer: %v", err)
+	}
+
+	// block until the buffered payloads are sent to keep the order
+	for s.diskBuffer.Len() > 0 {
+		if !s.replayDiskBuffer(reliableDestinations, unreliableDestinations) {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	return false
+}
+
+// replayDiskBuffer sends the buffered payloads in order until a payload is not accepted by
+// any reliable destination. It returns false when the buffer couldn't be emptied. The replayed
+// messages keep their origin, so the destination commits their offsets once it delivers them.
+func (s *Sender) replayDiskBuffer(reliableDestinations, unreliableDestinations []*DestinationSender) bool {
+	for s.diskBuffer.Len() > 0 {
+		payload, err := s.diskBuffer.Peek()
+		if err != nil {
+			log.Warnf("Unable to read a logs payload from the disk buffer, dropping it: %v", err)
+			continue
+		}
+		if !s.sendPayload(payload, reliableDestinations, unreliableDestinations, false) {
+			return false
+		}
+		s.diskBuffer.Pop()
+	}
+	return true
 }
 
 // Drains the output channel from destinations that don't update the auditor.
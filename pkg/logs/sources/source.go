@@ -50,6 +50,7 @@ type LogSource struct {
 	// the duration between when a message is decoded by the tailer/listener/decoder and when the message is handled by a sender
 	LatencyStats     *statstracker.Tracker
 	BytesRead        *status.CountInfo
+	sampledOut       *status.CountInfo
 	hiddenFromStatus bool
 }
 
@@ -159,6 +160,24 @@ func (s *LogSource) RecordBytes(n int64) {
 	}
 }
 
+// RecordSampledOut reports lines dropped by the sample and rate_limit processing rules. The count is only
+// registered on the status page once a first line has been dropped, to keep the sources without sampling unchanged.
+func (s *LogSource) RecordSampledOut(n int64) {
+	s.lock.Lock()
+	if s.sampledOut == nil {
+		s.sampledOut = status.NewCountInfo("Lines Sampled Out")
+		s.info.Register(s.sampledOut)
+	}
+	sampledOut := s.sampledOut
+	s.lock.Unlock()
+
+	sampledOut.Add(n)
+
+	if s.ParentSource != nil {
+		s.ParentSource.RecordSampledOut(n)
+	}
+}
+
 // Dump provides a dump of the LogSource contents, for debugging purposes.  If
 // multiline is true, the result contains newlines for readability.
 func (s *LogSource) Dump(multiline bool) string {
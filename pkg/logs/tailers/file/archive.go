@@ -0,0 +1,245 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+
+	"github.com/DataDog/zstd"
+	"go.uber.org/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// archiveFingerprintSize is the number of decompressed bytes used to identify a rotated file.
+const archiveFingerprintSize = 1024
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+	errEmptyArchive = errors.New("empty rotated file")
+)
+
+// ArchiveTailer reads a rotated file once, from the given offset to its end, decompressing
+// gzip and zstd archives on the fly. It is used to collect the lines of the files which
+// were rotated while the agent was not running.
+//
+// Rotated files are identified in the registry by a fingerprint of their first decompressed
+// bytes instead of their path, so that a file is never collected twice once it is renamed
+// or compressed by the next rotations. The offsets registered for an archive are offsets in
+// its decompressed content.
+type ArchiveTailer struct {
+	file       *File
+	identifier string
+	outputChan chan *message.Message
+	decoder    *decoder.Decoder
+
+	// skipUntil is the offset of the decompressed content from which the lines are forwarded,
+	// compressed archives can't be seeked so the lines before it are read and dropped.
+	skipUntil     int64
+	decodedOffset int64
+
+	tags []string
+
+	stop           chan struct{}
+	done           chan struct{}
+	isFinished     *atomic.Bool
+	forwardContext context.Context
+	stopForward    context.CancelFunc
+}
+
+// NewArchiveTailer returns a tailer reading the rotated file once, its identifier is
+// returned by ArchiveIdentifier.
+func NewArchiveTailer(file *File, identifier string, outputChan chan *message.Message, decoder *decoder.Decoder) *ArchiveTailer {
+	forwardContext, stopForward := context.WithCancel(context.Background())
+	return &ArchiveTailer{
+		file:       file,
+		identifier: identifier,
+		outputChan: outputChan,
+		decoder:    decoder,
+		tags: []string{
+			fmt.Sprintf("filename:%s", filepath.Base(file.Path)),
+			fmt.Sprintf("dirname:%s", filepath.Dir(file.Path)),
+		},
+		stop:           make(chan struct{}),
+		done:           make(chan struct{}),
+		isFinished:     atomic.NewBool(false),
+		forwardContext: forwardContext,
+		stopForward:    stopForward,
+	}
+}
+
+// ArchiveIdentifier returns the identifier of a rotated file in the registry. When fingerprinting
+// is enabled, it is the fingerprint the file had when it was tailed, so that the file is read from
+// the offset committed before it was rotated.
+func ArchiveIdentifier(path string, fingerprintSize int) (string, error) {
+	reader, err := openArchive(path)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	size := archiveFingerprintSize
+	if fingerprintSize > 0 {
+		size = fingerprintSize
+	}
+	head := make([]byte, size)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if n == 0 {
+		return "", errEmptyArchive
+	}
+	if fingerprintSize > 0 && n == fingerprintSize {
+		return auditor.Fingerprint(head), nil
+	}
+	sum := sha256.Sum256(head[:n])
+	return "archive:" + hex.EncodeToString(sum[:16]), nil
+}
+
+// Identifier returns the identifier of the rotated file in the registry.
+func (t *ArchiveTailer) Identifier() string {
+	return t.identifier
+}
+
+// Start reads the rotated file from the given offset of its decompressed content.
+func (t *ArchiveTailer) Start(offset int64) error {
+	reader, err := openArchive(t.file.Path)
+	if err != nil {
+		return err
+	}
+	t.skipUntil = offset
+
+	log.Infof("Reading the rotated file %s from offset %d", t.file.Path, offset)
+	go t.forwardMessages()
+	t.decoder.Start()
+	go t.readAll(reader)
+	return nil
+}
+
+// Stop stops reading the rotated file and returns once the in-flight messages are flushed.
+func (t *ArchiveTailer) Stop() {
+	t.stopForward()
+	close(t.stop)
+	<-t.done
+}
+
+// IsFinished returns true once the rotated file has been entirely read and forwarded,
+// or the tailer has been stopped.
+func (t *ArchiveTailer) IsFinished() bool {
+	return t.isFinished.Load()
+}
+
+func (t *ArchiveTailer) readAll(reader io.ReadCloser) {
+	defer func() {
+		reader.Close()
+		t.decoder.Stop()
+	}()
+
+	for {
+		inBuf := make([]byte, 4096)
+		n, err := reader.Read(inBuf)
+		if n > 0 {
+			t.file.Source.UnderlyingSource().BytesRead.Add(int64(n))
+			select {
+			case t.decoder.InputChan <- decoder.NewInput(inBuf[:n]):
+			case <-t.stop:
+				return
+			}
+		}
+		if err == io.EOF {
+			log.Infof("Finished reading the rotated file %s", t.file.Path)
+			return
+		}
+		if err != nil {
+			log.Warnf("Unable to read the rotated file %s: %v", t.file.Path, err)
+			return
+		}
+	}
+}
+
+func (t *ArchiveTailer) forwardMessages() {
+	defer func() {
+		t.isFinished.Store(true)
+		close(t.done)
+	}()
+	for output := range t.decoder.OutputChan {
+		t.decodedOffset += int64(output.RawDataLen)
+		// drop the lines already sent and the empty lines
+		if t.decodedOffset <= t.skipUntil || len(output.GetContent()) == 0 {
+			continue
+		}
+
+		origin := message.NewOrigin(t.file.Source.UnderlyingSource())
+		origin.Identifier = t.identifier
+		origin.Offset = strconv.FormatInt(t.decodedOffset, 10)
+		origin.SetTags(append(append([]string{}, t.tags...), output.ParsingExtra.Tags...))
+
+		msg := message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp)
+		msg.ServerlessExtra.Timestamp = output.ServerlessExtra.Timestamp
+		select {
+		case t.outputChan <- msg:
+		case <-t.forwardContext.Done():
+		}
+	}
+}
+
+// openArchive opens a rotated file, decompressing it when it is a gzip or zstd archive.
+func openArchive(path string) (io.ReadCloser, error) {
+	f, err := filesystem.OpenShared(path)
+	if err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewReader(f)
+	magic, _ := buffered.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &archiveReader{Reader: gz, closers: []io.Closer{gz, f}}, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr := zstd.NewReader(buffered)
+		return &archiveReader{Reader: zr, closers: []io.Closer{zr, f}}, nil
+	default:
+		return &archiveReader{Reader: buffered, closers: []io.Closer{f}}, nil
+	}
+}
+
+// archiveReader closes the decompressor along with the underlying file.
+type archiveReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *archiveReader) Close() error {
+	var err error
+	for _, closer := range r.closers {
+		if closeErr := closer.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
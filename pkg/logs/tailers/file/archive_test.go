@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DataDog/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+const archiveContent = "first line\nsecond line\nthird line\n"
+
+func writeArchive(t *testing.T, path string, compress func([]byte) []byte) {
+	require.NoError(t, os.WriteFile(path, compress([]byte(archiveContent)), 0600))
+}
+
+func gzipped(content []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(content) //nolint:errcheck
+	w.Close()
+	return buf.Bytes()
+}
+
+func zstded(content []byte) []byte {
+	compressed, _ := zstd.Compress(nil, content)
+	return compressed
+}
+
+func plain(content []byte) []byte {
+	return content
+}
+
+func readArchive(t *testing.T, path string, offset int64) []*message.Message {
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+	file := NewFile(path, source, false)
+	outputChan := make(chan *message.Message, 10)
+	archiveTailer := NewArchiveTailer(file, "archive:test", outputChan, decoder.NewDecoderFromSource(file.Source, status.NewInfoRegistry()))
+	require.NoError(t, archiveTailer.Start(offset))
+	<-archiveTailer.done
+	assert.True(t, archiveTailer.IsFinished())
+
+	close(outputChan)
+	var messages []*message.Message
+	for msg := range outputChan {
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func TestArchiveTailerReadsCompressedFiles(t *testing.T) {
+	for name, compress := range map[string]func([]byte) []byte{"plain": plain, "gzip": gzipped, "zstd": zstded} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log.2")
+			writeArchive(t, path, compress)
+
+			messages := readArchive(t, path, 0)
+			require.Len(t, messages, 3)
+			assert.Equal(t, "first line", string(messages[0].GetContent()))
+			assert.Equal(t, "third line", string(messages[2].GetContent()))
+			assert.Equal(t, "archive:test", messages[2].Origin.Identifier)
+			assert.Equal(t, "34", messages[2].Origin.Offset)
+			assert.Contains(t, messages[0].Origin.Tags(nil), "filename:app.log.2")
+		})
+	}
+}
+
+func TestArchiveTailerSkipsLinesBeforeOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.2.gz")
+	writeArchive(t, path, gzipped)
+
+	messages := readArchive(t, path, int64(len("first line\n")))
+	require.Len(t, messages, 2)
+	assert.Equal(t, "second line", string(messages[0].GetContent()))
+	assert.Equal(t, "23", messages[0].Origin.Offset)
+}
+
+func TestArchiveIdentifierDoesNotDependOnCompression(t *testing.T) {
+	dir := t.TempDir()
+	writeArchive(t, filepath.Join(dir, "app.log.1"), plain)
+	writeArchive(t, filepath.Join(dir, "app.log.2.gz"), gzipped)
+	writeArchive(t, filepath.Join(dir, "app.log.2.zst"), zstded)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.log.1"), []byte("other\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty.log.1"), nil, 0600))
+
+	identifier, err := ArchiveIdentifier(filepath.Join(dir, "app.log.1"), 0)
+	require.NoError(t, err)
+	for _, name := range []string{"app.log.2.gz", "app.log.2.zst"} {
+		other, err := ArchiveIdentifier(filepath.Join(dir, name), 0)
+		require.NoError(t, err)
+		assert.Equal(t, identifier, other, name)
+	}
+
+	other, err := ArchiveIdentifier(filepath.Join(dir, "other.log.1"), 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, identifier, other)
+
+	_, err = ArchiveIdentifier(filepath.Join(dir, "empty.log.1"), 0)
+	assert.Error(t, err)
+}
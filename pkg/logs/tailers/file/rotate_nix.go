@@ -21,6 +21,7 @@ import (
 // - renamed and recreated
 // - removed and recreated
 // - truncated
+// - truncated and rewritten, which is detected by the fingerprint of the file when it is enabled
 func (t *Tailer) DidRotate() (bool, error) {
 	f, err := filesystem.OpenShared(t.fullpath)
 	if err != nil {
@@ -50,5 +51,8 @@ func (t *Tailer) DidRotate() (bool, error) {
 		log.Debugf("File rotation detected due to size change, lastReadOffset=%d, fileSize=%d", lastReadOffset, fileSize)
 	}
 
-	return recreated || truncated, nil
+	if recreated || truncated {
+		return true, nil
+	}
+	return t.didFingerprintChange(), nil
 }
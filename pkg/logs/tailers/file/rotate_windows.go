@@ -17,7 +17,7 @@ import (
 // DidRotate returns true if the file has been log-rotated.
 //
 // On Windows, log rotation is identified by the file size being smaller
-// than the last offset read.
+// than the last offset read, or by the fingerprint of the file when it is enabled.
 func (t *Tailer) DidRotate() (bool, error) {
 	f, err := filesystem.OpenShared(t.fullpath)
 	if err != nil {
@@ -43,5 +43,5 @@ func (t *Tailer) DidRotate() (bool, error) {
 		return true, nil
 	}
 
-	return false, nil
+	return t.didFingerprintChange(), nil
 }
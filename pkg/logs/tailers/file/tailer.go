@@ -23,6 +23,7 @@ import (
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/tag"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/util"
@@ -57,6 +58,13 @@ type Tailer struct {
 	// fullpath is the absolute path to file.Path.
 	fullpath string
 
+	// fingerprint identifies the file by its first fingerprintSize bytes when
+	// logs_config.fingerprint_size_bytes is set and the file is long enough, it is
+	// used as the registry identifier and to detect rotations. Files starting with the
+	// same fingerprintSize bytes, e.g. a common header, share the same fingerprint.
+	fingerprint     string
+	fingerprintSize int
+
 	// osFile is the os.File object from which log data is read.  The read implementation
 	// is platform-specific.
 	osFile *os.File
@@ -151,6 +159,15 @@ func NewTailer(opts *TailerOptions) *Tailer {
 	forwardContext, stopForward := context.WithCancel(context.Background())
 	closeTimeout := coreConfig.Datadog().GetDuration("logs_config.close_timeout") * time.Second
 	windowsOpenFileTimeout := coreConfig.Datadog().GetDuration("logs_config.windows_open_file_timeout") * time.Second
+	fingerprintSize := coreConfig.Datadog().GetInt("logs_config.fingerprint_size_bytes")
+	var fingerprint string
+	if fingerprintSize > 0 {
+		if identifier, ok, err := auditor.FileFingerprint(opts.File.Path, fingerprintSize); err != nil {
+			log.Debugf("Unable to fingerprint %s, it is identified by its path: %v", opts.File.Path, err)
+		} else if ok {
+			fingerprint = identifier
+		}
+	}
 
 	bytesRead := status.NewCountInfo("Bytes Read")
 	fileRotated := opts.Rotated
@@ -172,6 +189,8 @@ func NewTailer(opts *TailerOptions) *Tailer {
 		sleepDuration:          opts.SleepDuration,
 		closeTimeout:           closeTimeout,
 		windowsOpenFileTimeout: windowsOpenFileTimeout,
+		fingerprint:            fingerprint,
+		fingerprintSize:        fingerprintSize,
 		stop:                   make(chan struct{}, 1),
 		done:                   make(chan struct{}, 1),
 		forwardContext:         forwardContext,
@@ -213,8 +232,24 @@ func (t *Tailer) NewRotatedTailer(file *File, decoder *decoder.Decoder, info *st
 	return NewTailer(options)
 }
 
-// Identifier returns a string that identifies this tailer in the registry.
+// Identifier returns a string that identifies this tailer in the registry: the fingerprint of
+// the file when fingerprinting is enabled and the file is long enough, its path otherwise.
 func (t *Tailer) Identifier() string {
+	if t.fingerprint != "" {
+		return t.fingerprint
+	}
+	return t.PathIdentifier()
+}
+
+// FingerprintSize returns the number of bytes used to identify the files by their content, 0 when
+// fingerprinting is disabled.
+func (t *Tailer) FingerprintSize() int {
+	return t.fingerprintSize
+}
+
+// PathIdentifier returns the identifier of the file based on its path, the only identifier
+// used when fingerprinting is disabled.
+func (t *Tailer) PathIdentifier() string {
 	// FIXME(remy): during container rotation, this Identifier() method could return
 	// the same value for different tailers. It is happening during container rotation
 	// where the dead container still has a tailer running on the log file, and the tailer
@@ -279,6 +314,25 @@ func (t *Tailer) StopAfterFileRotation() {
 	t.file.Source.RemoveInput(t.file.Path)
 }
 
+// didFingerprintChange returns true if the file at the path of the tailer doesn't start with
+// the bytes it had when the tailer was created, which happens when it is truncated and rewritten
+// or replaced by a file reusing the same inode.
+func (t *Tailer) didFingerprintChange() bool {
+	if t.fingerprint == "" {
+		return false
+	}
+	fingerprint, ok, err := auditor.FileFingerprint(t.fullpath, t.fingerprintSize)
+	if err != nil || !ok {
+		// the file is shorter than the fingerprint, a truncation is detected by its size
+		return false
+	}
+	if fingerprint != t.fingerprint {
+		log.Debugf("File rotation detected due to fingerprint change, previous: %s, current: %s", t.fingerprint, fingerprint)
+		return true
+	}
+	return false
+}
+
 // readForever lets the tailer tail the content of a file
 // until it is closed or the tailer is stopped.
 func (t *Tailer) readForever() {
@@ -360,9 +414,12 @@ func (t *Tailer) forwardMessages() {
 		// after a file rotation when it is stuck on it.
 		// We don't return directly to keep the same shutdown sequence that in the
 		// normal case.
-		select {
 		// XXX(remy): is it ok recreating a message like this here?
-		case t.outputChan <- message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp):
+		msg := message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp)
+		// keep the timestamp extracted by the parser of the log_format of the source
+		msg.ServerlessExtra.Timestamp = output.ServerlessExtra.Timestamp
+		select {
+		case t.outputChan <- msg:
 		case <-t.forwardContext.Done():
 		}
 	}
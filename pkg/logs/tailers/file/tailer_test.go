@@ -252,6 +252,67 @@ func (suite *TailerTestSuite) TestTailerIdentifier() {
 		suite.tailer.Identifier())
 }
 
+func (suite *TailerTestSuite) TestTailerIdentifierWithFingerprint() {
+	coreConfig.Datadog().SetWithoutSource("logs_config.fingerprint_size_bytes", 16)
+	defer coreConfig.Datadog().SetWithoutSource("logs_config.fingerprint_size_bytes", 0)
+	suite.tailer.StartFromBeginning()
+
+	newTailer := func() *Tailer {
+		info := status.NewInfoRegistry()
+		return NewTailer(&TailerOptions{
+			OutputChan:    make(chan *message.Message, chanSize),
+			File:          NewFile(suite.testPath, suite.source.UnderlyingSource(), false),
+			SleepDuration: 10 * time.Millisecond,
+			Decoder:       decoder.NewDecoderFromSource(suite.source, info),
+			Info:          info,
+		})
+	}
+
+	// too short to be fingerprinted
+	_, err := suite.testFile.WriteString("short\n")
+	suite.Nil(err)
+	tailer := newTailer()
+	suite.Equal(tailer.PathIdentifier(), tailer.Identifier())
+
+	_, err = suite.testFile.WriteString("long enough to be fingerprinted\n")
+	suite.Nil(err)
+	tailer = newTailer()
+	suite.NotEqual(tailer.PathIdentifier(), tailer.Identifier())
+	suite.Contains(tailer.Identifier(), "fingerprint:")
+	suite.Equal(16, tailer.FingerprintSize())
+}
+
+func (suite *TailerTestSuite) TestDidRotateWhenFingerprintChanges() {
+	coreConfig.Datadog().SetWithoutSource("logs_config.fingerprint_size_bytes", 16)
+	defer coreConfig.Datadog().SetWithoutSource("logs_config.fingerprint_size_bytes", 0)
+	suite.tailer.StartFromBeginning()
+
+	_, err := suite.testFile.WriteString("first version of the file\n")
+	suite.Nil(err)
+	outputChan := make(chan *message.Message, chanSize)
+	info := status.NewInfoRegistry()
+	tailer := NewTailer(&TailerOptions{
+		OutputChan:    outputChan,
+		File:          NewFile(suite.testPath, suite.source.UnderlyingSource(), false),
+		SleepDuration: 10 * time.Millisecond,
+		Decoder:       decoder.NewDecoderFromSource(suite.source, info),
+		Info:          info,
+	})
+	suite.Nil(tailer.StartFromBeginning())
+	defer tailer.Stop()
+	<-outputChan
+
+	didRotate, err := tailer.DidRotate()
+	suite.Nil(err)
+	suite.False(didRotate)
+
+	// truncated and rewritten with more data than already read before the next scan
+	suite.Nil(os.WriteFile(suite.testPath, []byte("second version of the file, longer than the first one\n"), 0644))
+	didRotate, err = tailer.DidRotate()
+	suite.Nil(err)
+	suite.True(didRotate)
+}
+
 func (suite *TailerTestSuite) TestOriginTagsWhenTailingFiles() {
 
 	suite.tailer.StartFromBeginning()
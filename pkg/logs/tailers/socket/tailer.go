@@ -12,9 +12,12 @@ import (
 	"net"
 	"strings"
 
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	coreConfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/framer"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/noop"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/syslog"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
@@ -40,13 +43,27 @@ func NewTailer(source *sources.LogSource, conn net.Conn, outputChan chan *messag
 		Conn:       conn,
 		outputChan: outputChan,
 		read:       read,
-		// tailer info is currently unused for this tailer type.
-		decoder: decoder.InitializeDecoder(sources.NewReplaceableSource(source), noop.New(), status.NewInfoRegistry()),
-		stop:    make(chan struct{}, 1),
-		done:    make(chan struct{}, 1),
+		decoder:    newDecoder(source),
+		stop:       make(chan struct{}, 1),
+		done:       make(chan struct{}, 1),
 	}
 }
 
+// newDecoder returns the decoder of the format of the source.
+func newDecoder(source *sources.LogSource) *decoder.Decoder {
+	// tailer info is currently unused for this tailer type.
+	if source.Config.Format != config.SyslogFormat {
+		return decoder.InitializeDecoder(sources.NewReplaceableSource(source), noop.New(), status.NewInfoRegistry())
+	}
+	// each UDP datagram holds a single syslog message, while the messages can be
+	// octet-counted over TCP
+	framing := framer.NoFraming
+	if source.Config.Type == config.TCPType {
+		framing = framer.SyslogOctetCounting
+	}
+	return decoder.NewDecoderWithFraming(sources.NewReplaceableSource(source), syslog.New(), framing, nil, status.NewInfoRegistry())
+}
+
 // Start prepares the tailer to read and decode data from the connection
 func (t *Tailer) Start() {
 	go t.forwardMessages()
@@ -71,7 +88,9 @@ func (t *Tailer) forwardMessages() {
 		if len(output.GetContent()) > 0 {
 			origin := message.NewOrigin(t.source)
 			origin.SetTags(output.ParsingExtra.Tags)
-			t.outputChan <- message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp)
+			msg := message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp)
+			msg.Hostname = output.Hostname
+			t.outputChan <- msg
 		}
 	}
 }
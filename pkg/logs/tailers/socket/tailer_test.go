@@ -40,6 +40,31 @@ func TestReadAndForwardShouldSucceedWithSuccessfulRead(t *testing.T) {
 	tailer.Stop()
 }
 
+func TestReadAndForwardSyslogMessages(t *testing.T) {
+	msgChan := make(chan *message.Message)
+	r, w := net.Pipe()
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.TCPType, Format: config.SyslogFormat, Tags: []string{"env:prod"}})
+	tailer := NewTailer(source, r, msgChan, read)
+	tailer.Start()
+
+	// octet-counted frames can contain newlines
+	w.Write([]byte("45 <13>1 - router01 - - - [meta seq=\"7\"] up\ndown"))
+	msg := <-msgChan
+	assert.Equal(t, "up\ndown", string(msg.GetContent()))
+	assert.Equal(t, message.StatusNotice, msg.Status)
+	assert.Equal(t, "router01", msg.Hostname)
+	assert.Subset(t, msg.Tags(), []string{"env:prod", "syslog_facility:user", "meta.seq:7"})
+
+	// newline-terminated frames are supported as well
+	w.Write([]byte("<11>Feb  5 17:32:18 router02 link: down\n"))
+	msg = <-msgChan
+	assert.Equal(t, "down", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.Status)
+	assert.Equal(t, "router02", msg.Hostname)
+
+	tailer.Stop()
+}
+
 func TestReadShouldFailWithError(t *testing.T) {
 	msgChan := make(chan *message.Message)
 	r, w := net.Pipe()
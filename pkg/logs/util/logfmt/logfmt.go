@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package logfmt splits logfmt lines into their key-value pairs. It is shared by the logfmt
+// parser of the decoder and by the remapping rules of the processor.
+package logfmt
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Pair is a key and its value, in the order of the line.
+type Pair struct {
+	Key   string
+	Value string
+}
+
+// Parse splits a logfmt line (e.g. `level=info msg="hello world" took=3ms dry_run`) into its
+// key-value pairs. Keys without a value, such as `dry_run`, get an empty value. The line must
+// start with a key=value pair so that the lines made of words are not taken for bare keys.
+func Parse(content []byte) ([]Pair, error) {
+	var pairs []Pair
+	line := string(bytes.TrimSpace(content))
+	for len(line) > 0 {
+		end := strings.IndexAny(line, "= ")
+		if end == 0 {
+			return nil, fmt.Errorf("invalid logfmt pair in %q", line)
+		}
+		if end < 0 || line[end] == ' ' {
+			if len(pairs) == 0 {
+				return nil, fmt.Errorf("invalid logfmt line %q, it doesn't start with a key=value pair", line)
+			}
+			if end < 0 {
+				end = len(line)
+			}
+			pairs = append(pairs, Pair{Key: line[:end]})
+			line = strings.TrimLeft(line[end:], " ")
+			continue
+		}
+		key := line[:end]
+		line = line[end+1:]
+
+		var value string
+		if strings.HasPrefix(line, `"`) {
+			closing, escaped := 1, false
+			for ; closing < len(line); closing++ {
+				if escaped {
+					escaped = false
+				} else if line[closing] == '\\' {
+					escaped = true
+				} else if line[closing] == '"' {
+					break
+				}
+			}
+			if closing == len(line) {
+				return nil, fmt.Errorf("unterminated quoted value for key %s", key)
+			}
+			unquoted, err := strconv.Unquote(line[:closing+1])
+			if err != nil {
+				return nil, err
+			}
+			value, line = unquoted, line[closing+1:]
+		} else if space := strings.IndexByte(line, ' '); space >= 0 {
+			value, line = line[:space], line[space:]
+		} else {
+			value, line = line, ""
+		}
+		pairs = append(pairs, Pair{Key: key, Value: value})
+		line = strings.TrimLeft(line, " ")
+	}
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("empty logfmt line")
+	}
+	return pairs, nil
+}
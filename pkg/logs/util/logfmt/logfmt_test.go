@@ -0,0 +1,33 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package logfmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	pairs, err := Parse([]byte(`  level=info msg="hello \"world\"" empty= took=3ms dry_run  verbose`))
+	require.NoError(t, err)
+	assert.Equal(t, []Pair{
+		{Key: "level", Value: "info"},
+		{Key: "msg", Value: `hello "world"`},
+		{Key: "empty", Value: ""},
+		{Key: "took", Value: "3ms"},
+		{Key: "dry_run", Value: ""},
+		{Key: "verbose", Value: ""},
+	}, pairs)
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, line := range []string{``, `   `, `=value`, `level=info =value`, `msg="unterminated`, `msg="bad \q escape"`, `Starting the server`, `listening on port=8080`} {
+		_, err := Parse([]byte(line))
+		assert.Error(t, err, line)
+	}
+}
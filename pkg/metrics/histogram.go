@@ -10,6 +10,8 @@ import (
 	"sort"
 	"strconv"
 
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+
 	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
@@ -27,11 +29,21 @@ func (w weightSamples) Less(i, j int) bool { return w[i].value < w[j].value }
 func (w weightSamples) Swap(i, j int)      { w[i], w[j] = w[j], w[i] }
 
 // Histogram tracks the distribution of samples added over one flush period
+//
+// By default the samples are kept in memory until the flush, which gives exact
+// percentiles. When `histogram_use_sketch` is enabled, the samples are inserted
+// in a sketch instead: its memory is bounded by the number of bins of the sketch
+// (4096 at most) whatever the number of samples, and the median and percentiles
+// have a relative error of about 1/128 (~0.8%), the relative width of the bins
+// of the sketch. Once the samples span more than 4096 bins, the lowest bins are
+// merged together and the percentiles falling in them lose that guarantee. The
+// max, min, avg, sum and count aggregates stay exact.
 type Histogram struct {
 	aggregates  []string // aggregates configured on this histogram
 	percentiles []int    // percentiles configured on this histogram, each in the 1-100 range
 	interval    int64    // interval over which the `count` value is normalized (bucket interval for Dogstatsd, 1 otherwise)
 	samples     weightSamples
+	sketch      *quantile.Agent // used instead of samples when the histogram is backed by a sketch
 	sum         float64
 	count       int64
 }
@@ -48,6 +60,7 @@ const (
 var (
 	defaultAggregates  = []string(nil)
 	defaultPercentiles = []int(nil)
+	defaultUseSketch   = (*bool)(nil)
 )
 
 type histogramPercentilesConfig struct {
@@ -90,12 +103,20 @@ func NewHistogram(interval int64, config pkgconfigmodel.Config) *Histogram {
 			sort.Ints(defaultPercentiles)
 		}
 	}
+	if defaultUseSketch == nil {
+		useSketch := config.GetBool("histogram_use_sketch")
+		defaultUseSketch = &useSketch
+	}
 
-	return &Histogram{
+	h := &Histogram{
 		interval:    interval,
 		aggregates:  defaultAggregates,
 		percentiles: defaultPercentiles,
 	}
+	if *defaultUseSketch {
+		h.sketch = &quantile.Agent{}
+	}
+	return h
 }
 
 func (h *Histogram) configure(aggregates []string, percentiles []int) {
@@ -111,12 +132,20 @@ func (h *Histogram) addSample(sample *MetricSample, _ float64) {
 		rate = 1
 	}
 
-	h.samples = append(h.samples, weightSample{sample.Value, int64(1 / rate)}) // add value and its weight
+	if h.sketch != nil {
+		h.sketch.Insert(sample.Value, rate)
+	} else {
+		h.samples = append(h.samples, weightSample{sample.Value, int64(1 / rate)}) // add value and its weight
+	}
 	h.sum += sample.Value * (1 / rate)
 	h.count += int64(1 / rate)
 }
 
 func (h *Histogram) flush(timestamp float64) ([]*Serie, error) {
+	if h.sketch != nil {
+		return h.flushSketch(timestamp)
+	}
+
 	if len(h.samples) == 0 {
 		return []*Serie{}, NoSerieError{}
 	}
@@ -196,6 +225,60 @@ func (h *Histogram) flush(timestamp float64) ([]*Serie, error) {
 	return series, nil
 }
 
+// flushSketch computes the aggregates and percentiles from the sketch.
+func (h *Histogram) flushSketch(timestamp float64) ([]*Serie, error) {
+	sketch := h.sketch.Finish()
+	if sketch == nil {
+		return []*Serie{}, NoSerieError{}
+	}
+
+	series := make([]*Serie, 0, len(h.aggregates)+len(h.percentiles))
+
+	for _, aggregate := range h.aggregates {
+		var value float64
+		mType := APIGaugeType
+		switch aggregate {
+		case maxAgg:
+			value = sketch.Basic.Max
+		case minAgg:
+			value = sketch.Basic.Min
+		case medianAgg:
+			value = sketch.Quantile(quantile.Default(), 0.5)
+		case avgAgg:
+			value = h.sum / float64(h.count)
+		case sumAgg:
+			value = h.sum
+		case countAgg:
+			value = float64(h.count) / float64(h.interval)
+			mType = APIRateType
+		default:
+			log.Infof("Configured aggregate '%s' is not implemented, skipping", aggregate)
+			continue
+		}
+
+		series = append(series, &Serie{
+			Points:     []Point{{Ts: timestamp, Value: value}},
+			MType:      mType,
+			NameSuffix: "." + aggregate,
+		})
+	}
+
+	for _, percentile := range h.percentiles {
+		series = append(series, &Serie{
+			Points:     []Point{{Ts: timestamp, Value: sketch.Quantile(quantile.Default(), float64(percentile)/100)}},
+			MType:      APIGaugeType,
+			NameSuffix: fmt.Sprintf(".%dpercentile", percentile),
+		})
+	}
+
+	// reset histogram
+	h.sketch.Reset()
+	h.sum = 0
+	h.count = 0
+
+	return series, nil
+}
+
 func (h *Histogram) isStateful() bool {
 	return false
 }
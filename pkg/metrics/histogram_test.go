@@ -274,6 +274,100 @@ func TestHistogramReset(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+// sketchEpsilon is the relative error of the percentiles computed from a sketch
+const sketchEpsilon = 1.0 / 64
+
+func TestHistogramUseSketchCached(t *testing.T) {
+	cfg := setupConfig()
+	cfg.SetWithoutSource("histogram_use_sketch", true)
+	defaultUseSketch = nil
+	defer func() { defaultUseSketch = nil }()
+	require.NotNil(t, NewHistogram(10, cfg).sketch)
+
+	// the setting is read on the first histogram creation only
+	cfg.SetWithoutSource("histogram_use_sketch", false)
+	assert.NotNil(t, NewHistogram(10, cfg).sketch)
+}
+
+func TestHistogramSketchSampleRate(t *testing.T) {
+	cfg := setupConfig()
+	cfg.SetWithoutSource("histogram_use_sketch", true)
+	defaultUseSketch = nil
+	defer func() { defaultUseSketch = nil }()
+	mHistogram := NewHistogram(10, cfg)
+	require.NotNil(t, mHistogram.sketch)
+	mHistogram.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []int{20, 95, 80})
+
+	mHistogram.addSample(&MetricSample{Value: 1}, 50)
+	mHistogram.addSample(&MetricSample{Value: 2, SampleRate: 0.5}, 50)
+	mHistogram.addSample(&MetricSample{Value: 3, SampleRate: 0.2}, 50)
+	mHistogram.addSample(&MetricSample{Value: 10, SampleRate: 0.5}, 50)
+
+	series, err := mHistogram.flush(60)
+	assert.Nil(t, err)
+	require.Len(t, series, 9)
+
+	assert.Empty(t, mHistogram.samples)
+	assert.InEpsilon(t, 10, series[0].Points[0].Value, epsilon)       // max
+	assert.Equal(t, ".max", series[0].NameSuffix)                     // max
+	assert.InEpsilon(t, 1, series[1].Points[0].Value, epsilon)        // min
+	assert.Equal(t, ".min", series[1].NameSuffix)                     // min
+	assert.InEpsilon(t, 3, series[2].Points[0].Value, sketchEpsilon)  // median
+	assert.Equal(t, ".median", series[2].NameSuffix)                  // median
+	assert.InEpsilon(t, 4, series[3].Points[0].Value, epsilon)        // avg
+	assert.Equal(t, ".avg", series[3].NameSuffix)                     // avg
+	assert.InEpsilon(t, 40, series[4].Points[0].Value, epsilon)       // sum
+	assert.Equal(t, ".sum", series[4].NameSuffix)                     // sum
+	assert.InEpsilon(t, 1, series[5].Points[0].Value, epsilon)        // count
+	assert.Equal(t, ".count", series[5].NameSuffix)                   // count
+	assert.InEpsilon(t, 2, series[6].Points[0].Value, sketchEpsilon)  // 0.20
+	assert.Equal(t, ".20percentile", series[6].NameSuffix)            // 0.20
+	assert.InEpsilon(t, 3, series[7].Points[0].Value, sketchEpsilon)  // 0.80
+	assert.Equal(t, ".80percentile", series[7].NameSuffix)            // 0.80
+	assert.InEpsilon(t, 10, series[8].Points[0].Value, sketchEpsilon) // 0.95
+	assert.Equal(t, ".95percentile", series[8].NameSuffix)            // 0.95
+
+	_, err = mHistogram.flush(61)
+	assert.NotNil(t, err)
+}
+
+func TestHistogramSketchMatchesSamples(t *testing.T) {
+	cfg := setupConfig()
+	defaultUseSketch = nil
+	defer func() { defaultUseSketch = nil }()
+	exact := NewHistogram(10, cfg)
+	cfg.SetWithoutSource("histogram_use_sketch", true)
+	defaultUseSketch = nil
+	sketched := NewHistogram(10, cfg)
+
+	aggregates := []string{"max", "min", "median", "avg", "sum", "count"}
+	percentiles := []int{50, 75, 95, 99}
+	exact.configure(aggregates, percentiles)
+	sketched.configure(aggregates, percentiles)
+
+	r := rand.New(rand.NewSource(42))
+	for i := 0; i < 100000; i++ {
+		sample := &MetricSample{Value: r.ExpFloat64() * 100}
+		exact.addSample(sample, 50)
+		sketched.addSample(sample, 50)
+	}
+
+	// the memory used by the sketch is bounded, whatever the number of samples
+	used, _ := sketched.sketch.Sketch.MemSize()
+	assert.Less(t, used, 100000*16)
+
+	exactSeries, err := exact.flush(60)
+	require.NoError(t, err)
+	sketchedSeries, err := sketched.flush(60)
+	require.NoError(t, err)
+
+	require.Len(t, sketchedSeries, len(exactSeries))
+	for i := range exactSeries {
+		assert.Equal(t, exactSeries[i].NameSuffix, sketchedSeries[i].NameSuffix)
+		assert.InEpsilon(t, exactSeries[i].Points[0].Value, sketchedSeries[i].Points[0].Value, sketchEpsilon, exactSeries[i].NameSuffix)
+	}
+}
+
 //
 // Benchmark
 //
@@ -14,6 +14,7 @@ var validProducts = map[string]struct{}{
 	ProductAgentTask:                    {},
 	ProductAgentIntegrations:            {},
 	ProductAPMSampling:                  {},
+	ProductAPMRedaction:                 {},
 	ProductCWSDD:                        {},
 	ProductCWSCustom:                    {},
 	ProductCWSProfiles:                  {},
@@ -49,6 +50,8 @@ const (
 	ProductAgentTask = "AGENT_TASK"
 	// ProductAPMSampling is the apm sampling product
 	ProductAPMSampling = "APM_SAMPLING"
+	// ProductAPMRedaction is the apm redaction product, receiving the rules redacting the span tags
+	ProductAPMRedaction = "APM_REDACTION"
 	// ProductCWSDD is the cloud workload security product managed by datadog employees
 	ProductCWSDD = "CWS_DD"
 	// ProductCWSCustom is the cloud workload security product managed by datadog customers
@@ -0,0 +1,20 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package apmredaction contains data types related to APM_REDACTION config
+package apmredaction
+
+// RedactionConfig represents the redaction rules of the span tags
+type RedactionConfig struct {
+	Rules []RedactionRule `json:"rules"`
+}
+
+// RedactionRule drops, hashes or truncates the tags matching Tag on the spans matching SpanType
+type RedactionRule struct {
+	SpanType string `json:"span_type"`
+	Tag      string `json:"tag"`
+	Action   string `json:"action"`
+	Length   int    `json:"length"`
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/filters"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
 	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/recorder"
 	"github.com/DataDog/datadog-agent/pkg/trace/remoteconfighandler"
 	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
 	"github.com/DataDog/datadog-agent/pkg/trace/stats"
@@ -55,6 +56,18 @@ const (
 	tagDecisionMaker = "_dd.p.dm"
 )
 
+// Reasons of the sampling decisions, shown with the recorded traces.
+const (
+	reasonRare          = "rare"
+	reasonProbabilistic = "probabilistic"
+	reasonPriority      = "priority"
+	reasonNoPriority    = "no_priority"
+	reasonError         = "error"
+	reasonManualDrop    = "manual_drop"
+	// reasonTailSampling is followed by the name of the tail sampling policy.
+	reasonTailSampling = "tail_sampling:"
+)
+
 // TraceWriter provides a way to write trace chunks
 type TraceWriter interface {
 	// Stop stops the TraceWriter and attempts to flush whatever is left in the senders buffers.
@@ -85,13 +98,18 @@ type Agent struct {
 	ClientStatsAggregator *stats.ClientStatsAggregator
 	Blacklister           *filters.Blacklister
 	Replacer              *filters.Replacer
+	SpanFilter            *filters.SpanFilter
+	Redactor              *filters.Redactor
+	Recorder              *recorder.Recorder
 	PrioritySampler       *sampler.PrioritySampler
 	ErrorsSampler         *sampler.ErrorsSampler
 	RareSampler           *sampler.RareSampler
 	NoPrioritySampler     *sampler.NoPrioritySampler
 	ProbabilisticSampler  *sampler.ProbabilisticSampler
+	TailSampler           *sampler.TailSampler
 	EventProcessor        *event.Processor
 	TraceWriter           TraceWriter
+	OTLPTraceWriter       *writer.OTLPTraceWriter
 	StatsWriter           *writer.DatadogStatsWriter
 	RemoteConfigHandler   *remoteconfighandler.RemoteConfigHandler
 	TelemetryCollector    telemetry.TelemetryCollector
@@ -146,6 +164,8 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 		ClientStatsAggregator: stats.NewClientStatsAggregator(conf, statsWriter, statsd),
 		Blacklister:           filters.NewBlacklister(conf.Ignore["resource"]),
 		Replacer:              filters.NewReplacer(conf.ReplaceTags),
+		SpanFilter:            filters.NewSpanFilter(conf.SpanFilters),
+		Redactor:              filters.NewRedactor(conf.RedactionRules, conf.RedactionHashKey),
 		PrioritySampler:       sampler.NewPrioritySampler(conf, dynConf, statsd),
 		ErrorsSampler:         sampler.NewErrorsSampler(conf, statsd),
 		RareSampler:           sampler.NewRareSampler(conf, statsd),
@@ -158,13 +178,23 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 		conf:                  conf,
 		ctx:                   ctx,
 		DebugServer:           api.NewDebugServer(conf),
+		Recorder:              recorder.New(conf.DebugRecentTraces),
 		Statsd:                statsd,
 		Timing:                timing,
 	}
 	agnt.Receiver = api.NewHTTPReceiver(conf, dynConf, in, agnt, telemetryCollector, statsd, timing)
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf, statsd, timing)
-	agnt.RemoteConfigHandler = remoteconfighandler.New(conf, agnt.PrioritySampler, agnt.RareSampler, agnt.ErrorsSampler)
+	agnt.RemoteConfigHandler = remoteconfighandler.New(conf, agnt.PrioritySampler, agnt.RareSampler, agnt.ErrorsSampler, agnt.Redactor)
 	agnt.TraceWriter = writer.NewTraceWriter(conf, agnt.PrioritySampler, agnt.ErrorsSampler, agnt.RareSampler, telemetryCollector, statsd, timing, comp)
+	otlpWriter, err := writer.NewOTLPTraceWriter(conf, statsd, timing)
+	if err != nil {
+		log.Errorf("Failed to create the OTLP trace writer, the traces won't be exported to %s: %v", conf.OTLPExport.Endpoint, err)
+	}
+	agnt.OTLPTraceWriter = otlpWriter
+	agnt.TailSampler = sampler.NewTailSampler(conf, statsd, agnt.writeTailDecision)
+	if agnt.Recorder != nil {
+		agnt.DebugServer.AddRoute(recorder.Path, agnt.Recorder)
+	}
 	return agnt
 }
 
@@ -180,6 +210,7 @@ func (a *Agent) Run() {
 		a.ErrorsSampler,
 		a.NoPrioritySampler,
 		a.ProbabilisticSampler,
+		a.TailSampler,
 		a.EventProcessor,
 		a.OTLPReceiver,
 		a.RemoteConfigHandler,
@@ -247,7 +278,9 @@ func (a *Agent) loop() {
 	for _, stopper := range []interface{ Stop() }{
 		a.Concentrator,
 		a.ClientStatsAggregator,
+		a.TailSampler, // before the TraceWriter, to write the buffered traces
 		a.TraceWriter,
+		a.OTLPTraceWriter,
 		a.StatsWriter,
 		a.PrioritySampler,
 		a.ErrorsSampler,
@@ -300,6 +333,12 @@ func (a *Agent) Process(p *api.Payload) {
 	defer a.Timing.Since("datadog.trace_agent.internal.process_payload_ms", now)
 	ts := p.Source
 	sampledChunks := new(writer.SampledChunks)
+	// tailPayload holds the payload attributes of the chunks buffered by the
+	// TailSampler, without the chunks.
+	var tailPayload *pb.TracerPayload
+	// tailChunks are buffered by the TailSampler once their stats are computed,
+	// as the samplers modify the metrics of their root when it decides on them.
+	var tailChunks []*sampler.TailChunk
 	statsInput := stats.NewStatsInput(len(p.TracerPayload.Chunks), p.TracerPayload.ContainerID, p.ClientComputedStats, a.conf)
 
 	p.TracerPayload.Env = traceutil.NormalizeTag(p.TracerPayload.Env)
@@ -343,6 +382,28 @@ func (a *Agent) Process(p *api.Payload) {
 			continue
 		}
 
+		if !a.SpanFilter.AllowsTrace(root) {
+			log.Debugf("Trace rejected by span filters. root: %v", root)
+			ts.TracesFiltered.Inc()
+			ts.SpansFiltered.Add(tracen)
+			p.RemoveChunk(i)
+			continue
+		}
+
+		// The spans are filtered before computing the stats, so that the
+		// filtered spans are not counted anywhere.
+		filteredSpans := a.SpanFilter.Filter(chunk)
+		if len(filteredSpans) > 0 {
+			ts.SpansFiltered.Add(int64(len(filteredSpans)))
+			if len(chunk.Spans) == 0 {
+				log.Debugf("Trace rejected by span filters, all its spans were filtered. root: %v", root)
+				ts.TracesFiltered.Inc()
+				p.RemoveChunk(i)
+				continue
+			}
+			root = traceutil.GetRoot(chunk.Spans)
+		}
+
 		// Extra sanitization steps of the trace.
 		for _, span := range chunk.Spans {
 			for k, v := range a.conf.GlobalTags {
@@ -362,12 +423,19 @@ func (a *Agent) Process(p *api.Payload) {
 			}
 		}
 		a.Replacer.Replace(chunk.Spans)
+		a.Redactor.Redact(chunk.Spans)
 
 		a.setRootSpanTags(root)
 		if !p.ClientComputedTopLevel {
 			// Figure out the top-level spans now as it involves modifying the Metrics map
 			// which is not thread-safe while samplers and concentrator might modify it too.
-			traceutil.ComputeTopLevel(chunk.Spans)
+			// The filtered spans are included so that their children are not promoted
+			// to top-level.
+			if len(filteredSpans) > 0 {
+				traceutil.ComputeTopLevel(append(chunk.Spans[:len(chunk.Spans):len(chunk.Spans)], filteredSpans...))
+			} else {
+				traceutil.ComputeTopLevel(chunk.Spans)
+			}
 		}
 
 		a.setPayloadAttributes(p, root, chunk)
@@ -377,7 +445,21 @@ func (a *Agent) Process(p *api.Payload) {
 			statsInput.Traces = append(statsInput.Traces, *pt.Clone())
 		}
 
-		keep, numEvents := a.sample(now, ts, pt)
+		if a.conf.TailSamplingEnabled {
+			// The chunk is sampled along with the rest of its trace once the
+			// TailSampler decides on it, see writeTailDecision.
+			if tailPayload == nil {
+				tailPayload = payloadAttributes(p.TracerPayload)
+			}
+			tailChunks = append(tailChunks, &sampler.TailChunk{Trace: pt, Context: &tailChunkContext{payload: tailPayload, source: ts}})
+			p.RemoveChunk(i)
+			continue
+		}
+
+		// the recorded spans are the ones received, before single span sampling
+		received := pt.TraceChunk.Spans
+		keep, numEvents, reason := a.sample(now, ts, pt)
+		a.Recorder.Record(now, pt.TracerEnv, pt.TraceChunk.Priority, received, keep, reason)
 		if !keep && len(pt.TraceChunk.Spans) == 0 {
 			// The entire trace was dropped and no spans were kept.
 			p.RemoveChunk(i)
@@ -402,18 +484,107 @@ func (a *Agent) Process(p *api.Payload) {
 			sampledChunks.TracerPayload = p.TracerPayload.Cut(i)
 			i = 0
 			sampledChunks.TracerPayload.Chunks = newChunksArray(sampledChunks.TracerPayload.Chunks)
-			a.TraceWriter.WriteChunks(sampledChunks)
+			a.writeChunks(sampledChunks)
 			sampledChunks = new(writer.SampledChunks)
 		}
 	}
 	sampledChunks.TracerPayload = p.TracerPayload
 	sampledChunks.TracerPayload.Chunks = newChunksArray(p.TracerPayload.Chunks)
 	if sampledChunks.Size > 0 {
-		a.TraceWriter.WriteChunks(sampledChunks)
+		a.writeChunks(sampledChunks)
 	}
 	if len(statsInput.Traces) > 0 {
 		a.Concentrator.Add(statsInput)
 	}
+	for _, c := range tailChunks {
+		a.TailSampler.Add(now, c)
+	}
+}
+
+// writeChunks writes the sampled chunks to the TraceWriter, and to the OTLPTraceWriter
+// when the traces are exported to an OTLP endpoint.
+func (a *Agent) writeChunks(sampledChunks *writer.SampledChunks) {
+	a.OTLPTraceWriter.WriteChunks(sampledChunks)
+	a.TraceWriter.WriteChunks(sampledChunks)
+}
+
+// tailChunkContext holds what is needed to write a chunk buffered by the TailSampler.
+type tailChunkContext struct {
+	// payload holds the attributes of the payload the chunk was received in.
+	payload *pb.TracerPayload
+	source  *info.TagStats
+}
+
+// writeTailDecision writes the chunks of a trace decided by the TailSampler. The
+// traces which were not kept by a tail sampling policy go through the other
+// samplers as if they were just received.
+func (a *Agent) writeTailDecision(d sampler.TailDecision) {
+	now := time.Now()
+	// the chunks received in the same payload are written together
+	payloads := make(map[*pb.TracerPayload]*writer.SampledChunks)
+	for _, c := range d.Chunks {
+		ctx := c.Context.(*tailChunkContext)
+		pt := c.Trace
+		numEvents := 0
+		if d.Policy == "" || c.DroppedByUser() {
+			received := pt.TraceChunk.Spans
+			var (
+				keep   bool
+				reason string
+			)
+			keep, numEvents, reason = a.sample(now, ctx.source, pt)
+			a.Recorder.Record(now, pt.TracerEnv, pt.TraceChunk.Priority, received, keep, reason)
+			if !keep && len(pt.TraceChunk.Spans) == 0 {
+				continue
+			}
+		} else {
+			// the trace is still counted by the samplers so that the rates sent
+			// to the tracers are not skewed, and its events are extracted
+			_, checkAnalyticsEvents, _ := a.runSamplers(now, ctx.source, *pt)
+			if checkAnalyticsEvents {
+				numEvents = len(a.getAnalyzedEvents(pt, ctx.source))
+			}
+			c.Keep(d.Policy)
+			a.Recorder.Record(now, pt.TracerEnv, pt.TraceChunk.Priority, pt.TraceChunk.Spans, true, reasonTailSampling+d.Policy)
+		}
+
+		sampledChunks, ok := payloads[ctx.payload]
+		if !ok {
+			sampledChunks = &writer.SampledChunks{TracerPayload: payloadAttributes(ctx.payload)}
+			payloads[ctx.payload] = sampledChunks
+		}
+		sampledChunks.TracerPayload.Chunks = append(sampledChunks.TracerPayload.Chunks, pt.TraceChunk)
+		if !pt.TraceChunk.DroppedTrace {
+			a.setFirstTraceTags(pt.Root)
+			sampledChunks.SpanCount += int64(len(pt.TraceChunk.Spans))
+		}
+		sampledChunks.EventCount += int64(numEvents)
+		sampledChunks.Size += pt.TraceChunk.Msgsize()
+
+		if sampledChunks.Size > writer.MaxPayloadSize {
+			a.writeChunks(sampledChunks)
+			delete(payloads, ctx.payload)
+		}
+	}
+	for _, sampledChunks := range payloads {
+		a.writeChunks(sampledChunks)
+	}
+}
+
+// payloadAttributes returns a new payload with the attributes of p and no chunks.
+// It does not modify p, which is shared by the chunks buffered by the TailSampler.
+func payloadAttributes(p *pb.TracerPayload) *pb.TracerPayload {
+	return &pb.TracerPayload{
+		ContainerID:     p.GetContainerID(),
+		LanguageName:    p.GetLanguageName(),
+		LanguageVersion: p.GetLanguageVersion(),
+		TracerVersion:   p.GetTracerVersion(),
+		RuntimeID:       p.GetRuntimeID(),
+		Env:             p.GetEnv(),
+		Hostname:        p.GetHostname(),
+		AppVersion:      p.GetAppVersion(),
+		Tags:            p.GetTags(),
+	}
 }
 
 func (a *Agent) setPayloadAttributes(p *api.Payload, root *pb.Span, chunk *pb.TraceChunk) {
@@ -510,7 +681,7 @@ func (a *Agent) processStats(in *pb.ClientStatsPayload, lang, tracerVersion stri
 		n := 0
 		for _, b := range group.Stats {
 			a.normalizeStatsGroup(b, lang)
-			if !a.Blacklister.AllowsStat(b) {
+			if !a.Blacklister.AllowsStat(b) || !a.SpanFilter.AllowsStat(b) {
 				continue
 			}
 			a.obfuscateStatsGroup(b)
@@ -546,12 +717,13 @@ func (a *Agent) ProcessStats(in *pb.ClientStatsPayload, lang, tracerVersion stri
 	a.ClientStatsAggregator.In <- a.processStats(in, lang, tracerVersion)
 }
 
-// sample performs all sampling on the processedTrace modifying it as needed and returning if the trace should be kept and the number of events in the trace
-func (a *Agent) sample(now time.Time, ts *info.TagStats, pt *traceutil.ProcessedTrace) (keep bool, numEvents int) {
+// sample performs all sampling on the processedTrace modifying it as needed and returning if the trace should be kept, the number of events in the trace
+// and the reason of the decision
+func (a *Agent) sample(now time.Time, ts *info.TagStats, pt *traceutil.ProcessedTrace) (keep bool, numEvents int, reason string) {
 	// We have a `keep` that is different from pt's `DroppedTrace` field as `DroppedTrace` will be sent to intake.
 	// For example: We want to maintain the overall trace level sampling decision for a trace with Analytics Events
 	// where a trace might be marked as DroppedTrace true, but we still sent analytics events in that ProcessedTrace.
-	keep, checkAnalyticsEvents := a.traceSampling(now, ts, pt)
+	keep, checkAnalyticsEvents, reason := a.traceSampling(now, ts, pt)
 
 	var events []*pb.Span
 	if checkAnalyticsEvents {
@@ -569,7 +741,7 @@ func (a *Agent) sample(now time.Time, ts *info.TagStats, pt *traceutil.Processed
 		}
 	}
 
-	return keep, len(events)
+	return keep, len(events), reason
 }
 
 // isManualUserDrop returns true if and only if the ProcessedTrace is marked as Priority User Drop
@@ -590,11 +762,11 @@ func isManualUserDrop(pt *traceutil.ProcessedTrace) bool {
 	return dm == manualSampling
 }
 
-// traceSampling reports whether the chunk should be kept as a trace, setting "DroppedTrace" on the chunk
-func (a *Agent) traceSampling(now time.Time, ts *info.TagStats, pt *traceutil.ProcessedTrace) (keep bool, checkAnalyticsEvents bool) {
-	sampled, check := a.runSamplers(now, ts, *pt)
+// traceSampling reports whether the chunk should be kept as a trace and why, setting "DroppedTrace" on the chunk
+func (a *Agent) traceSampling(now time.Time, ts *info.TagStats, pt *traceutil.ProcessedTrace) (keep bool, checkAnalyticsEvents bool, reason string) {
+	sampled, check, reason := a.runSamplers(now, ts, *pt)
 	pt.TraceChunk.DroppedTrace = !sampled
-	return sampled, check
+	return sampled, check, reason
 }
 
 // getAnalyzedEvents returns any sampled analytics events in the ProcessedTrace
@@ -606,29 +778,29 @@ func (a *Agent) getAnalyzedEvents(pt *traceutil.ProcessedTrace, ts *info.TagStat
 }
 
 // runSamplers runs the agent's configured samplers on pt and returns the sampling decision along
-// with the sampling rate.
+// with the reason of the decision.
 //
 // The rare sampler is run first, catching all rare traces early. If the probabilistic sampler is
 // enabled, it is run on the trace, followed by the error sampler. Otherwise, If the trace has a
 // priority set, the sampling priority is used with the Priority Sampler. When there is no priority
 // set, the NoPrioritySampler is run. Finally, if the trace has not been sampled by the other
 // samplers, the error sampler is run.
-func (a *Agent) runSamplers(now time.Time, ts *info.TagStats, pt traceutil.ProcessedTrace) (keep bool, checkAnalyticsEvents bool) {
+func (a *Agent) runSamplers(now time.Time, ts *info.TagStats, pt traceutil.ProcessedTrace) (keep bool, checkAnalyticsEvents bool, reason string) {
 	// run this early to make sure the signature gets counted by the RareSampler.
 	rare := a.RareSampler.Sample(now, pt.TraceChunk, pt.TracerEnv)
 
 	if a.conf.ProbabilisticSamplerEnabled {
 		if rare {
-			return true, true
+			return true, true, reasonRare
 		}
 		if a.ProbabilisticSampler.Sample(pt.Root) {
 			pt.TraceChunk.Tags[tagDecisionMaker] = probabilitySampling
-			return true, true
+			return true, true, reasonProbabilistic
 		}
 		if traceContainsError(pt.TraceChunk.Spans) {
-			return a.ErrorsSampler.Sample(now, pt.TraceChunk.Spans, pt.Root, pt.TracerEnv), true
+			return a.ErrorsSampler.Sample(now, pt.TraceChunk.Spans, pt.Root, pt.TracerEnv), true, reasonError
 		}
-		return false, true
+		return false, true, reasonProbabilistic
 	}
 
 	priority, hasPriority := sampler.GetSamplingPriority(pt.TraceChunk)
@@ -642,31 +814,33 @@ func (a *Agent) runSamplers(now time.Time, ts *info.TagStats, pt traceutil.Proce
 		// Note that we DON'T skip single span sampling. We only do this for historical
 		// reasons and analytics events are deprecated so hopefully this can all go away someday.
 		if isManualUserDrop(&pt) {
-			return false, false
+			return false, false, reasonManualDrop
 		}
 	} else { // This path to be deleted once manualUserDrop detection is available on all tracers for P < 1.
 		if priority < 0 {
-			return false, false
+			return false, false, reasonManualDrop
 		}
 	}
 
 	if rare {
-		return true, true
+		return true, true, reasonRare
 	}
 
+	reason = reasonNoPriority
 	if hasPriority {
+		reason = reasonPriority
 		if a.PrioritySampler.Sample(now, pt.TraceChunk, pt.Root, pt.TracerEnv, pt.ClientDroppedP0sWeight) {
-			return true, true
+			return true, true, reason
 		}
 	} else if a.NoPrioritySampler.Sample(now, pt.TraceChunk.Spans, pt.Root, pt.TracerEnv) {
-		return true, true
+		return true, true, reason
 	}
 
 	if traceContainsError(pt.TraceChunk.Spans) {
-		return a.ErrorsSampler.Sample(now, pt.TraceChunk.Spans, pt.Root, pt.TracerEnv), true
+		return a.ErrorsSampler.Sample(now, pt.TraceChunk.Spans, pt.Root, pt.TracerEnv), true, reasonError
 	}
 
-	return false, true
+	return false, true, reason
 }
 
 func traceContainsError(trace pb.Trace) bool {
@@ -8,6 +8,9 @@ package agent
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"math"
@@ -25,6 +28,7 @@ import (
 
 	gzip "github.com/DataDog/datadog-agent/comp/trace/compression/impl-gzip"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/DataDog/datadog-agent/pkg/obfuscate"
@@ -35,6 +39,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/filters"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
 	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/recorder"
 	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
 	"github.com/DataDog/datadog-agent/pkg/trace/stats"
 	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
@@ -244,6 +249,252 @@ func TestProcess(t *testing.T) {
 		assert.EqualValues(2, want.SpansFiltered.Load())
 	})
 
+	t.Run("SpanFilter", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.SpanFilters = []*config.SpanFilterRule{
+			{Expression: `resource == "GET /health"`, Scope: config.SpanFilterScopeTrace},
+			{Expression: `name == "redis.command" && duration < 1ms`},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+		defer cancel()
+
+		now := time.Now()
+		newSpan := func(spanID, parentID uint64, name, resource string, duration time.Duration) *pb.Span {
+			return &pb.Span{
+				TraceID:  1,
+				SpanID:   spanID,
+				ParentID: parentID,
+				Service:  "web",
+				Name:     name,
+				Resource: resource,
+				Start:    now.Add(-time.Second).UnixNano(),
+				Duration: duration.Nanoseconds(),
+			}
+		}
+
+		want := agnt.Receiver.Stats.GetTagStats(info.Tags{})
+		assert := assert.New(t)
+
+		// the whole chunk is dropped
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpans([]*pb.Span{
+				newSpan(1, 0, "http.request", "GET /health", time.Millisecond),
+				newSpan(2, 1, "redis.command", "GET", 2*time.Millisecond),
+			})),
+			Source: want,
+		})
+		assert.EqualValues(1, want.TracesFiltered.Load())
+		assert.EqualValues(2, want.SpansFiltered.Load())
+		assert.Empty(agnt.Concentrator.(*mockConcentrator).stats)
+
+		// the fast redis commands are dropped, they don't count in the stats
+		chunk := testutil.TraceChunkWithSpans([]*pb.Span{
+			newSpan(1, 0, "http.request", "GET /users", 10*time.Millisecond),
+			newSpan(2, 1, "redis.command", "GET", 100*time.Microsecond),
+			newSpan(3, 1, "redis.command", "GET", 2*time.Millisecond),
+		})
+		chunk.Priority = int32(sampler.PriorityUserKeep)
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(chunk),
+			Source:        want,
+		})
+		assert.EqualValues(1, want.TracesFiltered.Load())
+		assert.EqualValues(3, want.SpansFiltered.Load())
+		stats := agnt.Concentrator.(*mockConcentrator).stats
+		assert.Len(stats, 1)
+		assert.Len(stats[0].Traces[0].TraceChunk.Spans, 2)
+		payloads := agnt.TraceWriter.(*mockTraceWriter).payloads
+		assert.Len(payloads, 1)
+		for _, span := range payloads[0].TracerPayload.Chunks[0].Spans {
+			assert.NotEqual(uint64(2), span.SpanID)
+		}
+	})
+
+	t.Run("SpanFilterKeepsTopLevel", func(t *testing.T) {
+		// hits returns the hits of the stats computed on the trace, by span name
+		hits := func(spanFilters []*config.SpanFilterRule) map[string]uint64 {
+			cfg := config.New()
+			cfg.Endpoints[0].APIKey = "test"
+			cfg.SpanFilters = spanFilters
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+
+			start := time.Now().Add(-time.Second).UnixNano()
+			chunk := testutil.TraceChunkWithSpans([]*pb.Span{
+				{TraceID: 1, SpanID: 1, Service: "web", Name: "http.request", Resource: "GET /users", Start: start, Duration: 10},
+				{TraceID: 1, SpanID: 2, ParentID: 1, Service: "web", Name: "db.wrapper", Resource: "query", Start: start, Duration: 5},
+				{TraceID: 1, SpanID: 3, ParentID: 2, Service: "web", Name: "db.query", Resource: "SELECT", Start: start, Duration: 2},
+			})
+			chunk.Priority = int32(sampler.PriorityUserKeep)
+			agnt.Process(&api.Payload{
+				TracerPayload: testutil.TracerPayloadWithChunk(chunk),
+				Source:        agnt.Receiver.Stats.GetTagStats(info.Tags{}),
+			})
+
+			concentrator := stats.NewConcentrator(cfg, nil, time.Now(), &statsd.NoOpClient{})
+			for _, in := range agnt.Concentrator.(*mockConcentrator).stats {
+				concentrator.Add(in)
+			}
+			hits := make(map[string]uint64)
+			for _, payload := range concentrator.Flush(true).Stats {
+				for _, bucket := range payload.Stats {
+					for _, group := range bucket.Stats {
+						hits[group.Name] += group.Hits
+					}
+				}
+			}
+			return hits
+		}
+
+		// the child of the filtered span is not promoted to top-level
+		want := hits(nil)
+		assert.Equal(t, map[string]uint64{"http.request": 1}, want)
+		assert.Equal(t, want, hits([]*config.SpanFilterRule{{Expression: `name == "db.wrapper"`}}))
+	})
+
+	t.Run("Redaction", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.RedactionHashKey = "secret"
+		cfg.RedactionRules = []*config.RedactionRule{
+			{SpanType: "web", Tag: "user.email", Action: config.RedactionActionHash},
+			{Tag: "user.*", Action: config.RedactionActionDrop},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+		defer cancel()
+
+		now := time.Now()
+		span := &pb.Span{
+			TraceID:  1,
+			SpanID:   1,
+			Resource: "GET /users",
+			Type:     "web",
+			Start:    now.Add(-time.Second).UnixNano(),
+			Duration: (500 * time.Millisecond).Nanoseconds(),
+			Meta:     map[string]string{"user.email": "jane@example.com", "user.name": "Jane"},
+		}
+
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpan(span)),
+			Source:        info.NewReceiverStats().GetTagStats(info.Tags{}),
+		})
+
+		assert := assert.New(t)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte("jane@example.com"))
+		assert.Equal(hex.EncodeToString(mac.Sum(nil)), span.Meta["user.email"])
+		assert.NotContains(span.Meta, "user.name")
+
+		// the rules can be updated through remote configuration
+		agnt.Redactor.UpdateRules(nil)
+		span = &pb.Span{
+			TraceID:  2,
+			SpanID:   1,
+			Type:     "web",
+			Start:    now.Add(-time.Second).UnixNano(),
+			Duration: (500 * time.Millisecond).Nanoseconds(),
+			Meta:     map[string]string{"user.name": "Jane"},
+		}
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpan(span)),
+			Source:        info.NewReceiverStats().GetTagStats(info.Tags{}),
+		})
+		assert.Equal("Jane", span.Meta["user.name"])
+	})
+
+	t.Run("Recorder", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.DebugRecentTraces = 10
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+		defer cancel()
+
+		now := time.Now()
+		for i, priority := range []sampler.SamplingPriority{sampler.PriorityUserKeep, sampler.PriorityUserDrop} {
+			span := &pb.Span{
+				TraceID:  uint64(i + 1),
+				SpanID:   1,
+				Service:  "web",
+				Resource: "GET /users",
+				Start:    now.Add(-time.Second).UnixNano(),
+				Duration: (500 * time.Millisecond).Nanoseconds(),
+			}
+			chunk := testutil.TraceChunkWithSpan(span)
+			chunk.Priority = int32(priority)
+			agnt.Process(&api.Payload{
+				TracerPayload: testutil.TracerPayloadWithChunk(chunk),
+				Source:        info.NewReceiverStats().GetTagStats(info.Tags{}),
+			})
+		}
+
+		traces, err := agnt.Recorder.Query(recorder.Query{Service: "web"})
+		assert := assert.New(t)
+		assert.NoError(err)
+		assert.Len(traces, 2)
+		assert.Equal(uint64(2), traces[0].TraceID)
+		assert.Equal(recorder.DecisionDropped, traces[0].Decision)
+		assert.Equal(reasonManualDrop, traces[0].Reason)
+		assert.Equal(uint64(1), traces[1].TraceID)
+		assert.Equal(recorder.DecisionKept, traces[1].Decision)
+		assert.Equal(reasonPriority, traces[1].Reason)
+		assert.Len(traces[1].Spans, 1)
+	})
+
+	t.Run("OTLPExport", func(t *testing.T) {
+		exported := make(chan ptraceotlp.ExportRequest, 1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			req := ptraceotlp.NewExportRequest()
+			require.NoError(t, req.UnmarshalProto(body))
+			exported <- req
+		}))
+		defer srv.Close()
+
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.OTLPExport.Enabled = true
+		cfg.OTLPExport.Protocol = config.OTLPExportProtocolHTTP
+		cfg.OTLPExport.Endpoint = srv.URL
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+		defer cancel()
+		require.NotNil(t, agnt.OTLPTraceWriter)
+
+		now := time.Now()
+		for i, priority := range []sampler.SamplingPriority{sampler.PriorityUserKeep, sampler.PriorityUserDrop} {
+			span := &pb.Span{
+				TraceID:  uint64(i + 1),
+				SpanID:   1,
+				Service:  "web",
+				Name:     "http.request",
+				Resource: "GET /users",
+				Start:    now.Add(-time.Second).UnixNano(),
+				Duration: (500 * time.Millisecond).Nanoseconds(),
+			}
+			chunk := testutil.TraceChunkWithSpan(span)
+			chunk.Priority = int32(priority)
+			agnt.Process(&api.Payload{
+				TracerPayload: testutil.TracerPayloadWithChunk(chunk),
+				Source:        info.NewReceiverStats().GetTagStats(info.Tags{}),
+			})
+		}
+		agnt.OTLPTraceWriter.Stop()
+
+		// only the trace kept by the samplers is exported
+		req := <-exported
+		require.Equal(t, 1, req.Traces().SpanCount())
+		span := req.Traces().ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+		assert.Equal(t, "GET /users", span.Name())
+		assert.Equal(t, "00000000000000000000000000000001", span.TraceID().String())
+		assert.Len(t, agnt.TraceWriter.(*mockTraceWriter).payloads, 1)
+	})
+
 	t.Run("Block-all", func(t *testing.T) {
 		cfg := config.New()
 		cfg.Endpoints[0].APIKey = "test"
@@ -536,6 +787,112 @@ func TestProcess(t *testing.T) {
 	})
 }
 
+func TestTailSampling(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSamplingEnabled = true
+	cfg.TailSamplingPolicies = []*config.TailSamplingPolicy{{Type: config.TailSamplingPolicyError}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+	agnt.TailSampler.Start()
+
+	withError := spansToChunk(
+		&pb.Span{Service: "a", TraceID: 1, SpanID: 1, Start: time.Now().UnixNano(), Duration: 1},
+		&pb.Span{Service: "a", TraceID: 1, SpanID: 2, ParentID: 1, Error: 1, Start: time.Now().UnixNano(), Duration: 1},
+	)
+	withError.Priority = int32(sampler.PriorityAutoDrop)
+	dropped := spansToChunk(&pb.Span{Service: "a", TraceID: 2, SpanID: 1, Start: time.Now().UnixNano(), Duration: 1})
+	dropped.Priority = int32(sampler.PriorityAutoDrop)
+	kept := spansToChunk(&pb.Span{Service: "a", TraceID: 3, SpanID: 1, Start: time.Now().UnixNano(), Duration: 1})
+	kept.Priority = int32(sampler.PriorityUserKeep)
+	autoKeptWithError := spansToChunk(&pb.Span{Service: "a", TraceID: 4, SpanID: 1, Error: 1, Start: time.Now().UnixNano(), Duration: 1})
+	autoKeptWithError.Priority = int32(sampler.PriorityAutoKeep)
+	userDroppedWithError := spansToChunk(&pb.Span{Service: "a", TraceID: 5, SpanID: 1, Error: 1, Start: time.Now().UnixNano(), Duration: 1})
+	userDroppedWithError.Priority = int32(sampler.PriorityUserDrop)
+
+	tp := testutil.TracerPayloadWithChunks([]*pb.TraceChunk{withError, dropped, kept, autoKeptWithError, userDroppedWithError})
+	tp.Hostname = "tracer-hostname"
+	agnt.Process(&api.Payload{
+		TracerPayload: tp,
+		Source:        agnt.Receiver.Stats.GetTagStats(info.Tags{}),
+	})
+	// the chunks are buffered until the decision window expires
+	assert.Empty(t, agnt.TraceWriter.(*mockTraceWriter).payloads)
+	// the concentrator gets all the traces right away
+	assert.Len(t, agnt.Concentrator.(*mockConcentrator).stats[0].Traces, 5)
+
+	agnt.TailSampler.Stop()
+	var chunks []*pb.TraceChunk
+	for _, p := range agnt.TraceWriter.(*mockTraceWriter).payloads {
+		assert.Equal(t, "tracer-hostname", p.TracerPayload.Hostname)
+		chunks = append(chunks, p.TracerPayload.Chunks...)
+	}
+	assert.ElementsMatch(t, []*pb.TraceChunk{withError, kept, autoKeptWithError}, chunks)
+	assert.False(t, withError.DroppedTrace)
+	assert.Equal(t, "error", withError.Tags[sampler.KeyTailSamplingPolicy])
+	// the traces kept by a policy are still counted by the priority sampler
+	assert.Equal(t, int32(sampler.PriorityUserKeep), autoKeptWithError.Priority)
+	assert.Contains(t, autoKeptWithError.Spans[0].Metrics, "_sampling_priority_rate_v1")
+	// the policies don't override the drops of the users
+	assert.NotContains(t, userDroppedWithError.Tags, sampler.KeyTailSamplingPolicy)
+	assert.Equal(t, int32(sampler.PriorityUserDrop), userDroppedWithError.Priority)
+	// the traces not kept by a policy go through the other samplers
+	assert.False(t, kept.DroppedTrace)
+	assert.NotContains(t, kept.Tags, sampler.KeyTailSamplingPolicy)
+}
+
+func TestTailSamplingConcurrentFlush(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSamplingEnabled = true
+	cfg.TailSamplingDecisionWait = time.Millisecond
+	// the traces are evicted by the other Process goroutines while their
+	// payload is processed
+	cfg.TailSamplingMaxBufferBytes = 1000
+	cfg.TailSamplingPolicies = []*config.TailSamplingPolicy{{Type: config.TailSamplingPolicyError}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+	// the stats are computed while the traces are decided
+	agnt.Concentrator = stats.NewConcentrator(cfg, nil, time.Now(), &statsd.NoOpClient{})
+	agnt.TailSampler.Start()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				var chunks []*pb.TraceChunk
+				for k := 0; k < 10; k++ {
+					traceID := uint64(i*1000 + j*10 + k + 1)
+					chunk := spansToChunk(
+						&pb.Span{Service: "a", TraceID: traceID, SpanID: 1, Start: time.Now().UnixNano(), Duration: 1},
+						&pb.Span{Service: "a", TraceID: traceID, SpanID: 2, ParentID: 1, Error: int32(k % 2), Start: time.Now().UnixNano(), Duration: 1},
+					)
+					chunk.Priority = int32(sampler.PriorityAutoKeep)
+					chunks = append(chunks, chunk)
+				}
+				agnt.Process(&api.Payload{
+					TracerPayload: &pb.TracerPayload{Chunks: chunks},
+					Source:        agnt.Receiver.Stats.GetTagStats(info.Tags{}),
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+	agnt.TailSampler.Stop()
+
+	var spans int
+	for _, p := range agnt.TraceWriter.(*mockTraceWriter).payloads {
+		for _, chunk := range p.TracerPayload.Chunks {
+			spans += len(chunk.Spans)
+		}
+	}
+	assert.Equal(t, 800, spans)
+}
+
 func spansToChunk(spans ...*pb.Span) *pb.TraceChunk {
 	return &pb.TraceChunk{Spans: spans, Tags: make(map[string]string)}
 }
@@ -1258,7 +1615,7 @@ func TestSampling(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			a := configureAgent(tt.agentConfig)
 			for _, tc := range tt.testCases {
-				sampled, _ := a.traceSampling(time.Now(), &info.TagStats{}, &tc.trace)
+				sampled, _, _ := a.traceSampling(time.Now(), &info.TagStats{}, &tc.trace)
 				assert.EqualValues(t, tc.wantSampled, sampled)
 			}
 		})
@@ -1337,12 +1694,12 @@ func TestSample(t *testing.T) {
 			conf:              cfg,
 		}
 		t.Run(name, func(t *testing.T) {
-			keep, _ := a.traceSampling(now, info.NewReceiverStats().GetTagStats(info.Tags{}), &tt.trace)
+			keep, _, _ := a.traceSampling(now, info.NewReceiverStats().GetTagStats(info.Tags{}), &tt.trace)
 			assert.Equal(t, tt.keep, keep)
 			assert.Equal(t, !tt.keep, tt.trace.TraceChunk.DroppedTrace)
 			cfg.Features["error_rare_sample_tracer_drop"] = struct{}{}
 			defer delete(cfg.Features, "error_rare_sample_tracer_drop")
-			keep, _ = a.traceSampling(now, info.NewReceiverStats().GetTagStats(info.Tags{}), &tt.trace)
+			keep, _, _ = a.traceSampling(now, info.NewReceiverStats().GetTagStats(info.Tags{}), &tt.trace)
 			assert.Equal(t, tt.keepWithFeature, keep)
 			assert.Equal(t, !tt.keepWithFeature, tt.trace.TraceChunk.DroppedTrace)
 		})
@@ -1373,7 +1730,7 @@ func TestSampleManualUserDropNoAnalyticsEvents(t *testing.T) {
 		EventProcessor:    newEventProcessor(cfg, statsd),
 		conf:              cfg,
 	}
-	keep, _ := a.sample(now, info.NewReceiverStats().GetTagStats(info.Tags{}), &pt)
+	keep, _, _ := a.sample(now, info.NewReceiverStats().GetTagStats(info.Tags{}), &pt)
 	assert.False(t, keep)
 	assert.Empty(t, pt.Root.Metrics["_dd.analyzed"])
 }
@@ -1387,6 +1744,8 @@ func TestPartialSamplingFree(t *testing.T) {
 		Concentrator:      &mockConcentrator{},
 		Blacklister:       filters.NewBlacklister(cfg.Ignore["resource"]),
 		Replacer:          filters.NewReplacer(cfg.ReplaceTags),
+		SpanFilter:        filters.NewSpanFilter(cfg.SpanFilters),
+		Redactor:          filters.NewRedactor(cfg.RedactionRules, cfg.RedactionHashKey),
 		NoPrioritySampler: sampler.NewNoPrioritySampler(cfg, statsd),
 		ErrorsSampler:     sampler.NewErrorsSampler(cfg, statsd),
 		PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}, statsd),
@@ -1894,6 +2253,7 @@ func TestConvertStats(t *testing.T) {
 		Blacklister: filters.NewBlacklister([]string{"blocked_resource"}),
 		obfuscator:  obfuscate.NewObfuscator(obfuscate.Config{}),
 		Replacer:    filters.NewReplacer([]*config.ReplaceRule{{Name: "http.status_code", Pattern: "400", Re: regexp.MustCompile("400"), Repl: "200"}}),
+		SpanFilter:  filters.NewSpanFilter(nil),
 		conf:        &config.AgentConfig{DefaultEnv: "agent_env", Hostname: "agent_hostname", MaxResourceLen: 5000},
 	}
 	for _, testCase := range testCases {
@@ -2001,7 +2361,7 @@ func TestSampleWithPriorityNone(t *testing.T) {
 	}
 	// before := traceutil.CopyTraceChunk(pt.TraceChunk)
 	before := pt.TraceChunk.ShallowCopy()
-	keep, numEvents := agnt.sample(time.Now(), info.NewReceiverStats().GetTagStats(info.Tags{}), &pt)
+	keep, numEvents, _ := agnt.sample(time.Now(), info.NewReceiverStats().GetTagStats(info.Tags{}), &pt)
 	assert.True(t, keep) // Score Sampler should keep the trace.
 	assert.False(t, pt.TraceChunk.DroppedTrace)
 	assert.Equal(t, before, pt.TraceChunk)
@@ -2360,7 +2720,7 @@ func TestSingleSpanPlusAnalyticsEvents(t *testing.T) {
 	var b bytes.Buffer
 	oldLogger := log.SetLogger(log.NewBufferLogger(&b))
 	defer func() { log.SetLogger(oldLogger) }()
-	keep, numEvents := traceAgent.sample(time.Now(), info.NewReceiverStats().GetTagStats(info.Tags{}), payload)
+	keep, numEvents, _ := traceAgent.sample(time.Now(), info.NewReceiverStats().GetTagStats(info.Tags{}), payload)
 	assert.Equal(t, "[WARN] Detected both analytics events AND single span sampling in the same trace. Single span sampling wins because App Analytics is deprecated.", b.String())
 	assert.False(t, keep) //The sampling decision was FALSE but the trace itself is marked as not dropped
 	assert.False(t, payload.TraceChunk.DroppedTrace)
@@ -39,6 +39,40 @@ type Endpoint struct {
 	NoProxy bool
 }
 
+const (
+	// OTLPExportProtocolGRPC exports the traces over OTLP/gRPC.
+	OTLPExportProtocolGRPC = "grpc"
+	// OTLPExportProtocolHTTP exports the traces over OTLP/HTTP, encoded in protobuf.
+	OTLPExportProtocolHTTP = "http"
+)
+
+// OTLPExport holds the configuration for exporting the sampled traces to an OTLP endpoint,
+// in addition to sending them to Datadog.
+type OTLPExport struct {
+	// Enabled specifies whether the sampled traces are exported.
+	Enabled bool
+
+	// Endpoint is the host:port of the OTLP/gRPC endpoint, or the URL of the OTLP/HTTP
+	// endpoint. The path /v1/traces is used when the URL has none.
+	Endpoint string
+
+	// Protocol is either OTLPExportProtocolGRPC or OTLPExportProtocolHTTP.
+	Protocol string
+
+	// Headers are added to the export requests, as gRPC metadata or HTTP headers.
+	Headers map[string]string
+
+	// Insecure disables TLS for the gRPC connections.
+	Insecure bool
+
+	// Timeout is the maximum duration of an export request.
+	Timeout time.Duration
+
+	// QueueSize is the maximum number of batches waiting to be retried after a
+	// failed export. The oldest ones are dropped when it's full.
+	QueueSize int
+}
+
 // TelemetryEndpointPrefix specifies the prefix of the telemetry endpoint URL.
 const TelemetryEndpointPrefix = "https://instrumentation-telemetry-intake."
 
@@ -175,6 +209,90 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// Scopes of the span filter rules.
+const (
+	// SpanFilterScopeSpan drops the matching spans.
+	SpanFilterScopeSpan = "span"
+	// SpanFilterScopeTrace drops the trace chunks whose root span matches.
+	SpanFilterScopeTrace = "trace"
+)
+
+// SpanFilterRule specifies a span filter.
+type SpanFilterRule struct {
+	// Expression selects the spans to drop, for example
+	// `service == "web" && meta["http.url"] =~ "/health" && duration < 5ms`.
+	Expression string `mapstructure:"expression"`
+
+	// Scope is either "span" (default) or "trace".
+	Scope string `mapstructure:"scope"`
+}
+
+// Actions of the redaction rules.
+const (
+	// RedactionActionDrop removes the matching tags.
+	RedactionActionDrop = "drop"
+	// RedactionActionHash replaces the values of the matching tags with their
+	// HMAC-SHA256, keyed by RedactionHashKey, so that they can still be joined.
+	RedactionActionHash = "hash"
+	// RedactionActionTruncate truncates the values of the matching tags to Length bytes.
+	RedactionActionTruncate = "truncate"
+)
+
+// RedactionRule specifies how to redact the tags of the spans, of their span
+// links and of their span events.
+type RedactionRule struct {
+	// SpanType is a glob matching the type of the spans the rule applies to. An
+	// empty SpanType matches all the spans.
+	SpanType string `mapstructure:"span_type" json:"span_type"`
+
+	// Tag is a glob matching the keys of the tags to redact, such as "user.*".
+	// The hidden tags, starting with "_", are only matched by globs starting with "_".
+	Tag string `mapstructure:"tag" json:"tag"`
+
+	// Action is one of "drop", "hash" or "truncate".
+	Action string `mapstructure:"action" json:"action"`
+
+	// Length is the number of bytes kept by the "truncate" action.
+	Length int `mapstructure:"length" json:"length"`
+}
+
+// Types of the tail sampling policies.
+const (
+	// TailSamplingPolicyError keeps the traces having a span with an error.
+	TailSamplingPolicyError = "error"
+	// TailSamplingPolicyLatency keeps the traces whose root span lasts at least ThresholdMs.
+	TailSamplingPolicyLatency = "latency"
+	// TailSamplingPolicyAttribute keeps the traces having a span with the tag Key matching Value.
+	TailSamplingPolicyAttribute = "attribute"
+	// TailSamplingPolicyProbabilistic keeps SamplingPercentage percent of the traces.
+	TailSamplingPolicyProbabilistic = "probabilistic"
+)
+
+// TailSamplingPolicy specifies a policy of the tail sampler. A trace is kept
+// when any of the policies matches it.
+type TailSamplingPolicy struct {
+	// Name identifies the policy in the tags of the kept traces. It defaults to Type.
+	Name string `mapstructure:"name"`
+
+	// Type is one of "error", "latency", "attribute" and "probabilistic".
+	Type string `mapstructure:"type"`
+
+	// ThresholdMs is the minimum duration of the root span, in milliseconds, for "latency" policies.
+	ThresholdMs float64 `mapstructure:"threshold_ms"`
+
+	// Key is the span tag looked up by "attribute" policies.
+	Key string `mapstructure:"key"`
+
+	// Value is a regexp pattern the tag must match for "attribute" policies. Any value matches when empty.
+	Value string `mapstructure:"value"`
+
+	// Re holds the compiled Value and is only used internally.
+	Re *regexp.Regexp `mapstructure:"-"`
+
+	// SamplingPercentage is the percentage (0-100) of the traces kept by "probabilistic" policies.
+	SamplingPercentage float64 `mapstructure:"sampling_percentage"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -309,6 +427,12 @@ type AgentConfig struct {
 	ProbabilisticSamplerHashSeed           uint32
 	ProbabilisticSamplerSamplingPercentage float32
 
+	// Tail Sampler configuration
+	TailSamplingEnabled        bool
+	TailSamplingDecisionWait   time.Duration
+	TailSamplingMaxBufferBytes int
+	TailSamplingPolicies       []*TailSamplingPolicy
+
 	// Receiver
 	ReceiverEnabled bool // specifies whether Receiver listeners are enabled. Unless OTLPReceiver is used, this should always be true.
 	ReceiverHost    string
@@ -364,10 +488,20 @@ type AgentConfig struct {
 	// filtering
 	Ignore map[string][]string
 
+	// SpanFilters drop the spans and the traces matching their expression.
+	SpanFilters []*SpanFilterRule
+
 	// ReplaceTags is used to filter out sensitive information from tag values.
 	// It maps tag keys to a set of replacements. Only supported in A6.
 	ReplaceTags []*ReplaceRule
 
+	// RedactionRules redact the personal data found in the tags of the spans.
+	// They can be updated through remote configuration.
+	RedactionRules []*RedactionRule
+
+	// RedactionHashKey is the secret key of the HMAC used by the "hash" redaction rules.
+	RedactionHashKey string
+
 	// GlobalTags list metadata that will be added to all spans
 	GlobalTags map[string]string
 
@@ -399,6 +533,9 @@ type AgentConfig struct {
 	// OTLPReceiver holds the configuration for OpenTelemetry receiver.
 	OTLPReceiver *OTLP
 
+	// OTLPExport holds the configuration for exporting the sampled traces to an OTLP endpoint.
+	OTLPExport *OTLPExport
+
 	// ProfilingProxy specifies settings for the profiling proxy.
 	ProfilingProxy ProfilingProxyConfig
 
@@ -437,6 +574,10 @@ type AgentConfig struct {
 	// DebugServerPort defines the port used by the debug server
 	DebugServerPort int
 
+	// DebugRecentTraces is the number of recently processed trace chunks kept,
+	// along with their sampling decision, to be served by the debug server.
+	DebugRecentTraces int
+
 	// Install Signature
 	InstallSignature InstallSignatureConfig
 
@@ -487,6 +628,9 @@ func New() *AgentConfig {
 		RareSamplerCooldownPeriod: 5 * time.Minute,
 		RareSamplerCardinality:    200,
 
+		TailSamplingDecisionWait:   10 * time.Second,
+		TailSamplingMaxBufferBytes: 50 * 1024 * 1024,
+
 		ReceiverEnabled:        true,
 		ReceiverHost:           "localhost",
 		ReceiverPort:           8126,
@@ -528,6 +672,11 @@ func New() *AgentConfig {
 			Enabled:        true,
 			MaxPayloadSize: 5 * 1024 * 1024,
 		},
+		OTLPExport: &OTLPExport{
+			Protocol:  OTLPExportProtocolGRPC,
+			Timeout:   10 * time.Second,
+			QueueSize: 100,
+		},
 
 		Features: make(map[string]struct{}),
 	}
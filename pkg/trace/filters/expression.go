@@ -0,0 +1,404 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+)
+
+// expression is a compiled span filter expression, such as:
+//
+//	service == "web" && meta["http.url"] =~ "/health" && duration < 5ms
+//
+// Expressions combine comparisons with &&, || and !, and parentheses. The left
+// side of a comparison is one of the string fields service, name, resource,
+// type and meta["key"], or one of the numeric fields duration, error and
+// metrics["key"]. The strings support ==, !=, =~ (matches the regular
+// expression) and !~, the numbers support ==, !=, <, <=, > and >=. The right
+// side is a quoted string or a number, which may be a duration such as 5ms or
+// 1.5s for the numeric fields. A comparison on a missing meta or metric never
+// matches.
+type expression interface {
+	match(span *pb.Span) bool
+	// statFields reports whether the expression only uses fields found in the
+	// client stats.
+	statFields() bool
+}
+
+type andExpression struct{ left, right expression }
+
+func (e *andExpression) match(span *pb.Span) bool { return e.left.match(span) && e.right.match(span) }
+func (e *andExpression) statFields() bool         { return e.left.statFields() && e.right.statFields() }
+
+type orExpression struct{ left, right expression }
+
+func (e *orExpression) match(span *pb.Span) bool { return e.left.match(span) || e.right.match(span) }
+func (e *orExpression) statFields() bool         { return e.left.statFields() && e.right.statFields() }
+
+type notExpression struct{ expr expression }
+
+func (e *notExpression) match(span *pb.Span) bool { return !e.expr.match(span) }
+func (e *notExpression) statFields() bool         { return e.expr.statFields() }
+
+type stringComparison struct {
+	field func(*pb.Span) (string, bool)
+	stat  bool
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+func (c *stringComparison) match(span *pb.Span) bool {
+	v, ok := c.field(span)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "==":
+		return v == c.value
+	case "!=":
+		return v != c.value
+	case "=~":
+		return c.re.MatchString(v)
+	default: // "!~"
+		return !c.re.MatchString(v)
+	}
+}
+
+func (c *stringComparison) statFields() bool { return c.stat }
+
+type numberComparison struct {
+	field func(*pb.Span) (float64, bool)
+	op    string
+	value float64
+}
+
+func (c *numberComparison) match(span *pb.Span) bool {
+	v, ok := c.field(span)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "==":
+		return v == c.value
+	case "!=":
+		return v != c.value
+	case "<":
+		return v < c.value
+	case "<=":
+		return v <= c.value
+	case ">":
+		return v > c.value
+	default: // ">="
+		return v >= c.value
+	}
+}
+
+func (c *numberComparison) statFields() bool { return false }
+
+// stringFields are the string fields of the spans, which are all found in the
+// client stats.
+var stringFields = map[string]func(*pb.Span) (string, bool){
+	"service":  func(s *pb.Span) (string, bool) { return s.Service, true },
+	"name":     func(s *pb.Span) (string, bool) { return s.Name, true },
+	"resource": func(s *pb.Span) (string, bool) { return s.Resource, true },
+	"type":     func(s *pb.Span) (string, bool) { return s.Type, true },
+}
+
+var numberFields = map[string]func(*pb.Span) (float64, bool){
+	"duration": func(s *pb.Span) (float64, bool) { return float64(s.Duration), true },
+	"error":    func(s *pb.Span) (float64, bool) { return float64(s.Error), true },
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	pos   int
+	value float64 // for numbers
+}
+
+// operators are sorted so that the longest ones are matched first.
+var operators = []string{"&&", "||", "==", "!=", "=~", "!~", "<=", ">=", "<", ">", "!", "(", ")", "[", "]"}
+
+// tokenize splits an expression into tokens.
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			value, err := strconv.Unquote(s[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %v", i, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value, pos: i})
+			i = end + 1
+		case c == '-' || c == '.' || unicode.IsDigit(c):
+			end := i + 1
+			for end < len(s) && (s[end] == '.' || unicode.IsLetter(rune(s[end])) || unicode.IsDigit(rune(s[end])) ||
+				// the sign of an exponent, e.g. 1e-3
+				((s[end] == '-' || s[end] == '+') && (s[end-1] == 'e' || s[end-1] == 'E'))) {
+				end++
+			}
+			value, err := parseNumber(s[i:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at offset %d", s[i:end], i)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: s[i:end], pos: i, value: value})
+			i = end
+		case c == '_' || unicode.IsLetter(c):
+			end := i + 1
+			for end < len(s) && (s[end] == '_' || unicode.IsLetter(rune(s[end])) || unicode.IsDigit(rune(s[end]))) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: s[i:end], pos: i})
+			i = end
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(s)}), nil
+}
+
+// parseNumber parses a number or a duration, returned in nanoseconds.
+func parseNumber(s string) (float64, error) {
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	return float64(d), nil
+}
+
+// parser is a recursive descent parser for the expressions:
+//
+//	or         = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" or ")" | comparison
+//	comparison = field operator ( string | number )
+//	field      = ident | ident "[" string "]"
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parseExpression compiles a span filter expression.
+func parseExpression(s string) (expression, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, unexpected(t)
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the given operator.
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokenOperator && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func unexpected(t token) error {
+	if t.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+}
+
+func (p *parser) parseOr() (expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpression{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expression, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpression{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (expression, error) {
+	if p.accept("!") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpression{expr: expr}, nil
+	}
+	if p.accept("(") {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, unexpected(p.peek())
+		}
+		return expr, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (expression, error) {
+	t := p.next()
+	if t.kind != tokenIdent {
+		return nil, unexpected(t)
+	}
+
+	var (
+		stringField func(*pb.Span) (string, bool)
+		numberField func(*pb.Span) (float64, bool)
+		stat        bool
+	)
+	switch t.text {
+	case "meta", "metrics":
+		if !p.accept("[") {
+			return nil, unexpected(p.peek())
+		}
+		k := p.next()
+		if k.kind != tokenString {
+			return nil, unexpected(k)
+		}
+		if !p.accept("]") {
+			return nil, unexpected(p.peek())
+		}
+		key := k.text
+		if t.text == "meta" {
+			stringField = func(s *pb.Span) (string, bool) {
+				v, ok := s.Meta[key]
+				return v, ok
+			}
+		} else {
+			numberField = func(s *pb.Span) (float64, bool) {
+				v, ok := s.Metrics[key]
+				return v, ok
+			}
+		}
+	default:
+		var ok bool
+		if stringField, ok = stringFields[t.text]; ok {
+			stat = true
+		} else if numberField, ok = numberFields[t.text]; !ok {
+			return nil, fmt.Errorf("unknown field %q at offset %d", t.text, t.pos)
+		}
+	}
+
+	op := p.next()
+	if op.kind != tokenOperator {
+		return nil, unexpected(op)
+	}
+	value := p.next()
+
+	if stringField != nil {
+		if value.kind != tokenString {
+			return nil, fmt.Errorf("%s must be compared to a string at offset %d", t.text, value.pos)
+		}
+		c := &stringComparison{field: stringField, stat: stat, op: op.text, value: value.text}
+		switch op.text {
+		case "==", "!=":
+		case "=~", "!~":
+			re, err := regexp.Compile(value.text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression at offset %d: %v", value.pos, err)
+			}
+			c.re = re
+		default:
+			return nil, fmt.Errorf("operator %q can't be used with %s at offset %d", op.text, t.text, op.pos)
+		}
+		return c, nil
+	}
+
+	if value.kind != tokenNumber {
+		return nil, fmt.Errorf("%s must be compared to a number at offset %d", t.text, value.pos)
+	}
+	switch op.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return nil, fmt.Errorf("operator %q can't be used with %s at offset %d", op.text, t.text, op.pos)
+	}
+	return &numberComparison{field: numberField, op: op.text, value: value.value}, nil
+}
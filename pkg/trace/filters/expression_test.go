@@ -0,0 +1,113 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpression(t *testing.T) {
+	span := &pb.Span{
+		Service:  "web",
+		Name:     "http.request",
+		Resource: "GET /health",
+		Type:     "web",
+		Duration: int64(3 * time.Millisecond),
+		Error:    0,
+		Meta:     map[string]string{"http.url": "http://localhost/health?verbose=1", "env": "prod"},
+		Metrics:  map[string]float64{"http.status_code": 200},
+	}
+
+	tests := []struct {
+		expr        string
+		expectation bool
+	}{
+		{`service == "web"`, true},
+		{`service != "web"`, false},
+		{`name == "http.request" && resource == "GET /health" && type == "web"`, true},
+		{`resource =~ "^GET /heal"`, true},
+		{`resource !~ "^GET /heal"`, false},
+		{`service == "web" && meta["http.url"] =~ "/health" && duration < 5ms`, true},
+		{`service == "web" && meta["http.url"] =~ "/health" && duration < 2ms`, false},
+		{`duration >= 3000000`, true},
+		{`duration >= 3e6`, true},
+		{`duration > 3E+6`, false},
+		{`metrics["http.status_code"] > 2e-3`, true},
+		{`duration > 0.003s`, false},
+		{`duration <= 3ms`, true},
+		{`error == 1`, false},
+		{`error != 1`, true},
+		{`metrics["http.status_code"] >= 200 && metrics["http.status_code"] < 300`, true},
+		{`service == "api" || meta["env"] == "prod"`, true},
+		{`service == "api" || meta["env"] == "staging"`, false},
+		{`!(service == "api")`, true},
+		{`!service == "web"`, false},
+		{`service == "api" || service == "web" && error == 1`, false},
+		{`(service == "api" || service == "web") && error == 0`, true},
+		// a comparison on a missing meta or metric never matches
+		{`meta["missing"] == ""`, false},
+		{`meta["missing"] != "x"`, false},
+		{`metrics["missing"] != 1`, false},
+		{`!(meta["missing"] == "x")`, true},
+		{`meta["http.url"] == "http://localhost/health?verbose=1"`, true},
+		{`meta["quote"] != "a \"quoted\" string"`, false},
+	}
+
+	for _, test := range tests {
+		expr, err := parseExpression(test.expr)
+		require.NoError(t, err, test.expr)
+		assert.Equal(t, test.expectation, expr.match(span), test.expr)
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{``, `unexpected end of expression`},
+		{`service`, `unexpected end of expression`},
+		{`service ==`, `service must be compared to a string at offset 10`},
+		{`service == 1`, `service must be compared to a string at offset 11`},
+		{`duration < "5ms"`, `duration must be compared to a number at offset 11`},
+		{`duration =~ 5ms`, `operator "=~" can't be used with duration at offset 9`},
+		{`service < "web"`, `operator "<" can't be used with service at offset 8`},
+		{`host == "web"`, `unknown field "host" at offset 0`},
+		{`meta.env == "prod"`, `invalid number ".env" at offset 4`},
+		{`meta[env] == "prod"`, `unexpected "env" at offset 5`},
+		{`service == "web`, `unterminated string at offset 11`},
+		{`resource =~ "("`, "invalid regular expression at offset 12: error parsing regexp: missing closing ): `(`"},
+		{`duration < 5parsecs`, `invalid number "5parsecs" at offset 11`},
+		{`(service == "web"`, `unexpected end of expression`},
+		{`service == "web")`, `unexpected ")" at offset 16`},
+		{`service == "web" & error == 1`, `unexpected character '&' at offset 17`},
+	}
+
+	for _, test := range tests {
+		_, err := parseExpression(test.expr)
+		assert.EqualError(t, err, test.err, test.expr)
+	}
+}
+
+func TestExpressionStatFields(t *testing.T) {
+	for expr, expectation := range map[string]bool{
+		`service == "web" && resource =~ "health"`:   true,
+		`!(name == "a" || type == "b")`:              true,
+		`service == "web" && meta["env"] == "prod"`:  false,
+		`service == "web" || duration < 5ms`:         false,
+		`service == "web" && !(metrics["a"] == 1.5)`: false,
+	} {
+		e, err := parseExpression(expr)
+		require.NoError(t, err, expr)
+		assert.Equal(t, expectation, e.statFields(), expr)
+	}
+}
@@ -0,0 +1,265 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/tinylib/msgp/msgp"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+// eventsKey is the meta holding the span events, encoded in JSON by the tracers
+// and by the OTLP receiver.
+const eventsKey = "events"
+
+// Redactor is a filter which drops, hashes or truncates the tags of the spans,
+// of their span links and of their span events based on its rules. It keeps
+// all spans. Its rules can be updated while it is in use.
+type Redactor struct {
+	key   []byte
+	rules atomic.Pointer[[]*redactionRule]
+}
+
+type redactionRule struct {
+	spanType *regexp.Regexp // nil matches all the spans
+	tag      *regexp.Regexp
+	hidden   bool // whether the rule matches the hidden tags
+	action   string
+	length   int
+}
+
+// NewRedactor returns a new Redactor which will use the given set of rules, and
+// the given key for the "hash" rules.
+func NewRedactor(rules []*config.RedactionRule, hashKey string) *Redactor {
+	r := &Redactor{key: []byte(hashKey)}
+	r.UpdateRules(rules)
+	return r
+}
+
+// UpdateRules replaces the rules of the Redactor. The invalid rules are logged
+// and ignored, except the "hash" rules without a key which drop the tags
+// instead of leaving them in clear text.
+func (r *Redactor) UpdateRules(rules []*config.RedactionRule) {
+	compiled := make([]*redactionRule, 0, len(rules))
+	for _, rule := range rules {
+		c, ok := r.compileRule(rule)
+		if !ok {
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+	r.rules.Store(&compiled)
+}
+
+func (r *Redactor) compileRule(rule *config.RedactionRule) (*redactionRule, bool) {
+	if rule.Tag == "" {
+		log.Errorf("Invalid redaction rule %+v: missing tag", *rule)
+		return nil, false
+	}
+	action := rule.Action
+	switch rule.Action {
+	case config.RedactionActionDrop:
+	case config.RedactionActionHash:
+		if len(r.key) == 0 {
+			log.Errorf("Invalid redaction rule %+v: apm_config.redaction.hash_key must be set to hash the tags, dropping them instead", *rule)
+			action = config.RedactionActionDrop
+		}
+	case config.RedactionActionTruncate:
+		if rule.Length < 0 {
+			log.Errorf("Invalid redaction rule %+v: negative length", *rule)
+			return nil, false
+		}
+	default:
+		log.Errorf("Invalid redaction rule %+v: unknown action %q", *rule, rule.Action)
+		return nil, false
+	}
+	c := &redactionRule{
+		tag:    compileGlob(rule.Tag),
+		hidden: strings.HasPrefix(rule.Tag, hiddenTagPrefix),
+		action: action,
+		length: rule.Length,
+	}
+	if rule.SpanType != "" {
+		c.spanType = compileGlob(rule.SpanType)
+	}
+	return c, true
+}
+
+// compileGlob compiles a glob where "*" matches any sequence of characters.
+func compileGlob(glob string) *regexp.Regexp {
+	return regexp.MustCompile("^" + strings.ReplaceAll(regexp.QuoteMeta(glob), `\*`, ".*") + "$")
+}
+
+// Redact redacts the tags of the given spans. The first rule matching the type
+// of a span and the key of a tag applies.
+func (r *Redactor) Redact(spans []*pb.Span) {
+	rules := *r.rules.Load()
+	if len(rules) == 0 {
+		return
+	}
+	applicable := make([]*redactionRule, 0, len(rules))
+	for _, s := range spans {
+		applicable = applicable[:0]
+		for _, rule := range rules {
+			if rule.spanType == nil || rule.spanType.MatchString(s.Type) {
+				applicable = append(applicable, rule)
+			}
+		}
+		if len(applicable) == 0 {
+			continue
+		}
+		for k, v := range s.Meta {
+			if k == eventsKey {
+				if events, ok := r.redactEvents(applicable, v); ok {
+					s.Meta[k] = events
+				}
+				continue
+			}
+			if rule := matchRule(applicable, k); rule != nil {
+				if v, ok := r.apply(rule, v); ok {
+					s.Meta[k] = v
+				} else {
+					delete(s.Meta, k)
+				}
+			}
+		}
+		for k, v := range s.MetaStruct {
+			rule := matchRule(applicable, k)
+			if rule == nil {
+				continue
+			}
+			// the structured values can't be truncated, so the truncate
+			// rules drop them
+			if rule.action == config.RedactionActionHash {
+				s.MetaStruct[k] = msgp.AppendString(nil, r.hash(v))
+			} else {
+				delete(s.MetaStruct, k)
+			}
+		}
+		for _, link := range s.SpanLinks {
+			for k, v := range link.Attributes {
+				if rule := matchRule(applicable, k); rule != nil {
+					if v, ok := r.apply(rule, v); ok {
+						link.Attributes[k] = v
+					} else {
+						delete(link.Attributes, k)
+					}
+				}
+			}
+		}
+	}
+}
+
+// matchRule returns the first rule matching the given tag key, or nil.
+func matchRule(rules []*redactionRule, key string) *redactionRule {
+	hidden := strings.HasPrefix(key, hiddenTagPrefix)
+	for _, rule := range rules {
+		if hidden && !rule.hidden {
+			continue
+		}
+		if rule.tag.MatchString(key) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// apply returns the redacted value, and false if the tag must be dropped.
+func (r *Redactor) apply(rule *redactionRule, v string) (string, bool) {
+	switch rule.action {
+	case config.RedactionActionHash:
+		return r.hash([]byte(v)), true
+	case config.RedactionActionTruncate:
+		return traceutil.TruncateUTF8(v, rule.length), true
+	default:
+		return "", false
+	}
+}
+
+// hash returns the hex encoded HMAC-SHA256 of v.
+func (r *Redactor) hash(v []byte) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write(v)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// redactEvents redacts the attributes of the JSON encoded span events. It
+// returns false if the events can't be decoded or are left unchanged.
+func (r *Redactor) redactEvents(rules []*redactionRule, v string) (string, bool) {
+	var events []map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(v), &events); err != nil {
+		log.Debugf("Can't redact the span events %q: %v", v, err)
+		return "", false
+	}
+	changed := false
+	for _, e := range events {
+		raw, ok := e["attributes"]
+		if !ok {
+			continue
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &attrs); err != nil {
+			log.Debugf("Can't redact the span event attributes %s: %v", raw, err)
+			continue
+		}
+		redacted := false
+		for k, a := range attrs {
+			rule := matchRule(rules, k)
+			if rule == nil {
+				continue
+			}
+			// the attributes aren't always strings, the others are hashed
+			// from their JSON encoding and left unchanged when truncating
+			var s string
+			isString := json.Unmarshal(a, &s) == nil
+			switch rule.action {
+			case config.RedactionActionHash:
+				if !isString {
+					s = string(a)
+				}
+				attrs[k], _ = json.Marshal(r.hash([]byte(s)))
+			case config.RedactionActionTruncate:
+				if !isString {
+					continue
+				}
+				attrs[k], _ = json.Marshal(traceutil.TruncateUTF8(s, rule.length))
+			default:
+				delete(attrs, k)
+			}
+			redacted = true
+		}
+		if !redacted {
+			continue
+		}
+		raw, err := json.Marshal(attrs)
+		if err != nil {
+			log.Debugf("Can't encode the redacted span event attributes: %v", err)
+			continue
+		}
+		e["attributes"] = raw
+		changed = true
+	}
+	if !changed {
+		return "", false
+	}
+	out, err := json.Marshal(events)
+	if err != nil {
+		log.Debugf("Can't encode the redacted span events: %v", err)
+		return "", false
+	}
+	return string(out), true
+}
@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/tinylib/msgp/msgp"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hmacHex(key, v string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(v))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor([]*config.RedactionRule{
+		{Tag: "user.email", Action: config.RedactionActionHash},
+		{SpanType: "sql", Tag: "db.statement", Action: config.RedactionActionTruncate, Length: 6},
+		{SpanType: "web", Tag: "http.client_ip", Action: config.RedactionActionDrop},
+		{Tag: "user.*", Action: config.RedactionActionDrop},
+		{Tag: "_dd.user.*", Action: config.RedactionActionDrop},
+		// invalid rules are ignored
+		{Action: config.RedactionActionDrop},
+		{Tag: "password", Action: "encrypt"},
+		{Tag: "password", Action: config.RedactionActionTruncate, Length: -1},
+	}, "secret")
+	require.Len(t, *r.rules.Load(), 5)
+
+	web := &pb.Span{
+		Type: "web",
+		Meta: map[string]string{
+			"user.email":     "jane@example.com",
+			"user.name":      "Jane",
+			"http.client_ip": "10.0.0.1",
+			"db.statement":   "SELECT * FROM users",
+			"_dd.user.id":    "42",
+			"_dd.p.dm":       "-0",
+			"env":            "prod",
+		},
+		MetaStruct: map[string][]byte{
+			"user.profile": msgp.AppendString(nil, "profile"),
+			"user.email":   []byte("raw"),
+			"appsec":       []byte("data"),
+		},
+		SpanLinks: []*pb.SpanLink{{Attributes: map[string]string{"user.email": "jane@example.com", "user.name": "Jane", "link.kind": "follows"}}},
+	}
+	sql := &pb.Span{
+		Type: "sql",
+		Meta: map[string]string{"db.statement": "SELECT * FROM users", "http.client_ip": "10.0.0.1"},
+	}
+	r.Redact([]*pb.Span{web, sql})
+
+	assert.Equal(t, map[string]string{
+		"user.email":   hmacHex("secret", "jane@example.com"),
+		"db.statement": "SELECT * FROM users",
+		"_dd.p.dm":     "-0",
+		"env":          "prod",
+	}, web.Meta)
+	assert.Equal(t, map[string][]byte{
+		"user.email": msgp.AppendString(nil, hmacHex("secret", "raw")),
+		"appsec":     []byte("data"),
+	}, web.MetaStruct)
+	assert.Equal(t, map[string]string{
+		"user.email": hmacHex("secret", "jane@example.com"),
+		"link.kind":  "follows",
+	}, web.SpanLinks[0].Attributes)
+	assert.Equal(t, map[string]string{"db.statement": "SELECT", "http.client_ip": "10.0.0.1"}, sql.Meta)
+}
+
+func TestRedactorEvents(t *testing.T) {
+	r := NewRedactor([]*config.RedactionRule{
+		{Tag: "user.email", Action: config.RedactionActionHash},
+		{Tag: "user.id", Action: config.RedactionActionHash},
+		{Tag: "exception.message", Action: config.RedactionActionTruncate, Length: 5},
+		{Tag: "exception.code", Action: config.RedactionActionTruncate, Length: 1},
+		{Tag: "user.*", Action: config.RedactionActionDrop},
+	}, "secret")
+
+	span := &pb.Span{Meta: map[string]string{
+		"events": `[{"time_unix_nano":123,"name":"login","attributes":{"user.email":"jane@example.com","user.id":42,"user.name":"Jane","result":"ok"}},` +
+			`{"name":"exception","attributes":{"exception.message":"error: invalid password","exception.code":500}},{"name":"empty"}]`,
+	}}
+	r.Redact([]*pb.Span{span})
+	assert.JSONEq(t, `[{"time_unix_nano":123,"name":"login","attributes":{"user.email":"`+hmacHex("secret", "jane@example.com")+`","user.id":"`+hmacHex("secret", "42")+`","result":"ok"}},`+
+		`{"name":"exception","attributes":{"exception.message":"error","exception.code":500}},{"name":"empty"}]`, span.Meta["events"])
+
+	// the events which can't be decoded are left unchanged
+	span = &pb.Span{Meta: map[string]string{"events": `not json`}}
+	r.Redact([]*pb.Span{span})
+	assert.Equal(t, "not json", span.Meta["events"])
+}
+
+func TestRedactorHashRequiresKey(t *testing.T) {
+	r := NewRedactor([]*config.RedactionRule{
+		{Tag: "user.email", Action: config.RedactionActionHash},
+		{Tag: "user.name", Action: config.RedactionActionDrop},
+	}, "")
+	require.Len(t, *r.rules.Load(), 2)
+
+	// the tags aren't sent in clear text without a key, they are dropped
+	span := &pb.Span{
+		Meta:       map[string]string{"user.email": "jane@example.com", "user.name": "Jane", "http.method": "GET"},
+		MetaStruct: map[string][]byte{"user.email": msgp.AppendString(nil, "jane@example.com")},
+	}
+	r.Redact([]*pb.Span{span})
+	assert.Equal(t, map[string]string{"http.method": "GET"}, span.Meta)
+	assert.Empty(t, span.MetaStruct)
+}
+
+func TestRedactorUpdateRules(t *testing.T) {
+	r := NewRedactor(nil, "secret")
+	span := &pb.Span{Meta: map[string]string{"user.email": "jane@example.com"}}
+	r.Redact([]*pb.Span{span})
+	assert.Equal(t, "jane@example.com", span.Meta["user.email"])
+
+	r.UpdateRules([]*config.RedactionRule{{Tag: "user.email", Action: config.RedactionActionHash}})
+	r.Redact([]*pb.Span{span})
+	assert.Equal(t, hmacHex("secret", "jane@example.com"), span.Meta["user.email"])
+
+	r.UpdateRules(nil)
+	span.Meta["user.email"] = "jane@example.com"
+	r.Redact([]*pb.Span{span})
+	assert.Equal(t, "jane@example.com", span.Meta["user.email"])
+}
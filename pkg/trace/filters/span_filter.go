@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+)
+
+// SpanFilter drops the spans, and the trace chunks, matching the expressions of
+// the span filter rules.
+type SpanFilter struct {
+	spans  []expression
+	traces []expression
+}
+
+// NewSpanFilter creates a new SpanFilter from the given rules. The invalid rules
+// are logged and ignored.
+func NewSpanFilter(rules []*config.SpanFilterRule) *SpanFilter {
+	f := &SpanFilter{}
+	for _, r := range rules {
+		expr, err := parseExpression(r.Expression)
+		if err != nil {
+			log.Errorf("Invalid span filter %q: %v", r.Expression, err)
+			continue
+		}
+		switch r.Scope {
+		case "", config.SpanFilterScopeSpan:
+			f.spans = append(f.spans, expr)
+		case config.SpanFilterScopeTrace:
+			f.traces = append(f.traces, expr)
+		default:
+			log.Errorf("Invalid span filter %q: unknown scope %q", r.Expression, r.Scope)
+		}
+	}
+	return f
+}
+
+// AllowsTrace returns false if the root span of a chunk matches a trace filter.
+func (f *SpanFilter) AllowsTrace(root *pb.Span) bool {
+	for _, expr := range f.traces {
+		if expr.match(root) {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter removes the spans of a chunk matching a span filter and returns the
+// removed spans.
+func (f *SpanFilter) Filter(chunk *pb.TraceChunk) []*pb.Span {
+	if len(f.spans) == 0 {
+		return nil
+	}
+	var removed []*pb.Span
+	n := 0
+	for _, span := range chunk.Spans {
+		if f.allowsSpan(span) {
+			chunk.Spans[n] = span
+			n++
+		} else {
+			removed = append(removed, span)
+		}
+	}
+	// set everything at the back of the array to nil to avoid memory leaking
+	for i := n; i < len(chunk.Spans); i++ {
+		chunk.Spans[i] = nil
+	}
+	chunk.Spans = chunk.Spans[:n]
+	return removed
+}
+
+func (f *SpanFilter) allowsSpan(span *pb.Span) bool {
+	for _, expr := range f.spans {
+		if expr.match(span) {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowsStat returns false if a filter only using the service, the name, the
+// resource and the type of the spans matches the stats group, so that the stats
+// computed by the tracers don't count the filtered spans either.
+func (f *SpanFilter) AllowsStat(stat *pb.ClientGroupedStats) bool {
+	span := &pb.Span{Service: stat.Service, Name: stat.Name, Resource: stat.Resource, Type: stat.Type}
+	for _, exprs := range [][]expression{f.spans, f.traces} {
+		for _, expr := range exprs {
+			if expr.statFields() && expr.match(span) {
+				return false
+			}
+		}
+	}
+	return true
+}
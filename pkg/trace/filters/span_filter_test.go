@@ -0,0 +1,55 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpanFilter(t *testing.T) {
+	filter := NewSpanFilter([]*config.SpanFilterRule{
+		{Expression: `resource == "GET /health"`, Scope: config.SpanFilterScopeTrace},
+		{Expression: `name == "redis.command" && duration < 1ms`},
+		{Expression: `service == "internal"`, Scope: config.SpanFilterScopeSpan},
+		// invalid rules are ignored
+		{Expression: `service ==`},
+		{Expression: `service == "web"`, Scope: "chunk"},
+	})
+	assert.Len(t, filter.spans, 2)
+	assert.Len(t, filter.traces, 1)
+
+	assert.False(t, filter.AllowsTrace(&pb.Span{Resource: "GET /health"}))
+	assert.True(t, filter.AllowsTrace(&pb.Span{Resource: "GET /users"}))
+	// the trace filters don't drop single spans
+	assert.Empty(t, filter.Filter(&pb.TraceChunk{Spans: []*pb.Span{{Resource: "GET /health"}}}))
+
+	root := &pb.Span{SpanID: 1, Service: "web", Resource: "GET /users"}
+	fast := &pb.Span{SpanID: 2, ParentID: 1, Name: "redis.command", Duration: int64(500 * time.Microsecond)}
+	slow := &pb.Span{SpanID: 3, ParentID: 1, Name: "redis.command", Duration: int64(2 * time.Millisecond)}
+	internal := &pb.Span{SpanID: 4, ParentID: 1, Service: "internal"}
+	chunk := &pb.TraceChunk{Spans: []*pb.Span{root, fast, slow, internal}}
+	assert.Equal(t, []*pb.Span{fast, internal}, filter.Filter(chunk))
+	assert.Equal(t, []*pb.Span{root, slow}, chunk.Spans)
+}
+
+func TestSpanFilterAllowsStat(t *testing.T) {
+	filter := NewSpanFilter([]*config.SpanFilterRule{
+		{Expression: `resource =~ "/health$"`, Scope: config.SpanFilterScopeTrace},
+		{Expression: `service == "internal"`},
+		// the stats don't have the duration of the single spans
+		{Expression: `service == "web" && duration < 1ms`},
+	})
+
+	assert.False(t, filter.AllowsStat(&pb.ClientGroupedStats{Service: "web", Resource: "GET /health"}))
+	assert.False(t, filter.AllowsStat(&pb.ClientGroupedStats{Service: "internal", Resource: "GET /users"}))
+	assert.True(t, filter.AllowsStat(&pb.ClientGroupedStats{Service: "web", Resource: "GET /users"}))
+}
@@ -0,0 +1,314 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package recorder keeps the recently processed trace chunks along with their
+// sampling decision, so that they can be inspected through the debug server.
+package recorder
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+// Path is the path of the debug server endpoint serving the recorded traces.
+const Path = "/debug/traces"
+
+const (
+	// DecisionKept is the decision of the kept trace chunks.
+	DecisionKept = "kept"
+	// DecisionDropped is the decision of the dropped trace chunks.
+	DecisionDropped = "dropped"
+)
+
+// defaultLimit is the number of traces returned by a query without a limit.
+const defaultLimit = 20
+
+// tagTraceIDHigh is the tag holding the high 64 bits of the 128 bits trace IDs.
+const tagTraceIDHigh = "_dd.p.tid"
+
+// Trace is a recorded trace chunk.
+type Trace struct {
+	TraceID    uint64    `json:"trace_id"`
+	ReceivedAt time.Time `json:"received_at"`
+	Env        string    `json:"env"`
+	Service    string    `json:"service"`
+	Name       string    `json:"name"`
+	Resource   string    `json:"resource"`
+	// Error is true if a span of the chunk has an error.
+	Error    bool    `json:"error"`
+	Priority int32   `json:"priority"`
+	Decision string  `json:"decision"`
+	Reason   string  `json:"reason"`
+	Spans    []*Span `json:"spans"`
+}
+
+// Span is a recorded span.
+type Span struct {
+	SpanID   uint64             `json:"span_id"`
+	ParentID uint64             `json:"parent_id"`
+	Service  string             `json:"service"`
+	Name     string             `json:"name"`
+	Resource string             `json:"resource"`
+	Type     string             `json:"type"`
+	Start    int64              `json:"start"`
+	Duration int64              `json:"duration"`
+	Error    int32              `json:"error"`
+	Meta     map[string]string  `json:"meta,omitempty"`
+	Metrics  map[string]float64 `json:"metrics,omitempty"`
+}
+
+// Query selects recorded traces. The zero value selects them all.
+type Query struct {
+	// Service selects the traces having a span of this service.
+	Service string
+	// Resource selects the traces whose root span has this resource.
+	Resource string
+	// TraceID is the decimal trace ID, or the hexadecimal 128 bits trace ID.
+	TraceID string
+	// Error selects the traces having an error, or not, when set.
+	Error *bool
+	// Limit is the maximum number of traces returned, 20 by default.
+	Limit int
+}
+
+// Recorder is a ring buffer of the recently processed trace chunks. A nil
+// Recorder records nothing.
+type Recorder struct {
+	mu     sync.Mutex
+	traces []*Trace
+	next   int
+	full   bool
+}
+
+// New returns a Recorder keeping the given number of trace chunks, or nil if
+// size isn't positive.
+func New(size int) *Recorder {
+	if size <= 0 {
+		return nil
+	}
+	return &Recorder{traces: make([]*Trace, size)}
+}
+
+// Record records the given spans of a trace chunk and the sampling decision
+// made on it. The spans are copied, so that they can be modified afterwards.
+func (r *Recorder) Record(now time.Time, env string, priority int32, spans []*pb.Span, kept bool, reason string) {
+	if r == nil || len(spans) == 0 {
+		return
+	}
+	root := traceutil.GetRoot(spans)
+	t := &Trace{
+		TraceID:    root.TraceID,
+		ReceivedAt: now,
+		Env:        env,
+		Service:    root.Service,
+		Name:       root.Name,
+		Resource:   root.Resource,
+		Priority:   priority,
+		Decision:   DecisionDropped,
+		Reason:     reason,
+		Spans:      make([]*Span, 0, len(spans)),
+	}
+	if kept {
+		t.Decision = DecisionKept
+	}
+	for _, s := range spans {
+		if s.Error != 0 {
+			t.Error = true
+		}
+		t.Spans = append(t.Spans, &Span{
+			SpanID:   s.SpanID,
+			ParentID: s.ParentID,
+			Service:  s.Service,
+			Name:     s.Name,
+			Resource: s.Resource,
+			Type:     s.Type,
+			Start:    s.Start,
+			Duration: s.Duration,
+			Error:    s.Error,
+			Meta:     maps.Clone(s.Meta),
+			Metrics:  maps.Clone(s.Metrics),
+		})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.traces[r.next] = t
+	r.next++
+	if r.next == len(r.traces) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// Query returns the recorded traces selected by q, the most recent first.
+func (r *Recorder) Query(q Query) ([]*Trace, error) {
+	match, err := q.matcher()
+	if err != nil {
+		return nil, err
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	out := []*Trace{}
+	if r == nil {
+		return out, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := r.next
+	if r.full {
+		n = len(r.traces)
+	}
+	for i := 1; i <= n && len(out) < limit; i++ {
+		t := r.traces[(r.next-i+len(r.traces))%len(r.traces)]
+		if match(t) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// matcher returns a function reporting whether a trace is selected by q.
+func (q Query) matcher() (func(*Trace) bool, error) {
+	var (
+		traceID     uint64
+		traceIDHigh string
+	)
+	if q.TraceID != "" {
+		var err error
+		if len(q.TraceID) == 32 {
+			// 128 bits trace IDs are hexadecimal, as in the W3C trace context
+			if _, err = hex.DecodeString(q.TraceID); err == nil {
+				traceIDHigh = strings.ToLower(q.TraceID[:16])
+				traceID, err = strconv.ParseUint(q.TraceID[16:], 16, 64)
+			}
+		} else {
+			traceID, err = strconv.ParseUint(q.TraceID, 10, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid trace ID %q: it must be a decimal or a 32 characters hexadecimal ID", q.TraceID)
+		}
+	}
+	return func(t *Trace) bool {
+		if q.Service != "" && !hasService(t, q.Service) {
+			return false
+		}
+		if q.Resource != "" && t.Resource != q.Resource {
+			return false
+		}
+		if q.Error != nil && t.Error != *q.Error {
+			return false
+		}
+		if q.TraceID != "" {
+			if t.TraceID != traceID {
+				return false
+			}
+			if traceIDHigh != "" && !hasTraceIDHigh(t, traceIDHigh) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func hasService(t *Trace, service string) bool {
+	for _, s := range t.Spans {
+		if s.Service == service {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTraceIDHigh reports whether the spans of t carry the given high 64 bits of
+// the trace ID. The chunks without them match any.
+func hasTraceIDHigh(t *Trace, high string) bool {
+	for _, s := range t.Spans {
+		if v, ok := s.Meta[tagTraceIDHigh]; ok {
+			return v == high
+		}
+	}
+	return true
+}
+
+// Values returns the query parameters of the recorded traces endpoint selecting
+// the same traces as q.
+func (q Query) Values() url.Values {
+	v := url.Values{}
+	if q.Service != "" {
+		v.Set("service", q.Service)
+	}
+	if q.Resource != "" {
+		v.Set("resource", q.Resource)
+	}
+	if q.TraceID != "" {
+		v.Set("trace_id", q.TraceID)
+	}
+	if q.Error != nil {
+		v.Set("error", strconv.FormatBool(*q.Error))
+	}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	return v
+}
+
+// parseQuery parses the query parameters of a request to the recorded traces endpoint.
+func parseQuery(req *http.Request) (Query, error) {
+	v := req.URL.Query()
+	q := Query{
+		Service:  v.Get("service"),
+		Resource: v.Get("resource"),
+		TraceID:  v.Get("trace_id"),
+	}
+	if s := v.Get("error"); s != "" {
+		e, err := strconv.ParseBool(s)
+		if err != nil {
+			return q, fmt.Errorf("invalid error %q: it must be a boolean", s)
+		}
+		q.Error = &e
+	}
+	if s := v.Get("limit"); s != "" {
+		l, err := strconv.Atoi(s)
+		if err != nil || l <= 0 {
+			return q, fmt.Errorf("invalid limit %q: it must be a positive integer", s)
+		}
+		q.Limit = l
+	}
+	return q, nil
+}
+
+// ServeHTTP serves the recorded traces selected by the query parameters
+// service, resource, trace_id, error and limit, in JSON.
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	q, err := parseQuery(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	traces, err := r.Query(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(traces); err != nil {
+		log.Errorf("Error encoding the recorded traces: %v", err)
+	}
+}
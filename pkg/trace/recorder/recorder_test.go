@@ -0,0 +1,173 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+)
+
+func recordTrace(r *Recorder, traceID uint64, service, resource string, errored bool, kept bool) {
+	root := &pb.Span{TraceID: traceID, SpanID: 1, Service: service, Name: "http.request", Resource: resource}
+	child := &pb.Span{TraceID: traceID, SpanID: 2, ParentID: 1, Service: service + "-db", Name: "postgres.query"}
+	if errored {
+		child.Error = 1
+	}
+	r.Record(time.Now(), "prod", 1, []*pb.Span{root, child}, kept, "priority")
+}
+
+func traceIDs(traces []*Trace) []uint64 {
+	ids := make([]uint64, 0, len(traces))
+	for _, t := range traces {
+		ids = append(ids, t.TraceID)
+	}
+	return ids
+}
+
+func TestRecorderQuery(t *testing.T) {
+	r := New(3)
+	recordTrace(r, 1, "web", "GET /users", false, true)
+	recordTrace(r, 2, "api", "GET /health", true, false)
+	recordTrace(r, 3, "web", "GET /health", false, true)
+
+	for name, tt := range map[string]struct {
+		query Query
+		ids   []uint64
+	}{
+		"all":           {Query{}, []uint64{3, 2, 1}},
+		"limit":         {Query{Limit: 2}, []uint64{3, 2}},
+		"service":       {Query{Service: "web"}, []uint64{3, 1}},
+		"child service": {Query{Service: "api-db"}, []uint64{2}},
+		"resource":      {Query{Resource: "GET /health"}, []uint64{3, 2}},
+		"error":         {Query{Error: &[]bool{true}[0]}, []uint64{2}},
+		"no error":      {Query{Error: &[]bool{false}[0]}, []uint64{3, 1}},
+		"trace ID":      {Query{TraceID: "2"}, []uint64{2}},
+		"128 bits":      {Query{TraceID: "00000000000000010000000000000001"}, []uint64{1}},
+		"combined":      {Query{Service: "web", Resource: "GET /users"}, []uint64{1}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			traces, err := r.Query(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.ids, traceIDs(traces))
+		})
+	}
+
+	// the oldest traces are overwritten
+	recordTrace(r, 4, "web", "GET /users", false, true)
+	traces, err := r.Query(Query{})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{4, 3, 2}, traceIDs(traces))
+
+	_, err = r.Query(Query{TraceID: "abc"})
+	assert.EqualError(t, err, `invalid trace ID "abc": it must be a decimal or a 32 characters hexadecimal ID`)
+}
+
+func TestRecorderRecord(t *testing.T) {
+	r := New(1)
+	meta := map[string]string{"_dd.p.tid": "00000000000000ab"}
+	spans := []*pb.Span{{TraceID: 5, SpanID: 1, Service: "web", Name: "http.request", Resource: "GET /", Meta: meta}}
+	r.Record(time.Now(), "prod", 2, spans, false, "error")
+	// the recorded spans are copies
+	meta["user"] = "jane"
+
+	traces, err := r.Query(Query{})
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	tr := traces[0]
+	assert.Equal(t, uint64(5), tr.TraceID)
+	assert.Equal(t, "web", tr.Service)
+	assert.Equal(t, "GET /", tr.Resource)
+	assert.Equal(t, int32(2), tr.Priority)
+	assert.Equal(t, DecisionDropped, tr.Decision)
+	assert.Equal(t, "error", tr.Reason)
+	assert.Equal(t, map[string]string{"_dd.p.tid": "00000000000000ab"}, tr.Spans[0].Meta)
+
+	traces, err = r.Query(Query{TraceID: "00000000000000ab0000000000000005"})
+	require.NoError(t, err)
+	assert.Len(t, traces, 1)
+	traces, err = r.Query(Query{TraceID: "00000000000000cd0000000000000005"})
+	require.NoError(t, err)
+	assert.Empty(t, traces)
+}
+
+func TestRecorderNil(t *testing.T) {
+	r := New(0)
+	assert.Nil(t, r)
+	assert.NotPanics(t, func() { recordTrace(r, 1, "web", "GET /", false, true) })
+	traces, err := r.Query(Query{})
+	assert.NoError(t, err)
+	assert.Empty(t, traces)
+}
+
+func TestRecorderServeHTTP(t *testing.T) {
+	r := New(10)
+	recordTrace(r, 1, "web", "GET /users", false, true)
+	recordTrace(r, 2, "api", "GET /health", true, false)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path+"?"+query, nil))
+		return rec
+	}
+
+	errored := true
+	rec := get(Query{Service: "api", Error: &errored, Limit: 5}.Values().Encode())
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var traces []*Trace
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &traces))
+	require.Len(t, traces, 1)
+	assert.Equal(t, uint64(2), traces[0].TraceID)
+	assert.Equal(t, DecisionDropped, traces[0].Decision)
+	assert.Len(t, traces[0].Spans, 2)
+
+	rec = get("service=none")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "[]\n", rec.Body.String())
+
+	for _, query := range []string{"error=maybe", "limit=0", "trace_id=x"} {
+		assert.Equal(t, http.StatusBadRequest, get(query).Code, query)
+	}
+}
+
+func TestWriteTree(t *testing.T) {
+	tr := &Trace{
+		TraceID:    1234,
+		ReceivedAt: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		Env:        "prod",
+		Priority:   1,
+		Decision:   DecisionKept,
+		Reason:     "priority",
+		Spans: []*Span{
+			{SpanID: 3, ParentID: 1, Service: "web-db", Name: "postgres.query", Resource: "SELECT * FROM users", Start: 3, Duration: int64(2 * time.Millisecond), Error: 1},
+			{SpanID: 1, Service: "web", Name: "http.request", Resource: "GET /users", Start: 1, Duration: int64(12 * time.Millisecond)},
+			{SpanID: 4, ParentID: 2, Service: "web", Name: "template.render", Resource: "users.html", Start: 4, Duration: int64(time.Millisecond)},
+			{SpanID: 2, ParentID: 1, Service: "web", Name: "redis.command", Resource: "GET", Start: 2, Duration: int64(500 * time.Microsecond)},
+			// the parent of this span is in another chunk
+			{SpanID: 5, ParentID: 42, Service: "worker", Name: "job", Resource: "send", Start: 5, Duration: int64(time.Second)},
+		},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, WriteTree(&buf, []*Trace{tr, {TraceID: 5, ReceivedAt: tr.ReceivedAt, Decision: DecisionDropped, Reason: "error"}}))
+	assert.Equal(t, `trace 1234 (env: prod, priority: 1) kept by priority at 2024-01-02T15:04:05Z
+├─ web http.request GET /users 12ms
+│  ├─ web redis.command GET 500µs
+│  │  └─ web template.render users.html 1ms
+│  └─ web-db postgres.query SELECT * FROM users 2ms [error]
+└─ worker job send 1s
+
+trace 5 (env: , priority: 0) dropped by error at 2024-01-02T15:04:05Z
+`, buf.String())
+}
@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package recorder
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// WriteTree writes the given traces as trees of spans, such as:
+//
+//	trace 1234 (env: prod, priority: 1) kept by priority at 2024-01-02T15:04:05Z
+//	└─ web http.request GET /users 12ms
+//	   ├─ web redis.command GET 500µs
+//	   └─ web-db postgres.query SELECT * FROM users 2ms [error]
+//
+// The spans whose parent isn't in the chunk are shown as roots.
+func WriteTree(w io.Writer, traces []*Trace) error {
+	for i, t := range traces {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if err := writeTrace(w, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTrace(w io.Writer, t *Trace) error {
+	_, err := fmt.Fprintf(w, "trace %d (env: %s, priority: %d) %s by %s at %s\n",
+		t.TraceID, t.Env, t.Priority, t.Decision, t.Reason, t.ReceivedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+
+	ids := make(map[uint64]bool, len(t.Spans))
+	for _, s := range t.Spans {
+		ids[s.SpanID] = true
+	}
+	var roots []*Span
+	children := make(map[uint64][]*Span)
+	for _, s := range t.Spans {
+		if s.ParentID == 0 || s.ParentID == s.SpanID || !ids[s.ParentID] {
+			roots = append(roots, s)
+		} else {
+			children[s.ParentID] = append(children[s.ParentID], s)
+		}
+	}
+	return writeSpans(w, roots, children, "")
+}
+
+// writeSpans writes the spans, sorted by start, and their children below them.
+func writeSpans(w io.Writer, spans []*Span, children map[uint64][]*Span, indent string) error {
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	for i, s := range spans {
+		branch, next := "├─ ", "│  "
+		if i == len(spans)-1 {
+			branch, next = "└─ ", "   "
+		}
+		errFlag := ""
+		if s.Error != 0 {
+			errFlag = " [error]"
+		}
+		if _, err := fmt.Fprintf(w, "%s%s%s %s %s %s%s\n", indent, branch, s.Service, s.Name, s.Resource, time.Duration(s.Duration), errFlag); err != nil {
+			return err
+		}
+		if err := writeSpans(w, children[s.SpanID], children, indent+next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
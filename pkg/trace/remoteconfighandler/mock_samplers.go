@@ -1,11 +1,13 @@
 // Code generated by MockGen. DO NOT EDIT.
 // Source: remote_config_handler.go
 
+// Package remoteconfighandler is a generated GoMock package.
 package remoteconfighandler
 
 import (
 	reflect "reflect"
 
+	config "github.com/DataDog/datadog-agent/pkg/trace/config"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -113,3 +115,38 @@ func (mr *MockrareSamplerMockRecorder) SetEnabled(enabled interface{}) *gomock.C
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetEnabled", reflect.TypeOf((*MockrareSampler)(nil).SetEnabled), enabled)
 }
+
+// Mockredactor is a mock of redactor interface.
+type Mockredactor struct {
+	ctrl     *gomock.Controller
+	recorder *MockredactorMockRecorder
+}
+
+// MockredactorMockRecorder is the mock recorder for Mockredactor.
+type MockredactorMockRecorder struct {
+	mock *Mockredactor
+}
+
+// NewMockredactor creates a new mock instance.
+func NewMockredactor(ctrl *gomock.Controller) *Mockredactor {
+	mock := &Mockredactor{ctrl: ctrl}
+	mock.recorder = &MockredactorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *Mockredactor) EXPECT() *MockredactorMockRecorder {
+	return m.recorder
+}
+
+// UpdateRules mocks base method.
+func (m *Mockredactor) UpdateRules(rules []*config.RedactionRule) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateRules", rules)
+}
+
+// UpdateRules indicates an expected call of UpdateRules.
+func (mr *MockredactorMockRecorder) UpdateRules(rules interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRules", reflect.TypeOf((*Mockredactor)(nil).UpdateRules), rules)
+}
@@ -3,7 +3,7 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-present Datadog, Inc.
 
-// Package remoteconfighandler holds the logic responsible for updating the samplers when the remote configuration changes.
+// Package remoteconfighandler holds the logic responsible for updating the samplers and the redaction rules when the remote configuration changes.
 package remoteconfighandler
 
 import (
@@ -11,9 +11,11 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
 
 	"github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
+	"github.com/DataDog/datadog-agent/pkg/remoteconfig/state/products/apmredaction"
 	"github.com/DataDog/datadog-agent/pkg/remoteconfig/state/products/apmsampling"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/log"
@@ -23,6 +25,8 @@ import (
 	"github.com/davecgh/go-spew/spew"
 )
 
+//go:generate mockgen -source=$GOFILE -package=$GOPACKAGE -destination=mock_samplers.go
+
 type prioritySampler interface {
 	UpdateTargetTPS(targetTPS float64)
 }
@@ -35,19 +39,24 @@ type rareSampler interface {
 	SetEnabled(enabled bool)
 }
 
-// RemoteConfigHandler holds pointers to samplers that need to be updated when APM remote config changes
+type redactor interface {
+	UpdateRules(rules []*config.RedactionRule)
+}
+
+// RemoteConfigHandler holds pointers to samplers and to the redactor that need to be updated when APM remote config changes
 type RemoteConfigHandler struct {
 	remoteClient                  config.RemoteClient
 	prioritySampler               prioritySampler
 	errorsSampler                 errorsSampler
 	rareSampler                   rareSampler
+	redactor                      redactor
 	agentConfig                   *config.AgentConfig
 	configState                   *state.AgentConfigState
 	configSetEndpointFormatString string
 }
 
 // New creates a new RemoteConfigHandler
-func New(conf *config.AgentConfig, prioritySampler prioritySampler, rareSampler rareSampler, errorsSampler errorsSampler) *RemoteConfigHandler {
+func New(conf *config.AgentConfig, prioritySampler prioritySampler, rareSampler rareSampler, errorsSampler errorsSampler, redactor redactor) *RemoteConfigHandler {
 	if conf.RemoteConfigClient == nil {
 		return nil
 	}
@@ -63,6 +72,7 @@ func New(conf *config.AgentConfig, prioritySampler prioritySampler, rareSampler
 		prioritySampler: prioritySampler,
 		rareSampler:     rareSampler,
 		errorsSampler:   errorsSampler,
+		redactor:        redactor,
 		agentConfig:     conf,
 		configState: &state.AgentConfigState{
 			FallbackLogLevel: level.String(),
@@ -82,6 +92,45 @@ func (h *RemoteConfigHandler) Start() {
 	h.remoteClient.Start()
 	h.remoteClient.Subscribe(state.ProductAPMSampling, h.onUpdate)
 	h.remoteClient.Subscribe(state.ProductAgentConfig, h.onAgentConfigUpdate)
+	h.remoteClient.Subscribe(state.ProductAPMRedaction, h.onRedactionUpdate)
+}
+
+// onRedactionUpdate applies the remote redaction rules before the ones of the
+// local configuration, which can't be removed remotely.
+func (h *RemoteConfigHandler) onRedactionUpdate(updates map[string]state.RawConfig, applyStateCallback func(string, state.ApplyStatus)) {
+	paths := make([]string, 0, len(updates))
+	for cfgPath := range updates {
+		paths = append(paths, cfgPath)
+	}
+	// sort the configurations to apply their rules in a stable order
+	sort.Strings(paths)
+
+	var rules []*config.RedactionRule
+	for _, cfgPath := range paths {
+		var redactionConfig apmredaction.RedactionConfig
+		if err := json.Unmarshal(updates[cfgPath].Config, &redactionConfig); err != nil {
+			log.Errorf("couldn't apply the remote configuration redaction rules: %s", err)
+			applyStateCallback(cfgPath, state.ApplyStatus{
+				State: state.ApplyStateError,
+				Error: err.Error(),
+			})
+			continue
+		}
+		for _, r := range redactionConfig.Rules {
+			rules = append(rules, &config.RedactionRule{
+				SpanType: r.SpanType,
+				Tag:      r.Tag,
+				Action:   r.Action,
+				Length:   r.Length,
+			})
+		}
+		applyStateCallback(cfgPath, state.ApplyStatus{State: state.ApplyStateAcknowledged})
+	}
+
+	log.Debugf("updating the redaction rules with %d rules from remote configuration", len(rules))
+	// the local rules apply first, so that a remote rule can't weaken them
+	local := h.agentConfig.RedactionRules
+	h.redactor.UpdateRules(append(local[:len(local):len(local)], rules...))
 }
 
 func (h *RemoteConfigHandler) onAgentConfigUpdate(updates map[string]state.RawConfig, applyStateCallback func(string, state.ApplyStatus)) {
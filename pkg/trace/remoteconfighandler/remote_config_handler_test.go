@@ -13,9 +13,12 @@ import (
 	"strings"
 	"testing"
 
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
 	"github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
+	"github.com/DataDog/datadog-agent/pkg/remoteconfig/state/products/apmredaction"
 	"github.com/DataDog/datadog-agent/pkg/remoteconfig/state/products/apmsampling"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/filters"
 	pkglog "github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/DataDog/datadog-agent/pkg/util/pointer"
 	"github.com/cihub/seelog"
@@ -35,10 +38,11 @@ func TestStart(t *testing.T) {
 	rareSampler := NewMockrareSampler(ctrl)
 	pkglog.SetupLogger(seelog.Default, "debug")
 
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, NewMockredactor(ctrl))
 
 	remoteClient.EXPECT().Subscribe(state.ProductAPMSampling, gomock.Any()).Times(1)
 	remoteClient.EXPECT().Subscribe(state.ProductAgentConfig, gomock.Any()).Times(1)
+	remoteClient.EXPECT().Subscribe(state.ProductAPMRedaction, gomock.Any()).Times(1)
 	remoteClient.EXPECT().Start().Times(1)
 
 	h.Start()
@@ -60,7 +64,7 @@ func TestPrioritySampler(t *testing.T) {
 	pkglog.SetupLogger(seelog.Default, "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, NewMockredactor(ctrl))
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -91,7 +95,7 @@ func TestErrorsSampler(t *testing.T) {
 	pkglog.SetupLogger(seelog.Default, "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, NewMockredactor(ctrl))
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -122,7 +126,7 @@ func TestRareSampler(t *testing.T) {
 	pkglog.SetupLogger(seelog.Default, "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, NewMockredactor(ctrl))
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -153,7 +157,7 @@ func TestEnvPrecedence(t *testing.T) {
 	pkglog.SetupLogger(seelog.Default, "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true, DefaultEnv: "agent-env"}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, NewMockredactor(ctrl))
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -205,7 +209,7 @@ func TestLogLevel(t *testing.T) {
 		ReceiverHost:       "127.0.0.1",
 		ReceiverPort:       port,
 	}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, NewMockredactor(ctrl))
 
 	layer := state.RawConfig{Config: []byte(`{"name": "layer1", "config": {"log_level": "debug"}}`)}
 	configOrder := state.RawConfig{Config: []byte(`{"internal_order": ["layer1", "layer2"]}`)}
@@ -226,3 +230,72 @@ func TestLogLevel(t *testing.T) {
 
 	ctrl.Finish()
 }
+
+func TestRedaction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	remoteClient := NewMockRemoteClient(ctrl)
+	prioritySampler := NewMockprioritySampler(ctrl)
+	errorsSampler := NewMockerrorsSampler(ctrl)
+	rareSampler := NewMockrareSampler(ctrl)
+	redactor := NewMockredactor(ctrl)
+	pkglog.SetupLogger(seelog.Default, "debug")
+
+	localRule := &config.RedactionRule{Tag: "user.*", Action: config.RedactionActionDrop}
+	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, RedactionRules: []*config.RedactionRule{localRule}}
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, redactor)
+
+	payload := apmredaction.RedactionConfig{
+		Rules: []apmredaction.RedactionRule{
+			{SpanType: "web", Tag: "user.email", Action: "hash"},
+			{Tag: "db.statement", Action: "truncate", Length: 100},
+		},
+	}
+	raw, _ := json.Marshal(payload)
+
+	// the local rules apply before the remote ones
+	redactor.EXPECT().UpdateRules([]*config.RedactionRule{
+		localRule,
+		{SpanType: "web", Tag: "user.email", Action: "hash"},
+		{Tag: "db.statement", Action: "truncate", Length: 100},
+	}).Times(1)
+	remoteClient.EXPECT().UpdateApplyStatus(
+		"datadog/2/APM_REDACTION/rules/config",
+		state.ApplyStatus{State: state.ApplyStateAcknowledged},
+	)
+	remoteClient.EXPECT().UpdateApplyStatus(
+		"datadog/2/APM_REDACTION/invalid/config",
+		state.ApplyStatus{State: state.ApplyStateError, Error: "invalid character 'o' in literal null (expecting 'u')"},
+	)
+	h.onRedactionUpdate(map[string]state.RawConfig{
+		"datadog/2/APM_REDACTION/rules/config":   {Config: raw},
+		"datadog/2/APM_REDACTION/invalid/config": {Config: []byte("not json")},
+	}, remoteClient.UpdateApplyStatus)
+
+	// the local rules are restored when the remote ones are removed
+	redactor.EXPECT().UpdateRules([]*config.RedactionRule{localRule}).Times(1)
+	h.onRedactionUpdate(map[string]state.RawConfig{}, remoteClient.UpdateApplyStatus)
+
+	ctrl.Finish()
+}
+
+func TestRedactionRemoteRulesDontOverrideLocalOnes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	remoteClient := NewMockRemoteClient(ctrl)
+	localRules := []*config.RedactionRule{{Tag: "user.email", Action: config.RedactionActionDrop}}
+	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, RedactionRules: localRules}
+	redactor := filters.NewRedactor(localRules, "secret")
+	h := New(&agentConfig, NewMockprioritySampler(ctrl), NewMockrareSampler(ctrl), NewMockerrorsSampler(ctrl), redactor)
+
+	raw, _ := json.Marshal(apmredaction.RedactionConfig{
+		Rules: []apmredaction.RedactionRule{{Tag: "user.email", Action: "hash"}},
+	})
+	remoteClient.EXPECT().UpdateApplyStatus("datadog/2/APM_REDACTION/rules/config", state.ApplyStatus{State: state.ApplyStateAcknowledged})
+	h.onRedactionUpdate(map[string]state.RawConfig{"datadog/2/APM_REDACTION/rules/config": {Config: raw}}, remoteClient.UpdateApplyStatus)
+
+	span := &pb.Span{Meta: map[string]string{"user.email": "jane@example.com"}}
+	redactor.Redact([]*pb.Span{span})
+	assert.NotContains(t, span.Meta, "user.email")
+	assert.Len(t, agentConfig.RedactionRules, 1)
+
+	ctrl.Finish()
+}
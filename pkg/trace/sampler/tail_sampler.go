@@ -0,0 +1,377 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+
+	"go.uber.org/atomic"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+// KeyTailSamplingPolicy is the chunk tag holding the name of the tail sampling
+// policy which kept the trace.
+const KeyTailSamplingPolicy = "_dd.tail_sampling.policy"
+
+// TailChunk is a trace chunk buffered by the TailSampler.
+type TailChunk struct {
+	Trace *traceutil.ProcessedTrace
+
+	// Context is not used by the sampler, it is given back along with the
+	// decision so that the caller can write the chunk.
+	Context interface{}
+
+	size int
+}
+
+// Keep marks the chunk as kept by the tail sampling policy: it is no longer
+// dropped, its priority is raised to PriorityUserKeep and it is tagged with
+// the name of the policy. The chunks dropped by the user are left untouched.
+func (c *TailChunk) Keep(policy string) {
+	if c.DroppedByUser() {
+		return
+	}
+	chunk := c.Trace.TraceChunk
+	chunk.DroppedTrace = false
+	if chunk.Priority < int32(PriorityUserKeep) {
+		chunk.Priority = int32(PriorityUserKeep)
+	}
+	if chunk.Tags == nil {
+		chunk.Tags = make(map[string]string)
+	}
+	chunk.Tags[KeyTailSamplingPolicy] = policy
+}
+
+// DroppedByUser returns whether the tracer explicitly dropped the chunk, which
+// the tail sampling policies don't override.
+func (c *TailChunk) DroppedByUser() bool {
+	return c.Trace.TraceChunk.Priority <= int32(PriorityUserDrop)
+}
+
+// TailDecision is the sampling decision made on a trace by the TailSampler.
+type TailDecision struct {
+	// Chunks are the chunks of the trace received during the decision window.
+	Chunks []*TailChunk
+
+	// Policy is the name of the policy which kept the trace. It is empty when
+	// no policy matched, the trace is then left to the other samplers. The
+	// chunks of a kept trace are marked with TailChunk.Keep by the caller.
+	Policy string
+}
+
+// tailTraceID is the 128-bit ID of a trace.
+type tailTraceID struct {
+	upper uint64
+	lower uint64
+}
+
+// newTailTraceID returns the ID of the trace of the given spans, the upper 64
+// bits of the 128-bit trace IDs are read from the "_dd.p.tid" tag.
+func newTailTraceID(spans []*pb.Span) tailTraceID {
+	id := tailTraceID{lower: spans[0].TraceID}
+	for _, s := range spans {
+		if tid, ok := s.Meta["_dd.p.tid"]; ok {
+			id.upper, _ = strconv.ParseUint(tid, 16, 64)
+			break
+		}
+	}
+	return id
+}
+
+// tailTrace holds the chunks of a trace waiting for a decision.
+type tailTrace struct {
+	id       tailTraceID
+	deadline time.Time
+	chunks   []*TailChunk
+	size     int
+}
+
+// tailPolicy is a compiled config.TailSamplingPolicy.
+type tailPolicy struct {
+	name  string
+	match func(t *tailTrace) bool
+}
+
+// TailSampler buffers the chunks of a trace for a decision window starting with
+// its first chunk, and then decides on the whole trace with the configured
+// policies. The buffer is bounded in memory: when it is full, the oldest traces
+// are decided early. Chunks received after the decision on their trace start a
+// new decision window.
+type TailSampler struct {
+	enabled        bool
+	decisionWait   time.Duration
+	maxBufferBytes int
+	policies       []tailPolicy
+
+	// decide is called with every decision, without holding the lock.
+	decide func(TailDecision)
+
+	mu     sync.Mutex
+	traces map[tailTraceID]*tailTrace
+	queue  []*tailTrace // ordered by deadline
+	size   int
+
+	statsd        statsd.ClientInterface
+	tracesSeen    *atomic.Int64
+	tracesKept    *atomic.Int64
+	tracesEvicted *atomic.Int64
+	tags          []string
+
+	// start/stop synchronization
+	stopOnce sync.Once
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// NewTailSampler returns a new TailSampler calling decide with the decision made
+// on every buffered trace.
+func NewTailSampler(conf *config.AgentConfig, statsd statsd.ClientInterface, decide func(TailDecision)) *TailSampler {
+	return &TailSampler{
+		enabled:        conf.TailSamplingEnabled,
+		decisionWait:   conf.TailSamplingDecisionWait,
+		maxBufferBytes: conf.TailSamplingMaxBufferBytes,
+		policies:       newTailPolicies(conf.TailSamplingPolicies),
+		decide:         decide,
+		traces:         make(map[tailTraceID]*tailTrace),
+		statsd:         statsd,
+		tracesSeen:     atomic.NewInt64(0),
+		tracesKept:     atomic.NewInt64(0),
+		tracesEvicted:  atomic.NewInt64(0),
+		tags:           []string{"sampler:tail"},
+		stop:           make(chan struct{}),
+		stopped:        make(chan struct{}),
+	}
+}
+
+func newTailPolicies(policies []*config.TailSamplingPolicy) []tailPolicy {
+	compiled := make([]tailPolicy, 0, len(policies))
+	for _, p := range policies {
+		name := p.Name
+		if name == "" {
+			name = p.Type
+		}
+		var match func(t *tailTrace) bool
+		switch p.Type {
+		case config.TailSamplingPolicyError:
+			match = tailTraceHasError
+		case config.TailSamplingPolicyLatency:
+			threshold := int64(p.ThresholdMs * float64(time.Millisecond))
+			match = func(t *tailTrace) bool {
+				return tailTraceDuration(t) >= threshold
+			}
+		case config.TailSamplingPolicyAttribute:
+			match = func(t *tailTrace) bool {
+				return tailTraceHasAttribute(t, p)
+			}
+		case config.TailSamplingPolicyProbabilistic:
+			rate := p.SamplingPercentage / 100
+			match = func(t *tailTrace) bool {
+				return SampleByRate(t.id.lower, rate)
+			}
+		default:
+			log.Errorf("Ignoring tail sampling policy %q of unknown type %q", name, p.Type)
+			continue
+		}
+		compiled = append(compiled, tailPolicy{name: name, match: match})
+	}
+	return compiled
+}
+
+// Start starts up the TailSampler's support routine, which periodically decides
+// on the traces whose decision window expired and sends stats.
+func (s *TailSampler) Start() {
+	if !s.enabled {
+		close(s.stopped)
+		return
+	}
+	flushInterval := time.Second
+	if s.decisionWait > 0 && s.decisionWait < flushInterval {
+		flushInterval = s.decisionWait
+	}
+	go func() {
+		defer watchdog.LogOnPanic(s.statsd)
+		flushTicker := time.NewTicker(flushInterval)
+		defer flushTicker.Stop()
+		statsTicker := time.NewTicker(10 * time.Second)
+		defer statsTicker.Stop()
+		for {
+			select {
+			case now := <-flushTicker.C:
+				s.flush(now)
+			case <-statsTicker.C:
+				s.report()
+			case <-s.stop:
+				s.flushAll()
+				s.report()
+				close(s.stopped)
+				return
+			}
+		}
+	}()
+}
+
+// Stop shuts down the TailSampler's support routine, deciding on all the
+// buffered traces.
+func (s *TailSampler) Stop() {
+	if !s.enabled {
+		return
+	}
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		<-s.stopped
+	})
+}
+
+// Add buffers a chunk until the decision on its trace. The chunk must not be
+// empty.
+func (s *TailSampler) Add(now time.Time, c *TailChunk) {
+	c.size = c.Trace.TraceChunk.Msgsize()
+	id := newTailTraceID(c.Trace.TraceChunk.Spans)
+
+	s.mu.Lock()
+	t, ok := s.traces[id]
+	if !ok {
+		t = &tailTrace{id: id, deadline: now.Add(s.decisionWait)}
+		s.traces[id] = t
+		s.queue = append(s.queue, t)
+		s.tracesSeen.Inc()
+	}
+	t.chunks = append(t.chunks, c)
+	t.size += c.size
+	s.size += c.size
+
+	var evicted []*tailTrace
+	for s.size > s.maxBufferBytes && len(s.queue) > 0 {
+		evicted = append(evicted, s.pop())
+	}
+	s.mu.Unlock()
+
+	s.tracesEvicted.Add(int64(len(evicted)))
+	s.decideAll(evicted)
+}
+
+// pop removes the oldest trace from the buffer. The lock must be held.
+func (s *TailSampler) pop() *tailTrace {
+	t := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	delete(s.traces, t.id)
+	s.size -= t.size
+	return t
+}
+
+// flush decides on the traces whose decision window expired.
+func (s *TailSampler) flush(now time.Time) {
+	var expired []*tailTrace
+	s.mu.Lock()
+	for len(s.queue) > 0 && !s.queue[0].deadline.After(now) {
+		expired = append(expired, s.pop())
+	}
+	s.mu.Unlock()
+	s.decideAll(expired)
+}
+
+// flushAll decides on all the buffered traces.
+func (s *TailSampler) flushAll() {
+	var all []*tailTrace
+	s.mu.Lock()
+	for len(s.queue) > 0 {
+		all = append(all, s.pop())
+	}
+	s.mu.Unlock()
+	s.decideAll(all)
+}
+
+func (s *TailSampler) decideAll(traces []*tailTrace) {
+	for _, t := range traces {
+		s.decide(s.evaluate(t))
+	}
+}
+
+// evaluate runs the policies on a trace. The chunks of the traces kept by a
+// policy are left untouched, so that the other samplers can still count them
+// before they are marked with Keep.
+func (s *TailSampler) evaluate(t *tailTrace) TailDecision {
+	d := TailDecision{Chunks: t.chunks}
+	for _, p := range s.policies {
+		if p.match(t) {
+			d.Policy = p.name
+			break
+		}
+	}
+	if d.Policy != "" {
+		s.tracesKept.Inc()
+	}
+	return d
+}
+
+func (s *TailSampler) report() {
+	s.mu.Lock()
+	traces, size := len(s.queue), s.size
+	s.mu.Unlock()
+	_ = s.statsd.Count("datadog.trace_agent.sampler.kept", s.tracesKept.Swap(0), s.tags, 1)
+	_ = s.statsd.Count("datadog.trace_agent.sampler.seen", s.tracesSeen.Swap(0), s.tags, 1)
+	_ = s.statsd.Count("datadog.trace_agent.tail_sampler.evicted", s.tracesEvicted.Swap(0), nil, 1)
+	_ = s.statsd.Gauge("datadog.trace_agent.tail_sampler.buffered_traces", float64(traces), nil, 1)
+	_ = s.statsd.Gauge("datadog.trace_agent.tail_sampler.buffered_bytes", float64(size), nil, 1)
+}
+
+func tailTraceHasError(t *tailTrace) bool {
+	for _, c := range t.chunks {
+		for _, span := range c.Trace.TraceChunk.Spans {
+			if span.Error != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tailTraceDuration returns the duration of the root span of the trace, or of
+// its longest span if the root span was not received.
+func tailTraceDuration(t *tailTrace) int64 {
+	var longest int64
+	for _, c := range t.chunks {
+		for _, span := range c.Trace.TraceChunk.Spans {
+			if span.ParentID == 0 {
+				return span.Duration
+			}
+			longest = max(longest, span.Duration)
+		}
+	}
+	return longest
+}
+
+func tailTraceHasAttribute(t *tailTrace, p *config.TailSamplingPolicy) bool {
+	for _, c := range t.chunks {
+		for _, span := range c.Trace.TraceChunk.Spans {
+			if v, ok := spanAttribute(span, p.Key); ok && (p.Re == nil || p.Re.MatchString(v)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// spanAttribute returns the value of the meta or the metric key of a span.
+func spanAttribute(span *pb.Span, key string) (string, bool) {
+	if v, ok := span.Meta[key]; ok {
+		return v, true
+	}
+	if v, ok := span.Metrics[key]; ok {
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
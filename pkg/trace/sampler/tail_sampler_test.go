@@ -0,0 +1,209 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+func newTestTailSampler(policies ...*config.TailSamplingPolicy) (*TailSampler, *[]TailDecision) {
+	conf := &config.AgentConfig{
+		TailSamplingEnabled:        true,
+		TailSamplingDecisionWait:   10 * time.Second,
+		TailSamplingMaxBufferBytes: 1 << 20,
+		TailSamplingPolicies:       policies,
+	}
+	var decisions []TailDecision
+	s := NewTailSampler(conf, &statsd.NoOpClient{}, func(d TailDecision) {
+		decisions = append(decisions, d)
+	})
+	return s, &decisions
+}
+
+func newTailChunk(spans ...*pb.Span) *TailChunk {
+	chunk := &pb.TraceChunk{Spans: spans, DroppedTrace: true}
+	return &TailChunk{Trace: &traceutil.ProcessedTrace{TraceChunk: chunk, Root: traceutil.GetRoot(spans)}}
+}
+
+func TestTailSamplerDecisionWindow(t *testing.T) {
+	s, decisions := newTestTailSampler(&config.TailSamplingPolicy{Type: config.TailSamplingPolicyError})
+	now := time.Now()
+
+	// the chunks of a trace are decided together
+	s.Add(now, newTailChunk(&pb.Span{TraceID: 1, SpanID: 2, ParentID: 1}))
+	s.Add(now.Add(time.Second), newTailChunk(&pb.Span{TraceID: 2, SpanID: 1}))
+	s.Add(now.Add(2*time.Second), newTailChunk(&pb.Span{TraceID: 1, SpanID: 1, Error: 1}))
+
+	s.flush(now.Add(9 * time.Second))
+	assert.Empty(t, *decisions)
+
+	s.flush(now.Add(10 * time.Second))
+	require.Len(t, *decisions, 1)
+	d := (*decisions)[0]
+	assert.Equal(t, "error", d.Policy)
+	require.Len(t, d.Chunks, 2)
+	for _, c := range d.Chunks {
+		// the chunks are only marked as kept by the caller
+		assert.True(t, c.Trace.TraceChunk.DroppedTrace)
+		c.Keep(d.Policy)
+		assert.False(t, c.Trace.TraceChunk.DroppedTrace)
+		assert.Equal(t, int32(PriorityUserKeep), c.Trace.TraceChunk.Priority)
+		assert.Equal(t, "error", c.Trace.TraceChunk.Tags[KeyTailSamplingPolicy])
+	}
+
+	// no policy matches, the trace is left to the other samplers
+	s.flush(now.Add(11 * time.Second))
+	require.Len(t, *decisions, 2)
+	d = (*decisions)[1]
+	assert.Empty(t, d.Policy)
+	require.Len(t, d.Chunks, 1)
+	assert.True(t, d.Chunks[0].Trace.TraceChunk.DroppedTrace)
+	assert.NotContains(t, d.Chunks[0].Trace.TraceChunk.Tags, KeyTailSamplingPolicy)
+
+	assert.Empty(t, s.traces)
+	assert.Zero(t, s.size)
+	assert.EqualValues(t, 2, s.tracesSeen.Load())
+	assert.EqualValues(t, 1, s.tracesKept.Load())
+}
+
+func TestTailChunkKeepLeavesUserDrops(t *testing.T) {
+	c := newTailChunk(&pb.Span{TraceID: 1, SpanID: 1, Error: 1})
+	c.Trace.TraceChunk.Priority = int32(PriorityUserDrop)
+	assert.True(t, c.DroppedByUser())
+
+	c.Keep("error")
+	assert.True(t, c.Trace.TraceChunk.DroppedTrace)
+	assert.Equal(t, int32(PriorityUserDrop), c.Trace.TraceChunk.Priority)
+	assert.NotContains(t, c.Trace.TraceChunk.Tags, KeyTailSamplingPolicy)
+
+	c = newTailChunk(&pb.Span{TraceID: 1, SpanID: 1, Error: 1})
+	c.Trace.TraceChunk.Priority = int32(PriorityAutoDrop)
+	assert.False(t, c.DroppedByUser())
+	c.Keep("error")
+	assert.False(t, c.Trace.TraceChunk.DroppedTrace)
+	assert.Equal(t, int32(PriorityUserKeep), c.Trace.TraceChunk.Priority)
+}
+
+func TestTailSampler128BitTraceIDs(t *testing.T) {
+	s, decisions := newTestTailSampler(&config.TailSamplingPolicy{Type: config.TailSamplingPolicyError})
+	now := time.Now()
+
+	// the traces only differ by the upper 64 bits of their IDs
+	s.Add(now, newTailChunk(&pb.Span{TraceID: 1, SpanID: 1, Meta: map[string]string{"_dd.p.tid": "6639a2b300000000"}}))
+	s.Add(now, newTailChunk(&pb.Span{TraceID: 1, SpanID: 2, Error: 1, Meta: map[string]string{"_dd.p.tid": "6639a2b400000000"}}))
+	s.Add(now, newTailChunk(&pb.Span{TraceID: 1, SpanID: 3, ParentID: 2}, &pb.Span{TraceID: 1, SpanID: 4, ParentID: 3, Meta: map[string]string{"_dd.p.tid": "6639a2b400000000"}}))
+	assert.Len(t, s.traces, 2)
+
+	s.flushAll()
+	require.Len(t, *decisions, 2)
+	assert.Empty(t, (*decisions)[0].Policy)
+	assert.Len(t, (*decisions)[0].Chunks, 1)
+	assert.Equal(t, "error", (*decisions)[1].Policy)
+	assert.Len(t, (*decisions)[1].Chunks, 2)
+}
+
+func TestTailSamplerMaxBufferBytes(t *testing.T) {
+	s, decisions := newTestTailSampler()
+	now := time.Now()
+
+	first := newTailChunk(&pb.Span{TraceID: 1, SpanID: 1})
+	second := newTailChunk(&pb.Span{TraceID: 2, SpanID: 1})
+	s.maxBufferBytes = first.Trace.TraceChunk.Msgsize() + second.Trace.TraceChunk.Msgsize()
+	s.Add(now, first)
+	s.Add(now, second)
+	assert.Empty(t, *decisions)
+
+	// the oldest trace is decided early to make room for the new one
+	s.Add(now, newTailChunk(&pb.Span{TraceID: 3, SpanID: 1}))
+	require.Len(t, *decisions, 1)
+	assert.Equal(t, []*TailChunk{first}, (*decisions)[0].Chunks)
+	assert.EqualValues(t, 1, s.tracesEvicted.Load())
+	assert.Len(t, s.traces, 2)
+}
+
+func TestTailSamplerPolicies(t *testing.T) {
+	for name, tt := range map[string]struct {
+		policy *config.TailSamplingPolicy
+		keep   [][]*pb.Span
+		drop   [][]*pb.Span
+	}{
+		"error": {
+			policy: &config.TailSamplingPolicy{Type: config.TailSamplingPolicyError},
+			keep:   [][]*pb.Span{{{TraceID: 1, SpanID: 1}, {TraceID: 1, SpanID: 2, ParentID: 1, Error: 1}}},
+			drop:   [][]*pb.Span{{{TraceID: 1, SpanID: 1}}},
+		},
+		"latency": {
+			policy: &config.TailSamplingPolicy{Type: config.TailSamplingPolicyLatency, ThresholdMs: 500},
+			keep: [][]*pb.Span{
+				{{TraceID: 1, SpanID: 1, Duration: int64(time.Second)}},
+				// the longest span is used when the root is missing
+				{{TraceID: 1, SpanID: 2, ParentID: 1, Duration: int64(600 * time.Millisecond)}},
+			},
+			drop: [][]*pb.Span{
+				{{TraceID: 1, SpanID: 1, Duration: int64(100 * time.Millisecond)}, {TraceID: 1, SpanID: 2, ParentID: 1, Duration: int64(time.Second)}},
+			},
+		},
+		"attribute": {
+			policy: &config.TailSamplingPolicy{Type: config.TailSamplingPolicyAttribute, Key: "http.status_code", Re: regexp.MustCompile("^5")},
+			keep: [][]*pb.Span{
+				{{TraceID: 1, SpanID: 1}, {TraceID: 1, SpanID: 2, ParentID: 1, Meta: map[string]string{"http.status_code": "503"}}},
+				{{TraceID: 1, SpanID: 1, Metrics: map[string]float64{"http.status_code": 500}}},
+			},
+			drop: [][]*pb.Span{
+				{{TraceID: 1, SpanID: 1, Meta: map[string]string{"http.status_code": "200"}}},
+				{{TraceID: 1, SpanID: 1}},
+			},
+		},
+		"probabilistic": {
+			policy: &config.TailSamplingPolicy{Type: config.TailSamplingPolicyProbabilistic, SamplingPercentage: 100},
+			keep:   [][]*pb.Span{{{TraceID: 1, SpanID: 1}}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, decisions := newTestTailSampler(tt.policy)
+			for _, spans := range tt.keep {
+				s.Add(time.Now(), newTailChunk(spans...))
+				s.flushAll()
+				assert.Equal(t, name, (*decisions)[len(*decisions)-1].Policy, "%v", spans)
+			}
+			for _, spans := range tt.drop {
+				s.Add(time.Now(), newTailChunk(spans...))
+				s.flushAll()
+				assert.Empty(t, (*decisions)[len(*decisions)-1].Policy, "%v", spans)
+			}
+		})
+	}
+}
+
+func TestTailSamplerUnknownPolicy(t *testing.T) {
+	s, _ := newTestTailSampler(
+		&config.TailSamplingPolicy{Type: "unknown"},
+		&config.TailSamplingPolicy{Name: "slow", Type: config.TailSamplingPolicyLatency},
+	)
+	require.Len(t, s.policies, 1)
+	assert.Equal(t, "slow", s.policies[0].name)
+}
+
+func TestTailSamplerStop(t *testing.T) {
+	s, decisions := newTestTailSampler()
+	s.Start()
+	s.Add(time.Now(), newTailChunk(&pb.Span{TraceID: 1, SpanID: 1}))
+	s.Stop()
+
+	// the buffered traces are decided when stopping
+	require.Len(t, *decisions, 1)
+	assert.Empty(t, s.traces)
+}
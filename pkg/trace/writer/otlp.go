@@ -0,0 +1,484 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"go.uber.org/atomic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/timing"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+// pathOTLPTraces is the default path of the OTLP/HTTP traces endpoint.
+const pathOTLPTraces = "/v1/traces"
+
+// otlpMaxAttempts is the maximum number of times the export of a batch is attempted.
+const otlpMaxAttempts = 5
+
+// OTLPMaxBatchSpans specifies the number of buffered spans triggering an export;
+// replaced in tests.
+var OTLPMaxBatchSpans = 8192
+
+// otlpExporter exports traces to an OTLP endpoint.
+type otlpExporter interface {
+	// export exports the traces, returning the number of spans rejected by the endpoint.
+	// The errors which may be retried are *retriableError.
+	export(ctx context.Context, td ptrace.Traces) (rejected int64, err error)
+	// close releases the resources of the exporter.
+	close()
+}
+
+// otlpBatch is a batch of OTLP spans to be exported.
+type otlpBatch struct {
+	traces   ptrace.Traces
+	spans    int
+	attempts int
+	retryAt  time.Time // time after which a failed batch is retried
+}
+
+// otlpWriterStats holds the telemetry of the OTLPTraceWriter.
+type otlpWriterStats struct {
+	Payloads     atomic.Int64
+	Spans        atomic.Int64
+	Bytes        atomic.Int64
+	Retries      atomic.Int64
+	Errors       atomic.Int64
+	DroppedSpans atomic.Int64
+}
+
+// OTLPTraceWriter converts the sampled chunks written to it to OTLP spans, and exports them
+// to an OTLP endpoint over gRPC or HTTP. It is written the same chunks as the TraceWriter, so
+// that it exports the traces kept by the same sampling decisions. A nil OTLPTraceWriter
+// exports nothing.
+type OTLPTraceWriter struct {
+	exporter otlpExporter
+	hostname string
+	env      string
+	timeout  time.Duration
+	tick     time.Duration // flush frequency
+
+	mu       sync.Mutex
+	traces   ptrace.Traces // spans buffered
+	buffered int           // number of spans buffered
+
+	out       chan *otlpBatch // full batches waiting to be exported
+	queue     []*otlpBatch    // failed batches waiting to be retried, the oldest first
+	queueSize int
+
+	stop  chan struct{}
+	wg    sync.WaitGroup
+	stats *otlpWriterStats
+
+	statsd  statsd.ClientInterface
+	timing  timing.Reporter
+	easylog *log.ThrottledLogger
+}
+
+// NewOTLPTraceWriter returns a new OTLPTraceWriter exporting the traces to the endpoint of
+// cfg.OTLPExport, or nil if the export isn't enabled.
+func NewOTLPTraceWriter(cfg *config.AgentConfig, statsd statsd.ClientInterface, timing timing.Reporter) (*OTLPTraceWriter, error) {
+	ecfg := cfg.OTLPExport
+	if ecfg == nil || !ecfg.Enabled {
+		return nil, nil
+	}
+	userAgent := fmt.Sprintf("Datadog Trace Agent/%s/%s", cfg.AgentVersion, cfg.GitCommit)
+	var (
+		exporter otlpExporter
+		err      error
+	)
+	switch ecfg.Protocol {
+	case config.OTLPExportProtocolGRPC, "":
+		exporter, err = newGRPCOTLPExporter(ecfg, userAgent)
+	case config.OTLPExportProtocolHTTP:
+		exporter, err = newHTTPOTLPExporter(ecfg, userAgent)
+	default:
+		err = fmt.Errorf("unknown protocol %q, it must be %q or %q", ecfg.Protocol, config.OTLPExportProtocolGRPC, config.OTLPExportProtocolHTTP)
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("OTLP trace writer initialized (endpoint=%s protocol=%s)", ecfg.Endpoint, ecfg.Protocol)
+	return newOTLPTraceWriter(cfg, exporter, statsd, timing), nil
+}
+
+func newOTLPTraceWriter(cfg *config.AgentConfig, exporter otlpExporter, statsd statsd.ClientInterface, timing timing.Reporter) *OTLPTraceWriter {
+	w := &OTLPTraceWriter{
+		exporter:  exporter,
+		hostname:  cfg.Hostname,
+		env:       cfg.DefaultEnv,
+		timeout:   cfg.OTLPExport.Timeout,
+		tick:      5 * time.Second,
+		traces:    ptrace.NewTraces(),
+		out:       make(chan *otlpBatch, 1),
+		queueSize: cfg.OTLPExport.QueueSize,
+		stop:      make(chan struct{}),
+		stats:     &otlpWriterStats{},
+		statsd:    statsd,
+		timing:    timing,
+		easylog:   log.NewThrottled(5, 10*time.Second), // no more than 5 messages every 10 seconds
+	}
+	if w.timeout <= 0 {
+		w.timeout = 10 * time.Second
+	}
+	if s := cfg.TraceWriter.FlushPeriodSeconds; s != 0 {
+		w.tick = time.Duration(s*1000) * time.Millisecond
+	}
+	w.wg.Add(1)
+	go w.run()
+	return w
+}
+
+// run exports the batches, flushes the buffered spans, and retries the failed batches.
+func (w *OTLPTraceWriter) run() {
+	defer w.wg.Done()
+	tck := time.NewTicker(w.tick)
+	defer tck.Stop()
+	for {
+		select {
+		case b := <-w.out:
+			w.export(b)
+		case now := <-tck.C:
+			w.flush()
+			w.retry(now)
+			w.report()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Stop stops the OTLPTraceWriter, exporting the buffered spans and the batches waiting to be
+// retried. The remaining batches are dropped after the first failure.
+func (w *OTLPTraceWriter) Stop() {
+	if w == nil {
+		return
+	}
+	log.Debug("Exiting OTLP trace writer. Trying to export whatever is left...")
+	close(w.stop)
+	w.wg.Wait()
+	w.mu.Lock()
+	pending := w.queue
+	w.queue = nil
+	for len(w.out) > 0 {
+		pending = append(pending, <-w.out)
+	}
+	if w.buffered > 0 {
+		pending = append(pending, w.takeBatch())
+	}
+	w.mu.Unlock()
+	for i, b := range pending {
+		b.attempts = otlpMaxAttempts - 1 // the batches aren't retried anymore
+		if !w.export(b) {
+			for _, b := range pending[i+1:] {
+				w.drop(b, "the writer is stopping")
+			}
+			break
+		}
+	}
+	w.report()
+	w.exporter.close()
+}
+
+// WriteChunks converts the sampled chunks to OTLP spans, and buffers them to be exported.
+func (w *OTLPTraceWriter) WriteChunks(pkg *SampledChunks) {
+	if w == nil || len(pkg.TracerPayload.Chunks) == 0 {
+		return
+	}
+	td, n := otlpTraces(pkg.TracerPayload, w.hostname, w.env)
+	if n == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	td.ResourceSpans().MoveAndAppendTo(w.traces.ResourceSpans())
+	w.buffered += n
+	var b *otlpBatch
+	if w.buffered >= OTLPMaxBatchSpans {
+		b = w.takeBatch()
+	}
+	w.mu.Unlock()
+
+	if b == nil {
+		return
+	}
+	select {
+	case w.out <- b:
+	default:
+		// the exporter is busy, the batch will be exported with the retries
+		w.mu.Lock()
+		w.enqueue(b)
+		w.mu.Unlock()
+	}
+}
+
+// takeBatch returns the buffered spans as a batch, and resets the buffer. w must be locked.
+func (w *OTLPTraceWriter) takeBatch() *otlpBatch {
+	b := &otlpBatch{traces: w.traces, spans: w.buffered}
+	w.traces = ptrace.NewTraces()
+	w.buffered = 0
+	return b
+}
+
+// flush exports the buffered spans.
+func (w *OTLPTraceWriter) flush() {
+	w.mu.Lock()
+	if w.buffered == 0 {
+		w.mu.Unlock()
+		return
+	}
+	b := w.takeBatch()
+	w.mu.Unlock()
+	w.export(b)
+}
+
+// export exports the batch, queueing it to be retried if it fails with a retriable error.
+// It reports whether the batch was exported.
+func (w *OTLPTraceWriter) export(b *otlpBatch) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	defer cancel()
+	b.attempts++
+	start := time.Now()
+	rejected, err := w.exporter.export(ctx, b.traces)
+	if err != nil {
+		w.stats.Errors.Inc()
+		var rerr *retriableError
+		if !errors.As(err, &rerr) || b.attempts >= otlpMaxAttempts {
+			w.drop(b, err.Error())
+			return false
+		}
+		log.Debugf("Retrying to export OTLP traces; error: %s", err)
+		b.retryAt = time.Now().Add(backoffDuration(b.attempts))
+		w.mu.Lock()
+		w.enqueue(b)
+		w.mu.Unlock()
+		return false
+	}
+	w.timing.Since("datadog.trace_agent.otlp_writer.flush_duration", start)
+	w.stats.Payloads.Inc()
+	w.stats.Spans.Add(int64(b.spans) - rejected)
+	w.stats.Bytes.Add(int64((&ptrace.ProtoMarshaler{}).TracesSize(b.traces)))
+	if rejected > 0 {
+		w.easylog.Warn("OTLP endpoint rejected %d spans.", rejected)
+		w.stats.DroppedSpans.Add(rejected)
+	}
+	log.Debugf("Exported %d spans to the OTLP endpoint; time: %s", b.spans, time.Since(start))
+	return true
+}
+
+// enqueue queues the batch to be retried, dropping the oldest batch if the queue is full.
+// w must be locked.
+func (w *OTLPTraceWriter) enqueue(b *otlpBatch) {
+	if w.queueSize <= 0 {
+		w.drop(b, "the retry queue is disabled")
+		return
+	}
+	if len(w.queue) >= w.queueSize {
+		w.drop(w.queue[0], "the retry queue is full")
+		w.queue = w.queue[1:]
+	}
+	w.queue = append(w.queue, b)
+}
+
+// retry exports the queued batches which are due to be retried at now. It stops at the
+// first failure, leaving the other batches queued.
+func (w *OTLPTraceWriter) retry(now time.Time) {
+	w.mu.Lock()
+	var due []*otlpBatch
+	queue := w.queue[:0]
+	for _, b := range w.queue {
+		if b.retryAt.After(now) {
+			queue = append(queue, b)
+		} else {
+			due = append(due, b)
+		}
+	}
+	w.queue = queue
+	w.mu.Unlock()
+
+	for i, b := range due {
+		if b.attempts > 0 {
+			w.stats.Retries.Inc()
+		}
+		if !w.export(b) {
+			w.mu.Lock()
+			for _, b := range due[i+1:] {
+				w.enqueue(b)
+			}
+			w.mu.Unlock()
+			return
+		}
+	}
+}
+
+func (w *OTLPTraceWriter) drop(b *otlpBatch, reason string) {
+	w.easylog.Warn("OTLP trace export failed, %d spans dropped: %s", b.spans, reason)
+	w.stats.DroppedSpans.Add(int64(b.spans))
+}
+
+func (w *OTLPTraceWriter) report() {
+	_ = w.statsd.Count("datadog.trace_agent.otlp_writer.payloads", w.stats.Payloads.Swap(0), nil, 1)
+	_ = w.statsd.Count("datadog.trace_agent.otlp_writer.spans", w.stats.Spans.Swap(0), nil, 1)
+	_ = w.statsd.Count("datadog.trace_agent.otlp_writer.bytes", w.stats.Bytes.Swap(0), nil, 1)
+	_ = w.statsd.Count("datadog.trace_agent.otlp_writer.retries", w.stats.Retries.Swap(0), nil, 1)
+	_ = w.statsd.Count("datadog.trace_agent.otlp_writer.errors", w.stats.Errors.Swap(0), nil, 1)
+	_ = w.statsd.Count("datadog.trace_agent.otlp_writer.dropped_spans", w.stats.DroppedSpans.Swap(0), nil, 1)
+	w.mu.Lock()
+	queued := len(w.queue)
+	w.mu.Unlock()
+	_ = w.statsd.Gauge("datadog.trace_agent.otlp_writer.queued_batches", float64(queued), nil, 1)
+}
+
+// grpcOTLPExporter exports traces over OTLP/gRPC.
+type grpcOTLPExporter struct {
+	conn   *grpc.ClientConn
+	client ptraceotlp.GRPCClient
+	md     metadata.MD
+}
+
+func newGRPCOTLPExporter(cfg *config.OTLPExport, userAgent string) (*grpcOTLPExporter, error) {
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if cfg.Insecure {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.NewClient(cfg.Endpoint, grpc.WithTransportCredentials(creds), grpc.WithUserAgent(userAgent))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP/gRPC endpoint %q: %v", cfg.Endpoint, err)
+	}
+	return &grpcOTLPExporter{
+		conn:   conn,
+		client: ptraceotlp.NewGRPCClient(conn),
+		md:     metadata.New(cfg.Headers),
+	}, nil
+}
+
+func (e *grpcOTLPExporter) export(ctx context.Context, td ptrace.Traces) (int64, error) {
+	ctx = metadata.NewOutgoingContext(ctx, e.md)
+	resp, err := e.client.Export(ctx, ptraceotlp.NewExportRequestFromTraces(td))
+	if err != nil {
+		if isRetriableGRPCCode(status.Code(err)) {
+			return 0, &retriableError{err}
+		}
+		return 0, err
+	}
+	return resp.PartialSuccess().RejectedSpans(), nil
+}
+
+func (e *grpcOTLPExporter) close() {
+	if err := e.conn.Close(); err != nil {
+		log.Debugf("Error closing the OTLP/gRPC connection: %v", err)
+	}
+}
+
+// isRetriableGRPCCode reports whether the export requests failing with the given code should
+// be retried, as specified by OTLP.
+func isRetriableGRPCCode(code codes.Code) bool {
+	switch code {
+	case codes.Canceled, codes.DeadlineExceeded, codes.Aborted, codes.OutOfRange,
+		codes.Unavailable, codes.DataLoss, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// httpOTLPExporter exports traces over OTLP/HTTP, encoded in protobuf.
+type httpOTLPExporter struct {
+	client    *http.Client
+	url       string
+	headers   map[string]string
+	userAgent string
+}
+
+func newHTTPOTLPExporter(cfg *config.OTLPExport, userAgent string) (*httpOTLPExporter, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP/HTTP endpoint %q: it must be an http or https URL", cfg.Endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = pathOTLPTraces
+	}
+	return &httpOTLPExporter{
+		client:    &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()},
+		url:       u.String(),
+		headers:   cfg.Headers,
+		userAgent: userAgent,
+	}, nil
+}
+
+func (e *httpOTLPExporter) export(ctx context.Context, td ptrace.Traces) (int64, error) {
+	body, err := ptraceotlp.NewExportRequestFromTraces(td).MarshalProto()
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set(headerUserAgent, e.userAgent)
+	resp, err := e.client.Do(req)
+	if err != nil {
+		// request errors include timeouts or name resolution errors and
+		// should thus be retried.
+		return 0, &retriableError{err}
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		log.Debugf("Error reading the OTLP/HTTP response: %v", err)
+	}
+	switch {
+	case resp.StatusCode/100 == 2:
+		out := ptraceotlp.NewExportResponse()
+		if err := out.UnmarshalProto(respBody); err != nil {
+			// the response body is optional
+			return 0, nil
+		}
+		return out.PartialSuccess().RejectedSpans(), nil
+	case isRetriableOTLPHTTPCode(resp.StatusCode):
+		return 0, &retriableError{fmt.Errorf("server responded with %q", resp.Status)}
+	default:
+		return 0, errors.New(resp.Status)
+	}
+}
+
+func (e *httpOTLPExporter) close() {
+	e.client.CloseIdleConnections()
+}
+
+// isRetriableOTLPHTTPCode reports whether the export requests failing with the given HTTP
+// status code should be retried, as specified by OTLP.
+func isRetriableOTLPHTTPCode(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,427 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/timing"
+)
+
+func TestOTLPTraces(t *testing.T) {
+	p := &pb.TracerPayload{
+		ContainerID:   "abc",
+		LanguageName:  "go",
+		TracerVersion: "1.60.0",
+		AppVersion:    "1.0",
+		Chunks: []*pb.TraceChunk{
+			{
+				Spans: []*pb.Span{
+					{
+						Service: "web", Name: "http.request", Resource: "GET /users", Type: "web",
+						TraceID: 2, SpanID: 1, Start: 100, Duration: 50,
+						Meta: map[string]string{
+							"_dd.p.tid": "0000000000000001",
+							"span.kind": "server",
+							"http.url":  "/users",
+							"events":    `[{"time_unix_nano":120,"name":"retry","attributes":{"attempt":2,"reason":"timeout"}}]`,
+						},
+						Metrics: map[string]float64{"_sampling_priority_v1": 2, "_top_level": 1},
+						SpanLinks: []*pb.SpanLink{
+							{TraceID: 4, TraceIDHigh: 3, SpanID: 5, Tracestate: "dd=s:1", Flags: 1 | 1<<31, Attributes: map[string]string{"link.kind": "follows"}},
+						},
+					},
+					{
+						Service: "web-db", Name: "postgres.query", Resource: "SELECT 1",
+						TraceID: 2, SpanID: 6, ParentID: 1, Start: 110, Duration: 10, Error: 1,
+						Meta: map[string]string{"error.msg": "timeout", "version": "2.0"},
+					},
+				},
+			},
+			// only the events and the single sampled spans of the dropped chunks are kept
+			{DroppedTrace: true, Spans: []*pb.Span{{Service: "web", TraceID: 7, SpanID: 8}}},
+		},
+	}
+	td, n := otlpTraces(p, "host", "prod")
+	assert.Equal(t, 2, n)
+	require.Equal(t, 2, td.ResourceSpans().Len())
+
+	web := td.ResourceSpans().At(0)
+	assert.Equal(t, map[string]interface{}{
+		"service.name":           "web",
+		"deployment.environment": "prod",
+		"service.version":        "1.0",
+		"host.name":              "host",
+		"container.id":           "abc",
+		"telemetry.sdk.language": "go",
+		"telemetry.sdk.version":  "1.60.0",
+	}, web.Resource().Attributes().AsRaw())
+	require.Equal(t, 1, web.ScopeSpans().Len())
+	require.Equal(t, 1, web.ScopeSpans().At(0).Spans().Len())
+	span := web.ScopeSpans().At(0).Spans().At(0)
+	assert.Equal(t, pcommon.TraceID{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2}, span.TraceID())
+	assert.Equal(t, pcommon.SpanID{0, 0, 0, 0, 0, 0, 0, 1}, span.SpanID())
+	assert.True(t, span.ParentSpanID().IsEmpty())
+	assert.Equal(t, "GET /users", span.Name())
+	assert.Equal(t, ptrace.SpanKindServer, span.Kind())
+	assert.Equal(t, pcommon.Timestamp(100), span.StartTimestamp())
+	assert.Equal(t, pcommon.Timestamp(150), span.EndTimestamp())
+	assert.Equal(t, ptrace.StatusCodeUnset, span.Status().Code())
+	assert.Equal(t, map[string]interface{}{
+		"operation.name":    "http.request",
+		"resource.name":     "GET /users",
+		"span.type":         "web",
+		"http.url":          "/users",
+		"sampling.priority": int64(2),
+		"_top_level":        float64(1),
+	}, span.Attributes().AsRaw())
+	require.Equal(t, 1, span.Events().Len())
+	assert.Equal(t, "retry", span.Events().At(0).Name())
+	assert.Equal(t, pcommon.Timestamp(120), span.Events().At(0).Timestamp())
+	assert.Equal(t, map[string]interface{}{"attempt": float64(2), "reason": "timeout"}, span.Events().At(0).Attributes().AsRaw())
+	require.Equal(t, 1, span.Links().Len())
+	link := span.Links().At(0)
+	assert.Equal(t, pcommon.TraceID{0, 0, 0, 0, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 4}, link.TraceID())
+	assert.Equal(t, pcommon.SpanID{0, 0, 0, 0, 0, 0, 0, 5}, link.SpanID())
+	assert.Equal(t, "dd=s:1", link.TraceState().AsRaw())
+	assert.Equal(t, uint32(1), link.Flags())
+	assert.Equal(t, map[string]interface{}{"link.kind": "follows"}, link.Attributes().AsRaw())
+
+	db := td.ResourceSpans().At(1)
+	v, _ := db.Resource().Attributes().Get("service.version")
+	assert.Equal(t, "2.0", v.Str())
+	span = db.ScopeSpans().At(0).Spans().At(0)
+	assert.Equal(t, pcommon.SpanID{0, 0, 0, 0, 0, 0, 0, 1}, span.ParentSpanID())
+	assert.Equal(t, ptrace.StatusCodeError, span.Status().Code())
+	assert.Equal(t, "timeout", span.Status().Message())
+}
+
+func TestOTLPTracesFromOTLP(t *testing.T) {
+	// the spans received through OTLP are converted back to their original fields
+	span := &pb.Span{
+		Service: "web", Name: "opentelemetry.client", Resource: "GET", TraceID: 2, SpanID: 1,
+		Meta: map[string]string{
+			"otel.trace_id":        "72df520af2bde7a5240031ead750e5f3",
+			"otel.library.name":    "net/http",
+			"otel.library.version": "0.1",
+			"otel.status_code":     "Ok",
+			"span.kind":            "client",
+			"w3c.tracestate":       "foo=bar",
+			"_dd.span_links":       `[{"trace_id":"fedcba98765432100123456789abcdef","span_id":"abcdef0123456789","trace_state":"dd=s:2","attributes":{"a":"1"}}]`,
+			"events":               "invalid",
+		},
+	}
+	td, n := otlpTraces(&pb.TracerPayload{Chunks: []*pb.TraceChunk{{Spans: []*pb.Span{span}}}}, "host", "")
+	assert.Equal(t, 1, n)
+	ss := td.ResourceSpans().At(0).ScopeSpans().At(0)
+	assert.Equal(t, "net/http", ss.Scope().Name())
+	assert.Equal(t, "0.1", ss.Scope().Version())
+	out := ss.Spans().At(0)
+	assert.Equal(t, "72df520af2bde7a5240031ead750e5f3", out.TraceID().String())
+	assert.Equal(t, ptrace.SpanKindClient, out.Kind())
+	assert.Equal(t, ptrace.StatusCodeOk, out.Status().Code())
+	assert.Equal(t, "foo=bar", out.TraceState().AsRaw())
+	require.Equal(t, 1, out.Links().Len())
+	assert.Equal(t, "fedcba98765432100123456789abcdef", out.Links().At(0).TraceID().String())
+	assert.Equal(t, "abcdef0123456789", out.Links().At(0).SpanID().String())
+	assert.Equal(t, "dd=s:2", out.Links().At(0).TraceState().AsRaw())
+	// the events which can't be decoded are kept as an attribute
+	assert.Equal(t, 0, out.Events().Len())
+	assert.Equal(t, map[string]interface{}{
+		"operation.name": "opentelemetry.client",
+		"resource.name":  "GET",
+		"events":         "invalid",
+	}, out.Attributes().AsRaw())
+}
+
+// mockOTLPExporter records the exported traces, and fails the exports with its errors.
+type mockOTLPExporter struct {
+	mu       sync.Mutex
+	errs     []error // returned by the next exports
+	exported []ptrace.Traces
+	closed   bool
+}
+
+func (e *mockOTLPExporter) export(_ context.Context, td ptrace.Traces) (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.errs) > 0 {
+		err := e.errs[0]
+		e.errs = e.errs[1:]
+		return 0, err
+	}
+	e.exported = append(e.exported, td)
+	return 0, nil
+}
+
+func (e *mockOTLPExporter) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.closed = true
+}
+
+// exportedSpans returns the number of spans of each export.
+func (e *mockOTLPExporter) exportedSpans() []int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var out []int
+	for _, td := range e.exported {
+		out = append(out, td.SpanCount())
+	}
+	return out
+}
+
+func newTestOTLPTraceWriter(exporter otlpExporter, queueSize int) *OTLPTraceWriter {
+	cfg := config.New()
+	cfg.OTLPExport.QueueSize = queueSize
+	// the tests flush the writer
+	cfg.TraceWriter.FlushPeriodSeconds = 3600
+	return newOTLPTraceWriter(cfg, exporter, &statsd.NoOpClient{}, &timing.NoopReporter{})
+}
+
+func testSampledChunks(spans int) *SampledChunks {
+	chunk := &pb.TraceChunk{}
+	for i := 0; i < spans; i++ {
+		chunk.Spans = append(chunk.Spans, &pb.Span{Service: "web", TraceID: 1, SpanID: uint64(i + 1)})
+	}
+	return &SampledChunks{TracerPayload: &pb.TracerPayload{Chunks: []*pb.TraceChunk{chunk}}, SpanCount: int64(spans)}
+}
+
+func TestOTLPTraceWriter(t *testing.T) {
+	t.Run("flush", func(t *testing.T) {
+		exporter := &mockOTLPExporter{}
+		w := newTestOTLPTraceWriter(exporter, 10)
+		w.WriteChunks(testSampledChunks(2))
+		w.WriteChunks(testSampledChunks(3))
+		w.flush()
+		w.WriteChunks(testSampledChunks(1))
+		w.Stop()
+		assert.Equal(t, []int{5, 1}, exporter.exportedSpans())
+		assert.True(t, exporter.closed)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		defer func(old int) { OTLPMaxBatchSpans = old }(OTLPMaxBatchSpans)
+		OTLPMaxBatchSpans = 3
+		exporter := &mockOTLPExporter{}
+		w := newTestOTLPTraceWriter(exporter, 10)
+		w.WriteChunks(testSampledChunks(2))
+		w.WriteChunks(testSampledChunks(2))
+		assert.Eventually(t, func() bool { return len(exporter.exportedSpans()) == 1 }, time.Second, 10*time.Millisecond)
+		w.Stop()
+		assert.Equal(t, []int{4}, exporter.exportedSpans())
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		exporter := &mockOTLPExporter{errs: []error{&retriableError{errors.New("unavailable")}}}
+		w := newTestOTLPTraceWriter(exporter, 10)
+		w.WriteChunks(testSampledChunks(2))
+		w.flush()
+		assert.Empty(t, exporter.exportedSpans())
+		assert.Len(t, w.queue, 1)
+		w.retry(time.Now().Add(time.Minute))
+		assert.Empty(t, w.queue)
+		assert.Equal(t, []int{2}, exporter.exportedSpans())
+		assert.EqualValues(t, 1, w.stats.Retries.Load())
+		assert.EqualValues(t, 1, w.stats.Errors.Load())
+		assert.EqualValues(t, 0, w.stats.DroppedSpans.Load())
+		w.Stop()
+	})
+
+	t.Run("max-attempts", func(t *testing.T) {
+		exporter := &mockOTLPExporter{}
+		for i := 0; i < otlpMaxAttempts; i++ {
+			exporter.errs = append(exporter.errs, &retriableError{errors.New("unavailable")})
+		}
+		w := newTestOTLPTraceWriter(exporter, 10)
+		w.WriteChunks(testSampledChunks(2))
+		w.flush()
+		for i := 1; i < otlpMaxAttempts; i++ {
+			w.retry(time.Now().Add(time.Minute))
+		}
+		assert.Empty(t, w.queue)
+		assert.Empty(t, exporter.exportedSpans())
+		assert.EqualValues(t, 2, w.stats.DroppedSpans.Load())
+		w.Stop()
+	})
+
+	t.Run("not-retriable", func(t *testing.T) {
+		exporter := &mockOTLPExporter{errs: []error{errors.New("400 Bad Request")}}
+		w := newTestOTLPTraceWriter(exporter, 10)
+		w.WriteChunks(testSampledChunks(2))
+		w.flush()
+		assert.Empty(t, w.queue)
+		assert.EqualValues(t, 2, w.stats.DroppedSpans.Load())
+		w.Stop()
+		assert.Empty(t, exporter.exportedSpans())
+	})
+
+	t.Run("queue-full", func(t *testing.T) {
+		unavailable := &retriableError{errors.New("unavailable")}
+		exporter := &mockOTLPExporter{errs: []error{unavailable, unavailable, unavailable}}
+		w := newTestOTLPTraceWriter(exporter, 2)
+		for i := 1; i <= 3; i++ {
+			w.WriteChunks(testSampledChunks(i))
+			w.flush()
+		}
+		// the oldest batch was dropped
+		assert.Len(t, w.queue, 2)
+		assert.EqualValues(t, 1, w.stats.DroppedSpans.Load())
+		w.Stop()
+		assert.Equal(t, []int{2, 3}, exporter.exportedSpans())
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		unavailable := &retriableError{errors.New("unavailable")}
+		exporter := &mockOTLPExporter{errs: []error{unavailable, unavailable, unavailable}}
+		w := newTestOTLPTraceWriter(exporter, 10)
+		w.WriteChunks(testSampledChunks(1))
+		w.flush()
+		w.WriteChunks(testSampledChunks(2))
+		// the queued batch fails again, the buffered one is dropped without being attempted
+		w.Stop()
+		assert.Empty(t, exporter.exportedSpans())
+		assert.Len(t, exporter.errs, 1)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		var w *OTLPTraceWriter
+		assert.NotPanics(t, func() {
+			w.WriteChunks(testSampledChunks(1))
+			w.Stop()
+		})
+	})
+}
+
+func TestNewOTLPTraceWriter(t *testing.T) {
+	cfg := config.New()
+	w, err := NewOTLPTraceWriter(cfg, &statsd.NoOpClient{}, &timing.NoopReporter{})
+	assert.NoError(t, err)
+	assert.Nil(t, w)
+
+	cfg.OTLPExport.Enabled = true
+	cfg.OTLPExport.Protocol = "thrift"
+	_, err = NewOTLPTraceWriter(cfg, &statsd.NoOpClient{}, &timing.NoopReporter{})
+	assert.EqualError(t, err, `unknown protocol "thrift", it must be "grpc" or "http"`)
+
+	cfg.OTLPExport.Protocol = config.OTLPExportProtocolHTTP
+	cfg.OTLPExport.Endpoint = "collector:4318"
+	_, err = NewOTLPTraceWriter(cfg, &statsd.NoOpClient{}, &timing.NoopReporter{})
+	assert.EqualError(t, err, `invalid OTLP/HTTP endpoint "collector:4318": it must be an http or https URL`)
+}
+
+// testOTLPGRPCServer is an OTLP/gRPC server recording the export requests.
+type testOTLPGRPCServer struct {
+	ptraceotlp.UnimplementedGRPCServer
+	err  error
+	reqs chan ptraceotlp.ExportRequest
+	md   chan metadata.MD
+}
+
+func (s *testOTLPGRPCServer) Export(ctx context.Context, req ptraceotlp.ExportRequest) (ptraceotlp.ExportResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.md <- md
+	s.reqs <- req
+	return ptraceotlp.NewExportResponse(), s.err
+}
+
+func TestGRPCOTLPExporter(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &testOTLPGRPCServer{reqs: make(chan ptraceotlp.ExportRequest, 2), md: make(chan metadata.MD, 2)}
+	gsrv := grpc.NewServer()
+	ptraceotlp.RegisterGRPCServer(gsrv, srv)
+	go gsrv.Serve(ln)
+	defer gsrv.Stop()
+
+	e, err := newGRPCOTLPExporter(&config.OTLPExport{Endpoint: ln.Addr().String(), Insecure: true, Headers: map[string]string{"x-api-key": "secret"}}, "test")
+	require.NoError(t, err)
+	defer e.close()
+
+	td, _ := otlpTraces(testSampledChunks(2).TracerPayload, "host", "prod")
+	rejected, err := e.export(context.Background(), td)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, rejected)
+	assert.Equal(t, 2, (<-srv.reqs).Traces().SpanCount())
+	assert.Equal(t, []string{"secret"}, (<-srv.md).Get("x-api-key"))
+
+	var rerr *retriableError
+	srv.err = status.Error(codes.Unavailable, "overloaded")
+	_, err = e.export(context.Background(), td)
+	assert.True(t, errors.As(err, &rerr), err)
+	srv.err = status.Error(codes.InvalidArgument, "invalid")
+	_, err = e.export(context.Background(), td)
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &rerr), err)
+}
+
+func TestHTTPOTLPExporter(t *testing.T) {
+	code := http.StatusOK
+	reqs := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reqs <- r
+		bodies <- body
+		w.WriteHeader(code)
+		if code == http.StatusOK {
+			resp := ptraceotlp.NewExportResponse()
+			resp.PartialSuccess().SetRejectedSpans(1)
+			out, _ := resp.MarshalProto()
+			w.Write(out)
+		}
+	}))
+	defer srv.Close()
+
+	e, err := newHTTPOTLPExporter(&config.OTLPExport{Endpoint: srv.URL, Headers: map[string]string{"X-Api-Key": "secret"}}, "test")
+	require.NoError(t, err)
+	defer e.close()
+
+	td, _ := otlpTraces(testSampledChunks(2).TracerPayload, "host", "prod")
+	rejected, err := e.export(context.Background(), td)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, rejected)
+	req := <-reqs
+	assert.Equal(t, "/v1/traces", req.URL.Path)
+	assert.Equal(t, "application/x-protobuf", req.Header.Get("Content-Type"))
+	assert.Equal(t, "secret", req.Header.Get("X-Api-Key"))
+	exported := ptraceotlp.NewExportRequest()
+	require.NoError(t, exported.UnmarshalProto(<-bodies))
+	assert.Equal(t, 2, exported.Traces().SpanCount())
+
+	var rerr *retriableError
+	code = http.StatusServiceUnavailable
+	_, err = e.export(context.Background(), td)
+	<-reqs
+	<-bodies
+	assert.True(t, errors.As(err, &rerr), err)
+	code = http.StatusBadRequest
+	_, err = e.export(context.Background(), td)
+	<-reqs
+	<-bodies
+	assert.EqualError(t, err, "400 Bad Request")
+}
@@ -0,0 +1,303 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/collector/semconv/v1.6.1"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+)
+
+const (
+	tagTraceIDHigh      = "_dd.p.tid"
+	tagSamplingPriority = "_sampling_priority_v1"
+	tagSpanLinks        = "_dd.span_links"
+	tagEvents           = "events"
+	tagSpanKind         = "span.kind"
+	tagOTelTraceID      = "otel.trace_id"
+	tagTraceState       = "w3c.tracestate"
+)
+
+// otlpSkippedMeta are the span tags which aren't converted to OTLP attributes, because
+// they are converted to the fields of the OTLP spans, or of their resource and scope.
+var otlpSkippedMeta = map[string]bool{
+	"env":                         true,
+	"version":                     true,
+	tagTraceIDHigh:                true,
+	tagSpanLinks:                  true,
+	tagEvents:                     true,
+	tagSpanKind:                   true,
+	tagOTelTraceID:                true,
+	tagTraceState:                 true,
+	semconv.OtelLibraryName:       true,
+	semconv.OtelLibraryVersion:    true,
+	semconv.OtelStatusCode:        true,
+	semconv.OtelStatusDescription: true,
+	semconv.AttributeServiceName:  true,
+}
+
+var otlpSpanKinds = map[string]ptrace.SpanKind{
+	"internal": ptrace.SpanKindInternal,
+	"server":   ptrace.SpanKindServer,
+	"client":   ptrace.SpanKindClient,
+	"producer": ptrace.SpanKindProducer,
+	"consumer": ptrace.SpanKindConsumer,
+}
+
+// otlpScopeKey identifies the OTLP resource and instrumentation scope of a span.
+type otlpScopeKey struct {
+	service, env, version string
+	scope, scopeVersion   string
+}
+
+// otlpTraces converts the chunks of the tracer payload to OTLP spans, grouped by service,
+// env, version and instrumentation scope. It returns the number of spans converted. The
+// dropped chunks, whose spans are only kept for the events and the single span sampling,
+// aren't converted. The hostname and env are used when the payload doesn't specify them.
+func otlpTraces(p *pb.TracerPayload, hostname, env string) (ptrace.Traces, int) {
+	td := ptrace.NewTraces()
+	if p.Hostname != "" {
+		hostname = p.Hostname
+	}
+	if p.Env != "" {
+		env = p.Env
+	}
+	resources := make(map[otlpScopeKey]ptrace.ResourceSpans)
+	scopes := make(map[otlpScopeKey]ptrace.SpanSlice)
+	var n int
+	for _, chunk := range p.Chunks {
+		if chunk.DroppedTrace {
+			continue
+		}
+		high := chunkTraceIDHigh(chunk)
+		for _, s := range chunk.Spans {
+			key := otlpScopeKey{service: s.Service, env: env, version: p.AppVersion}
+			if v := s.Meta["env"]; v != "" {
+				key.env = v
+			}
+			if v := s.Meta["version"]; v != "" {
+				key.version = v
+			}
+			rkey := key
+			key.scope, key.scopeVersion = s.Meta[semconv.OtelLibraryName], s.Meta[semconv.OtelLibraryVersion]
+			spans, ok := scopes[key]
+			if !ok {
+				rs, ok := resources[rkey]
+				if !ok {
+					rs = td.ResourceSpans().AppendEmpty()
+					setOTLPResource(rs.Resource(), p, rkey, hostname)
+					resources[rkey] = rs
+				}
+				ss := rs.ScopeSpans().AppendEmpty()
+				ss.Scope().SetName(key.scope)
+				ss.Scope().SetVersion(key.scopeVersion)
+				spans = ss.Spans()
+				scopes[key] = spans
+			}
+			convertOTLPSpan(s, high, spans.AppendEmpty())
+			n++
+		}
+	}
+	return td, n
+}
+
+func setOTLPResource(r pcommon.Resource, p *pb.TracerPayload, rkey otlpScopeKey, hostname string) {
+	attrs := r.Attributes()
+	attrs.PutStr(semconv.AttributeServiceName, rkey.service)
+	putNonEmpty := func(k, v string) {
+		if v != "" {
+			attrs.PutStr(k, v)
+		}
+	}
+	putNonEmpty(semconv.AttributeDeploymentEnvironment, rkey.env)
+	putNonEmpty(semconv.AttributeServiceVersion, rkey.version)
+	putNonEmpty(semconv.AttributeHostName, hostname)
+	putNonEmpty(semconv.AttributeContainerID, p.ContainerID)
+	putNonEmpty(semconv.AttributeTelemetrySDKLanguage, p.LanguageName)
+	putNonEmpty(semconv.AttributeTelemetrySDKVersion, p.TracerVersion)
+}
+
+// chunkTraceIDHigh returns the high 64 bits of the 128 bits trace ID of the chunk, if any.
+func chunkTraceIDHigh(chunk *pb.TraceChunk) uint64 {
+	v, ok := chunk.Tags[tagTraceIDHigh]
+	for i := 0; !ok && i < len(chunk.Spans); i++ {
+		v, ok = chunk.Spans[i].Meta[tagTraceIDHigh]
+	}
+	if !ok {
+		return 0
+	}
+	high, err := strconv.ParseUint(v, 16, 64)
+	if err != nil {
+		return 0
+	}
+	return high
+}
+
+func otlpTraceID(high, low uint64) pcommon.TraceID {
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], high)
+	binary.BigEndian.PutUint64(id[8:], low)
+	return id
+}
+
+func otlpSpanID(id uint64) pcommon.SpanID {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], id)
+	return b
+}
+
+// convertOTLPSpan converts the span to OTLP. It reverses the conversion of the spans
+// received through OTLP, so that their tags are converted back to the original fields.
+func convertOTLPSpan(s *pb.Span, traceIDHigh uint64, out ptrace.Span) {
+	out.SetTraceID(otlpTraceID(traceIDHigh, s.TraceID))
+	if v, ok := s.Meta[tagOTelTraceID]; ok {
+		if id, err := hex.DecodeString(v); err == nil && len(id) == 16 {
+			out.SetTraceID(pcommon.TraceID(id))
+		}
+	}
+	out.SetSpanID(otlpSpanID(s.SpanID))
+	if s.ParentID != 0 {
+		out.SetParentSpanID(otlpSpanID(s.ParentID))
+	}
+	// the OTLP span name is the equivalent of the resource name
+	if s.Resource != "" {
+		out.SetName(s.Resource)
+	} else {
+		out.SetName(s.Name)
+	}
+	out.SetKind(otlpSpanKinds[s.Meta[tagSpanKind]])
+	out.SetStartTimestamp(pcommon.Timestamp(s.Start))
+	out.SetEndTimestamp(pcommon.Timestamp(s.Start + s.Duration))
+	if v := s.Meta[tagTraceState]; v != "" {
+		out.TraceState().FromRaw(v)
+	}
+
+	attrs := out.Attributes()
+	attrs.EnsureCapacity(len(s.Meta) + len(s.Metrics) + 3)
+	attrs.PutStr("operation.name", s.Name)
+	attrs.PutStr("resource.name", s.Resource)
+	if s.Type != "" {
+		attrs.PutStr("span.type", s.Type)
+	}
+	for k, v := range s.Meta {
+		if !otlpSkippedMeta[k] {
+			attrs.PutStr(k, v)
+		}
+	}
+	for k, v := range s.Metrics {
+		if k == tagSamplingPriority {
+			attrs.PutInt("sampling.priority", int64(v))
+			continue
+		}
+		attrs.PutDouble(k, v)
+	}
+
+	if s.Error != 0 {
+		out.Status().SetCode(ptrace.StatusCodeError)
+		msg := s.Meta[semconv.OtelStatusDescription]
+		if msg == "" {
+			msg = s.Meta["error.msg"]
+		}
+		out.Status().SetMessage(msg)
+	} else if s.Meta[semconv.OtelStatusCode] == ptrace.StatusCodeOk.String() {
+		out.Status().SetCode(ptrace.StatusCodeOk)
+	}
+
+	if v, ok := s.Meta[tagEvents]; ok && !convertOTLPEvents(v, out.Events()) {
+		attrs.PutStr(tagEvents, v)
+	}
+	if len(s.SpanLinks) > 0 {
+		convertOTLPLinks(s.SpanLinks, out.Links())
+	} else if v, ok := s.Meta[tagSpanLinks]; ok && !convertOTLPJSONLinks(v, out.Links()) {
+		attrs.PutStr(tagSpanLinks, v)
+	}
+}
+
+// convertOTLPEvents converts the span events encoded in JSON by the OTLP receiver. It
+// reports whether they could be decoded.
+func convertOTLPEvents(v string, out ptrace.SpanEventSlice) bool {
+	var events []struct {
+		TimeUnixNano uint64                 `json:"time_unix_nano"`
+		Name         string                 `json:"name"`
+		Attributes   map[string]interface{} `json:"attributes"`
+		Dropped      uint32                 `json:"dropped_attributes_count"`
+	}
+	if err := json.Unmarshal([]byte(v), &events); err != nil {
+		return false
+	}
+	out.EnsureCapacity(len(events))
+	for _, e := range events {
+		oe := out.AppendEmpty()
+		oe.SetTimestamp(pcommon.Timestamp(e.TimeUnixNano))
+		oe.SetName(e.Name)
+		oe.SetDroppedAttributesCount(e.Dropped)
+		// JSON values are all supported by FromRaw
+		_ = oe.Attributes().FromRaw(e.Attributes)
+	}
+	return true
+}
+
+func convertOTLPLinks(links []*pb.SpanLink, out ptrace.SpanLinkSlice) {
+	out.EnsureCapacity(len(links))
+	for _, l := range links {
+		ol := out.AppendEmpty()
+		ol.SetTraceID(otlpTraceID(l.TraceIDHigh, l.TraceID))
+		ol.SetSpanID(otlpSpanID(l.SpanID))
+		ol.TraceState().FromRaw(l.Tracestate)
+		// the high bit only reports whether the W3C trace flags are set
+		ol.SetFlags(l.Flags & 0xff)
+		attrs := ol.Attributes()
+		attrs.EnsureCapacity(len(l.Attributes))
+		for k, v := range l.Attributes {
+			attrs.PutStr(k, v)
+		}
+	}
+}
+
+// convertOTLPJSONLinks converts the span links encoded in JSON by the OTLP receiver. It
+// reports whether they could be decoded.
+func convertOTLPJSONLinks(v string, out ptrace.SpanLinkSlice) bool {
+	var links []struct {
+		TraceID    string            `json:"trace_id"`
+		SpanID     string            `json:"span_id"`
+		TraceState string            `json:"trace_state"`
+		Attributes map[string]string `json:"attributes"`
+		Dropped    uint32            `json:"dropped_attributes_count"`
+	}
+	if err := json.Unmarshal([]byte(v), &links); err != nil {
+		return false
+	}
+	traceIDs := make([][]byte, len(links))
+	spanIDs := make([][]byte, len(links))
+	for i, l := range links {
+		var err error
+		if traceIDs[i], err = hex.DecodeString(l.TraceID); err != nil || len(traceIDs[i]) != 16 {
+			return false
+		}
+		if spanIDs[i], err = hex.DecodeString(l.SpanID); err != nil || len(spanIDs[i]) != 8 {
+			return false
+		}
+	}
+	out.EnsureCapacity(len(links))
+	for i, l := range links {
+		ol := out.AppendEmpty()
+		ol.SetTraceID(pcommon.TraceID(traceIDs[i]))
+		ol.SetSpanID(pcommon.SpanID(spanIDs[i]))
+		ol.TraceState().FromRaw(l.TraceState)
+		ol.SetDroppedAttributesCount(l.Dropped)
+		for k, v := range l.Attributes {
+			ol.Attributes().PutStr(k, v)
+		}
+	}
+	return true
+}
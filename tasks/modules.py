@@ -233,6 +233,7 @@ DEFAULT_MODULES = {
     "pkg/logs/sources": GoModule("pkg/logs/sources", independent=True, used_by_otel=True),
     "pkg/logs/status/statusinterface": GoModule("pkg/logs/status/statusinterface", independent=True, used_by_otel=True),
     "pkg/logs/status/utils": GoModule("pkg/logs/status/utils", independent=True, used_by_otel=True),
+    "pkg/logs/util/logfmt": GoModule("pkg/logs/util/logfmt", independent=True, used_by_otel=True),
     "pkg/logs/util/testutils": GoModule("pkg/logs/util/testutils", independent=True, used_by_otel=True),
     "pkg/metrics": GoModule("pkg/metrics", independent=True, used_by_otel=True),
     "pkg/networkdevice/profile": GoModule("pkg/networkdevice/profile", independent=True),
@@ -8,6 +8,9 @@ package main
 import (
 	"bytes"
 	"compress/zlib"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -20,27 +23,41 @@ import (
 	proto "github.com/golang/protobuf/proto"
 )
 
+// encryptedFileMagic starts the retry files encrypted by the Agent, followed by
+// the version of the format, the ID of the key and the nonce.
+var encryptedFileMagic = []byte("\xfeENC")
+
+const encryptedFileHeaderSize = 4 + 1 + 8
+
 func main() {
-	folder, err := parseArg()
+	folder, key, err := parseArg()
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	if err = dumpRetryFiles(folder); err != nil {
+	if err = dumpRetryFiles(folder, key); err != nil {
 		fmt.Println(err)
 	}
 }
 
-func parseArg() (string, error) {
+func parseArg() (string, []byte, error) {
 	var folder = flag.String("folder", "", "The folder containing `.retry` files.")
+	var encodedKey = flag.String("key", "", "The base64 encoded key used to encrypt the `.retry` files, if any.")
 	flag.Parse()
 	if *folder == "" {
-		return "", errors.New("Invalid folder: Usage `./retry_file_dump --folder=/opt/datadog-agent/run/transactions_to_retry/c47da40ac935c8fd5ca1441a5ee3d068/`")
+		return "", nil, errors.New("Invalid folder: Usage `./retry_file_dump --folder=/opt/datadog-agent/run/transactions_to_retry/c47da40ac935c8fd5ca1441a5ee3d068/`")
 	}
-	return *folder, nil
+	var key []byte
+	if *encodedKey != "" {
+		var err error
+		if key, err = base64.StdEncoding.DecodeString(*encodedKey); err != nil {
+			return "", nil, fmt.Errorf("Invalid key: %v", err)
+		}
+	}
+	return *folder, key, nil
 }
 
-func dumpRetryFiles(folder string) error {
+func dumpRetryFiles(folder string, key []byte) error {
 	entries, err := os.ReadDir(folder)
 	if err != nil {
 		return err
@@ -50,7 +67,7 @@ func dumpRetryFiles(folder string) error {
 		if entry.Type().IsRegular() && filepath.Ext(entry.Name()) == ".retry" {
 			fmt.Println(entry.Name())
 			filePath := path.Join(folder, entry.Name())
-			fileContent, err := dumpRetryFile(filePath)
+			fileContent, err := dumpRetryFile(filePath, key)
 			if err != nil {
 				return err
 			}
@@ -64,11 +81,16 @@ func dumpRetryFiles(folder string) error {
 	return nil
 }
 
-func dumpRetryFile(file string) ([]byte, error) {
+func dumpRetryFile(file string, key []byte) ([]byte, error) {
 	content, err := os.ReadFile(file)
 	if err != nil {
 		return nil, err
 	}
+	if bytes.HasPrefix(content, encryptedFileMagic) {
+		if content, err = decryptRetryFile(content, key); err != nil {
+			return nil, err
+		}
+	}
 	collection := HttpTransactionProtoCollection{}
 
 	if err := proto.Unmarshal(content, &collection); err != nil {
@@ -90,6 +112,25 @@ func dumpRetryFile(file string) ([]byte, error) {
 	return json.MarshalIndent(jsonTrs, "", "  ")
 }
 
+func decryptRetryFile(content []byte, key []byte) ([]byte, error) {
+	if key == nil {
+		return nil, errors.New("the file is encrypted, use --key to decrypt it")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) < encryptedFileHeaderSize+aead.NonceSize() {
+		return nil, errors.New("truncated encrypted file")
+	}
+	nonce := content[encryptedFileHeaderSize : encryptedFileHeaderSize+aead.NonceSize()]
+	return aead.Open(nil, nonce, content[encryptedFileHeaderSize+aead.NonceSize():], content[:encryptedFileHeaderSize])
+}
+
 // JSONHTTPTransaction is a transaction object with a string-type Payload associated
 // with it
 type JSONHTTPTransaction struct {